@@ -17,6 +17,11 @@ type Node interface {
 	String() string
 }
 
+// SpanOf returns the source range covered by a node.
+func SpanOf(node Node) lexer.Span {
+	return lexer.Span{Start: node.Pos(), End: node.End()}
+}
+
 // Expression represents all expression nodes.
 type Expression interface {
 	Node
@@ -282,6 +287,7 @@ type CallExpression struct {
 	LParen    lexer.Position // position of '('
 	Arguments []Expression   // arguments
 	RParen    lexer.Position // position of ')'
+	Optional  bool           // true for f?.(), which skips the call when f is null or undefined
 }
 
 func (ce *CallExpression) Pos() lexer.Position { return ce.Callee.Pos() }
@@ -302,6 +308,9 @@ func (ce *CallExpression) String() string {
 		}
 		args = append(args, argStr)
 	}
+	if ce.Optional {
+		return ce.Callee.String() + "?.(" + strings.Join(args, ", ") + ")"
+	}
 	return ce.Callee.String() + "(" + strings.Join(args, ", ") + ")"
 }
 func (ce *CallExpression) expressionNode() {}
@@ -314,6 +323,7 @@ type MemberExpression struct {
 	LBracket lexer.Position // position of '[' (if computed)
 	RBracket lexer.Position // position of ']' (if computed)
 	Dot      lexer.Position // position of '.' (if not computed)
+	Optional bool           // true for obj?.prop and obj?.[prop], which skip the access when obj is null or undefined
 }
 
 func (me *MemberExpression) Pos() lexer.Position { return me.Object.Pos() }
@@ -328,13 +338,41 @@ func (me *MemberExpression) End() lexer.Position {
 	return me.Property.End()
 }
 func (me *MemberExpression) String() string {
+	optional := ""
+	if me.Optional {
+		optional = "?."
+	}
 	if me.Computed {
-		return me.Object.String() + "[" + me.Property.String() + "]"
+		return me.Object.String() + optional + "[" + me.Property.String() + "]"
+	}
+	if me.Optional {
+		return me.Object.String() + "?." + me.Property.String()
 	}
 	return me.Object.String() + "." + me.Property.String()
 }
 func (me *MemberExpression) expressionNode() {}
 
+// IsOptionalChain reports whether expr is a chain of member accesses and
+// calls with a ?. link, such as a.b?.c().
+func IsOptionalChain(expr Expression) bool {
+	for {
+		switch e := expr.(type) {
+		case *MemberExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Object
+		case *CallExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Callee
+		default:
+			return false
+		}
+	}
+}
+
 // ConditionalExpression represents a ternary conditional expression (test ? consequent : alternate).
 type ConditionalExpression struct {
 	Test       Expression     // condition
@@ -351,6 +389,25 @@ func (ce *ConditionalExpression) String() string {
 }
 func (ce *ConditionalExpression) expressionNode() {}
 
+// SequenceExpression represents comma-separated expressions evaluated in
+// order (a, b); its value is the last expression.
+type SequenceExpression struct {
+	Expressions []Expression // expressions in evaluation order
+}
+
+func (se *SequenceExpression) Pos() lexer.Position { return se.Expressions[0].Pos() }
+func (se *SequenceExpression) End() lexer.Position {
+	return se.Expressions[len(se.Expressions)-1].End()
+}
+func (se *SequenceExpression) String() string {
+	var parts []string
+	for _, expr := range se.Expressions {
+		parts = append(parts, expr.String())
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+func (se *SequenceExpression) expressionNode() {}
+
 // ============================================================================
 // ARRAY AND OBJECT LITERALS
 // ============================================================================
@@ -385,7 +442,7 @@ func (al *ArrayLiteral) expressionNode() {}
 
 // Property represents a property in an object literal.
 type Property struct {
-	Key       Expression     // property key
+	Key       Expression     // property key (nil for spread properties)
 	Colon     lexer.Position // position of ':'
 	Value     Expression     // property value
 	Computed  bool           // true for [key]: value
@@ -393,16 +450,30 @@ type Property struct {
 	Shorthand bool           // true for {x} shorthand
 }
 
-func (p *Property) Pos() lexer.Position { return p.Key.Pos() }
+func (p *Property) Pos() lexer.Position {
+	if p.Key == nil {
+		return p.Value.Pos()
+	}
+	return p.Key.Pos()
+}
 func (p *Property) End() lexer.Position { return p.Value.End() }
 func (p *Property) String() string {
+	if p.Key == nil {
+		// Spread property: { ...value }
+		return p.Value.String()
+	}
 	if p.Shorthand {
 		return p.Key.String()
 	}
+	key := p.Key.String()
 	if p.Computed {
-		return "[" + p.Key.String() + "]: " + p.Value.String()
+		key = "[" + key + "]"
 	}
-	return p.Key.String() + ": " + p.Value.String()
+	if fn, ok := p.Value.(*FunctionExpression); ok && p.Method {
+		// key(params) { body }, without the 'function' keyword
+		return key + strings.TrimPrefix(fn.String(), "function")
+	}
+	return key + ": " + p.Value.String()
 }
 
 // ObjectLiteral represents an object literal.
@@ -428,3 +499,137 @@ func (ol *ObjectLiteral) String() string {
 	return "{" + strings.Join(props, ", ") + "}"
 }
 func (ol *ObjectLiteral) expressionNode() {}
+
+// SpreadElement represents ...expr in an array literal, object literal or
+// argument list.
+type SpreadElement struct {
+	Ellipsis lexer.Position // position of '...'
+	Argument Expression     // expression being spread
+}
+
+func (se *SpreadElement) Pos() lexer.Position { return se.Ellipsis }
+func (se *SpreadElement) End() lexer.Position { return se.Argument.End() }
+func (se *SpreadElement) String() string      { return "..." + se.Argument.String() }
+func (se *SpreadElement) expressionNode()     {}
+
+// ============================================================================
+// DESTRUCTURING PATTERNS
+// ============================================================================
+
+// ArrayPattern represents an array destructuring pattern.
+type ArrayPattern struct {
+	LBracket lexer.Position  // position of '['
+	Elements []BindingTarget // element targets (nil for holes)
+	RBracket lexer.Position  // position of ']'
+}
+
+func (ap *ArrayPattern) Pos() lexer.Position { return ap.LBracket }
+func (ap *ArrayPattern) End() lexer.Position {
+	return lexer.Position{
+		Line:   ap.RBracket.Line,
+		Column: ap.RBracket.Column + 1,
+		Offset: ap.RBracket.Offset + 1,
+	}
+}
+func (ap *ArrayPattern) String() string {
+	var elements []string
+	for _, elem := range ap.Elements {
+		if elem == nil {
+			elements = append(elements, "")
+		} else {
+			elements = append(elements, elem.String())
+		}
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+func (ap *ArrayPattern) expressionNode() {}
+func (ap *ArrayPattern) bindingTarget()  {}
+func (ap *ArrayPattern) pattern()        {}
+
+// PatternProperty represents a property in an object destructuring pattern.
+type PatternProperty struct {
+	Key       Expression    // property key
+	Value     BindingTarget // target bound to the property value
+	Computed  bool          // true for [key]: target
+	Shorthand bool          // true for {x} and {x = default}
+}
+
+func (pp *PatternProperty) Pos() lexer.Position { return pp.Key.Pos() }
+func (pp *PatternProperty) End() lexer.Position { return pp.Value.End() }
+func (pp *PatternProperty) String() string {
+	if pp.Shorthand {
+		return pp.Value.String()
+	}
+	if pp.Computed {
+		return "[" + pp.Key.String() + "]: " + pp.Value.String()
+	}
+	return pp.Key.String() + ": " + pp.Value.String()
+}
+
+// ObjectPattern represents an object destructuring pattern.
+type ObjectPattern struct {
+	LBrace     lexer.Position     // position of '{'
+	Properties []*PatternProperty // pattern properties
+	RBrace     lexer.Position     // position of '}'
+}
+
+func (op *ObjectPattern) Pos() lexer.Position { return op.LBrace }
+func (op *ObjectPattern) End() lexer.Position {
+	return lexer.Position{
+		Line:   op.RBrace.Line,
+		Column: op.RBrace.Column + 1,
+		Offset: op.RBrace.Offset + 1,
+	}
+}
+func (op *ObjectPattern) String() string {
+	var props []string
+	for _, prop := range op.Properties {
+		props = append(props, prop.String())
+	}
+	return "{" + strings.Join(props, ", ") + "}"
+}
+func (op *ObjectPattern) expressionNode() {}
+func (op *ObjectPattern) bindingTarget()  {}
+func (op *ObjectPattern) pattern()        {}
+
+// AssignmentPattern represents a pattern element with a default value,
+// used when the destructured value is null or undefined.
+type AssignmentPattern struct {
+	Left   BindingTarget  // target bound to the value
+	Assign lexer.Position // position of '='
+	Right  Expression     // default value
+}
+
+func (ap *AssignmentPattern) Pos() lexer.Position { return ap.Left.Pos() }
+func (ap *AssignmentPattern) End() lexer.Position { return ap.Right.End() }
+func (ap *AssignmentPattern) String() string {
+	return ap.Left.String() + " = " + ap.Right.String()
+}
+func (ap *AssignmentPattern) expressionNode() {}
+func (ap *AssignmentPattern) bindingTarget()  {}
+func (ap *AssignmentPattern) pattern()        {}
+
+// BindingNames returns the identifiers bound by target, in source order.
+func BindingNames(target BindingTarget) []*Identifier {
+	switch t := target.(type) {
+	case *Identifier:
+		return []*Identifier{t}
+	case *AssignmentPattern:
+		return BindingNames(t.Left)
+	case *ArrayPattern:
+		var names []*Identifier
+		for _, elem := range t.Elements {
+			if elem != nil {
+				names = append(names, BindingNames(elem)...)
+			}
+		}
+		return names
+	case *ObjectPattern:
+		var names []*Identifier
+		for _, prop := range t.Properties {
+			names = append(names, BindingNames(prop.Value)...)
+		}
+		return names
+	}
+	return nil
+}
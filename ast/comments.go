@@ -0,0 +1,89 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/xingleixu/TG-Script/lexer"
+)
+
+// Comment represents a single // or /* */ comment.
+type Comment struct {
+	Slash lexer.Position // position of the leading '/'
+	Text  string         // comment text including the comment markers
+}
+
+func (c *Comment) Pos() lexer.Position { return c.Slash }
+func (c *Comment) End() lexer.Position {
+	lines := strings.Count(c.Text, "\n")
+	if lines == 0 {
+		return lexer.Position{
+			Line:   c.Slash.Line,
+			Column: c.Slash.Column + len(c.Text),
+			Offset: c.Slash.Offset + len(c.Text),
+		}
+	}
+	return lexer.Position{
+		Line:   c.Slash.Line + lines,
+		Column: len(c.Text) - strings.LastIndex(c.Text, "\n"),
+		Offset: c.Slash.Offset + len(c.Text),
+	}
+}
+func (c *Comment) String() string { return c.Text }
+
+// Content returns the comment text without comment markers.
+// Leading '*' decorations of block comment lines are removed as well.
+func (c *Comment) Content() string {
+	if strings.HasPrefix(c.Text, "//") {
+		return strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+	}
+	
+	text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "*"))
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// LeadingComments returns the comments directly preceding node, without a
+// blank line between them or between the last comment and the node.
+// Comments trailing code on the same line are not included.
+func (p *Program) LeadingComments(node Node) []*Comment {
+	line := node.Pos().Line
+	end := -1
+	for i, comment := range p.Comments {
+		if comment.Pos().Line >= line {
+			break
+		}
+		if comment.End().Line == line-1 && !p.isTrailingComment(comment) {
+			end = i
+		}
+	}
+	if end < 0 {
+		return nil
+	}
+	
+	start := end
+	for start > 0 {
+		prev := p.Comments[start-1]
+		if prev.End().Line < p.Comments[start].Pos().Line-1 || p.isTrailingComment(prev) {
+			break
+		}
+		start--
+	}
+	return p.Comments[start : end+1]
+}
+
+// isTrailingComment reports whether a top-level statement ends on the line
+// where the comment starts, before the comment.
+func (p *Program) isTrailingComment(comment *Comment) bool {
+	for _, stmt := range p.Body {
+		end := stmt.End()
+		if end.Line == comment.Pos().Line && end.Offset <= comment.Pos().Offset {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xingleixu/TG-Script/lexer"
+)
+
+// ChangeKind describes how a node differs between two trees.
+type ChangeKind int
+
+const (
+	// NodeAdded means the node only exists in the new tree.
+	NodeAdded ChangeKind = iota
+	// NodeRemoved means the node only exists in the old tree.
+	NodeRemoved
+	// NodeChanged means the node exists in both trees but differs.
+	NodeChanged
+)
+
+// String returns a human-readable name for the change kind.
+func (k ChangeKind) String() string {
+	switch k {
+	case NodeAdded:
+		return "added"
+	case NodeRemoved:
+		return "removed"
+	case NodeChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a single structural difference between two trees.
+type Change struct {
+	Kind ChangeKind
+	Old  Node           // node in the old tree (nil for NodeAdded)
+	New  Node           // node in the new tree (nil for NodeRemoved)
+	Pos  lexer.Position // position of the change in the tree it belongs to
+}
+
+// String returns a string representation of the change.
+func (c Change) String() string {
+	switch c.Kind {
+	case NodeAdded:
+		return fmt.Sprintf("%d:%d: added %s", c.Pos.Line, c.Pos.Column, c.New.String())
+	case NodeRemoved:
+		return fmt.Sprintf("%d:%d: removed %s", c.Pos.Line, c.Pos.Column, c.Old.String())
+	default:
+		return fmt.Sprintf("%d:%d: changed %s -> %s", c.Pos.Line, c.Pos.Column, c.Old.String(), c.New.String())
+	}
+}
+
+// Diff reports the structural differences between two trees.
+// Positions are ignored when comparing, so moving code around without
+// modifying it produces no changes. Each change refers to the innermost
+// node that differs.
+func Diff(a, b Node) []Change {
+	var changes []Change
+	diffNodes(a, b, &changes)
+	return changes
+}
+
+// Equal reports whether two trees are structurally identical.
+func Equal(a, b Node) bool {
+	return len(Diff(a, b)) == 0
+}
+
+var (
+	nodeType     = reflect.TypeOf((*Node)(nil)).Elem()
+	positionType = reflect.TypeOf(lexer.Position{})
+)
+
+// diffNodes compares two nodes and appends the differences to changes.
+func diffNodes(a, b Node, changes *[]Change) {
+	aNil, bNil := isNilNode(a), isNilNode(b)
+	switch {
+	case aNil && bNil:
+		return
+	case aNil:
+		*changes = append(*changes, Change{Kind: NodeAdded, New: b, Pos: b.Pos()})
+		return
+	case bNil:
+		*changes = append(*changes, Change{Kind: NodeRemoved, Old: a, Pos: a.Pos()})
+		return
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		*changes = append(*changes, Change{Kind: NodeChanged, Old: a, New: b, Pos: b.Pos()})
+		return
+	}
+
+	for va.Kind() == reflect.Ptr {
+		va, vb = va.Elem(), vb.Elem()
+	}
+	if va.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(va.Interface(), vb.Interface()) {
+			*changes = append(*changes, Change{Kind: NodeChanged, Old: a, New: b, Pos: b.Pos()})
+		}
+		return
+	}
+
+	// A node whose own attributes differ is reported as a whole
+	if !sameAttributes(va, vb) {
+		*changes = append(*changes, Change{Kind: NodeChanged, Old: a, New: b, Pos: b.Pos()})
+		return
+	}
+
+	// Otherwise descend into the children
+	for i := 0; i < va.NumField(); i++ {
+		fa, fb := va.Field(i), vb.Field(i)
+		switch {
+		case isNodeType(fa.Type()):
+			diffNodes(nodeOf(fa), nodeOf(fb), changes)
+		case fa.Kind() == reflect.Slice && isNodeType(fa.Type().Elem()):
+			diffNodeLists(nodeList(fa), nodeList(fb), changes)
+		}
+	}
+}
+
+// diffNodeLists compares two lists of nodes. Unchanged nodes at the start
+// and end of the lists are skipped, so inserting or removing a single
+// element only reports that element.
+func diffNodeLists(a, b []Node, changes *[]Change) {
+	start := 0
+	for start < len(a) && start < len(b) && Equal(a[start], b[start]) {
+		start++
+	}
+
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && Equal(a[endA-1], b[endB-1]) {
+		endA--
+		endB--
+	}
+
+	i, j := start, start
+	for i < endA && j < endB {
+		diffNodes(a[i], b[j], changes)
+		i++
+		j++
+	}
+	for ; i < endA; i++ {
+		diffNodes(a[i], nil, changes)
+	}
+	for ; j < endB; j++ {
+		diffNodes(nil, b[j], changes)
+	}
+}
+
+// sameAttributes compares the non-node, non-position fields of two structs.
+func sameAttributes(a, b reflect.Value) bool {
+	for i := 0; i < a.NumField(); i++ {
+		fa, fb := a.Field(i), b.Field(i)
+		t := fa.Type()
+		if t == positionType || isNodeType(t) {
+			continue
+		}
+		if fa.Kind() == reflect.Slice && isNodeType(t.Elem()) {
+			continue
+		}
+		if !a.Type().Field(i).IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNodeType reports whether values of type t are AST nodes.
+func isNodeType(t reflect.Type) bool {
+	return t.Implements(nodeType)
+}
+
+// isNilNode reports whether n is nil or a typed nil pointer.
+func isNilNode(n Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// nodeOf converts a struct field holding a node into a Node.
+func nodeOf(v reflect.Value) Node {
+	if (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && v.IsNil() {
+		return nil
+	}
+	return v.Interface().(Node)
+}
+
+// nodeList converts a slice field holding nodes into a []Node.
+func nodeList(v reflect.Value) []Node {
+	nodes := make([]Node, v.Len())
+	for i := range nodes {
+		nodes[i] = nodeOf(v.Index(i))
+	}
+	return nodes
+}
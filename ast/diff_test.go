@@ -0,0 +1,86 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/xingleixu/TG-Script/ast"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+)
+
+// Helper function to parse a program for diffing
+func parseProgram(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	return program
+}
+
+func TestDiffIdenticalPrograms(t *testing.T) {
+	a := parseProgram(t, "let x = 1;\nlet y = x + 2;")
+	b := parseProgram(t, "let x = 1;\n\n  let y = x + 2;")
+
+	if changes := ast.Diff(a, b); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestDiffChangedStatement(t *testing.T) {
+	a := parseProgram(t, "let x = 1;\nlet y = x + 2;\nprint(y);")
+	b := parseProgram(t, "let x = 1;\nlet y = x * 2;\nprint(y);")
+
+	changes := ast.Diff(a, b)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %v", len(changes), changes)
+	}
+
+	change := changes[0]
+	if change.Kind != ast.NodeChanged {
+		t.Fatalf("expected kind %s, got %s", ast.NodeChanged, change.Kind)
+	}
+
+	if change.Old.String() != "(x + 2)" || change.New.String() != "(x * 2)" {
+		t.Fatalf("wrong nodes reported: %s -> %s", change.Old.String(), change.New.String())
+	}
+
+	if change.Pos.Line != 2 || change.Pos.Column != 9 {
+		t.Fatalf("wrong position. expected 2:9, got %d:%d", change.Pos.Line, change.Pos.Column)
+	}
+}
+
+func TestDiffChangedLiteral(t *testing.T) {
+	a := parseProgram(t, "let x = 1;\nlet y = 2;")
+	b := parseProgram(t, "let x = 1;\nlet y = 3;")
+
+	changes := ast.Diff(a, b)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %v", len(changes), changes)
+	}
+
+	if _, ok := changes[0].New.(*ast.IntegerLiteral); !ok {
+		t.Fatalf("expected changed node to be *ast.IntegerLiteral, got %T", changes[0].New)
+	}
+}
+
+func TestDiffAddedAndRemovedStatements(t *testing.T) {
+	a := parseProgram(t, "let x = 1;\nlet z = 3;")
+	b := parseProgram(t, "let x = 1;\nlet y = 2;\nlet z = 3;")
+
+	changes := ast.Diff(a, b)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %v", len(changes), changes)
+	}
+	if changes[0].Kind != ast.NodeAdded || changes[0].Pos.Line != 2 {
+		t.Fatalf("expected statement added at line 2, got %v", changes[0])
+	}
+
+	changes = ast.Diff(b, a)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %v", len(changes), changes)
+	}
+	if changes[0].Kind != ast.NodeRemoved || changes[0].Old.String() != b.Body[1].String() {
+		t.Fatalf("expected second statement removed, got %v", changes[0])
+	}
+}
@@ -90,6 +90,7 @@ func (fe *FunctionExpression) expressionNode() {}
 type FunctionDeclaration struct {
 	FunctionPos lexer.Position // position of 'function'
 	Name        *Identifier    // function name
+	TypeParameters []*TypeParameter // generic type parameters
 	LParen      lexer.Position // position of '('
 	Parameters  []*Parameter   // parameters
 	RParen      lexer.Position // position of ')'
@@ -112,6 +113,14 @@ func (fd *FunctionDeclaration) String() string {
 	}
 	result += " " + fd.Name.String()
 	
+	if len(fd.TypeParameters) > 0 {
+		var typeParams []string
+		for _, param := range fd.TypeParameters {
+			typeParams = append(typeParams, param.String())
+		}
+		result += "<" + strings.Join(typeParams, ", ") + ">"
+	}
+	
 	var params []string
 	for _, param := range fd.Parameters {
 		params = append(params, param.String())
@@ -179,6 +188,7 @@ type ArrowFunctionParams struct {
 	LParen     lexer.Position // position of '('
 	Parameters []*Parameter   // parameters
 	RParen     lexer.Position // position of ')'
+	ReturnType TypeNode       // return type annotation (optional)
 }
 
 func (afp *ArrowFunctionParams) Pos() lexer.Position { return afp.LParen }
@@ -188,7 +198,11 @@ func (afp *ArrowFunctionParams) String() string {
 	for _, param := range afp.Parameters {
 		params = append(params, param.String())
 	}
-	return "(" + strings.Join(params, ", ") + ")"
+	result := "(" + strings.Join(params, ", ") + ")"
+	if afp.ReturnType != nil {
+		result += ": " + afp.ReturnType.String()
+	}
+	return result
 }
 func (afp *ArrowFunctionParams) expressionNode() {}
 
@@ -205,6 +219,8 @@ type MethodDefinition struct {
 	Computed   bool            // true for computed property names
 	Async      bool            // true for async methods
 	Generator  bool            // true for generator methods
+	Override   bool            // true for methods marked override
+	Modifiers  []*Modifier     // modifiers in source order
 }
 
 func (md *MethodDefinition) Pos() lexer.Position { return md.Key.Pos() }
@@ -214,6 +230,9 @@ func (md *MethodDefinition) String() string {
 	if md.Static {
 		result += "static "
 	}
+	if md.Override {
+		result += "override "
+	}
 	if md.Async {
 		result += "async "
 	}
@@ -256,6 +275,8 @@ type PropertyDefinition struct {
 	Static           bool           // true for static properties
 	Computed         bool           // true for computed property names
 	Readonly         bool           // true for readonly properties
+	Override         bool           // true for properties marked override
+	Modifiers        []*Modifier    // modifiers in source order
 }
 
 func (pd *PropertyDefinition) Pos() lexer.Position { return pd.Key.Pos() }
@@ -273,6 +294,9 @@ func (pd *PropertyDefinition) String() string {
 	if pd.Static {
 		result += "static "
 	}
+	if pd.Override {
+		result += "override "
+	}
 	if pd.Readonly {
 		result += "readonly "
 	}
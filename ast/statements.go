@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/xingleixu/TG-Script/lexer"
@@ -12,7 +13,8 @@ import (
 
 // Program represents the root node of an AST.
 type Program struct {
-	Body []Statement // top-level statements
+	Body     []Statement // top-level statements
+	Comments []*Comment  // all comments in source order
 }
 
 func (p *Program) Pos() lexer.Position {
@@ -190,6 +192,23 @@ func (ws *WhileStatement) String() string {
 }
 func (ws *WhileStatement) statementNode() {}
 
+// DoWhileStatement represents a do-while loop.
+type DoWhileStatement struct {
+	DoPos    lexer.Position // position of 'do'
+	Body     Statement      // loop body
+	WhilePos lexer.Position // position of 'while'
+	LParen   lexer.Position // position of '('
+	Test     Expression     // condition
+	RParen   lexer.Position // position of ')'
+}
+
+func (dws *DoWhileStatement) Pos() lexer.Position { return dws.DoPos }
+func (dws *DoWhileStatement) End() lexer.Position { return dws.RParen }
+func (dws *DoWhileStatement) String() string {
+	return "do " + dws.Body.String() + " while (" + dws.Test.String() + ")"
+}
+func (dws *DoWhileStatement) statementNode() {}
+
 // ForStatement represents a for loop.
 type ForStatement struct {
 	ForPos lexer.Position // position of 'for'
@@ -256,6 +275,64 @@ func (fos *ForOfStatement) String() string {
 }
 func (fos *ForOfStatement) statementNode() {}
 
+// SwitchCase represents a case or default clause of a switch statement.
+type SwitchCase struct {
+	CasePos    lexer.Position // position of 'case' or 'default'
+	Test       Expression     // case expression (nil for default)
+	Colon      lexer.Position // position of ':'
+	Consequent []Statement    // statements run when the case matches
+}
+
+func (sc *SwitchCase) Pos() lexer.Position { return sc.CasePos }
+func (sc *SwitchCase) End() lexer.Position {
+	if len(sc.Consequent) > 0 {
+		return sc.Consequent[len(sc.Consequent)-1].End()
+	}
+	return lexer.Position{
+		Line:   sc.Colon.Line,
+		Column: sc.Colon.Column + 1,
+		Offset: sc.Colon.Offset + 1,
+	}
+}
+func (sc *SwitchCase) String() string {
+	result := "default:"
+	if sc.Test != nil {
+		result = "case " + sc.Test.String() + ":"
+	}
+	for _, stmt := range sc.Consequent {
+		result += " " + stmt.String()
+	}
+	return result
+}
+
+// SwitchStatement represents a switch statement.
+type SwitchStatement struct {
+	SwitchPos    lexer.Position // position of 'switch'
+	LParen       lexer.Position // position of '('
+	Discriminant Expression     // value compared against each case
+	RParen       lexer.Position // position of ')'
+	LBrace       lexer.Position // position of '{'
+	Cases        []*SwitchCase  // case and default clauses in source order
+	RBrace       lexer.Position // position of '}'
+}
+
+func (ss *SwitchStatement) Pos() lexer.Position { return ss.SwitchPos }
+func (ss *SwitchStatement) End() lexer.Position {
+	return lexer.Position{
+		Line:   ss.RBrace.Line,
+		Column: ss.RBrace.Column + 1,
+		Offset: ss.RBrace.Offset + 1,
+	}
+}
+func (ss *SwitchStatement) String() string {
+	result := "switch (" + ss.Discriminant.String() + ") {"
+	for _, c := range ss.Cases {
+		result += " " + c.String()
+	}
+	return result + " }"
+}
+func (ss *SwitchStatement) statementNode() {}
+
 // ============================================================================
 // JUMP STATEMENTS
 // ============================================================================
@@ -359,6 +436,74 @@ func (cs *ContinueStatement) String() string {
 }
 func (cs *ContinueStatement) statementNode() {}
 
+// ThrowStatement represents a throw statement.
+type ThrowStatement struct {
+	ThrowPos  lexer.Position // position of 'throw'
+	Argument  Expression     // thrown value
+	Semicolon lexer.Position // position of ';' (optional)
+}
+
+func (ts *ThrowStatement) Pos() lexer.Position { return ts.ThrowPos }
+func (ts *ThrowStatement) End() lexer.Position {
+	if ts.Semicolon.Line > 0 {
+		return lexer.Position{
+			Line:   ts.Semicolon.Line,
+			Column: ts.Semicolon.Column + 1,
+			Offset: ts.Semicolon.Offset + 1,
+		}
+	}
+	return ts.Argument.End()
+}
+func (ts *ThrowStatement) String() string { return "throw " + ts.Argument.String() + ";" }
+func (ts *ThrowStatement) statementNode() {}
+
+// CatchClause represents the catch clause of a try statement.
+type CatchClause struct {
+	CatchPos lexer.Position  // position of 'catch'
+	Param    *Identifier     // caught value binding (optional)
+	Body     *BlockStatement // handler body
+}
+
+func (cc *CatchClause) Pos() lexer.Position { return cc.CatchPos }
+func (cc *CatchClause) End() lexer.Position { return cc.Body.End() }
+func (cc *CatchClause) String() string {
+	if cc.Param != nil {
+		return "catch (" + cc.Param.String() + ") " + cc.Body.String()
+	}
+	return "catch " + cc.Body.String()
+}
+
+// TryStatement represents a try statement with a catch clause, a finally
+// block, or both.
+type TryStatement struct {
+	TryPos    lexer.Position  // position of 'try'
+	Block     *BlockStatement // protected block
+	Handler   *CatchClause    // catch clause (optional)
+	Finalizer *BlockStatement // finally block (optional)
+}
+
+func (ts *TryStatement) Pos() lexer.Position { return ts.TryPos }
+func (ts *TryStatement) End() lexer.Position {
+	if ts.Finalizer != nil {
+		return ts.Finalizer.End()
+	}
+	if ts.Handler != nil {
+		return ts.Handler.End()
+	}
+	return ts.Block.End()
+}
+func (ts *TryStatement) String() string {
+	result := "try " + ts.Block.String()
+	if ts.Handler != nil {
+		result += " " + ts.Handler.String()
+	}
+	if ts.Finalizer != nil {
+		result += " finally " + ts.Finalizer.String()
+	}
+	return result
+}
+func (ts *TryStatement) statementNode() {}
+
 // ============================================================================
 // OTHER STATEMENTS
 // ============================================================================
@@ -389,4 +534,103 @@ func (ls *LabeledStatement) End() lexer.Position { return ls.Statement.End() }
 func (ls *LabeledStatement) String() string {
 	return ls.Label.String() + ": " + ls.Statement.String()
 }
-func (ls *LabeledStatement) statementNode() {}
\ No newline at end of file
+func (ls *LabeledStatement) statementNode() {}
+// ============================================================================
+// MODULE STATEMENTS
+// ============================================================================
+
+// ImportDeclaration represents an import of names from another module
+// (e.g., import { add, PI as pi } from "./math.tg").
+type ImportDeclaration struct {
+	ImportPos  lexer.Position     // position of 'import'
+	Specifiers []*ImportSpecifier // imported names
+	Source     *StringLiteral     // path of the module
+	Semicolon  lexer.Position     // position of ';' (optional)
+}
+
+func (id *ImportDeclaration) Pos() lexer.Position { return id.ImportPos }
+func (id *ImportDeclaration) End() lexer.Position {
+	if id.Semicolon.Line > 0 {
+		return lexer.Position{
+			Line:   id.Semicolon.Line,
+			Column: id.Semicolon.Column + 1,
+			Offset: id.Semicolon.Offset + 1,
+		}
+	}
+	return id.Source.End()
+}
+func (id *ImportDeclaration) String() string {
+	var specifiers []string
+	for _, spec := range id.Specifiers {
+		specifiers = append(specifiers, spec.String())
+	}
+	return "import { " + strings.Join(specifiers, ", ") + " } from " + strconv.Quote(id.Source.Value) + ";"
+}
+func (id *ImportDeclaration) statementNode() {}
+
+// ImportSpecifier represents one imported name, optionally renamed.
+type ImportSpecifier struct {
+	Imported *Identifier // name exported by the module
+	Local    *Identifier // name bound in the importing module (same as Imported without 'as')
+}
+
+func (is *ImportSpecifier) Pos() lexer.Position { return is.Imported.Pos() }
+func (is *ImportSpecifier) End() lexer.Position { return is.Local.End() }
+func (is *ImportSpecifier) String() string {
+	if is.Local.Name == is.Imported.Name {
+		return is.Imported.String()
+	}
+	return is.Imported.String() + " as " + is.Local.String()
+}
+
+// ExportDeclaration represents an exported declaration
+// (e.g., export function add(a: int, b: int): int { ... }).
+type ExportDeclaration struct {
+	ExportPos   lexer.Position // position of 'export'
+	Declaration Statement      // exported declaration
+}
+
+func (ed *ExportDeclaration) Pos() lexer.Position { return ed.ExportPos }
+func (ed *ExportDeclaration) End() lexer.Position { return ed.Declaration.End() }
+func (ed *ExportDeclaration) String() string {
+	return "export " + ed.Declaration.String()
+}
+func (ed *ExportDeclaration) statementNode() {}
+
+// DeclaredNames returns the names a top-level declaration binds: the
+// variables of a variable declaration and the name of a function, class,
+// interface, type alias or enum declaration. Exported declarations bind the
+// names of the declaration they export.
+func DeclaredNames(stmt Statement) []*Identifier {
+	switch s := stmt.(type) {
+	case *VariableDeclaration:
+		var names []*Identifier
+		for _, declarator := range s.Declarations {
+			names = append(names, BindingNames(declarator.Id)...)
+		}
+		return names
+	case *FunctionDeclaration:
+		return []*Identifier{s.Name}
+	case *ClassDeclaration:
+		return []*Identifier{s.Name}
+	case *InterfaceDeclaration:
+		return []*Identifier{s.Name}
+	case *TypeAliasDeclaration:
+		return []*Identifier{s.Name}
+	case *EnumDeclaration:
+		return []*Identifier{s.Name}
+	case *ExportDeclaration:
+		return DeclaredNames(s.Declaration)
+	}
+	return nil
+}
+
+// TerminatesBlock reports whether control never falls through stmt to the
+// next statement of its block
+func TerminatesBlock(stmt Statement) bool {
+	switch stmt.(type) {
+	case *ReturnStatement, *BreakStatement, *ContinueStatement, *ThrowStatement:
+		return true
+	}
+	return false
+}
@@ -25,7 +25,16 @@ func (bt *BasicType) End() lexer.Position {
 		Offset: bt.TypePos.Offset + length,
 	}
 }
-func (bt *BasicType) String() string { return bt.Kind.String() }
+func (bt *BasicType) String() string {
+	// null and undefined are literal tokens whose names are upper case
+	switch bt.Kind {
+	case lexer.NULL:
+		return "null"
+	case lexer.UNDEFINED:
+		return "undefined"
+	}
+	return bt.Kind.String()
+}
 func (bt *BasicType) typeNode()      {}
 
 // TypeReference represents a type reference (e.g., string, number, MyClass).
@@ -69,6 +78,11 @@ func (at *ArrayType) End() lexer.Position { return lexer.Position{
 	Offset: at.RBracket.Offset + 1,
 } }
 func (at *ArrayType) String() string {
+	// Element types that bind looser than [] need parentheses
+	switch at.ElementType.(type) {
+	case *UnionType, *IntersectionType, *FunctionType:
+		return "(" + at.ElementType.String() + ")[]"
+	}
 	return at.ElementType.String() + "[]"
 }
 func (at *ArrayType) typeNode() {}
@@ -404,7 +418,7 @@ func (nna *NonNullAssertion) expressionNode() {}
 
 // Modifier represents TypeScript modifiers.
 type Modifier struct {
-	Kind string         // "public", "private", "protected", "static", "readonly", "abstract", etc.
+	Kind string         // "public", "private", "protected", "static", "readonly", "abstract", "override", etc.
 	Pos  lexer.Position // position of modifier
 }
 
@@ -7,7 +7,11 @@ import (
 	"strings"
 
 	"github.com/xingleixu/TG-Script/compiler"
+	"github.com/xingleixu/TG-Script/diagnostics"
+	"github.com/xingleixu/TG-Script/doc"
 	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/loader"
+	"github.com/xingleixu/TG-Script/migrate"
 	"github.com/xingleixu/TG-Script/parser"
 	"github.com/xingleixu/TG-Script/types"
 	"github.com/xingleixu/TG-Script/vm"
@@ -35,6 +39,12 @@ func main() {
 		handleCheck(os.Args[2:])
 	case "migrate":
 		handleMigrate(os.Args[2:])
+	case "repl":
+		handleRepl(os.Args[2:])
+	case "doc":
+		handleDoc(os.Args[2:])
+	case "dump", "disasm":
+		handleDump(os.Args[2:])
 	case "version", "-v", "--version":
 		fmt.Printf("TG-Script %s\n", version)
 	case "help", "-h", "--help":
@@ -53,12 +63,18 @@ Usage:
   tg <command> [arguments]
 
 Commands:
-  run <file.tg>              Run TG-Script file
+  run <file.tg> [-no-optimize]
+                             Run TG-Script file
   compile <file.tg> [-o output]  Compile to bytecode
   exec <file.tgc>            Execute bytecode file
   fmt <file.tg>              Format code
-  check <file.tg>            Check syntax and types
-  migrate <file.ts>          Migrate from TypeScript
+  check <file.tg> [-json] [-no-warn] [-warnings-as-errors]
+                             Check syntax and types
+  repl                       Start an interactive session
+  migrate <file.ts> [-o output]  Migrate from TypeScript
+  doc <file.tg> [-json]      Generate documentation from comments
+  dump <file.tg> [-no-optimize]
+                             Print the compiled bytecode (alias: disasm)
   version                    Show version information
   help                       Show help information
 
@@ -67,18 +83,21 @@ Examples:
   tg compile hello.tg -o hello.tgc  # Compile script
   tg fmt hello.tg            # Format code
   tg migrate hello.ts        # Migrate TypeScript file
+  tg doc lib.tg > lib.md     # Generate Markdown docs
+  tg dump hello.tg           # Disassemble script
 
 For more information visit: https://github.com/xingleixu/TG-Script
 `, version)
 }
 
 func handleRun(args []string) {
-	if len(args) == 0 {
+	files, optimize := parseOptimizeFlag(args)
+	if len(files) == 0 {
 		fmt.Println("Error: Please specify a .tg file to run")
 		os.Exit(1)
 	}
 	
-	filename := args[0]
+	filename := files[0]
 	
 	// Check file extension
 	if !strings.HasSuffix(filename, ".tg") {
@@ -92,107 +111,167 @@ func handleRun(args []string) {
 		os.Exit(1)
 	}
 	
-	// Read source code
-	source, err := ioutil.ReadFile(filename)
-	if err != nil {
-		fmt.Printf("Error reading file %s: %v\n", filename, err)
-		os.Exit(1)
-	}
-	
-	// Execute the script
-	if err := executeScript(string(source), filename); err != nil {
+	// Execute the script and the modules it imports
+	if err := executeScript(filename, optimize); err != nil {
 		fmt.Printf("Error executing script: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func executeScript(source, filename string) error {
-	// Lexical analysis
-	l := lexer.New(source)
-	
-	// Parse
-	p := parser.New(l)
-	program := p.ParseProgram()
-	
-	// Check for parser errors
-	if errors := p.Errors(); len(errors) > 0 {
-		fmt.Printf("Parser errors in %s:\n", filename)
-		for _, err := range errors {
-			fmt.Printf("  %s\n", err)
-		}
-		return fmt.Errorf("parsing failed")
+func executeScript(filename string, optimize bool) error {
+	// Load, parse and type check the script and its modules
+	graph, err := loadScript(filename)
+	if err != nil {
+		return err
 	}
-	
-	// Type checking
-	checker := types.NewTypeChecker()
-	typeErrors := checker.Check(program)
-	
-	// Check for type errors
-	if len(typeErrors) > 0 {
-		fmt.Printf("Type errors in %s:\n", filename)
-		for _, err := range typeErrors {
-			fmt.Printf("  %s\n", err.Error())
+	graph.NoOptimize = !optimize
+	if !graph.Check() {
+		for _, m := range graph.Modules {
+			if len(m.TypeErrors) > 0 {
+				fmt.Printf("Type errors in %s:\n", m.Path)
+				printTypeErrors(m.Source, m.TypeErrors)
+			}
 		}
 		return fmt.Errorf("type checking failed")
 	}
 	
 	// Compile
-	function, err := compiler.CompileFunction(program)
-	if err != nil {
+	if err := graph.Compile(); err != nil {
 		return fmt.Errorf("compilation failed: %v", err)
 	}
 	
 	// Execute
 	machine := vm.NewVM()
-	closure := vm.NewClosure(function)
-	result, err := machine.Execute(closure, []vm.Value{})
+	result, err := graph.Run(machine)
 	if err != nil {
+		if trace := vm.StackTraceOf(err); len(trace) > 0 {
+			return fmt.Errorf("execution failed: %v\nStack trace:\n%s", err, trace)
+		}
 		return fmt.Errorf("execution failed: %v", err)
 	}
 	
-	// Print result if it's not nil
-	if !result.IsNil() {
-		fmt.Printf("Result: %v\n", result)
+	// Print the result if there is one
+	if !result.IsNil() && !result.IsVoid() {
+		fmt.Printf("Result: %s\n", result.ToString())
 	}
 	
 	return nil
 }
 
-func checkScript(source, filename string) error {
-	// Lexical analysis
-	l := lexer.New(source)
-	
-	// Parse
-	p := parser.New(l)
-	program := p.ParseProgram()
+// loadScript loads the script in filename and the modules it imports,
+// printing the parser errors of every module that has any
+func loadScript(filename string) (*loader.Graph, error) {
+	graph, err := loader.Load(filename)
+	if err != nil {
+		return nil, err
+	}
 	
-	// Check for parser errors
-	if errors := p.Errors(); len(errors) > 0 {
-		fmt.Printf("Parser errors in %s:\n", filename)
-		for _, err := range errors {
-			fmt.Printf("  %s\n", err)
+	if graph.HasParseErrors() {
+		for _, m := range graph.Modules {
+			if len(m.ParseErrors) == 0 {
+				continue
+			}
+			fmt.Printf("Parser errors in %s:\n", m.Path)
+			for _, err := range m.ParseErrors {
+				fmt.Printf("  %s\n", err.Message)
+			}
 		}
-		return fmt.Errorf("parsing failed")
+		return nil, fmt.Errorf("parsing failed")
 	}
 	
+	return graph, nil
+}
 
+// checkOptions controls how 'tg check' treats warnings
+type checkOptions struct {
+	noWarn           bool // don't report warnings
+	warningsAsErrors bool // fail the check when there are warnings
+}
+
+func checkScript(filename string, opts checkOptions) error {
+	// Load and parse the script and its modules
+	graph, err := loadScript(filename)
+	if err != nil {
+		return err
+	}
 	
 	// Type checking
-	checker := types.NewTypeChecker()
-	typeErrors := checker.Check(program)
-	
-	// Check for type errors
-	if len(typeErrors) > 0 {
-		fmt.Printf("Type errors in %s:\n", filename)
-		for _, err := range typeErrors {
-			fmt.Printf("  %s\n", err.Error())
+	ok := graph.Check()
+	warningCount := 0
+	
+	// Report type errors, then warnings, of each module
+	for _, m := range graph.Modules {
+		var warnings []*types.TypeError
+		if !opts.noWarn {
+			warnings = m.Warnings
+		}
+		warningCount += len(warnings)
+		
+		if len(m.TypeErrors) > 0 {
+			fmt.Printf("Type errors in %s:\n", m.Path)
+			printTypeErrors(m.Source, m.TypeErrors)
+		}
+		if len(warnings) > 0 {
+			fmt.Printf("Warnings in %s:\n", m.Path)
+			printTypeErrors(m.Source, warnings)
 		}
+	}
+	
+	if !ok {
 		return fmt.Errorf("type checking failed")
 	}
+	if warningCount > 0 && opts.warningsAsErrors {
+		return fmt.Errorf("%d warning(s) treated as errors", warningCount)
+	}
 	
 	return nil
 }
 
+// checkModules returns the diagnostics of the script in filename and the
+// modules it imports, stopping after parser errors like 'tg check'
+func checkModules(filename string) ([]diagnostics.Diagnostic, error) {
+	graph, err := loader.Load(filename)
+	if err != nil {
+		return nil, err
+	}
+	
+	diags := []diagnostics.Diagnostic{}
+	if graph.HasParseErrors() {
+		for _, m := range graph.Modules {
+			for _, err := range m.ParseErrors {
+				diags = append(diags, diagnostics.FromParserError(m.Path, err))
+			}
+		}
+		return diags, nil
+	}
+	
+	graph.Check()
+	for _, m := range graph.Modules {
+		for _, err := range m.TypeErrors {
+			diags = append(diags, diagnostics.FromTypeError(m.Path, err))
+		}
+		for _, warning := range m.Warnings {
+			diags = append(diags, diagnostics.FromTypeError(m.Path, warning))
+		}
+	}
+	return diags, nil
+}
+
+// printTypeErrors prints type errors or warnings with the source they refer to
+func printTypeErrors(source string, typeErrors []*types.TypeError) {
+	for _, err := range typeErrors {
+		fmt.Printf("  %s\n", err.Error())
+		if snippet := diagnostics.Underline(source, err.Span); snippet != "" {
+			fmt.Printf("%s\n", indentLines(snippet, "    "))
+		}
+	}
+}
+
+// indentLines prefixes every line of text with prefix
+func indentLines(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
+
 func handleCompile(args []string) {
 	if len(args) == 0 {
 		fmt.Println("Error: Please specify a .tg file to compile")
@@ -240,12 +319,29 @@ func handleFormat(args []string) {
 }
 
 func handleCheck(args []string) {
-	if len(args) == 0 {
+	// Parse -json and warning arguments
+	jsonOutput := false
+	var opts checkOptions
+	var files []string
+	for _, arg := range args {
+		switch arg {
+		case "-json", "--json":
+			jsonOutput = true
+		case "-no-warn", "--no-warn":
+			opts.noWarn = true
+		case "-warnings-as-errors", "--warnings-as-errors":
+			opts.warningsAsErrors = true
+		default:
+			files = append(files, arg)
+		}
+	}
+	
+	if len(files) == 0 {
 		fmt.Println("Error: Please specify a .tg file to check")
 		os.Exit(1)
 	}
 	
-	filename := args[0]
+	filename := files[0]
 	
 	// Check file extension
 	if !strings.HasSuffix(filename, ".tg") {
@@ -259,6 +355,112 @@ func handleCheck(args []string) {
 		os.Exit(1)
 	}
 	
+	// Emit structured diagnostics for editor integration
+	if jsonOutput {
+		diags, err := checkModules(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		errors, warnings := diagnostics.Split(diags)
+		if opts.noWarn {
+			warnings = nil
+		}
+		if err := diagnostics.WriteJSON(os.Stdout, append(errors, warnings...)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing diagnostics: %v\n", err)
+			os.Exit(1)
+		}
+		if len(errors) > 0 || (len(warnings) > 0 && opts.warningsAsErrors) {
+			os.Exit(1)
+		}
+		return
+	}
+	
+	// Perform syntax and type checking
+	if err := checkScript(filename, opts); err != nil {
+		fmt.Printf("Check failed: %v\n", err)
+		os.Exit(1)
+	}
+	
+	fmt.Printf("✓ Check passed for %s\n", filename)
+}
+
+func handleDump(args []string) {
+	files, optimize := parseOptimizeFlag(args)
+	if len(files) == 0 {
+		fmt.Println("Error: Please specify a .tg file to dump")
+		os.Exit(1)
+	}
+	
+	filename := files[0]
+	if strings.HasSuffix(filename, ".tgc") {
+		// There is no bytecode file format yet; see 'tg compile'
+		fmt.Printf("Error: %s: disassembling .tgc files is not supported yet, pass the .tg source\n", filename)
+		os.Exit(1)
+	}
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error reading file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	
+	// Compile without type checking or executing, so that code the checker
+	// rejects can still be inspected
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) > 0 {
+		fmt.Printf("Parser errors in %s:\n", filename)
+		for _, err := range errors {
+			fmt.Printf("  %s\n", err)
+		}
+		os.Exit(1)
+	}
+	
+	c := compiler.NewCompiler()
+	c.SetOptimize(optimize)
+	function, err := c.Compile(program)
+	if err != nil {
+		fmt.Printf("Compilation failed: %v\n", err)
+		os.Exit(1)
+	}
+	function.SourceFile = filename
+	fmt.Print(vm.Disassemble(function))
+}
+
+// parseOptimizeFlag removes -no-optimize from args, returning the other
+// arguments and whether the peephole pass should run
+func parseOptimizeFlag(args []string) ([]string, bool) {
+	optimize := true
+	var rest []string
+	for _, arg := range args {
+		if arg == "-no-optimize" || arg == "--no-optimize" {
+			optimize = false
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return rest, optimize
+}
+
+func handleDoc(args []string) {
+	// Parse -json argument
+	jsonOutput := false
+	var files []string
+	for _, arg := range args {
+		if arg == "-json" || arg == "--json" {
+			jsonOutput = true
+		} else {
+			files = append(files, arg)
+		}
+	}
+	
+	if len(files) == 0 {
+		fmt.Println("Error: Please specify a .tg file to document")
+		os.Exit(1)
+	}
+	
+	filename := files[0]
+	
 	// Read source code
 	source, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -266,13 +468,21 @@ func handleCheck(args []string) {
 		os.Exit(1)
 	}
 	
-	// Perform syntax and type checking
-	if err := checkScript(string(source), filename); err != nil {
-		fmt.Printf("Check failed: %v\n", err)
+	entries, err := doc.Generate(string(source))
+	if err != nil {
+		fmt.Printf("Error generating documentation: %v\n", err)
 		os.Exit(1)
 	}
 	
-	fmt.Printf("✓ Check passed for %s\n", filename)
+	if jsonOutput {
+		err = doc.WriteJSON(os.Stdout, entries)
+	} else {
+		err = doc.WriteMarkdown(os.Stdout, filename, entries)
+	}
+	if err != nil {
+		fmt.Printf("Error writing documentation: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 func handleMigrate(args []string) {
@@ -282,7 +492,49 @@ func handleMigrate(args []string) {
 	}
 	
 	filename := args[0]
-	fmt.Printf("Migrating TypeScript file: %s\n", filename)
-	// TODO: Implement TypeScript migration logic
-	fmt.Println("Note: Migration functionality not yet implemented")
+	
+	// Check file extension
+	if !strings.HasSuffix(filename, ".ts") {
+		fmt.Printf("Error: File must have .ts extension, got: %s\n", filename)
+		os.Exit(1)
+	}
+	output := strings.TrimSuffix(filename, ".ts") + ".tg" // Default output filename
+	
+	// Parse -o argument
+	for i, arg := range args {
+		if arg == "-o" && i+1 < len(args) {
+			output = args[i+1]
+			break
+		}
+	}
+	
+	// Read source code
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error reading file %s: %v\n", filename, err)
+		os.Exit(1)
+	}
+	
+	result := migrate.Migrate(string(source))
+	if err := ioutil.WriteFile(output, []byte(result.Output), 0644); err != nil {
+		fmt.Printf("Error writing file %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	
+	fmt.Printf("Migrated %s -> %s\n", filename, output)
+	if len(result.Changes) > 0 {
+		fmt.Println("Transformations applied:")
+		for _, change := range result.Changes {
+			fmt.Printf("  %4d  %s\n", change.Count, change.Description)
+		}
+	}
+	if len(result.Issues) == 0 {
+		fmt.Println("✓ No constructs need manual attention")
+		return
+	}
+	
+	fmt.Printf("%d construct(s) need manual attention:\n", len(result.Issues))
+	for _, issue := range result.Issues {
+		fmt.Printf("  %s:%d:%d: %s\n", filename, issue.Line, issue.Column, issue.Message)
+	}
 }
\ No newline at end of file
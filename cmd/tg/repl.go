@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/compiler"
+	"github.com/xingleixu/TG-Script/diagnostics"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/types"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+const (
+	replPrompt             = "> "
+	replContinuationPrompt = "... "
+)
+
+func handleRepl(args []string) {
+	fmt.Printf("TG-Script %s REPL. Enter .exit or press Ctrl+D to quit.\n", version)
+	if err := runREPL(os.Stdin, os.Stdout); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// replSession holds the state shared by the entries of an interactive session
+type replSession struct {
+	machine *vm.VM
+	out     io.Writer
+
+	// history is the source of every entry that ran, so each new entry is
+	// type checked against the declarations made before it
+	history      string
+	historyLines int
+}
+
+// runREPL reads entries from in until EOF or .exit, evaluating each one
+// and writing results and errors to out
+func runREPL(in io.Reader, out io.Writer) error {
+	session := &replSession{machine: vm.NewVM(), out: out}
+	session.machine.SetOutput(out, out)
+	scanner := bufio.NewScanner(in)
+
+	var entry strings.Builder
+	fmt.Fprint(out, replPrompt)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if entry.Len() == 0 && strings.TrimSpace(line) == ".exit" {
+			return nil
+		}
+
+		entry.WriteString(line)
+		entry.WriteString("\n")
+
+		// Keep reading while brackets are left open
+		if isIncomplete(entry.String()) {
+			fmt.Fprint(out, replContinuationPrompt)
+			continue
+		}
+
+		if source := entry.String(); strings.TrimSpace(source) != "" {
+			session.eval(source)
+		}
+		entry.Reset()
+		fmt.Fprint(out, replPrompt)
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// eval parses, type checks, compiles and runs one entry, printing its
+// result. Errors are reported and leave the session unchanged.
+func (s *replSession) eval(source string) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errors := p.ParserErrors(); len(errors) > 0 {
+		for _, err := range errors {
+			fmt.Fprintf(s.out, "Syntax error: %s\n", err.Error())
+		}
+		return
+	}
+
+	if !s.check(source) {
+		return
+	}
+
+	function, err := compiler.CompileREPL(program)
+	if err != nil {
+		fmt.Fprintf(s.out, "Compile error: %v\n", err)
+		return
+	}
+
+	result, err := s.machine.Execute(vm.NewClosure(function), []vm.Value{})
+	if err != nil {
+		fmt.Fprintf(s.out, "%v\n", err)
+		return
+	}
+
+	s.history += source
+	s.historyLines += strings.Count(source, "\n")
+	if result.Type != vm.TypeNil {
+		fmt.Fprintln(s.out, result.ToString())
+	}
+}
+
+// check type checks source after the session's history and reports the
+// errors that fall within source. It returns false if there were any.
+func (s *replSession) check(source string) bool {
+	p := parser.New(lexer.New(s.history + source))
+	program := p.ParseProgram()
+
+	ok := true
+	for _, err := range types.NewTypeChecker().Check(program) {
+		if err.Position.Line <= s.historyLines {
+			continue
+		}
+		ok = false
+
+		// Report positions relative to the entry
+		span := err.Span
+		span.Start.Line -= s.historyLines
+		span.End.Line -= s.historyLines
+		fmt.Fprintf(s.out, "Type error [%s] at line %d, column %d: %s\n",
+			err.Code, span.Start.Line, span.Start.Column, err.Message)
+		if snippet := diagnostics.Underline(source, span); snippet != "" {
+			fmt.Fprintln(s.out, indentLines(snippet, "  "))
+		}
+	}
+	return ok
+}
+
+// isIncomplete reports whether source has unclosed braces, brackets or
+// parentheses, so the entry continues on the next line
+func isIncomplete(source string) bool {
+	depth := 0
+	l := lexer.New(source)
+	for tok := l.NextToken(); tok.Type != lexer.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case lexer.LBRACE, lexer.LBRACKET, lexer.LPAREN:
+			depth++
+		case lexer.RBRACE, lexer.RBRACKET, lexer.RPAREN:
+			depth--
+		}
+	}
+	return depth > 0
+}
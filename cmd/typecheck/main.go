@@ -5,18 +5,30 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/xingleixu/TG-Script/diagnostics"
 	"github.com/xingleixu/TG-Script/lexer"
 	"github.com/xingleixu/TG-Script/parser"
 	"github.com/xingleixu/TG-Script/types"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: typecheck <file.tg>")
+	// Parse -json argument
+	jsonOutput := false
+	var files []string
+	for _, arg := range os.Args[1:] {
+		if arg == "-json" || arg == "--json" {
+			jsonOutput = true
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	if len(files) == 0 {
+		fmt.Println("Usage: typecheck [-json] <file.tg>")
 		os.Exit(1)
 	}
 
-	filename := os.Args[1]
+	filename := files[0]
 	
 	// Read the source file
 	source, err := ioutil.ReadFile(filename)
@@ -25,6 +37,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Emit structured diagnostics for editor integration
+	if jsonOutput {
+		diags := diagnostics.Check(filename, string(source))
+		if err := diagnostics.WriteJSON(os.Stdout, diags); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing diagnostics: %v\n", err)
+			os.Exit(1)
+		}
+		if len(diags) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create lexer
 	l := lexer.New(string(source))
 
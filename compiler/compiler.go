@@ -2,6 +2,8 @@ package compiler
 
 import (
 	"fmt"
+	"math"
+	"sort"
 
 	"github.com/xingleixu/TG-Script/ast"
 	"github.com/xingleixu/TG-Script/vm"
@@ -16,8 +18,44 @@ type Compiler struct {
 	freeRegisters []int  // Stack of free registers
 	variableRegisters map[int]bool  // Track registers used by variables
 	constants    []vm.Value
+	globals      []string // names of the global slots used by the function
 	instructions []vm.Instruction
+	lines        []int // source line of each instruction
+	line         int   // source line of the node being compiled
 	errors       []error
+	tryStack     []tryContext // active try regions, innermost last
+	breakStack   []*breakContext // enclosing loops and switches, innermost last
+	globalDeclarations bool // compile top-level variable declarations as globals
+	strict       bool // reject assignments to undeclared variables instead of creating globals
+	optionalExits *[]int // null checks of the optional chain being compiled, jumping to its end
+	module       *moduleScope // global names of the module being compiled (nil outside modules)
+	optimize     bool // run the peephole pass over the emitted instructions
+}
+
+// moduleScope maps the names a module uses to the globals that hold them.
+// A module's own top-level names are prefixed with its namespace, so that
+// modules run on the same VM don't clash, and imported names refer to the
+// globals of the module that exports them.
+type moduleScope struct {
+	namespace string            // prefix of the module's own globals
+	names     map[string]bool   // top-level names declared by the module
+	imports   map[string]string // imported local name -> global name
+}
+
+// tryContext is a region of code covered by an exception handler. Returns
+// from inside the region pop the handler and run its finally block first.
+type tryContext struct {
+	finalizer *ast.BlockStatement // finally block (nil if none)
+}
+
+// breakContext is a loop or switch that break statements can leave. Break
+// jumps are patched to the end of the statement once it is compiled;
+// continue jumps in a loop are patched to where its next iteration starts.
+type breakContext struct {
+	jumps     []int // positions of break jumps to patch
+	continues []int // positions of continue jumps to patch
+	loop      bool  // whether continue statements can target the statement
+	tryDepth  int   // try regions active when the statement began
 }
 
 // SymbolTable manages variable scoping
@@ -29,10 +67,11 @@ type SymbolTable struct {
 
 // Symbol represents a variable or function
 type Symbol struct {
-	Name     string
-	Type     SymbolType
-	Register int
-	Level    int
+	Name       string
+	Type       SymbolType
+	Register   int
+	Level      int
+	Conversion vm.NumericKind // width enforced on stores to sized numeric variables (0 if none)
 }
 
 // SymbolType represents the type of symbol
@@ -57,21 +96,103 @@ func NewCompiler() *Compiler {
 		constants:         make([]vm.Value, 0),
 		instructions:      make([]vm.Instruction, 0),
 		errors:            make([]error, 0),
+		optimize:          true,
 	}
 }
 
-// CompileFunction compiles a program to a function
+// CompileFunction compiles a program to a function. Assigning to an
+// undeclared variable creates a global; see SetStrictMode.
 func CompileFunction(program *ast.Program) (*vm.Function, error) {
+	return NewCompiler().Compile(program)
+}
+
+// Compile compiles a program to a function
+func (c *Compiler) Compile(program *ast.Program) (*vm.Function, error) {
+	if err := c.compileProgram(program); err != nil {
+		return nil, err
+	}
+	
+	if c.HasErrors() {
+		return nil, fmt.Errorf("compilation errors: %v", c.GetErrors())
+	}
+	
+	return c.GetFunction(), nil
+}
+
+// SetStrictMode sets whether assigning to a variable that was never
+// declared is an error. Otherwise the assignment creates a global.
+func (c *Compiler) SetStrictMode(strict bool) {
+	c.strict = strict
+}
+
+// SetOptimize sets whether the peephole pass runs over the emitted
+// instructions. It is on by default.
+func (c *Compiler) SetOptimize(optimize bool) {
+	c.optimize = optimize
+}
+
+// SetModule compiles the program as a module. Top-level names get the
+// global namespace+name, and the names in imports, which map local names
+// to the global names of imported bindings, refer to those globals. With a
+// namespace, top-level variables become globals so importers can read them.
+func (c *Compiler) SetModule(namespace string, imports map[string]string) {
+	c.module = &moduleScope{
+		namespace: namespace,
+		names:     make(map[string]bool),
+		imports:   imports,
+	}
+	c.globalDeclarations = namespace != ""
+}
+
+// CompileREPL compiles one entry of an interactive session. Top-level
+// variable declarations become globals so that later entries, compiled
+// separately, can see them. If the entry ends with an expression
+// statement, its value is the result returned by Execute.
+func CompileREPL(program *ast.Program) (*vm.Function, error) {
 	compiler := NewCompiler()
+	compiler.globalDeclarations = true
+	
+	body := program.Body
+	var last *ast.ExpressionStatement
+	if n := len(body); n > 0 {
+		if stmt, ok := body[n-1].(*ast.ExpressionStatement); ok {
+			last = stmt
+			body = body[:n-1]
+		}
+	}
 	
-	if err := compiler.compileProgram(program); err != nil {
-		return nil, err
+	for _, stmt := range body {
+		if err := compiler.compileStatement(stmt); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Execute returns register 0 of the main frame when it halts
+	if last != nil {
+		reg := compiler.AllocateRegister()
+		if err := compiler.compileExpression(last.Expression, reg); err != nil {
+			return nil, err
+		}
+		compiler.Emit(vm.OpMove, 0, reg)
+		compiler.FreeRegister(reg)
+	} else {
+		compiler.AllocateRegister() // ensure the frame has a register 0
+		compiler.Emit(vm.OpLoadNil, 0)
 	}
+	compiler.Emit(vm.OpHalt)
 	
 	if compiler.HasErrors() {
 		return nil, fmt.Errorf("compilation errors: %v", compiler.GetErrors())
 	}
 	
+	if compiler.optimize {
+		compiler.peephole()
+	}
+	compiler.function.Instructions = compiler.instructions
+	compiler.function.LineNumbers = compiler.lines
+	compiler.function.Constants = compiler.constants
+	compiler.function.Globals = compiler.globals
+	compiler.function.NumLocals = compiler.maxRegisters
 	return compiler.GetFunction(), nil
 }
 
@@ -126,6 +247,9 @@ func (c *Compiler) AllocateRegister() int {
 	
 	// Allocate a new register
 	reg := c.nextRegister
+	if reg == vm.MaxRegisters {
+		c.AddError(fmt.Errorf("expression too complex: needs more than %d registers", vm.MaxRegisters))
+	}
 	c.nextRegister++
 	if c.nextRegister > c.maxRegisters {
 		c.maxRegisters = c.nextRegister
@@ -133,6 +257,44 @@ func (c *Compiler) AllocateRegister() int {
 	return reg
 }
 
+// allocateRegisterBlock allocates count consecutive registers above all
+// registers in use and returns the first
+func (c *Compiler) allocateRegisterBlock(count int) int {
+	reg := c.nextRegister
+	if reg < vm.MaxRegisters && reg+count > vm.MaxRegisters {
+		c.AddError(fmt.Errorf("expression too complex: needs more than %d registers", vm.MaxRegisters))
+	}
+	c.nextRegister += count
+	if c.nextRegister > c.maxRegisters {
+		c.maxRegisters = c.nextRegister
+	}
+	return reg
+}
+
+// freeRegisterBlock frees registers allocated by allocateRegisterBlock
+func (c *Compiler) freeRegisterBlock(reg, count int) {
+	for i := count - 1; i >= 0; i-- {
+		c.FreeRegister(reg + i)
+	}
+}
+
+// exitScope leaves the current scope and frees the registers of the
+// variables declared in it, lowest first to be reused
+func (c *Compiler) exitScope() {
+	var regs []int
+	for _, symbol := range c.symbolTable.symbols {
+		if symbol.Type == SymbolLocal && c.variableRegisters[symbol.Register] {
+			regs = append(regs, symbol.Register)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(regs)))
+	for _, reg := range regs {
+		delete(c.variableRegisters, reg)
+		c.FreeRegister(reg)
+	}
+	c.symbolTable = c.symbolTable.parent
+}
+
 // FreeRegister frees a register
 func (c *Compiler) FreeRegister(reg int) {
 	// Don't free registers that are used by variables
@@ -154,25 +316,77 @@ func (c *Compiler) FreeRegister(reg int) {
 func (c *Compiler) AddConstant(value vm.Value) int {
 	// Check if constant already exists
 	for i, constant := range c.constants {
-		if constant.Type == value.Type {
-			switch value.Type {
-			case vm.TypeInt:
-				if constant.Data.(int64) == value.Data.(int64) {
-					return i
-				}
-			case vm.TypeString:
-				if constant.Data.(string) == value.Data.(string) {
-					return i
-				}
-			}
+		if sameConstant(constant, value) {
+			return i
 		}
 	}
 	
+	if len(c.constants) >= vm.MaxConstants {
+		c.AddError(fmt.Errorf("too many constants in one function (max %d)", vm.MaxConstants))
+		return 0
+	}
+	
 	// Add new constant
 	c.constants = append(c.constants, value)
 	return len(c.constants) - 1
 }
 
+// GlobalSlot returns the slot of the global variable name, which is the
+// operand of GETGLOBAL and SETGLOBAL instructions
+func (c *Compiler) GlobalSlot(name string) int {
+	name = c.globalName(name)
+	for i, global := range c.globals {
+		if global == name {
+			return i
+		}
+	}
+	
+	if len(c.globals) >= vm.MaxGlobals {
+		c.AddError(fmt.Errorf("too many globals in one function (max %d)", vm.MaxGlobals))
+		return 0
+	}
+	
+	c.globals = append(c.globals, name)
+	return len(c.globals) - 1
+}
+
+// globalName returns the name of the global that holds name in the module
+// being compiled
+func (c *Compiler) globalName(name string) string {
+	if c.module == nil {
+		return name
+	}
+	if global, ok := c.module.imports[name]; ok {
+		return global
+	}
+	if c.module.names[name] {
+		return c.module.namespace + name
+	}
+	return name
+}
+
+// sameConstant reports whether two constants can share a pool slot. Floats
+// are compared by bit pattern, so 0.0 and -0.0 stay distinct while NaN
+// shares a slot with itself.
+func sameConstant(a, b vm.Value) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case vm.TypeNil, vm.TypeVoid, vm.TypeNull:
+		return true
+	case vm.TypeBool:
+		return a.AsBool() == b.AsBool()
+	case vm.TypeInt:
+		return a.AsInt() == b.AsInt()
+	case vm.TypeFloat:
+		return math.Float64bits(a.AsFloat()) == math.Float64bits(b.AsFloat())
+	case vm.TypeString:
+		return a.AsString() == b.AsString()
+	}
+	return false
+}
+
 // Emit emits an instruction
 func (c *Compiler) Emit(opcode vm.OpCode, operands ...int) int {
 	var inst vm.Instruction
@@ -192,9 +406,20 @@ func (c *Compiler) Emit(opcode vm.OpCode, operands ...int) int {
 	}
 	
 	c.instructions = append(c.instructions, inst)
+	c.lines = append(c.lines, c.line)
 	return len(c.instructions) - 1
 }
 
+// trackLine makes node's line the source line of the instructions emitted
+// for it, and returns a function restoring the enclosing node's line
+func (c *Compiler) trackLine(node ast.Node) func() {
+	outer := c.line
+	if line := node.Pos().Line; line > 0 {
+		c.line = line
+	}
+	return func() { c.line = outer }
+}
+
 // PatchJump patches a jump instruction
 func (c *Compiler) PatchJump(pos int, target int) {
 	if pos >= len(c.instructions) {
@@ -230,6 +455,14 @@ func (c *Compiler) GetErrors() []error {
 
 // compileProgram compiles a program
 func (c *Compiler) compileProgram(program *ast.Program) error {
+	if c.module != nil {
+		for _, stmt := range program.Body {
+			for _, name := range ast.DeclaredNames(stmt) {
+				c.module.names[name.Name] = true
+			}
+		}
+	}
+	
 	for _, stmt := range program.Body {
 		if err := c.compileStatement(stmt); err != nil {
 			return err
@@ -240,8 +473,13 @@ func (c *Compiler) compileProgram(program *ast.Program) error {
 	c.Emit(vm.OpHalt)
 	
 	// Finalize function
+	if c.optimize {
+		c.peephole()
+	}
 	c.function.Instructions = c.instructions
+	c.function.LineNumbers = c.lines
 	c.function.Constants = c.constants
+	c.function.Globals = c.globals
 	c.function.NumLocals = c.maxRegisters
 	
 	return nil
@@ -249,6 +487,8 @@ func (c *Compiler) compileProgram(program *ast.Program) error {
 
 // compileStatement compiles a statement
 func (c *Compiler) compileStatement(stmt ast.Statement) error {
+	defer c.trackLine(stmt)()
+	
 	switch s := stmt.(type) {
 	case *ast.ExpressionStatement:
 		return c.compileExpressionStatement(s)
@@ -260,12 +500,38 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		return c.compileIfStatement(s)
 	case *ast.ForStatement:
 		return c.compileForStatement(s)
+	case *ast.ForOfStatement:
+		return c.compileForOfStatement(s)
+	case *ast.ForInStatement:
+		return c.compileForInStatement(s)
+	case *ast.TryStatement:
+		return c.compileTryStatement(s)
+	case *ast.ThrowStatement:
+		return c.compileThrowStatement(s)
 	case *ast.WhileStatement:
 		return c.compileWhileStatement(s)
+	case *ast.DoWhileStatement:
+		return c.compileDoWhileStatement(s)
+	case *ast.SwitchStatement:
+		return c.compileSwitchStatement(s)
+	case *ast.BreakStatement:
+		return c.compileBreakStatement(s)
+	case *ast.ContinueStatement:
+		return c.compileContinueStatement(s)
 	case *ast.ReturnStatement:
 		return c.compileReturnStatement(s)
 	case *ast.BlockStatement:
 		return c.compileBlockStatement(s)
+	case *ast.EnumDeclaration:
+		return c.compileEnumDeclaration(s)
+	case *ast.InterfaceDeclaration, *ast.TypeAliasDeclaration:
+		// Type declarations only exist at type-check time
+		return nil
+	case *ast.ImportDeclaration:
+		// Imported names are mapped to globals by SetModule
+		return nil
+	case *ast.ExportDeclaration:
+		return c.compileStatement(s.Declaration)
 	default:
 		return fmt.Errorf("unsupported statement type: %T", stmt)
 	}
@@ -276,12 +542,24 @@ func (c *Compiler) compileExpressionStatement(stmt *ast.ExpressionStatement) err
 	reg := c.AllocateRegister()
 	defer c.FreeRegister(reg)
 	
+	// A call whose value is unused discards its result
+	if call, ok := stmt.Expression.(*ast.CallExpression); ok {
+		return c.compileCall(call, reg, false)
+	}
+	
 	return c.compileExpression(stmt.Expression, reg)
 }
 
 // compileVariableDeclaration compiles a variable declaration
 func (c *Compiler) compileVariableDeclaration(stmt *ast.VariableDeclaration) error {
 	for _, decl := range stmt.Declarations {
+		if pattern, ok := decl.Id.(ast.Pattern); ok {
+			if err := c.compileDestructuringDeclaration(pattern, decl.Init); err != nil {
+				return err
+			}
+			continue
+		}
+		
 		reg := c.AllocateRegister()
 		
 		// Mark this register as used by a variable
@@ -297,17 +575,132 @@ func (c *Compiler) compileVariableDeclaration(stmt *ast.VariableDeclaration) err
 			c.Emit(vm.OpLoadNil, reg)
 		}
 		
-		// Define symbol - handle BindingTarget properly
+		// Enforce the width of sized numeric types
+		conversion := numericConversion(decl.TypeAnnotation)
+		if conversion != 0 && decl.Init != nil {
+			c.Emit(vm.OpConvert, reg, reg, int(conversion))
+		}
+		
+		// Define symbol
 		if id, ok := decl.Id.(*ast.Identifier); ok {
-			c.symbolTable.Define(id.Name, SymbolLocal, reg)
+			if symbol := c.bindVariable(id, reg); symbol != nil {
+				symbol.Conversion = conversion
+			}
 		}
 	}
 	
 	return nil
 }
 
+// bindVariable defines the variable id, whose value is in the variable
+// register reg. At the top level of a REPL entry the variable becomes a
+// global, reg is freed and nil is returned.
+func (c *Compiler) bindVariable(id *ast.Identifier, reg int) *Symbol {
+	if c.globalDeclarations && c.symbolTable.parent == nil {
+		c.Emit(vm.OpSetGlobal, reg, c.GlobalSlot(id.Name))
+		c.symbolTable.Define(id.Name, SymbolGlobal, -1)
+		delete(c.variableRegisters, reg)
+		c.FreeRegister(reg)
+		return nil
+	}
+	return c.symbolTable.Define(id.Name, SymbolLocal, reg)
+}
+
+// compileDestructuringDeclaration evaluates init once and binds the parts
+// of its value to the names in pattern
+func (c *Compiler) compileDestructuringDeclaration(pattern ast.Pattern, init ast.Expression) error {
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	
+	if init != nil {
+		if err := c.compileExpression(init, valueReg); err != nil {
+			return err
+		}
+	} else {
+		c.Emit(vm.OpLoadNil, valueReg)
+	}
+	
+	return c.compileBindingTarget(pattern, valueReg)
+}
+
+// compileBindingTarget binds the value in valueReg to target. valueReg is a
+// temporary that may be overwritten, e.g. by a default value.
+func (c *Compiler) compileBindingTarget(target ast.BindingTarget, valueReg int) error {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		reg := c.AllocateRegister()
+		c.variableRegisters[reg] = true
+		c.Emit(vm.OpMove, reg, valueReg)
+		c.bindVariable(t, reg)
+		return nil
+		
+	case *ast.AssignmentPattern:
+		// The default is used when the value is null or undefined
+		c.Emit(vm.OpTestNullish, valueReg)
+		skipDefault := c.Emit(vm.OpJmp, 0) // placeholder
+		if err := c.compileExpression(t.Right, valueReg); err != nil {
+			return err
+		}
+		c.PatchJump(skipDefault, len(c.instructions))
+		return c.compileBindingTarget(t.Left, valueReg)
+		
+	case *ast.ArrayPattern:
+		keyReg := c.AllocateRegister()
+		defer c.FreeRegister(keyReg)
+		elemReg := c.AllocateRegister()
+		defer c.FreeRegister(elemReg)
+		
+		for i, elem := range t.Elements {
+			if elem == nil {
+				continue // hole
+			}
+			constIndex := c.AddConstant(vm.NewIntValue(int64(i)))
+			c.Emit(vm.OpLoadK, keyReg, constIndex)
+			c.Emit(vm.OpGetTable, elemReg, valueReg, keyReg)
+			if err := c.compileBindingTarget(elem, elemReg); err != nil {
+				return err
+			}
+		}
+		return nil
+		
+	case *ast.ObjectPattern:
+		keyReg := c.AllocateRegister()
+		defer c.FreeRegister(keyReg)
+		propReg := c.AllocateRegister()
+		defer c.FreeRegister(propReg)
+		
+		for _, prop := range t.Properties {
+			// The name in {name: target} is a string key, not a variable reference
+			if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed {
+				constIndex := c.AddConstant(vm.NewStringValue(ident.Name))
+				c.Emit(vm.OpLoadK, keyReg, constIndex)
+			} else if err := c.compileExpression(prop.Key, keyReg); err != nil {
+				return err
+			}
+			c.Emit(vm.OpGetTable, propReg, valueReg, keyReg)
+			if err := c.compileBindingTarget(prop.Value, propReg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	
+	return fmt.Errorf("unsupported binding target: %T", target)
+}
+
 // compileIfStatement compiles an if statement
 func (c *Compiler) compileIfStatement(stmt *ast.IfStatement) error {
+	// A constant condition compiles only the branch that runs
+	if value, ok := constantValue(stmt.Test); ok {
+		if value.ToBool() {
+			return c.compileStatement(stmt.Consequent)
+		}
+		if stmt.Alternate != nil {
+			return c.compileStatement(stmt.Alternate)
+		}
+		return nil
+	}
+	
 	// Compile condition
 	condReg := c.AllocateRegister()
 	if err := c.compileExpression(stmt.Test, condReg); err != nil {
@@ -355,10 +748,18 @@ func (c *Compiler) compileReturnStatement(stmt *ast.ReturnStatement) error {
 			return err
 		}
 		
+		if err := c.compileTryExits(0); err != nil {
+			return err
+		}
+		
 		// Return with value (a=register, b=1 for one return value)
 		c.Emit(vm.OpReturn, reg, 1)
 		c.FreeRegister(reg)
 	} else {
+		if err := c.compileTryExits(0); err != nil {
+			return err
+		}
+		
 		// Return nil (a=0, b=0 for no return values)
 		c.Emit(vm.OpReturn, 0, 0)
 	}
@@ -366,6 +767,240 @@ func (c *Compiler) compileReturnStatement(stmt *ast.ReturnStatement) error {
 	return nil
 }
 
+// compileTryExits leaves the try regions above depth before a jump out of
+// them, popping each handler and running its finally block
+func (c *Compiler) compileTryExits(depth int) error {
+	saved := c.tryStack
+	defer func() {
+		c.tryStack = saved
+	}()
+	
+	for i := len(saved) - 1; i >= depth; i-- {
+		c.Emit(vm.OpPopTry)
+		
+		// A return inside the finally block only leaves the outer regions
+		c.tryStack = saved[:i]
+		if saved[i].finalizer != nil {
+			if err := c.compileBlockStatement(saved[i].finalizer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enterBreakable starts a loop or switch that break statements can leave.
+// Continue statements can also target it when loop is set.
+func (c *Compiler) enterBreakable(loop bool) {
+	c.breakStack = append(c.breakStack, &breakContext{loop: loop, tryDepth: len(c.tryStack)})
+}
+
+// exitBreakable ends the innermost loop or switch, patching its break jumps
+// to the current position
+func (c *Compiler) exitBreakable() {
+	context := c.breakStack[len(c.breakStack)-1]
+	c.breakStack = c.breakStack[:len(c.breakStack)-1]
+	for _, jump := range context.jumps {
+		c.PatchJump(jump, len(c.instructions))
+	}
+}
+
+// patchContinues patches the continue jumps of the innermost loop to target
+func (c *Compiler) patchContinues(target int) {
+	context := c.breakStack[len(c.breakStack)-1]
+	for _, jump := range context.continues {
+		c.PatchJump(jump, target)
+	}
+	context.continues = nil
+}
+
+// compileBreakStatement compiles a break out of the innermost loop or switch
+func (c *Compiler) compileBreakStatement(stmt *ast.BreakStatement) error {
+	if stmt.Label != nil {
+		return fmt.Errorf("labeled break is not supported")
+	}
+	if len(c.breakStack) == 0 {
+		return fmt.Errorf("break outside of a loop or switch")
+	}
+	
+	context := c.breakStack[len(c.breakStack)-1]
+	if err := c.compileTryExits(context.tryDepth); err != nil {
+		return err
+	}
+	context.jumps = append(context.jumps, c.Emit(vm.OpJmp, 0)) // placeholder
+	return nil
+}
+
+// compileContinueStatement compiles a jump to the next iteration of the
+// innermost loop. Switches in between are skipped.
+func (c *Compiler) compileContinueStatement(stmt *ast.ContinueStatement) error {
+	if stmt.Label != nil {
+		return fmt.Errorf("labeled continue is not supported")
+	}
+	var context *breakContext
+	for i := len(c.breakStack) - 1; i >= 0; i-- {
+		if c.breakStack[i].loop {
+			context = c.breakStack[i]
+			break
+		}
+	}
+	if context == nil {
+		return fmt.Errorf("continue outside of a loop")
+	}
+	
+	if err := c.compileTryExits(context.tryDepth); err != nil {
+		return err
+	}
+	context.continues = append(context.continues, c.Emit(vm.OpJmp, 0)) // placeholder
+	return nil
+}
+
+// compileSwitchStatement compiles a switch statement. The case tests are
+// compared against the discriminant in order, each jumping to its body on
+// a match; the bodies follow in source order so execution falls through
+// until a break. With no match, control goes to the default body or past
+// the switch.
+func (c *Compiler) compileSwitchStatement(stmt *ast.SwitchStatement) error {
+	discReg := c.AllocateRegister()
+	defer c.FreeRegister(discReg)
+	if err := c.compileExpression(stmt.Discriminant, discReg); err != nil {
+		return err
+	}
+	
+	// Dispatch: jump to the body of the first matching case
+	caseJumps := make([]int, len(stmt.Cases))
+	testReg := c.AllocateRegister()
+	for i, clause := range stmt.Cases {
+		if clause.Test == nil {
+			continue
+		}
+		if err := c.compileExpression(clause.Test, testReg); err != nil {
+			return err
+		}
+		c.Emit(vm.OpStrictEq, testReg, discReg, testReg)
+		c.Emit(vm.OpTest, testReg, 0, 1)
+		caseJumps[i] = c.Emit(vm.OpJmp, 0) // placeholder - jump to body on match
+	}
+	c.FreeRegister(testReg)
+	defaultJump := c.Emit(vm.OpJmp, 0) // placeholder - default body or end
+	
+	// Bodies share one scope and fall through into each other
+	c.enterBreakable(false)
+	c.symbolTable = NewSymbolTable(c.symbolTable)
+	hasDefault := false
+	for i, clause := range stmt.Cases {
+		if clause.Test == nil {
+			c.PatchJump(defaultJump, len(c.instructions))
+			hasDefault = true
+		} else {
+			c.PatchJump(caseJumps[i], len(c.instructions))
+		}
+		for _, consequent := range clause.Consequent {
+			if err := c.compileStatement(consequent); err != nil {
+				return err
+			}
+		}
+	}
+	c.exitScope()
+	
+	if !hasDefault {
+		c.PatchJump(defaultJump, len(c.instructions))
+	}
+	c.exitBreakable()
+	
+	return nil
+}
+
+// compileTryStatement compiles a try statement. The finally block is
+// emitted twice: once after normal completion and once on the exceptional
+// path, where it is followed by rethrowing the caught value.
+func (c *Compiler) compileTryStatement(stmt *ast.TryStatement) error {
+	excReg := c.AllocateRegister()
+	defer c.FreeRegister(excReg)
+	
+	var exits []int
+	
+	// Protected block
+	setup := c.Emit(vm.OpSetupTry, excReg, 0) // placeholder
+	if err := c.compileTryRegion(stmt.Block, stmt.Finalizer); err != nil {
+		return err
+	}
+	c.Emit(vm.OpPopTry)
+	exits = append(exits, c.Emit(vm.OpJmp, 0))
+	c.PatchJump(setup, len(c.instructions))
+	
+	// Catch clause, itself protected when there is a finally block
+	if stmt.Handler != nil {
+		catchSetup := -1
+		if stmt.Finalizer != nil {
+			catchSetup = c.Emit(vm.OpSetupTry, excReg, 0) // placeholder
+		}
+		
+		c.symbolTable = NewSymbolTable(c.symbolTable)
+		if stmt.Handler.Param != nil {
+			c.symbolTable.Define(stmt.Handler.Param.Name, SymbolLocal, excReg)
+		}
+		var err error
+		if catchSetup >= 0 {
+			err = c.compileTryRegion(stmt.Handler.Body, stmt.Finalizer)
+		} else {
+			err = c.compileBlockStatement(stmt.Handler.Body)
+		}
+		c.exitScope()
+		if err != nil {
+			return err
+		}
+		
+		if catchSetup >= 0 {
+			c.Emit(vm.OpPopTry)
+			exits = append(exits, c.Emit(vm.OpJmp, 0))
+			c.PatchJump(catchSetup, len(c.instructions))
+		}
+	}
+	
+	// Exceptional path: run the finally block, then rethrow
+	if stmt.Finalizer != nil {
+		if err := c.compileBlockStatement(stmt.Finalizer); err != nil {
+			return err
+		}
+		c.Emit(vm.OpThrow, excReg)
+	}
+	
+	for _, exit := range exits {
+		c.PatchJump(exit, len(c.instructions))
+	}
+	
+	// Normal path
+	if stmt.Finalizer != nil {
+		if err := c.compileBlockStatement(stmt.Finalizer); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+// compileTryRegion compiles a block covered by an exception handler
+func (c *Compiler) compileTryRegion(block *ast.BlockStatement, finalizer *ast.BlockStatement) error {
+	c.tryStack = append(c.tryStack, tryContext{finalizer: finalizer})
+	defer func() {
+		c.tryStack = c.tryStack[:len(c.tryStack)-1]
+	}()
+	return c.compileBlockStatement(block)
+}
+
+// compileThrowStatement compiles a throw statement
+func (c *Compiler) compileThrowStatement(stmt *ast.ThrowStatement) error {
+	reg := c.AllocateRegister()
+	defer c.FreeRegister(reg)
+	
+	if err := c.compileExpression(stmt.Argument, reg); err != nil {
+		return err
+	}
+	c.Emit(vm.OpThrow, reg)
+	return nil
+}
+
 // compileBlockStatement compiles a block statement
 func (c *Compiler) compileBlockStatement(block *ast.BlockStatement) error {
 	// Enter new scope
@@ -375,16 +1010,22 @@ func (c *Compiler) compileBlockStatement(block *ast.BlockStatement) error {
 		if err := c.compileStatement(stmt); err != nil {
 			return err
 		}
+		// Statements after an unconditional exit can never run
+		if ast.TerminatesBlock(stmt) {
+			break
+		}
 	}
 	
 	// Exit scope
-	c.symbolTable = c.symbolTable.parent
+	c.exitScope()
 	
 	return nil
 }
 
 // compileExpression compiles an expression
 func (c *Compiler) compileExpression(expr ast.Expression, targetReg int) error {
+	defer c.trackLine(expr)()
+	
 	switch e := expr.(type) {
 	case *ast.Identifier:
 		return c.compileIdentifier(e, targetReg)
@@ -404,6 +1045,8 @@ func (c *Compiler) compileExpression(expr ast.Expression, targetReg int) error {
 		return c.compileVoidLiteral(e, targetReg)
 	case *ast.ArrayLiteral:
 		return c.compileArrayLiteral(e, targetReg)
+	case *ast.ObjectLiteral:
+		return c.compileObjectLiteral(e, targetReg)
 	case *ast.BinaryExpression:
 		return c.compileBinaryExpression(e, targetReg)
 	case *ast.UnaryExpression:
@@ -416,6 +1059,22 @@ func (c *Compiler) compileExpression(expr ast.Expression, targetReg int) error {
 		return c.compileMemberExpression(e, targetReg)
 	case *ast.ArrowFunctionExpression:
 		return c.compileArrowFunctionExpression(e, targetReg)
+	case *ast.FunctionExpression:
+		return c.compileFunctionExpression(e, targetReg)
+	case *ast.ConditionalExpression:
+		return c.compileConditionalExpression(e, targetReg)
+	case *ast.SequenceExpression:
+		// Each expression overwrites the target; the last one is the value
+		for _, expr := range e.Expressions {
+			if err := c.compileExpression(expr, targetReg); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *ast.TypeAssertion:
+		return c.compileTypeAssertion(e, targetReg)
+	case *ast.SpreadElement:
+		return fmt.Errorf("spread syntax is only supported in array literals, object literals and argument lists")
 	default:
 		return fmt.Errorf("unsupported expression type: %T", expr)
 	}
@@ -430,13 +1089,11 @@ func (c *Compiler) compileIdentifier(expr *ast.Identifier, targetReg int) error
 			c.Emit(vm.OpMove, targetReg, symbol.Register)
 		} else {
 			// Handle other symbol types (global, function, etc.)
-			constIndex := c.AddConstant(vm.NewStringValue(expr.Name))
-			c.Emit(vm.OpGetGlobal, targetReg, constIndex)
+			c.Emit(vm.OpGetGlobal, targetReg, c.GlobalSlot(expr.Name))
 		}
 	} else {
 		// Treat as global variable
-		constIndex := c.AddConstant(vm.NewStringValue(expr.Name))
-		c.Emit(vm.OpGetGlobal, targetReg, constIndex)
+		c.Emit(vm.OpGetGlobal, targetReg, c.GlobalSlot(expr.Name))
 	}
 	
 	return nil
@@ -473,6 +1130,20 @@ func (c *Compiler) compileBooleanLiteral(expr *ast.BooleanLiteral, targetReg int
 	return nil
 }
 
+// compileConstant loads a value folded at compile time into targetReg
+func (c *Compiler) compileConstant(value vm.Value, targetReg int) error {
+	if value.Type == vm.TypeBool {
+		if value.AsBool() {
+			c.Emit(vm.OpLoadBool, targetReg, 1, 0)
+		} else {
+			c.Emit(vm.OpLoadBool, targetReg, 0, 0)
+		}
+		return nil
+	}
+	c.Emit(vm.OpLoadK, targetReg, c.AddConstant(value))
+	return nil
+}
+
 // compileNullLiteral compiles a null literal
 func (c *Compiler) compileNullLiteral(expr *ast.NullLiteral, targetReg int) error {
 	constIndex := c.AddConstant(vm.NullValue)
@@ -501,6 +1172,11 @@ func (c *Compiler) compileArrayLiteral(expr *ast.ArrayLiteral, targetReg int) er
 	
 	// Compile and set each element
 	for i, element := range expr.Elements {
+		// Element positions after a spread are only known at runtime
+		if _, ok := element.(*ast.SpreadElement); ok {
+			return c.compileSpreadElements(expr.Elements[i:], targetReg)
+		}
+		
 		if element != nil {
 			// Compile element value
 			valueReg := c.AllocateRegister()
@@ -526,8 +1202,87 @@ func (c *Compiler) compileArrayLiteral(expr *ast.ArrayLiteral, targetReg int) er
 	return nil
 }
 
+// compileSpreadElements appends elements to the array in targetReg. Spreads
+// are expanded by OpSpread; other elements are stored at the current length
+// of the array.
+func (c *Compiler) compileSpreadElements(elements []ast.Expression, targetReg int) error {
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	indexReg := c.AllocateRegister()
+	defer c.FreeRegister(indexReg)
+	
+	for _, element := range elements {
+		if spread, ok := element.(*ast.SpreadElement); ok {
+			if err := c.compileExpression(spread.Argument, valueReg); err != nil {
+				return err
+			}
+			c.Emit(vm.OpSpread, targetReg, valueReg, 0)
+			continue
+		}
+		
+		if element == nil {
+			c.Emit(vm.OpLoadNil, valueReg)
+		} else if err := c.compileExpression(element, valueReg); err != nil {
+			return err
+		}
+		c.Emit(vm.OpLen, indexReg, targetReg, 0)
+		c.Emit(vm.OpSetTable, targetReg, indexReg, valueReg)
+	}
+	
+	return nil
+}
+
+// compileObjectLiteral compiles an object literal
+func (c *Compiler) compileObjectLiteral(expr *ast.ObjectLiteral, targetReg int) error {
+	c.Emit(vm.OpNewTable, targetReg, 0, 0)
+	
+	keyReg := c.AllocateRegister()
+	defer c.FreeRegister(keyReg)
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	
+	for _, prop := range expr.Properties {
+		// Spread properties copy every property of their value
+		if spread, ok := prop.Value.(*ast.SpreadElement); ok && prop.Key == nil {
+			if err := c.compileExpression(spread.Argument, valueReg); err != nil {
+				return err
+			}
+			c.Emit(vm.OpSpread, targetReg, valueReg, 0)
+			continue
+		}
+		
+		// Identifier keys name the property; computed keys are evaluated
+		if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed {
+			constIndex := c.AddConstant(vm.NewStringValue(ident.Name))
+			c.Emit(vm.OpLoadK, keyReg, constIndex)
+		} else if err := c.compileExpression(prop.Key, keyReg); err != nil {
+			return err
+		}
+		
+		if err := c.compileExpression(prop.Value, valueReg); err != nil {
+			return err
+		}
+		
+		// Set property: object[key] = value
+		c.Emit(vm.OpSetTable, targetReg, keyReg, valueReg)
+	}
+	
+	return nil
+}
+
 // compileBinaryExpression compiles a binary expression
 func (c *Compiler) compileUnaryExpression(expr *ast.UnaryExpression, targetReg int) error {
+	switch expr.Operator.String() {
+	case "++", "--":
+		return c.compileUpdateExpression(expr, targetReg)
+	case "+":
+		// The checker requires a numeric operand, so + leaves it unchanged
+		return c.compileExpression(expr.Operand, targetReg)
+	}
+	if value, ok := constantValue(expr); ok {
+		return c.compileConstant(value, targetReg)
+	}
+	
 	operandReg := c.AllocateRegister()
 	defer c.FreeRegister(operandReg)
 
@@ -538,12 +1293,12 @@ func (c *Compiler) compileUnaryExpression(expr *ast.UnaryExpression, targetReg i
 	switch expr.Operator.String() {
 	case "!":
 		c.Emit(vm.OpNot, targetReg, operandReg)
+	case "typeof":
+		c.Emit(vm.OpTypeOf, targetReg, operandReg)
+	case "~":
+		c.Emit(vm.OpBitNot, targetReg, operandReg)
 	case "-":
-		// For negative numbers, we can use subtraction from 0
-		zeroReg := c.AllocateRegister()
-		defer c.FreeRegister(zeroReg)
-		c.Emit(vm.OpLoadK, zeroReg, c.AddConstant(vm.NewIntValue(0)))
-		c.Emit(vm.OpSub, targetReg, zeroReg, operandReg)
+		c.Emit(vm.OpNeg, targetReg, operandReg)
 	default:
 		return fmt.Errorf("unsupported unary operator: %s", expr.Operator.String())
 	}
@@ -551,15 +1306,88 @@ func (c *Compiler) compileUnaryExpression(expr *ast.UnaryExpression, targetReg i
 	return nil
 }
 
+// compileUpdateExpression compiles prefix and postfix ++ and --. The
+// variable or element is updated in place; the result is the new value for
+// prefix forms and the old value for postfix forms.
+func (c *Compiler) compileUpdateExpression(expr *ast.UnaryExpression, targetReg int) error {
+	opcode := vm.OpAdd
+	if expr.Operator.String() == "--" {
+		opcode = vm.OpSub
+	}
+	
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	oldReg := c.AllocateRegister()
+	defer c.FreeRegister(oldReg)
+	oneReg := c.AllocateRegister()
+	defer c.FreeRegister(oneReg)
+	c.Emit(vm.OpLoadK, oneReg, c.AddConstant(vm.NewIntValue(1)))
+	
+	switch operand := expr.Operand.(type) {
+	case *ast.Identifier:
+		if err := c.compileExpression(operand, valueReg); err != nil {
+			return err
+		}
+		c.Emit(vm.OpMove, oldReg, valueReg)
+		c.Emit(opcode, valueReg, valueReg, oneReg)
+		if err := c.compileStore(operand, valueReg); err != nil {
+			return err
+		}
+		
+	case *ast.MemberExpression:
+		// Evaluate the object and key only once
+		objReg := c.AllocateRegister()
+		defer c.FreeRegister(objReg)
+		
+		if err := c.compileExpression(operand.Object, objReg); err != nil {
+			return err
+		}
+		
+		propReg := c.AllocateRegister()
+		defer c.FreeRegister(propReg)
+		
+		if err := c.compileMemberKey(operand, propReg); err != nil {
+			return err
+		}
+		
+		c.Emit(vm.OpGetTable, valueReg, objReg, propReg)
+		c.Emit(vm.OpMove, oldReg, valueReg)
+		c.Emit(opcode, valueReg, valueReg, oneReg)
+		c.Emit(vm.OpSetTable, objReg, propReg, valueReg)
+		
+	default:
+		return fmt.Errorf("invalid operand for %s: %T", expr.Operator.String(), expr.Operand)
+	}
+	
+	if expr.Postfix {
+		c.Emit(vm.OpMove, targetReg, oldReg)
+	} else {
+		c.Emit(vm.OpMove, targetReg, valueReg)
+	}
+	return nil
+}
+
 func (c *Compiler) compileBinaryExpression(expr *ast.BinaryExpression, targetReg int) error {
-	// Compile operands
-	leftReg := c.AllocateRegister()
-	rightReg := c.AllocateRegister()
+	switch expr.Operator.String() {
+	case "&&", "||", "??":
+		return c.compileLogicalExpression(expr, targetReg)
+	}
+	if value, ok := constantValue(expr); ok {
+		return c.compileConstant(value, targetReg)
+	}
 	
+	// Compile the left operand straight into the target unless the target
+	// holds a variable the right operand may still read. Left-nested chains
+	// like a+b+c+... then need only one extra register.
+	leftReg := targetReg
+	if c.variableRegisters[targetReg] {
+		leftReg = c.AllocateRegister()
+	}
 	if err := c.compileExpression(expr.Left, leftReg); err != nil {
 		return err
 	}
 	
+	rightReg := c.AllocateRegister()
 	if err := c.compileExpression(expr.Right, rightReg); err != nil {
 		return err
 	}
@@ -576,10 +1404,17 @@ func (c *Compiler) compileBinaryExpression(expr *ast.BinaryExpression, targetReg
 		c.Emit(vm.OpDiv, targetReg, leftReg, rightReg)
 	case "%":
 		c.Emit(vm.OpMod, targetReg, leftReg, rightReg)
+	case "**":
+		c.Emit(vm.OpPow, targetReg, leftReg, rightReg)
 	case "==":
 		c.Emit(vm.OpEq, targetReg, leftReg, rightReg)
 	case "!=":
 		c.Emit(vm.OpNe, targetReg, leftReg, rightReg)
+	case "===":
+		c.Emit(vm.OpStrictEq, targetReg, leftReg, rightReg)
+	case "!==":
+		c.Emit(vm.OpStrictEq, targetReg, leftReg, rightReg)
+		c.Emit(vm.OpNot, targetReg, targetReg)
 	case "<":
 		c.Emit(vm.OpLt, targetReg, leftReg, rightReg)
 	case "<=":
@@ -588,125 +1423,185 @@ func (c *Compiler) compileBinaryExpression(expr *ast.BinaryExpression, targetReg
 		c.Emit(vm.OpGt, targetReg, leftReg, rightReg)
 	case ">=":
 		c.Emit(vm.OpGe, targetReg, leftReg, rightReg)
-	case "&&":
-		c.Emit(vm.OpAnd, targetReg, leftReg, rightReg)
-	case "||":
-		c.Emit(vm.OpOr, targetReg, leftReg, rightReg)
+	case "&":
+		c.Emit(vm.OpBitAnd, targetReg, leftReg, rightReg)
+	case "|":
+		c.Emit(vm.OpBitOr, targetReg, leftReg, rightReg)
+	case "^":
+		c.Emit(vm.OpBitXor, targetReg, leftReg, rightReg)
+	case "<<":
+		c.Emit(vm.OpShl, targetReg, leftReg, rightReg)
+	case ">>":
+		c.Emit(vm.OpShr, targetReg, leftReg, rightReg)
+	case ">>>":
+		c.Emit(vm.OpUShr, targetReg, leftReg, rightReg)
 	default:
 		return fmt.Errorf("unsupported binary operator: %s", expr.Operator.String())
 	}
 	
-	c.FreeRegister(leftReg)
+	if leftReg != targetReg {
+		c.FreeRegister(leftReg)
+	}
 	c.FreeRegister(rightReg)
 	
 	return nil
 }
 
-// compileCallExpression compiles a function call expression
-func (c *Compiler) compileCallExpression(expr *ast.CallExpression, targetReg int) error {
-	// Compile the function being called
-	funcReg := c.AllocateRegister()
-	if err := c.compileExpression(expr.Callee, funcReg); err != nil {
-		c.FreeRegister(funcReg)
+// compileConditionalExpression compiles a ternary expression, evaluating
+// only the selected branch into targetReg
+func (c *Compiler) compileConditionalExpression(expr *ast.ConditionalExpression, targetReg int) error {
+	if value, ok := constantValue(expr.Test); ok {
+		if value.ToBool() {
+			return c.compileExpression(expr.Consequent, targetReg)
+		}
+		return c.compileExpression(expr.Alternate, targetReg)
+	}
+	
+	condReg := c.AllocateRegister()
+	if err := c.compileExpression(expr.Test, condReg); err != nil {
 		return err
 	}
 	
-	// Compile arguments
-	argRegs := make([]int, len(expr.Arguments))
-	for i, arg := range expr.Arguments {
-		argReg := c.AllocateRegister()
-		if err := c.compileExpression(arg, argReg); err != nil {
-			// Free all allocated registers on error
-			c.FreeRegister(funcReg)
-			for j := 0; j < i; j++ {
-				c.FreeRegister(argRegs[j])
-			}
-			c.FreeRegister(argReg)
-			return err
+	// Test condition and jump if false
+	c.Emit(vm.OpTest, condReg)
+	jumpToElse := c.Emit(vm.OpJmp, 0) // placeholder
+	c.FreeRegister(condReg)
+	
+	if err := c.compileExpression(expr.Consequent, targetReg); err != nil {
+		return err
+	}
+	jumpToEnd := c.Emit(vm.OpJmp, 0) // placeholder
+	
+	c.PatchJump(jumpToElse, len(c.instructions))
+	if err := c.compileExpression(expr.Alternate, targetReg); err != nil {
+		return err
+	}
+	
+	c.PatchJump(jumpToEnd, len(c.instructions))
+	return nil
+}
+
+// compileLogicalExpression compiles &&, || and ?? with short-circuit evaluation.
+// The result is the value of the last operand evaluated, as in JavaScript.
+func (c *Compiler) compileLogicalExpression(expr *ast.BinaryExpression, targetReg int) error {
+	// A constant left operand decides at compile time which operand is the
+	// result
+	if left, ok := constantValue(expr.Left); ok {
+		if evaluatesRight(expr.Operator.String(), left) {
+			return c.compileExpression(expr.Right, targetReg)
 		}
-		argRegs[i] = argReg
+		return c.compileConstant(left, targetReg)
 	}
 	
-	// Move function to target register
-	c.Emit(vm.OpMove, targetReg, funcReg)
+	if err := c.compileExpression(expr.Left, targetReg); err != nil {
+		return err
+	}
 	
-	// Move arguments to consecutive registers after function
-	// We need to be careful about the order to avoid overwriting arguments
-	// If any argument register overlaps with target registers, we need to handle it carefully
-	targetArgRegs := make([]int, len(argRegs))
-	for i := range argRegs {
-		targetArgRegs[i] = targetReg + 1 + i
+	// Jump to end when the left operand decides the result
+	var jumpToEnd int
+	switch expr.Operator.String() {
+	case "&&":
+		// OpTest skips the jump if the left operand is truthy
+		c.Emit(vm.OpTest, targetReg)
+		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
+	case "||":
+		// A truthy left operand takes the jump
+		c.Emit(vm.OpTest, targetReg, 0, 1)
+		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
+	case "??":
+		// OpTestNullish skips the jump if the left operand is null or undefined
+		c.Emit(vm.OpTestNullish, targetReg)
+		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
 	}
 	
-	// We need to handle conflicts more carefully
-	// First, identify all conflicts and create a dependency graph
-	moved := make([]bool, len(argRegs))
+	if err := c.compileExpression(expr.Right, targetReg); err != nil {
+		return err
+	}
 	
-	// Keep moving arguments until all are moved
-	for {
-		progress := false
-		
-		for i, argReg := range argRegs {
-			if moved[i] {
-				continue
-			}
-			
-			targetArgReg := targetArgRegs[i]
-			
-			// Check if the target register is currently occupied by an unmoved argument
-			blocked := false
-			for j, otherArgReg := range argRegs {
-				if !moved[j] && j != i && otherArgReg == targetArgReg {
-					blocked = true
-					break
-				}
-			}
-			
-			if !blocked {
-				// Safe to move
-				c.Emit(vm.OpMove, targetArgReg, argReg)
-				moved[i] = true
-				progress = true
-			}
-		}
-		
-		// Check if all arguments are moved
-		allMoved := true
-		for _, m := range moved {
-			if !m {
-				allMoved = false
-				break
-			}
+	c.PatchJump(jumpToEnd, len(c.instructions))
+	return nil
+}
+
+// compileCallExpression compiles a function call expression
+func (c *Compiler) compileCallExpression(expr *ast.CallExpression, targetReg int) error {
+	return c.compileCall(expr, targetReg, true)
+}
+
+// compileCall compiles a call, storing the result in targetReg only when
+// keepResult is set
+func (c *Compiler) compileCall(expr *ast.CallExpression, targetReg int, keepResult bool) error {
+	if c.optionalExits == nil && ast.IsOptionalChain(expr) {
+		resultReg := -1
+		if keepResult {
+			resultReg = targetReg
 		}
-		
-		if allMoved {
+		return c.compileOptionalChain(resultReg, func() error {
+			return c.compileCall(expr, targetReg, keepResult)
+		})
+	}
+	
+	spread := false
+	for _, arg := range expr.Arguments {
+		if _, ok := arg.(*ast.SpreadElement); ok {
+			spread = true
 			break
 		}
-		
-		if !progress {
-			// We have a cycle - need to use a temporary register
-			for i, argReg := range argRegs {
-				if !moved[i] {
-					tempReg := c.AllocateRegister()
-					c.Emit(vm.OpMove, tempReg, argReg)
-					argRegs[i] = tempReg
-					break
-				}
+	}
+	
+	// With a spread the argument count is only known at runtime, so the
+	// arguments are collected into one array that OpCall expands
+	numArgs := len(expr.Arguments)
+	if spread {
+		numArgs = 1
+	}
+	
+	// The callee and its arguments must sit in consecutive registers, so
+	// take a fresh block above every live register
+	base := c.allocateRegisterBlock(numArgs + 1)
+	defer c.freeRegisterBlock(base, numArgs+1)
+	
+	// Compile the function being called
+	if err := c.compileChainLink(expr.Callee, base); err != nil {
+		return err
+	}
+	if expr.Optional {
+		c.emitOptionalExit(base)
+	}
+	
+	// Compile arguments
+	err := c.outsideChain(func() error {
+		if spread {
+			c.Emit(vm.OpNewArray, base+1, len(expr.Arguments))
+			return c.compileSpreadElements(expr.Arguments, base+1)
+		}
+		for i, arg := range expr.Arguments {
+			if err := c.compileExpression(arg, base+1+i); err != nil {
+				return err
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 	
 	// Emit call instruction
-	// OpCall format: R(A)..R(A+C-1) := R(A)(R(A+1)..R(A+B-1))
-	// A = target register (where result goes)
+	// OpCall format: R(A) := R(A)(R(A+1)..R(A+B))
+	// A = function register (where result goes)
 	// B = number of arguments
-	// C = number of return values + 1
-	c.Emit(vm.OpCall, targetReg, len(expr.Arguments), 1)
-	
-	// Free temporary registers
-	c.FreeRegister(funcReg)
-	for _, argReg := range argRegs {
-		c.FreeRegister(argReg)
+	// C = 1 to keep the result, 0 to discard it, plus vm.CallSpread when
+	//     R(A+1) holds the argument array
+	flags := 0
+	if spread {
+		flags |= vm.CallSpread
+	}
+	if !keepResult {
+		c.Emit(vm.OpCall, base, numArgs, flags)
+		return nil
+	}
+	c.Emit(vm.OpCall, base, numArgs, flags|1)
+	if base != targetReg {
+		c.Emit(vm.OpMove, targetReg, base)
 	}
 	
 	return nil
@@ -714,8 +1609,14 @@ func (c *Compiler) compileCallExpression(expr *ast.CallExpression, targetReg int
 
 // compileAssignmentExpression compiles an assignment expression
 func (c *Compiler) compileAssignmentExpression(expr *ast.AssignmentExpression, targetReg int) error {
-	// For now, only support simple assignment (=)
-	if expr.Operator.String() != "=" {
+	switch expr.Operator.String() {
+	case "=":
+		// Simple assignment, handled below
+	case "&&=", "||=", "??=":
+		return c.compileLogicalAssignment(expr, targetReg)
+	case "+=", "-=", "*=", "/=", "%=":
+		return c.compileCompoundAssignment(expr, targetReg)
+	default:
 		return fmt.Errorf("unsupported assignment operator: %s", expr.Operator.String())
 	}
 	
@@ -727,24 +1628,186 @@ func (c *Compiler) compileAssignmentExpression(expr *ast.AssignmentExpression, t
 		return err
 	}
 	
-	// Handle left-hand side assignment
+	if err := c.compileStore(expr.Left, valueReg); err != nil {
+		return err
+	}
+	
+	c.Emit(vm.OpMove, targetReg, valueReg)
+	return nil
+}
+
+// compileLogicalAssignment compiles &&=, ||= and ??=. The right-hand side
+// is only evaluated (and assigned) when the current value of the target
+// requires it. Member targets evaluate their object and key only once.
+func (c *Compiler) compileLogicalAssignment(expr *ast.AssignmentExpression, targetReg int) error {
+	// Load the current value of the target; it is the result if no assignment happens
+	objReg, propReg := -1, -1
+	switch left := expr.Left.(type) {
+	case *ast.Identifier:
+		if err := c.compileExpression(left, targetReg); err != nil {
+			return err
+		}
+		
+	case *ast.MemberExpression:
+		if ast.IsOptionalChain(left) {
+			return fmt.Errorf("cannot assign to optional chain '%s'", left.String())
+		}
+		
+		objReg = c.AllocateRegister()
+		defer c.FreeRegister(objReg)
+		
+		if err := c.compileExpression(left.Object, objReg); err != nil {
+			return err
+		}
+		
+		propReg = c.AllocateRegister()
+		defer c.FreeRegister(propReg)
+		
+		if err := c.compileMemberKey(left, propReg); err != nil {
+			return err
+		}
+		
+		c.Emit(vm.OpGetTable, targetReg, objReg, propReg)
+		
+	default:
+		return fmt.Errorf("unsupported assignment target: %T", expr.Left)
+	}
+	
+	// Jump to end when the assignment must be skipped
+	var jumpToEnd int
+	switch expr.Operator.String() {
+	case "&&=":
+		// OpTest skips the jump if the value is truthy
+		c.Emit(vm.OpTest, targetReg)
+		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
+	case "||=":
+		// A truthy value takes the jump
+		c.Emit(vm.OpTest, targetReg, 0, 1)
+		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
+	case "??=":
+		// OpTestNullish skips the jump if the value is null or undefined
+		c.Emit(vm.OpTestNullish, targetReg)
+		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
+	}
+	
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	
+	if err := c.compileExpression(expr.Right, valueReg); err != nil {
+		return err
+	}
+	
+	if objReg >= 0 {
+		c.Emit(vm.OpSetTable, objReg, propReg, valueReg)
+	} else if err := c.compileStore(expr.Left, valueReg); err != nil {
+		return err
+	}
+	
+	c.Emit(vm.OpMove, targetReg, valueReg)
+	c.PatchJump(jumpToEnd, len(c.instructions))
+	return nil
+}
+
+// compileCompoundAssignment compiles +=, -=, *=, /= and %=. The current
+// value of the target is loaded, combined with the right-hand side and
+// stored back. Member targets evaluate their object and key only once.
+func (c *Compiler) compileCompoundAssignment(expr *ast.AssignmentExpression, targetReg int) error {
+	var opcode vm.OpCode
+	switch expr.Operator.String() {
+	case "+=":
+		opcode = vm.OpAdd
+	case "-=":
+		opcode = vm.OpSub
+	case "*=":
+		opcode = vm.OpMul
+	case "/=":
+		opcode = vm.OpDiv
+	case "%=":
+		opcode = vm.OpMod
+	}
+	
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	rightReg := c.AllocateRegister()
+	defer c.FreeRegister(rightReg)
+	
 	switch left := expr.Left.(type) {
+	case *ast.Identifier:
+		// Load the current value
+		if err := c.compileExpression(left, valueReg); err != nil {
+			return err
+		}
+		
+		if err := c.compileExpression(expr.Right, rightReg); err != nil {
+			return err
+		}
+		
+		c.Emit(opcode, valueReg, valueReg, rightReg)
+		if err := c.compileStore(left, valueReg); err != nil {
+			return err
+		}
+		
+	case *ast.MemberExpression:
+		objReg := c.AllocateRegister()
+		defer c.FreeRegister(objReg)
+		
+		if err := c.compileExpression(left.Object, objReg); err != nil {
+			return err
+		}
+		
+		propReg := c.AllocateRegister()
+		defer c.FreeRegister(propReg)
+		
+		if err := c.compileMemberKey(left, propReg); err != nil {
+			return err
+		}
+		
+		// Load the current value
+		c.Emit(vm.OpGetTable, valueReg, objReg, propReg)
+		
+		if err := c.compileExpression(expr.Right, rightReg); err != nil {
+			return err
+		}
+		
+		c.Emit(opcode, valueReg, valueReg, rightReg)
+		c.Emit(vm.OpSetTable, objReg, propReg, valueReg)
+		
+	default:
+		return fmt.Errorf("unsupported assignment target: %T", expr.Left)
+	}
+	
+	c.Emit(vm.OpMove, targetReg, valueReg)
+	return nil
+}
+
+// compileStore stores the value in valueReg into an assignment target
+func (c *Compiler) compileStore(target ast.Expression, valueReg int) error {
+	switch left := target.(type) {
 	case *ast.Identifier:
 		// Simple variable assignment
 		symbol, exists := c.symbolTable.Resolve(left.Name)
 		if exists && symbol.Type == SymbolLocal {
 			// Local variable assignment
+			if symbol.Conversion != 0 {
+				c.Emit(vm.OpConvert, valueReg, valueReg, int(symbol.Conversion))
+			}
 			c.Emit(vm.OpMove, symbol.Register, valueReg)
-			c.Emit(vm.OpMove, targetReg, symbol.Register)
 		} else {
 			// Global variable assignment
-			constIndex := c.AddConstant(vm.NewStringValue(left.Name))
-			c.Emit(vm.OpSetGlobal, valueReg, constIndex)
-			c.Emit(vm.OpMove, targetReg, valueReg)
+			if !exists && c.strict {
+				pos := left.Pos()
+				c.AddError(fmt.Errorf("line %d, column %d: assignment to undeclared variable '%s'",
+					pos.Line, pos.Column, left.Name))
+			}
+			c.Emit(vm.OpSetGlobal, valueReg, c.GlobalSlot(left.Name))
 		}
 		return nil
 		
 	case *ast.MemberExpression:
+		if ast.IsOptionalChain(left) {
+			return fmt.Errorf("cannot assign to optional chain '%s'", left.String())
+		}
+		
 		// Member expression assignment (obj[prop] = value)
 		objReg := c.AllocateRegister()
 		defer c.FreeRegister(objReg)
@@ -756,17 +1819,16 @@ func (c *Compiler) compileAssignmentExpression(expr *ast.AssignmentExpression, t
 		propReg := c.AllocateRegister()
 		defer c.FreeRegister(propReg)
 		
-		if err := c.compileExpression(left.Property, propReg); err != nil {
+		if err := c.compileMemberKey(left, propReg); err != nil {
 			return err
 		}
 		
 		// Emit OpSetTable instruction to set the value
 		c.Emit(vm.OpSetTable, objReg, propReg, valueReg)
-		c.Emit(vm.OpMove, targetReg, valueReg)
 		return nil
 		
 	default:
-		return fmt.Errorf("unsupported assignment target: %T", expr.Left)
+		return fmt.Errorf("unsupported assignment target: %T", target)
 	}
 }
 
@@ -774,9 +1836,7 @@ func (c *Compiler) compileAssignmentExpression(expr *ast.AssignmentExpression, t
 func (c *Compiler) compileForStatement(stmt *ast.ForStatement) error {
 	// Enter new scope for loop variables
 	c.symbolTable = NewSymbolTable(c.symbolTable)
-	defer func() {
-		c.symbolTable = c.symbolTable.parent
-	}()
+	defer c.exitScope()
 	
 	// Compile initialization if present
 	if stmt.Init != nil {
@@ -785,6 +1845,9 @@ func (c *Compiler) compileForStatement(stmt *ast.ForStatement) error {
 		}
 	}
 	
+	c.enterBreakable(true)
+	defer c.exitBreakable()
+	
 	// Loop start position
 	loopStart := len(c.instructions)
 	
@@ -796,16 +1859,10 @@ func (c *Compiler) compileForStatement(stmt *ast.ForStatement) error {
 			return err
 		}
 		
-		// Negate the condition and test
-		// OpTest skips next instruction if condition is truthy
-		// We want to jump to end when condition is falsy
-		// So we use OpNot to negate the condition first
-		notReg := c.AllocateRegister()
-		c.Emit(vm.OpNot, notReg, condReg)
-		c.Emit(vm.OpTest, notReg)
+		// OpTest skips the exit jump while the condition is truthy
+		c.Emit(vm.OpTest, condReg)
 		jumpToEnd = c.Emit(vm.OpJmp, 0) // placeholder
 		c.FreeRegister(condReg)
-		c.FreeRegister(notReg)
 	}
 	
 	// Compile body
@@ -813,6 +1870,9 @@ func (c *Compiler) compileForStatement(stmt *ast.ForStatement) error {
 		return err
 	}
 	
+	// Continue runs the update before testing the condition again
+	c.patchContinues(len(c.instructions))
+	
 	// Compile update if present
 	if stmt.Update != nil {
 		reg := c.AllocateRegister()
@@ -834,8 +1894,72 @@ func (c *Compiler) compileForStatement(stmt *ast.ForStatement) error {
 	return nil
 }
 
+// compileForOfStatement compiles a for-of statement
+func (c *Compiler) compileForOfStatement(stmt *ast.ForOfStatement) error {
+	return c.compileIteration(stmt.Left, stmt.Right, stmt.Body, false)
+}
+
+// compileForInStatement compiles a for-in statement, iterating over the
+// keys of the object
+func (c *Compiler) compileForInStatement(stmt *ast.ForInStatement) error {
+	return c.compileIteration(stmt.Left, stmt.Right, stmt.Body, true)
+}
+
+// compileIteration compiles a loop binding each element of iterable to
+// left. When keys is set, the loop iterates over the iterable's keys. The
+// VM advances a cursor over the iterable and skips the exit jump while
+// elements remain.
+func (c *Compiler) compileIteration(left ast.BindingTarget, iterable ast.Expression, body ast.Statement, keys bool) error {
+	// Enter new scope for the loop variable
+	c.symbolTable = NewSymbolTable(c.symbolTable)
+	defer c.exitScope()
+	
+	iterReg := c.AllocateRegister()
+	defer c.FreeRegister(iterReg)
+	if err := c.compileExpression(iterable, iterReg); err != nil {
+		return err
+	}
+	if keys {
+		c.Emit(vm.OpKeys, iterReg, iterReg, 0)
+	}
+	
+	cursorReg := c.AllocateRegister()
+	defer c.FreeRegister(cursorReg)
+	c.Emit(vm.OpLoadK, cursorReg, c.AddConstant(vm.NewIntValue(0)))
+	
+	elementReg := c.AllocateRegister()
+	defer c.FreeRegister(elementReg)
+	if id, ok := left.(*ast.Identifier); ok {
+		c.symbolTable.Define(id.Name, SymbolLocal, elementReg)
+	}
+	
+	c.enterBreakable(true)
+	defer c.exitBreakable()
+	
+	// Loop start: fetch the next element or fall through to the exit jump
+	loopStart := len(c.instructions)
+	c.Emit(vm.OpIterNext, elementReg, iterReg, cursorReg)
+	jumpToEnd := c.Emit(vm.OpJmp, 0) // placeholder
+	
+	// Compile body
+	if err := c.compileStatement(body); err != nil {
+		return err
+	}
+	
+	// Jump back to loop start
+	jumpBackPos := c.Emit(vm.OpJmp, 0) // placeholder
+	c.PatchJump(jumpBackPos, loopStart)
+	c.patchContinues(loopStart)
+	c.PatchJump(jumpToEnd, len(c.instructions))
+	
+	return nil
+}
+
 // compileWhileStatement compiles a while statement
 func (c *Compiler) compileWhileStatement(stmt *ast.WhileStatement) error {
+	c.enterBreakable(true)
+	defer c.exitBreakable()
+	
 	// Loop start position
 	loopStart := len(c.instructions)
 	
@@ -845,16 +1969,10 @@ func (c *Compiler) compileWhileStatement(stmt *ast.WhileStatement) error {
 		return err
 	}
 	
-	// Negate the condition and test
-	// OpTest skips next instruction if condition is truthy
-	// We want to jump to end when condition is falsy
-	// So we use OpNot to negate the condition first
-	notReg := c.AllocateRegister()
-	c.Emit(vm.OpNot, notReg, condReg)
-	c.Emit(vm.OpTest, notReg)
+	// OpTest skips the exit jump while the condition is truthy
+	c.Emit(vm.OpTest, condReg)
 	jumpToEnd := c.Emit(vm.OpJmp, 0) // placeholder - jump to end if condition is false
 	c.FreeRegister(condReg)
-	c.FreeRegister(notReg)
 	
 	// Compile body
 	if err := c.compileStatement(stmt.Body); err != nil {
@@ -864,6 +1982,7 @@ func (c *Compiler) compileWhileStatement(stmt *ast.WhileStatement) error {
 	// Jump back to loop start
 	jumpBackPos := c.Emit(vm.OpJmp, 0) // placeholder
 	c.PatchJump(jumpBackPos, loopStart)
+	c.patchContinues(loopStart)
 	
 	// Patch jump to end
 	c.PatchJump(jumpToEnd, len(c.instructions))
@@ -871,18 +1990,58 @@ func (c *Compiler) compileWhileStatement(stmt *ast.WhileStatement) error {
 	return nil
 }
 
+// compileDoWhileStatement compiles a do-while statement
+func (c *Compiler) compileDoWhileStatement(stmt *ast.DoWhileStatement) error {
+	c.enterBreakable(true)
+	defer c.exitBreakable()
+	
+	// Loop start position; the body always runs at least once
+	loopStart := len(c.instructions)
+	
+	// Compile body
+	if err := c.compileStatement(stmt.Body); err != nil {
+		return err
+	}
+	
+	// Continue goes straight to the condition
+	c.patchContinues(len(c.instructions))
+	
+	// Compile test condition
+	condReg := c.AllocateRegister()
+	if err := c.compileExpression(stmt.Test, condReg); err != nil {
+		return err
+	}
+	
+	// Jump back to the start only when the condition is truthy
+	c.Emit(vm.OpTest, condReg, 0, 1)
+	jumpBackPos := c.Emit(vm.OpJmp, 0) // placeholder
+	c.PatchJump(jumpBackPos, loopStart)
+	c.FreeRegister(condReg)
+	
+	return nil
+}
+
 // compileMemberExpression compiles a member expression (obj[prop] or obj.prop)
 func (c *Compiler) compileMemberExpression(expr *ast.MemberExpression, targetReg int) error {
+	if c.optionalExits == nil && ast.IsOptionalChain(expr) {
+		return c.compileOptionalChain(targetReg, func() error {
+			return c.compileMemberExpression(expr, targetReg)
+		})
+	}
+	
 	// Compile the object
 	objReg := c.AllocateRegister()
-	if err := c.compileExpression(expr.Object, objReg); err != nil {
+	if err := c.compileChainLink(expr.Object, objReg); err != nil {
 		return err
 	}
 	defer c.FreeRegister(objReg)
+	if expr.Optional {
+		c.emitOptionalExit(objReg)
+	}
 
 	// Compile the property/index
 	propReg := c.AllocateRegister()
-	if err := c.compileExpression(expr.Property, propReg); err != nil {
+	if err := c.outsideChain(func() error { return c.compileMemberKey(expr, propReg) }); err != nil {
 		return err
 	}
 	defer c.FreeRegister(propReg)
@@ -893,27 +2052,234 @@ func (c *Compiler) compileMemberExpression(expr *ast.MemberExpression, targetReg
 	return nil
 }
 
+// compileOptionalChain compiles an optional chain with compile. When a ?.
+// link finds null or undefined, the rest of the chain is skipped and the
+// chain evaluates to nil in resultReg (-1 if the result is unused).
+func (c *Compiler) compileOptionalChain(resultReg int, compile func() error) error {
+	var exits []int
+	c.optionalExits = &exits
+	err := compile()
+	c.optionalExits = nil
+	if err != nil || len(exits) == 0 {
+		return err
+	}
+	
+	skipNil := c.Emit(vm.OpJmp, 0) // placeholder
+	for _, jump := range exits {
+		c.PatchJump(jump, len(c.instructions))
+	}
+	if resultReg >= 0 {
+		c.Emit(vm.OpLoadNil, resultReg)
+	}
+	c.PatchJump(skipNil, len(c.instructions))
+	return nil
+}
+
+// emitOptionalExit leaves the current optional chain when reg is null or
+// undefined
+func (c *Compiler) emitOptionalExit(reg int) {
+	c.Emit(vm.OpTestNullish, reg, 0, 1)
+	*c.optionalExits = append(*c.optionalExits, c.Emit(vm.OpJmp, 0)) // placeholder
+}
+
+// compileChainLink compiles the object of a member access or the callee of
+// a call into targetReg. Member accesses and calls continue the current
+// optional chain; anything else starts outside it.
+func (c *Compiler) compileChainLink(expr ast.Expression, targetReg int) error {
+	switch expr.(type) {
+	case *ast.MemberExpression, *ast.CallExpression:
+		return c.compileExpression(expr, targetReg)
+	}
+	return c.outsideChain(func() error { return c.compileExpression(expr, targetReg) })
+}
+
+// outsideChain runs compile for a part of an expression that doesn't belong
+// to the current optional chain, such as an argument, so that its own ?.
+// links don't leave the enclosing chain
+func (c *Compiler) outsideChain(compile func() error) error {
+	saved := c.optionalExits
+	c.optionalExits = nil
+	defer func() { c.optionalExits = saved }()
+	return compile()
+}
+
+// compileMemberKey loads the key of a member expression into targetReg.
+// The name in obj.prop is a string key, not a variable reference.
+func (c *Compiler) compileMemberKey(expr *ast.MemberExpression, targetReg int) error {
+	if ident, ok := expr.Property.(*ast.Identifier); ok && !expr.Computed {
+		constIndex := c.AddConstant(vm.NewStringValue(ident.Name))
+		c.Emit(vm.OpLoadK, targetReg, constIndex)
+		return nil
+	}
+	return c.compileExpression(expr.Property, targetReg)
+}
+
+// compileEnumDeclaration lowers an enum into a global object mapping
+// member names to their values
+func (c *Compiler) compileEnumDeclaration(stmt *ast.EnumDeclaration) error {
+	enumReg := c.AllocateRegister()
+	c.Emit(vm.OpNewTable, enumReg, 0, 0)
+	
+	keyReg := c.AllocateRegister()
+	defer c.FreeRegister(keyReg)
+	valueReg := c.AllocateRegister()
+	defer c.FreeRegister(valueReg)
+	
+	// Members without an initializer continue from the previous numeric value
+	var next int64
+	for _, member := range stmt.Members {
+		var value vm.Value
+		switch init := member.Value.(type) {
+		case nil:
+			value = vm.NewIntValue(next)
+		case *ast.StringLiteral:
+			value = vm.NewStringValue(init.Value)
+		case *ast.IntegerLiteral:
+			value = vm.NewIntValue(init.Value)
+		case *ast.UnaryExpression:
+			literal, ok := init.Operand.(*ast.IntegerLiteral)
+			if !ok || init.Operator.String() != "-" {
+				return fmt.Errorf("enum member '%s' must be a constant", member.Name.Name)
+			}
+			value = vm.NewIntValue(-literal.Value)
+		default:
+			return fmt.Errorf("enum member '%s' must be a constant", member.Name.Name)
+		}
+		if value.Type == vm.TypeInt {
+			n, _ := value.ToInt()
+			next = n + 1
+		}
+		
+		c.Emit(vm.OpLoadK, keyReg, c.AddConstant(vm.NewStringValue(member.Name.Name)))
+		c.Emit(vm.OpLoadK, valueReg, c.AddConstant(value))
+		c.Emit(vm.OpSetTable, enumReg, keyReg, valueReg)
+	}
+	
+	// Enums are stored as globals like function declarations
+	c.Emit(vm.OpSetGlobal, enumReg, c.GlobalSlot(stmt.Name.Name))
+	c.symbolTable.Define(stmt.Name.Name, SymbolGlobal, enumReg)
+	
+	return nil
+}
+
+// defineParameter defines a parameter in register reg, converting the
+// argument to the parameter's width if it has a sized numeric type
+func (c *Compiler) defineParameter(param *ast.Parameter, reg int) error {
+	symbol := c.symbolTable.Define(param.Name.Name, SymbolLocal, reg)
+	
+	// The default is used when the argument is missing, null or undefined,
+	// and may refer to earlier parameters
+	if param.DefaultValue != nil {
+		c.Emit(vm.OpTestNullish, reg)
+		skipDefault := c.Emit(vm.OpJmp, 0) // placeholder
+		if err := c.compileExpression(param.DefaultValue, reg); err != nil {
+			return err
+		}
+		c.PatchJump(skipDefault, len(c.instructions))
+	}
+	
+	symbol.Conversion = numericConversion(param.TypeAnnotation)
+	if symbol.Conversion != 0 {
+		c.Emit(vm.OpConvert, reg, reg, int(symbol.Conversion))
+	}
+	return nil
+}
+
+// hasRestParameter reports whether the last parameter is a rest parameter
+func hasRestParameter(params []*ast.Parameter) bool {
+	return len(params) > 0 && params[len(params)-1].Rest
+}
+
+// optionalParameters counts the parameters with defaults that end the list,
+// before any rest parameter. Calls may omit them.
+func optionalParameters(params []*ast.Parameter) int {
+	if hasRestParameter(params) {
+		params = params[:len(params)-1]
+	}
+	count := 0
+	for i := len(params) - 1; i >= 0 && params[i].DefaultValue != nil; i-- {
+		count++
+	}
+	return count
+}
+
+// numericConversion returns the conversion enforcing a sized numeric type
+// annotation, or 0 if the annotation is not a sized numeric type
+func numericConversion(annotation ast.TypeNode) vm.NumericKind {
+	basic, ok := annotation.(*ast.BasicType)
+	if !ok {
+		return 0
+	}
+	switch basic.Kind.String() {
+	case "int8":
+		return vm.KindInt8
+	case "int16":
+		return vm.KindInt16
+	case "int32":
+		return vm.KindInt32
+	case "int64":
+		return vm.KindInt64
+	case "float32":
+		return vm.KindFloat32
+	case "float64":
+		return vm.KindFloat64
+	default:
+		return 0
+	}
+}
+
+// compileTypeAssertion compiles an 'as' cast. Casts to numeric types convert
+// the value; other casts only exist at type-check time.
+func (c *Compiler) compileTypeAssertion(expr *ast.TypeAssertion, targetReg int) error {
+	if err := c.compileExpression(expr.Expression, targetReg); err != nil {
+		return err
+	}
+	
+	conversion := numericConversion(expr.Type)
+	if basic, ok := expr.Type.(*ast.BasicType); ok {
+		switch basic.Kind.String() {
+		case "int":
+			conversion = vm.KindInt64
+		case "float", "number":
+			conversion = vm.KindFloat64
+		}
+	}
+	if conversion != 0 {
+		c.Emit(vm.OpConvert, targetReg, targetReg, int(conversion))
+	}
+	return nil
+}
+
 // compileFunctionDeclaration compiles a function declaration
 func (c *Compiler) compileFunctionDeclaration(stmt *ast.FunctionDeclaration) error {
 	// Create a new function
 	function := vm.NewFunction(stmt.Name.Name)
 	function.NumParams = len(stmt.Parameters)
+	function.IsVariadic = hasRestParameter(stmt.Parameters)
+	function.NumOptional = optionalParameters(stmt.Parameters)
 	
 	// Create a new compiler for the function body
 	functionCompiler := NewCompiler()
 	functionCompiler.symbolTable = NewSymbolTable(c.symbolTable)
+	functionCompiler.line = c.line
+	functionCompiler.strict = c.strict
+	functionCompiler.module = c.module
+	functionCompiler.optimize = c.optimize
+	
+	// Set the next register to start after parameters, so default values
+	// are evaluated in registers of their own
+	functionCompiler.nextRegister = len(stmt.Parameters)
+	functionCompiler.maxRegisters = len(stmt.Parameters)
 	
 	// Define parameters in the function's symbol table
 	for i, param := range stmt.Parameters {
-		functionCompiler.symbolTable.Define(param.Name.Name, SymbolLocal, i)
+		if err := functionCompiler.defineParameter(param, i); err != nil {
+			return err
+		}
 		// Mark parameter registers as variable registers
 		functionCompiler.variableRegisters[i] = true
 	}
 	
-	// Set the next register to start after parameters
-	functionCompiler.nextRegister = len(stmt.Parameters)
-	functionCompiler.maxRegisters = len(stmt.Parameters)
-	
 	// Compile the function body
 	if err := functionCompiler.compileBlockStatement(stmt.Body); err != nil {
 		return err
@@ -925,19 +2291,25 @@ func (c *Compiler) compileFunctionDeclaration(stmt *ast.FunctionDeclaration) err
 		functionCompiler.Emit(vm.OpReturn, 0, 0) // return with no values
 	}
 	
+	// Report errors recorded while compiling the body
+	for _, err := range functionCompiler.GetErrors() {
+		c.AddError(err)
+	}
+	
 	// Set the compiled instructions and constants
+	if functionCompiler.optimize {
+		functionCompiler.peephole()
+	}
 	function.Instructions = functionCompiler.instructions
+	function.LineNumbers = functionCompiler.lines
 	function.Constants = functionCompiler.constants
+	function.Globals = functionCompiler.globals
 	function.NumLocals = functionCompiler.maxRegisters
 	
 	// Add the function as a constant
 	functionValue := vm.NewFunctionValue(function)
 	constIndex := c.AddConstant(functionValue)
 	
-	// Add function name as a constant for OpSetGlobal
-	nameValue := vm.NewStringValue(stmt.Name.Name)
-	nameIndex := c.AddConstant(nameValue)
-	
 	// Allocate a register for the function
 	funcReg := c.AllocateRegister()
 	
@@ -945,7 +2317,7 @@ func (c *Compiler) compileFunctionDeclaration(stmt *ast.FunctionDeclaration) err
 	c.Emit(vm.OpLoadK, funcReg, constIndex)
 	
 	// Emit OpSetGlobal to store the function as a global variable
-	c.Emit(vm.OpSetGlobal, funcReg, nameIndex)
+	c.Emit(vm.OpSetGlobal, funcReg, c.GlobalSlot(stmt.Name.Name))
 	
 	// Define the function in the symbol table as global
 	c.symbolTable.Define(stmt.Name.Name, SymbolGlobal, funcReg)
@@ -958,19 +2330,45 @@ func (c *Compiler) GetFunction() *vm.Function {
 	return c.function
 }
 
+// compileFunctionExpression compiles a function expression, such as the
+// value of a method shorthand property, like an arrow function with a
+// block body
+func (c *Compiler) compileFunctionExpression(expr *ast.FunctionExpression, targetReg int) error {
+	return c.compileArrowFunctionExpression(&ast.ArrowFunctionExpression{
+		Parameters: expr.Parameters,
+		Body:       expr.Body,
+		ReturnType: expr.ReturnType,
+	}, targetReg)
+}
+
 // compileArrowFunctionExpression compiles an arrow function expression
 func (c *Compiler) compileArrowFunctionExpression(expr *ast.ArrowFunctionExpression, targetReg int) error {
 	// Create a new function
 	function := vm.NewFunction("") // Arrow functions are anonymous
 	function.NumParams = len(expr.Parameters)
+	function.IsVariadic = hasRestParameter(expr.Parameters)
+	function.NumOptional = optionalParameters(expr.Parameters)
 	
 	// Create a new compiler for the function body
 	functionCompiler := NewCompiler()
 	functionCompiler.symbolTable = NewSymbolTable(c.symbolTable)
+	functionCompiler.line = c.line
+	functionCompiler.strict = c.strict
+	functionCompiler.module = c.module
+	functionCompiler.optimize = c.optimize
+	
+	// Set the next register to start after parameters, so default values
+	// are evaluated in registers of their own
+	functionCompiler.nextRegister = len(expr.Parameters)
+	functionCompiler.maxRegisters = len(expr.Parameters)
 	
 	// Define parameters in the function's symbol table
 	for i, param := range expr.Parameters {
-		functionCompiler.symbolTable.Define(param.Name.Name, SymbolLocal, i)
+		if err := functionCompiler.defineParameter(param, i); err != nil {
+			return err
+		}
+		// Mark parameter registers as variable registers
+		functionCompiler.variableRegisters[i] = true
 	}
 	
 	// Compile the function body
@@ -1000,9 +2398,19 @@ func (c *Compiler) compileArrowFunctionExpression(expr *ast.ArrowFunctionExpress
 		}
 	}
 	
+	// Report errors recorded while compiling the body
+	for _, err := range functionCompiler.GetErrors() {
+		c.AddError(err)
+	}
+	
 	// Set the compiled instructions and constants
+	if functionCompiler.optimize {
+		functionCompiler.peephole()
+	}
 	function.Instructions = functionCompiler.instructions
+	function.LineNumbers = functionCompiler.lines
 	function.Constants = functionCompiler.constants
+	function.Globals = functionCompiler.globals
 	function.NumLocals = functionCompiler.maxRegisters
 	
 	// Add the function as a constant
@@ -0,0 +1,2149 @@
+package compiler
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+// Helper function to compile and execute source code, returning the VM
+// so that tests can inspect globals assigned by the program
+func runProgram(t *testing.T, input string) *vm.VM {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+
+	function, err := CompileFunction(program)
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	machine := vm.NewVM()
+	if _, err := machine.Execute(vm.NewClosure(function), []vm.Value{}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	return machine
+}
+
+// Helper function to check the value of a global variable
+func testGlobal(t *testing.T, machine *vm.VM, name string, expected vm.Value) {
+	actual, ok := machine.GetGlobal(name)
+	if !ok {
+		t.Errorf("global '%s' is not defined", name)
+		return
+	}
+
+	if !actual.Equals(expected) {
+		t.Errorf("global '%s' wrong. expected=%s (%s), got=%s (%s)",
+			name, expected.ToString(), expected.TypeName(), actual.ToString(), actual.TypeName())
+	}
+}
+
+func TestTypeofOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"result = typeof 5", "number"},
+		{"result = typeof 2.5", "number"},
+		{`result = typeof "s"`, "string"},
+		{"result = typeof true", "boolean"},
+		{"result = typeof []", "object"},
+		{"result = typeof null", "object"},
+		{"result = typeof print", "function"},
+		{"result = typeof ((x) => x)", "function"},
+		{"let u = undefined; result = typeof u", "undefined"},
+	}
+
+	for _, tt := range tests {
+		machine := runProgram(t, tt.input)
+		testGlobal(t, machine, "result", vm.NewStringValue(tt.expected))
+	}
+}
+
+func TestEnumDeclaration(t *testing.T) {
+	input := `
+enum Color { Red, Green, Blue }
+enum Level { Low = 5, Mid, High = -1, Top }
+enum Mode { On = "on", Off = "off" }
+green = Color.Green
+mid = Level.Mid
+top = Level.Top
+off = Mode.Off
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "green", vm.NewIntValue(1))
+	testGlobal(t, machine, "mid", vm.NewIntValue(6))
+	testGlobal(t, machine, "top", vm.NewIntValue(0))
+	testGlobal(t, machine, "off", vm.NewStringValue("off"))
+}
+
+func TestObjectLiteral(t *testing.T) {
+	input := `
+key = "dyn"
+point = { x: 1, y: "two", [key]: true, nested: { z: 2.5 } }
+x = point.x
+y = point["y"]
+dyn = point.dyn
+z = point.nested.z
+empty = {}
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "x", vm.NewIntValue(1))
+	testGlobal(t, machine, "y", vm.NewStringValue("two"))
+	testGlobal(t, machine, "dyn", vm.NewBoolValue(true))
+	testGlobal(t, machine, "z", vm.NewFloatValue(2.5))
+
+	empty, _ := machine.GetGlobal("empty")
+	if empty.TypeName() != "object" {
+		t.Errorf("expected empty object, got %s", empty.TypeName())
+	}
+}
+
+func TestObjectLiteralShorthand(t *testing.T) {
+	input := `
+x = 1
+point = { x, double(n) { return n * 2 } }
+px = point.x
+doubled = point.double(21)
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "px", vm.NewIntValue(1))
+	testGlobal(t, machine, "doubled", vm.NewIntValue(42))
+}
+
+func TestShortCircuitEvaluation(t *testing.T) {
+	input := `
+calls = 0
+function bump() {
+    calls = calls + 1
+    return true
+}
+skippedAnd = false && bump()
+skippedOr = true || bump()
+afterSkips = calls
+evaluatedAnd = true && bump()
+evaluatedOr = false || bump()
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "skippedAnd", vm.NewBoolValue(false))
+	testGlobal(t, machine, "skippedOr", vm.NewBoolValue(true))
+	testGlobal(t, machine, "afterSkips", vm.NewIntValue(0))
+	testGlobal(t, machine, "evaluatedAnd", vm.NewBoolValue(true))
+	testGlobal(t, machine, "evaluatedOr", vm.NewBoolValue(true))
+	testGlobal(t, machine, "calls", vm.NewIntValue(2))
+}
+
+func TestLogicalOperatorsReturnOperands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected vm.Value
+	}{
+		{`result = 0 || "fallback"`, vm.NewStringValue("fallback")},
+		{`result = "first" || "second"`, vm.NewStringValue("first")},
+		{`result = "a" && 5`, vm.NewIntValue(5)},
+		{`result = null && 5`, vm.NullValue},
+		{`result = "" || 0 || 7`, vm.NewIntValue(7)},
+		{`result = 1 && 2 && 3`, vm.NewIntValue(3)},
+	}
+
+	for _, tt := range tests {
+		machine := runProgram(t, tt.input)
+		testGlobal(t, machine, "result", tt.expected)
+	}
+}
+
+func TestNullishCoalescing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected vm.Value
+	}{
+		{`result = 0 ?? 5`, vm.NewIntValue(0)},
+		{`result = "" ?? "x"`, vm.NewStringValue("")},
+		{`result = false ?? true`, vm.NewBoolValue(false)},
+		{`result = null ?? 5`, vm.NewIntValue(5)},
+		{`result = null ?? null ?? "last"`, vm.NewStringValue("last")},
+		{`o = {}
+result = o.missing ?? 3`, vm.NewIntValue(3)},
+		// The right operand is only evaluated when it is needed
+		{`result = 0
+function bump() { result = result + 1; return 9 }
+kept = 1 ?? bump()`, vm.NewIntValue(0)},
+	}
+
+	for _, tt := range tests {
+		machine := runProgram(t, tt.input)
+		testGlobal(t, machine, "result", tt.expected)
+	}
+}
+
+func TestLogicalAssignment(t *testing.T) {
+	input := `
+calls = 0
+function bump(): int {
+    calls = calls + 1
+    return calls
+}
+a = true
+a &&= "and"
+b = 0
+b &&= bump()
+c = ""
+c ||= "or"
+d = "kept"
+d ||= bump()
+e = null
+e ??= 10
+f = 0
+f ??= bump()
+skipped = calls
+result = (a ||= bump())
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "a", vm.NewStringValue("and"))
+	testGlobal(t, machine, "b", vm.NewIntValue(0))
+	testGlobal(t, machine, "c", vm.NewStringValue("or"))
+	testGlobal(t, machine, "d", vm.NewStringValue("kept"))
+	testGlobal(t, machine, "e", vm.NewIntValue(10))
+	testGlobal(t, machine, "f", vm.NewIntValue(0))
+	// The right-hand side is not evaluated when the assignment is skipped
+	testGlobal(t, machine, "skipped", vm.NewIntValue(0))
+	testGlobal(t, machine, "result", vm.NewStringValue("and"))
+	testGlobal(t, machine, "calls", vm.NewIntValue(0))
+}
+
+func TestLogicalAssignmentMemberTarget(t *testing.T) {
+	input := `
+keys = 0
+function key(): int {
+    keys = keys + 1
+    return keys - 1
+}
+arr = [null, 5]
+first = (arr[key()] ??= 7)
+second = (arr[key()] ||= 9)
+obj = {count: 0}
+obj.count ||= 3
+`
+	machine := runProgram(t, input)
+
+	// Each key expression is evaluated once, and the slot read is the one written
+	testGlobal(t, machine, "keys", vm.NewIntValue(2))
+	testGlobal(t, machine, "first", vm.NewIntValue(7))
+	testGlobal(t, machine, "second", vm.NewIntValue(5))
+
+	arr, _ := machine.GetGlobal("arr")
+	if arr.ToString() != "[7, 5]" {
+		t.Errorf("arr wrong. expected=[7, 5], got=%s", arr.ToString())
+	}
+	obj, _ := machine.GetGlobal("obj")
+	if count, _ := obj.AsObject().Get("count"); !count.Equals(vm.NewIntValue(3)) {
+		t.Errorf("obj.count wrong. expected=3, got=%s", count.ToString())
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	input := `
+a = 10
+a += 5
+afterAdd = a
+a -= 3
+a *= 2
+a /= 4
+afterDiv = a
+a %= 4
+s = "Hello"
+s += ", world"
+function compute(x: int): int {
+    let r = x
+    r += 5
+    r -= 1
+    r *= 3
+    r /= 2
+    r %= 7
+    return r
+}
+computed = compute(4)
+b = 1
+d = (b += 2)
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "afterAdd", vm.NewIntValue(15))
+	testGlobal(t, machine, "afterDiv", vm.NewIntValue(6))
+	testGlobal(t, machine, "a", vm.NewIntValue(2))
+	testGlobal(t, machine, "s", vm.NewStringValue("Hello, world"))
+	testGlobal(t, machine, "computed", vm.NewIntValue(5))
+	// The value of a compound assignment is the new value
+	testGlobal(t, machine, "d", vm.NewIntValue(3))
+	testGlobal(t, machine, "b", vm.NewIntValue(3))
+}
+
+func TestCompoundAssignmentMemberTarget(t *testing.T) {
+	input := `
+keys = 0
+function key(): int {
+    keys = keys + 1
+    return keys - 1
+}
+arr = [1, 2, 3]
+arr[key()] += 10
+arr[key()] -= 1
+arr[key()] *= 5
+obj = {total: 1}
+obj.total += 4
+total = obj.total
+`
+	machine := runProgram(t, input)
+
+	// Each key expression is evaluated once
+	testGlobal(t, machine, "keys", vm.NewIntValue(3))
+	testGlobal(t, machine, "total", vm.NewIntValue(5))
+
+	arr, _ := machine.GetGlobal("arr")
+	if arr.ToString() != "[11, 1, 15]" {
+		t.Errorf("arr wrong. expected=[11, 1, 15], got=%s", arr.ToString())
+	}
+}
+
+func TestOptionalChaining(t *testing.T) {
+	machine := runProgram(t, `
+o = {a: {b: 2}, f: (x) => x * 10}
+none = null
+calls = 0
+function side() { calls = calls + 1; return 1 }
+nested = o?.a?.b
+short = none?.a.b.c
+indexed = none?.[side()]
+called = o.f?.(3)
+skipped = none?.f(side())
+fallback = none?.x ?? 7
+present = o?.a.b ?? 7
+kept = 0 ?? 7
+none?.f(side())
+`)
+	testGlobal(t, machine, "nested", vm.NewIntValue(2))
+	testGlobal(t, machine, "short", vm.NilValue)
+	testGlobal(t, machine, "indexed", vm.NilValue)
+	testGlobal(t, machine, "called", vm.NewIntValue(30))
+	testGlobal(t, machine, "skipped", vm.NilValue)
+	testGlobal(t, machine, "fallback", vm.NewIntValue(7))
+	testGlobal(t, machine, "present", vm.NewIntValue(2))
+	testGlobal(t, machine, "kept", vm.NewIntValue(0))
+	testGlobal(t, machine, "calls", vm.NewIntValue(0))
+
+	program := parser.New(lexer.New("o = {}\no?.a = 1")).ParseProgram()
+	if _, err := CompileFunction(program); err == nil || !strings.Contains(err.Error(), "cannot assign to optional chain") {
+		t.Errorf("expected optional chain assignment error, got %v", err)
+	}
+}
+
+func TestComputedMemberKeys(t *testing.T) {
+	machine := runProgram(t, `
+arr = [10, 20, 30]
+byString = arr["1"]
+arr["2"] = 99
+updated = arr[2]
+`)
+	testGlobal(t, machine, "byString", vm.NewIntValue(20))
+	testGlobal(t, machine, "updated", vm.NewIntValue(99))
+
+	program := parser.New(lexer.New("arr = [1]\nbad = arr[true]")).ParseProgram()
+	function, err := CompileFunction(program)
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	_, err = vm.NewVM().Execute(vm.NewClosure(function), []vm.Value{})
+	if err == nil {
+		t.Fatalf("expected invalid key error")
+	}
+	if !strings.Contains(err.Error(), "invalid array key of type boolean") {
+		t.Errorf("wrong error message: %s", err.Error())
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected vm.Value
+	}{
+		{"result = true ? 1 : 2", vm.NewIntValue(1)},
+		{"result = false ? 1 : 2", vm.NewIntValue(2)},
+		{`result = 3 > 2 ? "yes" : "no"`, vm.NewStringValue("yes")},
+		// Nested ternaries associate to the right
+		{`n = 5; result = n < 0 ? "negative" : n == 0 ? "zero" : "positive"`, vm.NewStringValue("positive")},
+		{`n = 0; result = n < 0 ? "negative" : n == 0 ? "zero" : "positive"`, vm.NewStringValue("zero")},
+		{`n = -1; result = n < 0 ? "negative" : n == 0 ? "zero" : "positive"`, vm.NewStringValue("negative")},
+		{`result = (true ? false : true) ? "a" : "b"`, vm.NewStringValue("b")},
+	}
+
+	for _, tt := range tests {
+		machine := runProgram(t, tt.input)
+		testGlobal(t, machine, "result", tt.expected)
+	}
+}
+
+func TestConditionalExpressionEvaluatesOneBranch(t *testing.T) {
+	input := `
+calls = 0
+function bump(n) {
+    calls = calls + 1
+    return n
+}
+function double(n) {
+    return n * 2
+}
+picked = true ? bump(1) : bump(2)
+argument = double(calls > 0 ? 10 : 20)
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "picked", vm.NewIntValue(1))
+	testGlobal(t, machine, "calls", vm.NewIntValue(1))
+	testGlobal(t, machine, "argument", vm.NewIntValue(20))
+}
+
+func TestSizedIntegerConversion(t *testing.T) {
+	input := `
+let a: int8 = 127
+a += 1
+wrapped = a
+cast = 300 as int8
+truncated = 3.9 as int
+function narrow(x: int16): int16 {
+    return x
+}
+param = narrow(40000)
+let f: float32 = 0.1
+single = f
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "wrapped", vm.NewIntValue(-128))
+	testGlobal(t, machine, "cast", vm.NewIntValue(44))
+	testGlobal(t, machine, "truncated", vm.NewIntValue(3))
+	testGlobal(t, machine, "param", vm.NewIntValue(-25536))
+	testGlobal(t, machine, "single", vm.NewFloatValue(float64(float32(0.1))))
+}
+
+func TestIntegerDivisionIndexes(t *testing.T) {
+	input := `
+arr = [10, 20, 30, 40, 50]
+n = 5
+middle = arr[n / 2]
+half = n / 2
+even = 6 / 2
+exact = n / 2.0
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "middle", vm.NewIntValue(30))
+	testGlobal(t, machine, "half", vm.NewIntValue(2))
+	testGlobal(t, machine, "even", vm.NewIntValue(3))
+	testGlobal(t, machine, "exact", vm.NewFloatValue(2.5))
+}
+
+func TestForOfStatement(t *testing.T) {
+	input := `
+sum = 0
+for (const x of [1, 2, 3, 4]) {
+    sum = sum + x
+}
+chars = ""
+for (const ch of "abc") {
+    chars = ch + chars
+}
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "sum", vm.NewIntValue(10))
+	testGlobal(t, machine, "chars", vm.NewStringValue("cba"))
+}
+
+func TestForInStatement(t *testing.T) {
+	input := `
+obj = { b: 2, a: 1, c: 3 }
+keys = ""
+total = 0
+for (const key in obj) {
+    keys = keys + key
+    total = total + obj[key]
+}
+indexType = ""
+for (const i in [10, 20]) {
+    indexType = typeof i
+}
+`
+	machine := runProgram(t, input)
+
+	// Keys are strings, visited in insertion order
+	testGlobal(t, machine, "keys", vm.NewStringValue("bac"))
+	testGlobal(t, machine, "total", vm.NewIntValue(6))
+	testGlobal(t, machine, "indexType", vm.NewStringValue("string"))
+}
+
+func TestDoWhileStatement(t *testing.T) {
+	input := `
+runs = 0
+do {
+    runs = runs + 1
+} while (false)
+n = 0
+do {
+    n = n + 1
+    if (n == 3) {
+        break
+    }
+} while (n < 10)
+`
+	machine := runProgram(t, input)
+
+	// The body runs once even though the condition is false
+	testGlobal(t, machine, "runs", vm.NewIntValue(1))
+	testGlobal(t, machine, "n", vm.NewIntValue(3))
+}
+
+func TestWhileAndForLoops(t *testing.T) {
+	input := `
+i = 0
+while (i < 3) {
+    i = i + 1
+}
+skipped = 0
+while (false) {
+    skipped = 1
+}
+sum = 0
+for (let j = 0; j < 5; j++) {
+    sum = sum + j
+}
+found = -1
+for (let k = 0; k < 10; k++) {
+    if (k * k > 20) {
+        found = k
+        break
+    }
+}
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "i", vm.NewIntValue(3))
+	testGlobal(t, machine, "skipped", vm.NewIntValue(0))
+	testGlobal(t, machine, "sum", vm.NewIntValue(10))
+	testGlobal(t, machine, "found", vm.NewIntValue(5))
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestDisassemblyGolden compares the disassembly of the programs in
+// testdata with their .golden files. Run with -update after an intended
+// change to the generated code.
+func TestDisassemblyGolden(t *testing.T) {
+	for _, name := range []string{"calls", "if_else", "loop", "ternary"} {
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(filepath.Join("testdata", name+".tg"))
+			if err != nil {
+				t.Fatalf("reading source: %v", err)
+			}
+			function, err := CompileFunction(parser.New(lexer.New(string(source))).ParseProgram())
+			if err != nil {
+				t.Fatalf("compilation failed: %v", err)
+			}
+			got := vm.Disassemble(function)
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatalf("writing golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading golden file: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("disassembly differs from %s.\nexpected:\n%s\ngot:\n%s", golden, want, got)
+			}
+		})
+	}
+}
+
+func TestSwitchStatement(t *testing.T) {
+	input := `
+function describe(n) {
+    out = ""
+    switch (n) {
+        case 1:
+            out = out + "one,"
+        case 2:
+            out = out + "two,"
+            break
+        default:
+            out = out + "other,"
+        case 3:
+            out = out + "three,"
+    }
+    return out
+}
+one = describe(1)
+two = describe(2)
+three = describe(3)
+other = describe(9)
+function nested(a, b) {
+    switch (a) {
+        case 1:
+            switch (b) {
+                case "x":
+                    return "1x"
+                default:
+                    break
+            }
+            return "1?"
+        default:
+            return "?"
+    }
+}
+innerMatch = nested(1, "x")
+innerBreak = nested(1, "y")
+outerDefault = nested(2, "x")
+unmatched = "kept"
+switch ("z") {
+    case "a":
+        unmatched = "changed"
+}
+count = 0
+for (const n of [1, 2, 3, 4, 5, 6]) {
+    switch (n) {
+        case 3:
+            break
+    }
+    count = n
+    if (n == 5) {
+        break
+    }
+}
+function leave() {
+    log = ""
+    switch (1) {
+        case 1:
+            try {
+                break
+            } finally {
+                log = log + "finally"
+            }
+            log = log + "unreachable"
+    }
+    return log
+}
+tryBreak = leave()
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "one", vm.NewStringValue("one,two,"))
+	testGlobal(t, machine, "two", vm.NewStringValue("two,"))
+	testGlobal(t, machine, "three", vm.NewStringValue("three,"))
+	// Default in the middle runs when nothing matches and falls through
+	testGlobal(t, machine, "other", vm.NewStringValue("other,three,"))
+	testGlobal(t, machine, "innerMatch", vm.NewStringValue("1x"))
+	testGlobal(t, machine, "innerBreak", vm.NewStringValue("1?"))
+	testGlobal(t, machine, "outerDefault", vm.NewStringValue("?"))
+	testGlobal(t, machine, "unmatched", vm.NewStringValue("kept"))
+	// A break inside the switch only leaves the switch, not the loop
+	testGlobal(t, machine, "count", vm.NewIntValue(5))
+	testGlobal(t, machine, "tryBreak", vm.NewStringValue("finally"))
+}
+
+func TestContinueStatement(t *testing.T) {
+	input := `
+forSum = 0
+for (let i = 0; i < 6; i++) {
+    if (i % 2 == 0) {
+        continue
+    }
+    forSum = forSum + i
+}
+whileSum = 0
+n = 0
+while (n < 6) {
+    n = n + 1
+    if (n == 3) {
+        continue
+    }
+    whileSum = whileSum + n
+}
+doCount = 0
+m = 0
+do {
+    m = m + 1
+    if (m < 4) {
+        continue
+    }
+    doCount = doCount + 1
+} while (m < 5)
+ofSum = 0
+for (const v of [1, 2, 3, 4]) {
+    switch (v) {
+        case 2:
+            continue
+    }
+    ofSum = ofSum + v
+}
+function skip() {
+    log = ""
+    for (let i = 0; i < 2; i++) {
+        try {
+            continue
+        } finally {
+            log = log + "f"
+        }
+        log = log + "unreachable"
+    }
+    return log
+}
+tryContinue = skip()
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "forSum", vm.NewIntValue(9))
+	testGlobal(t, machine, "whileSum", vm.NewIntValue(18))
+	// Continue in a do-while still tests the condition
+	testGlobal(t, machine, "doCount", vm.NewIntValue(2))
+	// A continue inside a switch targets the enclosing loop
+	testGlobal(t, machine, "ofSum", vm.NewIntValue(8))
+	testGlobal(t, machine, "tryContinue", vm.NewStringValue("ff"))
+
+	for _, input := range []string{"continue", "x = 1\nswitch (x) { case 1: continue }", "break"} {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		if _, err := CompileFunction(program); err == nil {
+			t.Errorf("%q: expected a compile error", input)
+		}
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	input := `
+try {
+    throw "boom"
+} catch (e) {
+    caught = e
+}
+try {
+    x = 1 / 0
+} catch (e) {
+    divError = e
+} finally {
+    finallyRan = true
+}
+log = ""
+function cleanup(): int {
+    try {
+        return 1
+    } finally {
+        log = log + "finally"
+    }
+}
+returned = cleanup()
+function rethrow() {
+    try {
+        throw 42
+    } finally {
+        log = log + ",rethrow"
+    }
+}
+try {
+    rethrow()
+} catch (e) {
+    rethrown = e
+}
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "caught", vm.NewStringValue("boom"))
+	testGlobal(t, machine, "divError", vm.NewStringValue("division by zero"))
+	testGlobal(t, machine, "finallyRan", vm.NewBoolValue(true))
+	testGlobal(t, machine, "returned", vm.NewIntValue(1))
+	testGlobal(t, machine, "rethrown", vm.NewIntValue(42))
+	testGlobal(t, machine, "log", vm.NewStringValue("finally,rethrow"))
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	program := parser.New(lexer.New(`throw "boom"`)).ParseProgram()
+	function, err := CompileFunction(program)
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	_, err = vm.NewVM().Execute(vm.NewClosure(function), []vm.Value{})
+	throwErr, ok := err.(*vm.ThrowError)
+	if !ok {
+		t.Fatalf("expected *vm.ThrowError, got %T (%v)", err, err)
+	}
+	if !throwErr.Value.Equals(vm.NewStringValue("boom")) {
+		t.Errorf("wrong thrown value: %s", throwErr.Value.ToString())
+	}
+}
+
+func TestCompileREPL(t *testing.T) {
+	machine := vm.NewVM()
+	eval := func(input string) vm.Value {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		function, err := CompileREPL(program)
+		if err != nil {
+			t.Fatalf("compilation failed: %v", err)
+		}
+		result, err := machine.Execute(vm.NewClosure(function), []vm.Value{})
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		return result
+	}
+
+	// Declarations produce no result
+	if result := eval("let x = 20"); result.Type != vm.TypeNil {
+		t.Errorf("expected no result from a declaration, got %s", result.ToString())
+	}
+	eval("function add(n) { return n + x }")
+
+	// Top-level lets are globals, visible to later entries and functions
+	if result := eval("let y = 1\nadd(y + 1)"); !result.Equals(vm.NewIntValue(22)) {
+		t.Errorf("wrong result: %s", result.ToString())
+	}
+	if result := eval("x = x + y\nx"); !result.Equals(vm.NewIntValue(21)) {
+		t.Errorf("wrong result: %s", result.ToString())
+	}
+
+	// Block-scoped declarations stay local
+	eval("if (true) { let z = 1 }")
+	if _, ok := machine.GetGlobal("z"); ok {
+		t.Errorf("block-scoped 'z' leaked into globals")
+	}
+}
+
+func TestConstantDeduplication(t *testing.T) {
+	compile := func(input string) (*vm.Function, error) {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		return CompileFunction(program)
+	}
+	countConstants := func(function *vm.Function, typ vm.ValueType) int {
+		count := 0
+		for _, constant := range function.Constants {
+			if constant.Type == typ {
+				count++
+			}
+		}
+		return count
+	}
+
+	// A repeated float literal shares one constant
+	function, err := compile(strings.Repeat("x = 3.14\n", 1000))
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	if n := countConstants(function, vm.TypeFloat); n != 1 {
+		t.Errorf("expected 1 float constant, got %d", n)
+	}
+
+	// 0.0 and -0.0 compare equal but must keep separate slots
+	c := NewCompiler()
+	zero := c.AddConstant(vm.NewFloatValue(0))
+	negZero := c.AddConstant(vm.NewFloatValue(math.Copysign(0, -1)))
+	if zero == negZero {
+		t.Errorf("0.0 and -0.0 share constant %d", zero)
+	}
+	if c.AddConstant(vm.NewFloatValue(math.NaN())) != c.AddConstant(vm.NewFloatValue(math.NaN())) {
+		t.Errorf("NaN constants were not deduplicated")
+	}
+	if c.AddConstant(vm.NewBoolValue(true)) != c.AddConstant(vm.NewBoolValue(true)) {
+		t.Errorf("bool constants were not deduplicated")
+	}
+	if c.AddConstant(vm.Value{Type: vm.TypeNull}) != c.AddConstant(vm.Value{Type: vm.TypeNull}) {
+		t.Errorf("null constants were not deduplicated")
+	}
+	if c.HasErrors() {
+		t.Errorf("unexpected errors: %v", c.GetErrors())
+	}
+}
+
+func TestUnreachableCodeNotEmitted(t *testing.T) {
+	body := func(input string) *vm.Function {
+		function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("compilation failed: %v", err)
+		}
+		for _, constant := range function.Constants {
+			if constant.Type == vm.TypeFunction {
+				return constant.AsFunction()
+			}
+		}
+		t.Fatalf("no function constant in %q", input)
+		return nil
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function f(): int { return 1; print(\"dead\"); let y = 2 }", "function f(): int { return 1 }"},
+		{"function f() { throw \"e\"; print(\"dead\") }", "function f() { throw \"e\" }"},
+		{"function f() { while (true) { break; print(\"dead\") } }", "function f() { while (true) { break } }"},
+		{"function f() { while (true) { continue; print(\"dead\") } }", "function f() { while (true) { continue } }"},
+	}
+
+	for _, tt := range tests {
+		got, want := body(tt.input), body(tt.expected)
+		if len(got.Instructions) != len(want.Instructions) || len(got.Constants) != len(want.Constants) {
+			t.Errorf("%q: got %d instructions and %d constants, want %d and %d", tt.input,
+				len(got.Instructions), len(got.Constants), len(want.Instructions), len(want.Constants))
+		}
+	}
+
+	// Code after the block that exited is still compiled
+	machine := runProgram(t, "x = 0\nwhile (true) { if (x == 2) { break; x = 100 } x = x + 1 }\nx = x + 10")
+	testGlobal(t, machine, "x", vm.NewIntValue(12))
+}
+
+func TestStrictModeRejectsUndeclaredAssignment(t *testing.T) {
+	compile := func(input string, strict bool) error {
+		c := NewCompiler()
+		c.SetStrictMode(strict)
+		_, err := c.Compile(parser.New(lexer.New(input)).ParseProgram())
+		return err
+	}
+
+	valid := "let count = 0\ncount = count + 1\nfunction bump() { count = 2 }\nbump = bump"
+	if err := compile(valid, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := "let count = 0\nfunction bump() {\n    countr = count + 1\n}"
+	err := compile(invalid, true)
+	expected := "line 3, column 5: assignment to undeclared variable 'countr'"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error containing %q, got %v", expected, err)
+	}
+
+	// Without strict mode the assignment creates a global
+	if err := compile(invalid, false); err != nil {
+		t.Errorf("unexpected error outside strict mode: %v", err)
+	}
+}
+
+func TestTooManyConstants(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i <= vm.MaxConstants; i++ {
+		fmt.Fprintf(&sb, "x = \"s%d\"\n", i)
+	}
+	source := sb.String()
+
+	program := parser.New(lexer.New(source)).ParseProgram()
+	if _, err := CompileFunction(program); err == nil || !strings.Contains(err.Error(), "too many constants") {
+		t.Errorf("expected too many constants error, got %v", err)
+	}
+
+	// The limit applies to function bodies too
+	program = parser.New(lexer.New("function f() {\n" + source + "}\n")).ParseProgram()
+	if _, err := CompileFunction(program); err == nil || !strings.Contains(err.Error(), "too many constants") {
+		t.Errorf("expected too many constants error in function body, got %v", err)
+	}
+}
+
+func TestRegisterReuse(t *testing.T) {
+	terms := make([]string, 300)
+	for i := range terms {
+		terms[i] = "a"
+	}
+
+	// A long left-nested chain reuses its temporaries
+	program := parser.New(lexer.New("let a = 1\nresult = " + strings.Join(terms, " + "))).ParseProgram()
+	function, err := CompileFunction(program)
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	if function.NumLocals > 8 {
+		t.Errorf("expected a small register count, got %d", function.NumLocals)
+	}
+
+	machine := vm.NewVM()
+	if _, err := machine.Execute(vm.NewClosure(function), []vm.Value{}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	testGlobal(t, machine, "result", vm.NewIntValue(300))
+
+	// Deeply nested mixed expressions and block-scoped variables stay
+	// within a few registers
+	nested := "a"
+	for i := 0; i < 200; i++ {
+		nested = "(" + nested + " * 2 - a) % 1000"
+	}
+	source := "let a = 1\nresult = " + nested + "\ntotal = 0\n" +
+		strings.Repeat("for (let i = 0; i < 2; i++) { let x = i\nlet y = x + 1\ntotal = total + y }\n", 300)
+	function, err = CompileFunction(parser.New(lexer.New(source)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	if function.NumLocals > 8 {
+		t.Errorf("expected a small register count, got %d", function.NumLocals)
+	}
+	machine = vm.NewVM()
+	if _, err := machine.Execute(vm.NewClosure(function), []vm.Value{}); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	testGlobal(t, machine, "result", vm.NewIntValue(1))
+	testGlobal(t, machine, "total", vm.NewIntValue(900))
+
+	// Arguments must not overwrite live variables
+	machine = runProgram(t, `
+let a = 10
+let b = 3
+let c = 15
+let d = 4
+r1 = a * b + c / d
+r2 = a * b + c * d
+add = (x, y) => x + y
+r3 = add(add(1, 2), add(3, 4))
+`)
+	testGlobal(t, machine, "r1", vm.NewIntValue(33))
+	testGlobal(t, machine, "r2", vm.NewIntValue(90))
+	testGlobal(t, machine, "r3", vm.NewIntValue(10))
+}
+
+func TestBlockScoping(t *testing.T) {
+	machine := runProgram(t, `
+let x = 1
+{
+    let x = 2
+    inner = x
+}
+outer = x
+function siblings() {
+    let y = 1
+    {
+        let y = 2
+        let z = 3
+    }
+    {
+        let w = 9
+        let y = w + 1
+    }
+    return y
+}
+fromSiblings = siblings()
+function nested() {
+    let a = 1
+    let digits = 0
+    {
+        let b = 2
+        {
+            let a = 5
+            digits = digits * 10 + a
+        }
+        digits = (digits * 10 + a) * 10 + b
+    }
+    let c = 7
+    return (digits * 10 + a) * 10 + c
+}
+fromNested = nested()
+`)
+
+	testGlobal(t, machine, "inner", vm.NewIntValue(2))
+	testGlobal(t, machine, "outer", vm.NewIntValue(1))
+	testGlobal(t, machine, "fromSiblings", vm.NewIntValue(1))
+	testGlobal(t, machine, "fromNested", vm.NewIntValue(51217))
+}
+
+func TestTooManyRegisters(t *testing.T) {
+	// A right-nested chain keeps every left operand live
+	source := "let a = 1\nresult = " + strings.Repeat("a + (", 299) + "a" + strings.Repeat(")", 299)
+	program := parser.New(lexer.New(source)).ParseProgram()
+	if _, err := CompileFunction(program); err == nil || !strings.Contains(err.Error(), "expression too complex") {
+		t.Errorf("expected expression too complex error, got %v", err)
+	}
+}
+
+func TestDeepRecursion(t *testing.T) {
+	// Far more registers in total than a single frame can address
+	machine := runProgram(t, `
+function fib(n: int): int {
+    if (n < 2) {
+        return n
+    }
+    return fib(n - 1) + fib(n - 2)
+}
+function sum(n: int): int {
+    if (n == 0) {
+        return 0
+    }
+    return n + sum(n - 1)
+}
+a = fib(20)
+b = sum(500)
+`)
+	testGlobal(t, machine, "a", vm.NewIntValue(6765))
+	testGlobal(t, machine, "b", vm.NewIntValue(125250))
+}
+
+func BenchmarkRecursiveCalls(b *testing.B) {
+	input := `
+function down(n: int): int {
+    if (n == 0) {
+        return 0
+    }
+    return down(n - 1)
+}
+result = down(40)
+`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	function, err := CompileFunction(program)
+	if err != nil {
+		b.Fatalf("compilation failed: %v", err)
+	}
+
+	machine := vm.NewVM()
+	closure := vm.NewClosure(function)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := machine.Execute(closure, []vm.Value{}); err != nil {
+			b.Fatalf("execution failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkNativeCallLoop calls a native function and reads a global on
+// every iteration of a loop
+func BenchmarkNativeCallLoop(b *testing.B) {
+	input := `
+total = 0
+word = "abc"
+let i = 0
+while (i < 1000) {
+    total = total + len(word)
+    i = i + 1
+}
+`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	function, err := CompileFunction(program)
+	if err != nil {
+		b.Fatalf("compilation failed: %v", err)
+	}
+
+	machine := vm.NewVM()
+	closure := vm.NewClosure(function)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := machine.Execute(closure, []vm.Value{}); err != nil {
+			b.Fatalf("execution failed: %v", err)
+		}
+	}
+}
+
+func TestDestructuringDeclarations(t *testing.T) {
+	input := `
+function run() {
+    let [a, , b, c = 7] = [1, 2, 3]
+    let {x, y: {z}, w = "default"} = {x: 4, y: {z: 5}, w: null}
+    let [[p], {q: [r]}] = [[6], {q: [8]}]
+    return [a, b, c, x, z, w, p, r]
+}
+result = run()
+first = result[0]
+third = result[1]
+defaulted = result[2]
+nested = result[4]
+objectDefault = result[5]
+deepest = result[7]
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "first", vm.NewIntValue(1))
+	testGlobal(t, machine, "third", vm.NewIntValue(3))
+	testGlobal(t, machine, "defaulted", vm.NewIntValue(7))
+	testGlobal(t, machine, "nested", vm.NewIntValue(5))
+	testGlobal(t, machine, "objectDefault", vm.NewStringValue("default"))
+	testGlobal(t, machine, "deepest", vm.NewIntValue(8))
+}
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	input := `function inner(n) {
+    return 10 / n
+}
+function outer(n) {
+    let r = inner(n)
+    return r
+}
+outer(0)`
+	function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	_, err = vm.NewVM().Execute(vm.NewClosure(function), []vm.Value{})
+	if err == nil {
+		t.Fatalf("expected division by zero error")
+	}
+
+	expected := vm.StackTrace{
+		{Function: "inner", Line: 2},
+		{Function: "outer", Line: 5},
+		{Function: "main", Line: 8},
+	}
+	trace := vm.StackTraceOf(err)
+	if trace.String() != expected.String() {
+		t.Errorf("wrong stack trace.\nexpected:\n%s\ngot:\n%s", expected, trace)
+	}
+}
+
+func TestNestedCallStackTrace(t *testing.T) {
+	input := `function c(n) {
+    trace()
+    return 1 / n
+}
+function b(n) {
+    return c(n) + 1
+}
+function a(n) {
+    return b(n) * 2
+}
+a(0)`
+	function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	machine := vm.NewVM()
+	var during string
+	machine.RegisterNativeFunction("trace", func(machine *vm.VM, args []vm.Value) (vm.Value, error) {
+		during = machine.StackTrace()
+		return vm.NilValue, nil
+	}, 0, 0)
+	_, err = machine.Execute(vm.NewClosure(function), nil)
+	if err == nil {
+		t.Fatalf("expected division by zero error")
+	}
+
+	expected := "  at c (line %d)\n  at b (line 6)\n  at a (line 9)\n  at main (line 11)"
+	if want := fmt.Sprintf(expected, 2); during != want {
+		t.Errorf("wrong stack trace during the call.\nexpected:\n%s\ngot:\n%s", want, during)
+	}
+	if want, got := fmt.Sprintf(expected, 3), vm.StackTraceOf(err).String(); got != want {
+		t.Errorf("wrong stack trace in the error.\nexpected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestStackOverflowTrace(t *testing.T) {
+	input := `function depth(n) {
+    return depth(n + 1)
+}
+depth(0)`
+	function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	_, err = vm.NewVM().Execute(vm.NewClosure(function), nil)
+	if err == nil || !strings.Contains(err.Error(), "call stack overflow") {
+		t.Fatalf("expected call stack overflow, got %v", err)
+	}
+
+	expected := fmt.Sprintf("  at depth (line 2) ×%d\n  at main (line 4)", vm.MaxFrames-1)
+	if got := vm.StackTraceOf(err).String(); got != expected {
+		t.Errorf("wrong stack trace.\nexpected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestRuntimeErrorLine(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1\ny = 0\nz = x / y", "VM Error [DivisionByZero] at line 3: division by zero"},
+		{"x = 1\n\nprint(missing)", "at line 3: undefined variable: missing"},
+		{"function check(n) {\n    assert(n > 0, \"positive\")\n}\ncheck(-1)", "Runtime Error at line 2: assertion failed: positive"},
+		{"s = \"abc\"\nc = s[-1]", "Runtime Error at line 2: cannot read string character at negative index -1"},
+	}
+
+	for _, tt := range tests {
+		function, err := CompileFunction(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("%q: compilation failed: %v", tt.input, err)
+		}
+		_, err = vm.NewVM().Execute(vm.NewClosure(function), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestSpreadInLiterals(t *testing.T) {
+	input := `
+a = [1, 2, 3]
+spread = [...a, 4]
+mixed = [0, ...a, 4, ...[5, 6], 7]
+chars = [..."hi"]
+base = {x: 1, y: 2}
+merged = {...base, x: 10, z: 3}
+overridden = {x: 10, ...base}
+spreadLength = len(spread)
+mixedLength = len(mixed)
+fourth = spread[3]
+seventh = mixed[6]
+secondChar = chars[1]
+mergedX = merged.x
+mergedY = merged.y
+mergedZ = merged.z
+overriddenX = overridden.x
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "spreadLength", vm.NewIntValue(4))
+	testGlobal(t, machine, "mixedLength", vm.NewIntValue(8))
+	testGlobal(t, machine, "fourth", vm.NewIntValue(4))
+	testGlobal(t, machine, "seventh", vm.NewIntValue(6))
+	testGlobal(t, machine, "secondChar", vm.NewStringValue("i"))
+	testGlobal(t, machine, "mergedX", vm.NewIntValue(10))
+	testGlobal(t, machine, "mergedY", vm.NewIntValue(2))
+	testGlobal(t, machine, "mergedZ", vm.NewIntValue(3))
+	testGlobal(t, machine, "overriddenX", vm.NewIntValue(1))
+
+	// The source array is copied, not shared
+	machine = runProgram(t, "a = [1]\nb = [...a]\nb[0] = 2\nfirst = a[0]")
+	testGlobal(t, machine, "first", vm.NewIntValue(1))
+}
+
+func TestStrictEquality(t *testing.T) {
+	input := `
+looseNumbers = 1 == 1.0
+strictNumbers = 1 === 1.0
+strictSame = 1 === 1
+looseNotEqual = 1 != 1.0
+strictNotEqual = 1 !== 1.0
+strictStrings = "a" === "a"
+looseNullish = null == undefined
+strictNullish = null === undefined
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "looseNumbers", vm.NewBoolValue(true))
+	testGlobal(t, machine, "strictNumbers", vm.NewBoolValue(false))
+	testGlobal(t, machine, "strictSame", vm.NewBoolValue(true))
+	testGlobal(t, machine, "looseNotEqual", vm.NewBoolValue(false))
+	testGlobal(t, machine, "strictNotEqual", vm.NewBoolValue(true))
+	testGlobal(t, machine, "strictStrings", vm.NewBoolValue(true))
+	testGlobal(t, machine, "looseNullish", vm.NewBoolValue(true))
+	testGlobal(t, machine, "strictNullish", vm.NewBoolValue(false))
+}
+
+func TestSpreadCallArguments(t *testing.T) {
+	input := `
+function sum3(a, b, c) {
+	return a + b + c
+}
+nums = [1, 2, 3]
+all = sum3(...nums)
+tail = sum3(10, ...[20, 30])
+middle = sum3(1, ...[2], 3)
+length = len(...["abc"])
+items = ["a", 1, true]
+print(...items)
+print("items:", ...items)
+sum3(...nums)
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "all", vm.NewIntValue(6))
+	testGlobal(t, machine, "tail", vm.NewIntValue(60))
+	testGlobal(t, machine, "middle", vm.NewIntValue(6))
+	testGlobal(t, machine, "length", vm.NewIntValue(3))
+
+	// Native arity is checked against the expanded arguments
+	program, err := CompileFunction(parser.New(lexer.New("n = len(...[1, 2])")).ParseProgram())
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if _, err := vm.NewVM().Execute(vm.NewClosure(program), nil); err == nil {
+		t.Fatalf("expected arity error when spreading two arguments into len")
+	}
+}
+
+func TestRestParameters(t *testing.T) {
+	machine := runProgram(t, `
+function sum(...nums) {
+    let total = 0
+    for (const n of nums) {
+        total = total + n
+    }
+    return total
+}
+all = sum(1, 2, 3)
+none = sum()
+spread = sum(...[4, 5], 6)
+function count(first, ...rest) {
+    return first * 10 + len(rest)
+}
+some = count(1, 4, 5)
+onlyFirst = count(2)
+const tail = (a, ...rest) => rest
+restArray = tail(1, 2, 3)
+`)
+
+	testGlobal(t, machine, "all", vm.NewIntValue(6))
+	testGlobal(t, machine, "none", vm.NewIntValue(0))
+	testGlobal(t, machine, "spread", vm.NewIntValue(15))
+	testGlobal(t, machine, "some", vm.NewIntValue(12))
+	testGlobal(t, machine, "onlyFirst", vm.NewIntValue(20))
+	restArray, _ := machine.GetGlobal("restArray")
+	if restArray.ToString() != "[2, 3]" {
+		t.Errorf("expected the rest parameter to hold [2, 3], got %s", restArray.ToString())
+	}
+}
+
+func TestParameterDefaults(t *testing.T) {
+	machine := runProgram(t, `
+function greet(name = "world") {
+    return "hello " + name
+}
+defaulted = greet()
+passed = greet("you")
+function next(a, b = a + 1) {
+    return a * 10 + b
+}
+fromEarlier = next(1)
+overridden = next(1, 5)
+const pick = (x = 7) => x
+arrowDefault = pick()
+falsyKept = pick(0)
+nullDefaulted = pick(null)
+function both(a = 1, ...rest) {
+    return a + len(rest)
+}
+withRest = both()
+`)
+
+	testGlobal(t, machine, "defaulted", vm.NewStringValue("hello world"))
+	testGlobal(t, machine, "passed", vm.NewStringValue("hello you"))
+	testGlobal(t, machine, "fromEarlier", vm.NewIntValue(12))
+	testGlobal(t, machine, "overridden", vm.NewIntValue(15))
+	testGlobal(t, machine, "arrowDefault", vm.NewIntValue(7))
+	// Only a missing or null argument takes the default
+	testGlobal(t, machine, "falsyKept", vm.NewIntValue(0))
+	testGlobal(t, machine, "nullDefaulted", vm.NewIntValue(7))
+	testGlobal(t, machine, "withRest", vm.NewIntValue(1))
+}
+
+func TestUnaryMinusAndPlus(t *testing.T) {
+	input := `
+a = -5
+b = - -2.5
+c = +7
+d = -(-9223372036854775807 - 1)
+x = 3
+e = -x + +x
+z = -0.0
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "a", vm.NewIntValue(-5))
+	testGlobal(t, machine, "b", vm.NewFloatValue(2.5))
+	testGlobal(t, machine, "c", vm.NewIntValue(7))
+	testGlobal(t, machine, "d", vm.NewIntValue(math.MinInt64))
+	testGlobal(t, machine, "e", vm.NewIntValue(0))
+	if z, _ := machine.GetGlobal("z"); !math.Signbit(z.AsFloat()) {
+		t.Errorf("-0.0 lost its sign, got %s", z.ToString())
+	}
+
+	// Negation is a single instruction, with no zero constant
+	function, err := CompileFunction(parser.New(lexer.New("x = 3\ny = -x")).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	for _, inst := range function.Instructions {
+		if inst.GetOpCode() == vm.OpSub {
+			t.Errorf("unary minus compiled to SUB: %s", inst)
+		}
+	}
+	for _, constant := range function.Constants {
+		if constant.Equals(vm.NewIntValue(0)) {
+			t.Errorf("unary minus added a zero constant")
+		}
+	}
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	input := `
+x = 0x1234
+low = x & 0xff
+high = (x >> 8) & 0xff
+flags = 1 | 4 | 8
+toggled = flags ^ 4
+shifted = 1 << 10
+notZero = ~0
+notX = ~x
+unsigned = -1 >>> 60
+whole = 6.0 & 3
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "low", vm.NewIntValue(0x34))
+	testGlobal(t, machine, "high", vm.NewIntValue(0x12))
+	testGlobal(t, machine, "flags", vm.NewIntValue(13))
+	testGlobal(t, machine, "toggled", vm.NewIntValue(9))
+	testGlobal(t, machine, "shifted", vm.NewIntValue(1024))
+	testGlobal(t, machine, "notZero", vm.NewIntValue(-1))
+	testGlobal(t, machine, "notX", vm.NewIntValue(-0x1235))
+	testGlobal(t, machine, "unsigned", vm.NewIntValue(15))
+	testGlobal(t, machine, "whole", vm.NewIntValue(2))
+
+	for _, input := range []string{"n = 2.5 & 1", "n = ~\"a\"", "n = 1 << -1"} {
+		function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("%q: compilation failed: %v", input, err)
+		}
+		if _, err := vm.NewVM().Execute(vm.NewClosure(function), nil); err == nil {
+			t.Errorf("%q: expected runtime error", input)
+		}
+	}
+}
+
+func TestBitwiseNegativeOperandsAndWideShifts(t *testing.T) {
+	input := `
+n = -6
+and = n & 0xff
+or = n | 1
+xor = n ^ -1
+shl = n << 2
+shr = n >> 1
+ushr = n >>> 1
+shlWide = 1 << 64
+shrWide = n >> 64
+shrWidePositive = 6 >> 100
+ushrWide = n >>> 64
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "and", vm.NewIntValue(250))
+	testGlobal(t, machine, "or", vm.NewIntValue(-5))
+	testGlobal(t, machine, "xor", vm.NewIntValue(5))
+	testGlobal(t, machine, "shl", vm.NewIntValue(-24))
+	testGlobal(t, machine, "shr", vm.NewIntValue(-3))
+	testGlobal(t, machine, "ushr", vm.NewIntValue(math.MaxInt64-2))
+	testGlobal(t, machine, "shlWide", vm.NewIntValue(0))
+	testGlobal(t, machine, "shrWide", vm.NewIntValue(-1))
+	testGlobal(t, machine, "shrWidePositive", vm.NewIntValue(0))
+	testGlobal(t, machine, "ushrWide", vm.NewIntValue(0))
+}
+
+func TestExponentiation(t *testing.T) {
+	input := `
+square = 7 ** 2
+rightAssoc = 2 ** 3 ** 2
+zero = 5 ** 0
+negativeBase = (-2) ** 3
+root = 2.0 ** 0.5
+mixed = 4 ** 0.5
+fraction = 2.0 ** -1
+precedence = 3 * 2 ** 2
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "square", vm.NewIntValue(49))
+	testGlobal(t, machine, "rightAssoc", vm.NewIntValue(512))
+	testGlobal(t, machine, "zero", vm.NewIntValue(1))
+	testGlobal(t, machine, "negativeBase", vm.NewIntValue(-8))
+	testGlobal(t, machine, "root", vm.NewFloatValue(math.Sqrt2))
+	testGlobal(t, machine, "mixed", vm.NewFloatValue(2))
+	testGlobal(t, machine, "fraction", vm.NewFloatValue(0.5))
+	testGlobal(t, machine, "precedence", vm.NewIntValue(12))
+
+	function, err := CompileFunction(parser.New(lexer.New("n = 2 ** -1")).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	if _, err := vm.NewVM().Execute(vm.NewClosure(function), nil); err == nil {
+		t.Errorf("expected runtime error for a negative integer exponent")
+	}
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	input := `
+let i = 0
+i++
+afterPostfix = i
+old = i++
+afterOld = i
+pre = ++i
+down = --i
+oldDown = i--
+final = i
+f = 1.5
+f++
+arr = [1, 2]
+arrOld = arr[1]++
+arrPre = ++arr[0]
+obj = {count: 0}
+obj.count++
+++obj.count
+count = obj.count
+let small: int8 = 127
+small++
+wrapped = small
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "afterPostfix", vm.NewIntValue(1))
+	testGlobal(t, machine, "old", vm.NewIntValue(1))
+	testGlobal(t, machine, "afterOld", vm.NewIntValue(2))
+	testGlobal(t, machine, "pre", vm.NewIntValue(3))
+	testGlobal(t, machine, "down", vm.NewIntValue(2))
+	testGlobal(t, machine, "oldDown", vm.NewIntValue(2))
+	testGlobal(t, machine, "final", vm.NewIntValue(1))
+	testGlobal(t, machine, "f", vm.NewFloatValue(2.5))
+	testGlobal(t, machine, "arrOld", vm.NewIntValue(2))
+	testGlobal(t, machine, "arrPre", vm.NewIntValue(2))
+	testGlobal(t, machine, "count", vm.NewIntValue(2))
+	testGlobal(t, machine, "wrapped", vm.NewIntValue(-128))
+
+	arr, _ := machine.GetGlobal("arr")
+	if arr.ToString() != "[2, 3]" {
+		t.Errorf("arr wrong. expected=[2, 3], got=%s", arr.ToString())
+	}
+}
+
+func TestIncrementPrintsUpdatedValue(t *testing.T) {
+	function, err := CompileFunction(parser.New(lexer.New("let i = 0\ni++\nprint(i)\nprint(i++)\nprint(i)")).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	var printed []string
+	machine := vm.NewVM()
+	machine.RegisterNativeFunction("print", func(_ *vm.VM, args []vm.Value) (vm.Value, error) {
+		printed = append(printed, args[0].ToString())
+		return vm.NilValue, nil
+	}, 1, 1)
+	if _, err := machine.Execute(vm.NewClosure(function), nil); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	if strings.Join(printed, " ") != "1 1 2" {
+		t.Errorf("wrong output. expected=1 1 2, got=%s", strings.Join(printed, " "))
+	}
+}
+
+func TestModuloKeepsIntegers(t *testing.T) {
+	input := `
+positive = 7 % 3
+negative = -7 % 3
+fractional = 7.5 % 2
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "positive", vm.NewIntValue(1))
+	testGlobal(t, machine, "negative", vm.NewIntValue(-1))
+	testGlobal(t, machine, "fractional", vm.NewFloatValue(1.5))
+}
+
+func TestMathObject(t *testing.T) {
+	input := `
+abs = Math.abs(-3)
+floor = Math.floor(2.7)
+ceil = Math.ceil(2.1)
+round = Math.round(2.5)
+roundNegative = Math.round(-2.5)
+sqrt = Math.sqrt(16)
+pow = Math.pow(2, 10)
+min = Math.min(4, 1.5, 3)
+max = Math.max(4, 1.5, 3)
+pi = Math.PI
+e = Math.E
+r = Math.random()
+inRange = r >= 0 && r < 1
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "abs", vm.NewFloatValue(3))
+	testGlobal(t, machine, "floor", vm.NewIntValue(2))
+	testGlobal(t, machine, "ceil", vm.NewIntValue(3))
+	testGlobal(t, machine, "round", vm.NewIntValue(3))
+	testGlobal(t, machine, "roundNegative", vm.NewIntValue(-2))
+	testGlobal(t, machine, "sqrt", vm.NewFloatValue(4))
+	testGlobal(t, machine, "pow", vm.NewFloatValue(1024))
+	testGlobal(t, machine, "min", vm.NewFloatValue(1.5))
+	testGlobal(t, machine, "max", vm.NewFloatValue(4))
+	testGlobal(t, machine, "pi", vm.NewFloatValue(math.Pi))
+	testGlobal(t, machine, "e", vm.NewFloatValue(math.E))
+	testGlobal(t, machine, "inRange", vm.NewBoolValue(true))
+
+	for _, input := range []string{`n = Math.sqrt("4")`, "n = Math.max()", "n = Math.floor(1 / 0.0)"} {
+		function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("%q: compilation failed: %v", input, err)
+		}
+		if _, err := vm.NewVM().Execute(vm.NewClosure(function), nil); err == nil {
+			t.Errorf("%q: expected runtime error", input)
+		}
+	}
+}
+
+func TestJSONObject(t *testing.T) {
+	input := `
+data = {name: "tg", version: [0, 1], nested: {ok: true, none: null}}
+text = JSON.stringify(data)
+copy = JSON.parse(text)
+name = copy.name
+minor = copy.version[1]
+ok = copy.nested.ok
+same = JSON.stringify(copy) == text
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "text", vm.NewStringValue(`{"name":"tg","version":[0,1],"nested":{"ok":true,"none":null}}`))
+	testGlobal(t, machine, "name", vm.NewStringValue("tg"))
+	testGlobal(t, machine, "minor", vm.NewIntValue(1))
+	testGlobal(t, machine, "ok", vm.NewBoolValue(true))
+	testGlobal(t, machine, "same", vm.NewBoolValue(true))
+
+	function, err := CompileFunction(parser.New(lexer.New(`n = JSON.parse("{bad")`)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	if _, err := vm.NewVM().Execute(vm.NewClosure(function), nil); err == nil {
+		t.Errorf("expected runtime error for malformed JSON")
+	}
+}
+
+func TestConsoleLog(t *testing.T) {
+	function, err := CompileFunction(parser.New(lexer.New(`console.log("hi", 42)
+print("done")`)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	var out strings.Builder
+	machine := vm.NewVM()
+	machine.Stdout = &out
+	if _, err := machine.Execute(vm.NewClosure(function), nil); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	if got := out.String(); got != "hi 42\ndone\n" {
+		t.Errorf("expected %q, got %q", "hi 42\ndone\n", got)
+	}
+}
+
+func TestStringIndexing(t *testing.T) {
+	input := `
+s = "h\u00e9llo"
+first = s[0]
+second = s[1]
+last = s[len(s) - 1]
+past = s[5]
+n = len(s)
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "first", vm.NewStringValue("h"))
+	testGlobal(t, machine, "second", vm.NewStringValue("\u00e9"))
+	testGlobal(t, machine, "last", vm.NewStringValue("o"))
+	testGlobal(t, machine, "past", vm.NilValue)
+	testGlobal(t, machine, "n", vm.NewIntValue(5))
+
+	// Negative indices are rejected for strings and arrays alike
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`s = "abc"
+c = s[-1]`, "cannot read string character at negative index -1"},
+		{`a = [1, 2, 3]
+x = a[-1]`, "cannot read array element at negative index -1"},
+	}
+	for _, tt := range tests {
+		function, err := CompileFunction(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("%q: compilation failed: %v", tt.input, err)
+		}
+		_, err = vm.NewVM().Execute(vm.NewClosure(function), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestArrayWrites(t *testing.T) {
+	input := `
+a = [1, 2]
+a[0] = 10
+a[len(a)] = 3
+pushed = push(a, 4, 5)
+popped = pop(a)
+n = len(a)
+past = a[10]
+empty = []
+none = pop(empty)
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "pushed", vm.NewIntValue(5))
+	testGlobal(t, machine, "popped", vm.NewIntValue(5))
+	testGlobal(t, machine, "n", vm.NewIntValue(4))
+	testGlobal(t, machine, "past", vm.NilValue)
+	testGlobal(t, machine, "none", vm.NilValue)
+	a, _ := machine.GetGlobal("a")
+	if got := a.ToString(); got != "[10, 2, 3, 4]" {
+		t.Errorf("a = %s, want [10, 2, 3, 4]", got)
+	}
+
+	// Writing past the length fails unless the VM allows holes
+	input = "a = [1]\na[3] = 4"
+	function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	_, err = vm.NewVM().Execute(vm.NewClosure(function), nil)
+	if err == nil || !strings.Contains(err.Error(), "cannot set array element at index 3 beyond length 1") {
+		t.Errorf("expected an out of range error, got %v", err)
+	}
+
+	machine = vm.NewVM()
+	machine.ArrayHoles = true
+	if _, err := machine.Execute(vm.NewClosure(function), nil); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+	a, _ = machine.GetGlobal("a")
+	if got := a.ToString(); got != "[1, nil, nil, 4]" {
+		t.Errorf("a = %s, want [1, nil, nil, 4]", got)
+	}
+}
+
+func TestDeepEqualAndJSONBuiltins(t *testing.T) {
+	input := `
+same = deepEqual([1, {a: "x"}], [1, {a: "x"}])
+different = deepEqual({a: 1}, {a: 1, b: 2})
+strict = [1, 2] == [1, 2]
+text = json({name: "tg \"1\"", tags: ["a", null], n: 1.5})
+`
+	machine := runProgram(t, input)
+
+	testGlobal(t, machine, "same", vm.NewBoolValue(true))
+	testGlobal(t, machine, "different", vm.NewBoolValue(false))
+	testGlobal(t, machine, "strict", vm.NewBoolValue(false))
+	testGlobal(t, machine, "text", vm.NewStringValue(`{"name":"tg \"1\"","tags":["a",null],"n":1.5}`))
+
+	function, err := CompileFunction(parser.New(lexer.New("text = json(print)")).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	_, err = vm.NewVM().Execute(vm.NewClosure(function), nil)
+	if err == nil || !strings.Contains(err.Error(), "json: cannot convert native_function to JSON") {
+		t.Errorf("expected json to reject a function, got %v", err)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	machine := runProgram(t, "assert(1 < 2)\nassert(true, \"unused message\")\nreached = true")
+	testGlobal(t, machine, "reached", vm.NewBoolValue(true))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`assert(1 > 2, "one is not greater than two")`, "assertion failed: one is not greater than two"},
+		{`assert(false)`, "assertion failed"},
+	}
+	for _, tt := range tests {
+		function, err := CompileFunction(parser.New(lexer.New(tt.input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("%q: compilation failed: %v", tt.input, err)
+		}
+		_, err = vm.NewVM().Execute(vm.NewClosure(function), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestMaxStepsCannotBeCaught(t *testing.T) {
+	input := `
+caught = 0
+while (true) {
+    try {
+        x = 1
+    } catch (e) {
+        caught = caught + 1
+    }
+}
+`
+	function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+	machine := vm.NewVM()
+	machine.MaxSteps = 10000
+	_, err = machine.Execute(vm.NewClosure(function), nil)
+	if err == nil || !strings.Contains(err.Error(), "step limit exceeded") {
+		t.Fatalf("expected step limit error, got %v", err)
+	}
+	testGlobal(t, machine, "caught", vm.NewIntValue(0))
+}
+
+func TestConstantFolding(t *testing.T) {
+	input := `
+day = 60 * 60 * 24
+greeting = "Hello, " + "world"
+mixed = 1 + 0.5
+wrapped = 9223372036854775807 + 1
+power = 2 ** 10
+truncated = -7 / 2
+flag = !(1 < 2) || 3 >= 3
+loose = 1 == 1.0
+strict = 1 === 1.0
+either = null ?? "default"
+negZero = -0.0
+`
+	machine := runProgram(t, input)
+	testGlobal(t, machine, "day", vm.NewIntValue(86400))
+	testGlobal(t, machine, "greeting", vm.NewStringValue("Hello, world"))
+	testGlobal(t, machine, "mixed", vm.NewFloatValue(1.5))
+	testGlobal(t, machine, "wrapped", vm.NewIntValue(math.MinInt64))
+	testGlobal(t, machine, "power", vm.NewIntValue(1024))
+	testGlobal(t, machine, "truncated", vm.NewIntValue(-3))
+	testGlobal(t, machine, "flag", vm.NewBoolValue(true))
+	testGlobal(t, machine, "loose", vm.NewBoolValue(true))
+	testGlobal(t, machine, "strict", vm.NewBoolValue(false))
+	testGlobal(t, machine, "either", vm.NewStringValue("default"))
+	if z, _ := machine.GetGlobal("negZero"); !math.Signbit(z.AsFloat()) {
+		t.Errorf("folding -0.0 lost its sign, got %s", z.ToString())
+	}
+
+	compile := func(input string) *vm.Function {
+		function, err := CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("compilation failed: %v", err)
+		}
+		return function
+	}
+
+	// Folded code is the same size as code using the result directly
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 60 * 60 * 24", "x = 86400"},
+		{"x = \"Hello, \" + \"world\"", "x = \"Hello, world\""},
+		{"x = -(2 + 3) * 1.5", "x = -7.5"},
+		{"x = !true && y", "x = false"},
+		{"x = true && y", "x = y"},
+		{"x = 1 < 2 ? \"yes\" : \"no\"", "x = \"yes\""},
+		{"if (false) { print(\"dead\") }", ""},
+		{"if (1 > 2) { print(\"dead\") } else { x = 1 }", "{ x = 1 }"},
+		{"if (true) { x = 1 } else { print(\"dead\") }", "{ x = 1 }"},
+	}
+	for _, tt := range tests {
+		got, want := compile(tt.input), compile(tt.expected)
+		if len(got.Instructions) != len(want.Instructions) || len(got.Constants) != len(want.Constants) {
+			t.Errorf("%q: got %d instructions and %d constants, want %d and %d", tt.input,
+				len(got.Instructions), len(got.Constants), len(want.Instructions), len(want.Constants))
+		}
+	}
+
+	// Operations that fail at runtime are left to the VM
+	for _, input := range []string{"x = 1 / 0", "x = 1 % 0", "x = 1.5 / 0.0", "x = 2 ** -1", "x = \"a\" + 1"} {
+		function := compile(input)
+		if _, err := vm.NewVM().Execute(vm.NewClosure(function), nil); err == nil {
+			t.Errorf("%q: expected a runtime error", input)
+		}
+	}
+}
+
+func TestPeepholeOptimization(t *testing.T) {
+	// listing returns the instructions of function, one per line with
+	// single spaces
+	listing := func(function *vm.Function) string {
+		var lines []string
+		for _, inst := range function.Instructions {
+			lines = append(lines, strings.Join(strings.Fields(inst.String()), " "))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// Hand-written sequences cover patterns the code generator only
+	// produces in some programs
+	tests := []struct {
+		name     string
+		input    []vm.Instruction
+		expected string
+	}{
+		{
+			"chained jumps",
+			[]vm.Instruction{
+				vm.CreateABx(vm.OpJmp, 0, 1+vm.BxOffset),
+				vm.CreateABC(vm.OpLoadNil, 0, 0, 0),
+				vm.CreateABx(vm.OpJmp, 0, 1+vm.BxOffset),
+				vm.CreateABC(vm.OpLoadNil, 1, 0, 0),
+				vm.CreateABC(vm.OpReturn, 0, 1, 0),
+			},
+			"RETURN R0, 1",
+		},
+		{
+			"conditional jump threaded",
+			[]vm.Instruction{
+				vm.CreateABC(vm.OpTest, 0, 0, 0),
+				vm.CreateABx(vm.OpJmp, 0, 2+vm.BxOffset),
+				vm.CreateABC(vm.OpLoadNil, 1, 0, 0),
+				vm.CreateABC(vm.OpReturn, 1, 1, 0),
+				vm.CreateABx(vm.OpJmp, 0, 1+vm.BxOffset),
+				vm.CreateABC(vm.OpLoadNil, 2, 0, 0),
+				vm.CreateABC(vm.OpReturn, 0, 1, 0),
+			},
+			"TEST R0, 0\nJMP +2\nLOADNIL R1\nRETURN R1, 1\nRETURN R0, 1",
+		},
+		{
+			"redundant moves",
+			[]vm.Instruction{
+				vm.CreateABC(vm.OpMove, 1, 1, 0),
+				vm.CreateABC(vm.OpMove, 2, 1, 0),
+				vm.CreateABC(vm.OpMove, 1, 2, 0),
+				vm.CreateABC(vm.OpReturn, 1, 1, 0),
+			},
+			"MOVE R2, R1\nRETURN R1, 1",
+		},
+		{
+			"repeated loads",
+			[]vm.Instruction{
+				vm.CreateABx(vm.OpLoadK, 0, 3),
+				vm.CreateABx(vm.OpLoadK, 0, 3),
+				vm.CreateABx(vm.OpLoadK, 1, 3),
+				vm.CreateABC(vm.OpLoadBool, 2, 1, 0),
+				vm.CreateABC(vm.OpLoadBool, 2, 1, 0),
+				vm.CreateABC(vm.OpReturn, 0, 3, 0),
+			},
+			"LOADK R0, 3\nLOADK R1, 3\nLOADBOOL R2, 1, 0\nRETURN R0, 3",
+		},
+		{
+			"jump target kept",
+			[]vm.Instruction{
+				vm.CreateABx(vm.OpLoadK, 0, 0),
+				vm.CreateABx(vm.OpLoadK, 0, 0),
+				vm.CreateABC(vm.OpTest, 1, 0, 0),
+				vm.CreateABx(vm.OpJmp, 0, -3+vm.BxOffset),
+				vm.CreateABC(vm.OpReturn, 0, 1, 0),
+			},
+			"LOADK R0, 0\nLOADK R0, 0\nTEST R1, 0\nJMP -3\nRETURN R0, 1",
+		},
+		{
+			"skipped instruction kept",
+			[]vm.Instruction{
+				vm.CreateABC(vm.OpLoadBool, 0, 1, 1),
+				vm.CreateABC(vm.OpMove, 0, 0, 0),
+				vm.CreateABC(vm.OpReturn, 0, 1, 0),
+			},
+			"LOADBOOL R0, 1, 1\nMOVE R0, R0\nRETURN R0, 1",
+		},
+		{
+			"load after skipped load kept",
+			[]vm.Instruction{
+				vm.CreateABC(vm.OpTest, 1, 0, 0),
+				vm.CreateABx(vm.OpLoadK, 0, 0),
+				vm.CreateABx(vm.OpLoadK, 0, 0),
+				vm.CreateABC(vm.OpReturn, 0, 1, 0),
+			},
+			"TEST R1, 0\nLOADK R0, 0\nLOADK R0, 0\nRETURN R0, 1",
+		},
+		{
+			"exception handler re-patched",
+			[]vm.Instruction{
+				vm.CreateABx(vm.OpSetupTry, 1, 4+vm.BxOffset),
+				vm.CreateABC(vm.OpMove, 0, 0, 0),
+				vm.CreateABC(vm.OpThrow, 0, 0, 0),
+				vm.CreateABC(vm.OpLoadNil, 2, 0, 0),
+				vm.CreateABC(vm.OpMove, 2, 2, 0),
+				vm.CreateABC(vm.OpReturn, 1, 1, 0),
+			},
+			"SETUPTRY R1, +1\nTHROW R0\nRETURN R1, 1",
+		},
+	}
+	for _, tt := range tests {
+		c := NewCompiler()
+		c.instructions = tt.input
+		c.lines = make([]int, len(tt.input))
+		c.peephole()
+		got := listing(&vm.Function{Instructions: c.instructions})
+		if got != tt.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, tt.expected, got)
+		}
+		if len(c.lines) != len(c.instructions) {
+			t.Errorf("%s: %d line numbers for %d instructions", tt.name, len(c.lines), len(c.instructions))
+		}
+	}
+
+	compile := func(input string, optimize bool) *vm.Function {
+		c := NewCompiler()
+		c.SetOptimize(optimize)
+		function, err := c.Compile(parser.New(lexer.New(input)).ParseProgram())
+		if err != nil {
+			t.Fatalf("compilation failed: %v", err)
+		}
+		return function
+	}
+
+	// The jump over an else branch that ends in a return is dropped
+	input := "function f(x: int): int { if (x > 0) { return 1 } else { return 2 } }"
+	function := compile(input, true).Constants[0].AsFunction()
+	expected := `MOVE R1, R0
+LOADK R2, 0
+GT R1, R1, R2
+TEST R1, 0
+JMP +2
+LOADK R1, 1
+RETURN R1, 1
+LOADK R1, 2
+RETURN R1, 1`
+	if got := listing(function); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+	if n := len(compile(input, false).Constants[0].AsFunction().Instructions); n != 10 {
+		t.Errorf("expected 10 instructions without the pass, got %d", n)
+	}
+
+	// A break out of an if statement no longer jumps over the else branch
+	input = "a = 0\nwhile (a < 3) { if (a == 1) { break } else { a = a + 1 } }"
+	if got, want := len(compile(input, true).Instructions), len(compile(input, false).Instructions)-1; got != want {
+		t.Errorf("expected %d instructions, got %d", want, got)
+	}
+
+	// Optimized programs compute the same results
+	input = `
+total = 0
+for (let i = 0; i < 10; i = i + 1) {
+    if (i % 2 == 0) { continue }
+    if (i > 7) { break } else { total = total + i }
+}
+try { throw "x" } catch (e) { total = total + 100 }
+`
+	for _, optimize := range []bool{true, false} {
+		machine := vm.NewVM()
+		if _, err := machine.Execute(vm.NewClosure(compile(input, optimize)), nil); err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		testGlobal(t, machine, "total", vm.NewIntValue(116))
+	}
+}
@@ -0,0 +1,197 @@
+package compiler
+
+import (
+	"math"
+
+	"github.com/xingleixu/TG-Script/ast"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+// constantValue returns the value of expr when it can be computed at
+// compile time: a literal, or arithmetic, string concatenation,
+// comparisons, unary minus and not, and logical operators over constants.
+// Folding follows the VM's rules, so int arithmetic wraps and mixing an int
+// with a float gives a float. Operations that fail at runtime, such as
+// division by zero, are not folded and keep their runtime error.
+func constantValue(expr ast.Expression) (vm.Value, bool) {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return vm.NewIntValue(e.Value), true
+	case *ast.FloatLiteral:
+		return vm.NewFloatValue(e.Value), true
+	case *ast.StringLiteral:
+		return vm.NewStringValue(e.Value), true
+	case *ast.BooleanLiteral:
+		return vm.NewBoolValue(e.Value), true
+	case *ast.NullLiteral:
+		return vm.NullValue, true
+	case *ast.UndefinedLiteral:
+		return vm.NilValue, true
+	case *ast.UnaryExpression:
+		return foldUnary(e)
+	case *ast.BinaryExpression:
+		return foldBinary(e)
+	}
+	return vm.NilValue, false
+}
+
+// foldUnary folds -, + and ! applied to a constant
+func foldUnary(expr *ast.UnaryExpression) (vm.Value, bool) {
+	op := expr.Operator.String()
+	if op != "-" && op != "+" && op != "!" {
+		return vm.NilValue, false
+	}
+	operand, ok := constantValue(expr.Operand)
+	if !ok {
+		return vm.NilValue, false
+	}
+
+	switch op {
+	case "!":
+		return vm.NewBoolValue(!operand.ToBool()), true
+	case "+":
+		return operand, operand.IsNumber()
+	}
+	switch operand.Type {
+	case vm.TypeInt:
+		return vm.NewIntValue(-operand.AsInt()), true
+	case vm.TypeFloat:
+		return vm.NewFloatValue(-operand.AsFloat()), true
+	}
+	return vm.NilValue, false
+}
+
+// foldBinary folds a binary operator applied to two constants. A logical
+// operator whose left operand decides the result folds to that operand.
+func foldBinary(expr *ast.BinaryExpression) (vm.Value, bool) {
+	op := expr.Operator.String()
+	left, ok := constantValue(expr.Left)
+	if !ok {
+		return vm.NilValue, false
+	}
+	switch op {
+	case "&&", "||", "??":
+		if !evaluatesRight(op, left) {
+			return left, true
+		}
+		return constantValue(expr.Right)
+	}
+	right, ok := constantValue(expr.Right)
+	if !ok {
+		return vm.NilValue, false
+	}
+
+	switch op {
+	case "==":
+		return vm.NewBoolValue(left.LooseEquals(right)), true
+	case "!=":
+		return vm.NewBoolValue(!left.LooseEquals(right)), true
+	case "===":
+		return vm.NewBoolValue(left.Equals(right)), true
+	case "!==":
+		return vm.NewBoolValue(!left.Equals(right)), true
+	case "<", "<=", ">", ">=":
+		cmp, ok := left.Compare(right)
+		if !ok {
+			return vm.FalseValue, true
+		}
+		switch op {
+		case "<":
+			return vm.NewBoolValue(cmp < 0), true
+		case "<=":
+			return vm.NewBoolValue(cmp <= 0), true
+		case ">":
+			return vm.NewBoolValue(cmp > 0), true
+		default:
+			return vm.NewBoolValue(cmp >= 0), true
+		}
+	case "+":
+		if left.IsString() && right.IsString() {
+			return vm.NewStringValue(left.AsString() + right.AsString()), true
+		}
+	}
+
+	if !left.IsNumber() || !right.IsNumber() {
+		return vm.NilValue, false
+	}
+	if left.IsInt() && right.IsInt() {
+		return foldInt(op, left.AsInt(), right.AsInt())
+	}
+	fl, _ := left.ToFloat()
+	fr, _ := right.ToFloat()
+	return foldFloat(op, fl, fr)
+}
+
+// evaluatesRight reports whether the logical operator op evaluates its
+// right operand when the left one is left
+func evaluatesRight(op string, left vm.Value) bool {
+	switch op {
+	case "&&":
+		return left.ToBool()
+	case "||":
+		return !left.ToBool()
+	default:
+		return left.IsNullish()
+	}
+}
+
+// foldInt folds int arithmetic, wrapping on overflow like the VM
+func foldInt(op string, a, b int64) (vm.Value, bool) {
+	switch op {
+	case "+":
+		return vm.NewIntValue(a + b), true
+	case "-":
+		return vm.NewIntValue(a - b), true
+	case "*":
+		return vm.NewIntValue(a * b), true
+	case "/":
+		if b == 0 {
+			return vm.NilValue, false
+		}
+		return vm.NewIntValue(a / b), true
+	case "%":
+		if b == 0 {
+			return vm.NilValue, false
+		}
+		return vm.NewIntValue(a % b), true
+	case "**":
+		if b < 0 {
+			return vm.NilValue, false
+		}
+		result := int64(1)
+		for b > 0 {
+			if b&1 != 0 {
+				result *= a
+			}
+			a *= a
+			b >>= 1
+		}
+		return vm.NewIntValue(result), true
+	}
+	return vm.NilValue, false
+}
+
+// foldFloat folds float arithmetic
+func foldFloat(op string, a, b float64) (vm.Value, bool) {
+	switch op {
+	case "+":
+		return vm.NewFloatValue(a + b), true
+	case "-":
+		return vm.NewFloatValue(a - b), true
+	case "*":
+		return vm.NewFloatValue(a * b), true
+	case "/":
+		if b == 0 {
+			return vm.NilValue, false
+		}
+		return vm.NewFloatValue(a / b), true
+	case "%":
+		if b == 0 {
+			return vm.NilValue, false
+		}
+		return vm.NewFloatValue(math.Mod(a, b)), true
+	case "**":
+		return vm.NewFloatValue(math.Pow(a, b)), true
+	}
+	return vm.NilValue, false
+}
@@ -0,0 +1,215 @@
+package compiler
+
+import "github.com/xingleixu/TG-Script/vm"
+
+// peephole runs the peephole pass over the instructions of the function
+// being compiled, repeating it until nothing changes. It
+//   - retargets jumps to jumps at the final destination,
+//   - drops instructions no path of execution reaches,
+//   - drops MOVE R(A), R(A), a MOVE undoing the MOVE before it, jumps to
+//     the next instruction along with a test deciding whether to skip
+//     them, and a load repeating the load before it.
+//
+// Jumps and exception handlers are re-patched to the instructions they
+// targeted. An instruction that a TEST-like instruction may skip is never
+// dropped, since that would change what is skipped, and neither is the
+// repeat of one it may skip to.
+func (c *Compiler) peephole() {
+	for {
+		threaded := c.threadJumps()
+		if !c.removeInstructions(c.redundantInstructions()) && !threaded {
+			return
+		}
+	}
+}
+
+// jumpTarget returns the index of the instruction that the jump or
+// handler setup at pc targets
+func jumpTarget(inst vm.Instruction, pc int) (int, bool) {
+	switch inst.GetOpCode() {
+	case vm.OpJmp, vm.OpSetupTry, vm.OpForPrep, vm.OpForLoop:
+		return pc + 1 + inst.GetSBx(), true
+	}
+	return 0, false
+}
+
+// skipsNext reports whether inst may skip the instruction after it
+func skipsNext(inst vm.Instruction) bool {
+	switch inst.GetOpCode() {
+	case vm.OpTest, vm.OpTestSet, vm.OpTestNullish, vm.OpIterNext:
+		return true
+	case vm.OpLoadBool:
+		return inst.GetC() != 0
+	}
+	return false
+}
+
+// threadJumps points each JMP whose target is another JMP at where the
+// chain of jumps ends. It reports whether any jump changed.
+func (c *Compiler) threadJumps() bool {
+	changed := false
+	for pc, inst := range c.instructions {
+		if inst.GetOpCode() != vm.OpJmp {
+			continue
+		}
+		target, _ := jumpTarget(inst, pc)
+		final, ended := target, false
+		// Bound the walk so that a cycle of jumps can't loop forever
+		for hops := 0; hops < len(c.instructions); hops++ {
+			if final >= len(c.instructions) || c.instructions[final].GetOpCode() != vm.OpJmp {
+				ended = true
+				break
+			}
+			final, _ = jumpTarget(c.instructions[final], final)
+		}
+		if ended && final != target {
+			c.PatchJump(pc, final)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// reachable marks the instructions some path of execution from the
+// function's entry, or from an exception handler, reaches
+func (c *Compiler) reachable() []bool {
+	n := len(c.instructions)
+	seen := make([]bool, n)
+	work := []int{0}
+	for len(work) > 0 {
+		pc := work[len(work)-1]
+		work = work[:len(work)-1]
+		if pc < 0 || pc >= n || seen[pc] {
+			continue
+		}
+		seen[pc] = true
+
+		inst := c.instructions[pc]
+		if target, ok := jumpTarget(inst, pc); ok {
+			work = append(work, target)
+		}
+		switch inst.GetOpCode() {
+		case vm.OpJmp, vm.OpForPrep, vm.OpReturn, vm.OpTailCall, vm.OpThrow, vm.OpHalt:
+			continue
+		}
+		work = append(work, pc+1)
+		if skipsNext(inst) {
+			work = append(work, pc+2)
+		}
+	}
+	return seen
+}
+
+// redundantInstructions marks the instructions the pass can drop
+func (c *Compiler) redundantInstructions() []bool {
+	n := len(c.instructions)
+	remove := make([]bool, n)
+	seen := c.reachable()
+
+	// An instruction a TEST-like instruction lands on by skipping is as
+	// much a target as one a jump lands on
+	targeted := make([]bool, n+2)
+	for pc, inst := range c.instructions {
+		if !seen[pc] {
+			continue
+		}
+		if target, ok := jumpTarget(inst, pc); ok && target >= 0 && target <= n {
+			targeted[target] = true
+		}
+		if skipsNext(inst) {
+			targeted[pc+2] = true
+		}
+	}
+
+	for pc, inst := range c.instructions {
+		if !seen[pc] {
+			remove[pc] = true
+			continue
+		}
+		if pc > 0 && skipsNext(c.instructions[pc-1]) {
+			continue
+		}
+
+		switch inst.GetOpCode() {
+		case vm.OpMove:
+			if inst.GetA() == inst.GetB() {
+				remove[pc] = true
+				continue
+			}
+		case vm.OpJmp:
+			if target, _ := jumpTarget(inst, pc); target == pc+1 {
+				remove[pc] = true
+				continue
+			}
+		case vm.OpTest, vm.OpTestNullish:
+			// A test that only decides whether to skip a jump to the
+			// instruction after it has no effect
+			if pc+1 < n && c.instructions[pc+1].GetOpCode() == vm.OpJmp {
+				if target, _ := jumpTarget(c.instructions[pc+1], pc+1); target == pc+2 {
+					remove[pc], remove[pc+1] = true, true
+					continue
+				}
+			}
+		}
+
+		// The rest repeat or undo the instruction before, which a jump
+		// to this one would bypass
+		if pc == 0 || targeted[pc] || remove[pc-1] {
+			continue
+		}
+		prev := c.instructions[pc-1]
+		switch inst.GetOpCode() {
+		case vm.OpMove:
+			// MOVE R(B), R(A) after MOVE R(A), R(B)
+			if prev.GetOpCode() == vm.OpMove && prev.GetA() == inst.GetB() && prev.GetB() == inst.GetA() {
+				remove[pc] = true
+			}
+		case vm.OpLoadK, vm.OpLoadNil, vm.OpLoadInt, vm.OpLoadBool:
+			if inst == prev {
+				remove[pc] = true
+			}
+		}
+	}
+	return remove
+}
+
+// removeInstructions drops the marked instructions and re-patches the
+// jumps around them. It reports whether any instruction was dropped.
+func (c *Compiler) removeInstructions(remove []bool) bool {
+	n := len(c.instructions)
+
+	// newIndex[pc] is where the first kept instruction at or after pc ends up
+	newIndex := make([]int, n+1)
+	kept := 0
+	for pc := 0; pc < n; pc++ {
+		newIndex[pc] = kept
+		if !remove[pc] {
+			kept++
+		}
+	}
+	newIndex[n] = kept
+	if kept == n {
+		return false
+	}
+
+	instructions := make([]vm.Instruction, 0, kept)
+	lines := make([]int, 0, kept)
+	var jumps [][2]int // new position and old target of each jump
+	for pc, inst := range c.instructions {
+		if remove[pc] {
+			continue
+		}
+		if target, ok := jumpTarget(inst, pc); ok {
+			jumps = append(jumps, [2]int{len(instructions), target})
+		}
+		instructions = append(instructions, inst)
+		lines = append(lines, c.lines[pc])
+	}
+	c.instructions = instructions
+	c.lines = lines
+
+	for _, jump := range jumps {
+		c.PatchJump(jump[0], newIndex[jump[1]])
+	}
+	return true
+}
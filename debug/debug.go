@@ -234,12 +234,13 @@ func (d *Debugger) LogVMState(machine *vm.VM) {
 	}
 	
 	// Log global variables
-	if len(machine.Globals) > 0 {
-		d.logf(DebugVerbose, "VM", "Globals: %d", len(machine.Globals))
+	globals := machine.Globals()
+	if len(globals) > 0 {
+		d.logf(DebugVerbose, "VM", "Globals: %d", len(globals))
 		count := 0
-		for name, value := range machine.Globals {
+		for name, value := range globals {
 			if count >= 3 {
-				d.logf(DebugVerbose, "VM", "  ... and %d more", len(machine.Globals)-3)
+				d.logf(DebugVerbose, "VM", "  ... and %d more", len(globals)-3)
 				break
 			}
 			d.logf(DebugVerbose, "VM", "  %s: %s", name, value.ToString())
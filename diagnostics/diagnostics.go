@@ -0,0 +1,153 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/types"
+)
+
+// Severity represents how serious a diagnostic is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a structured report about a source file, suitable for
+// editors and other tools
+type Diagnostic struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	EndLine    int      `json:"endLine"`
+	EndColumn  int      `json:"endColumn"`
+	Severity   Severity `json:"severity"`
+	Code       string   `json:"code,omitempty"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+	Context    string   `json:"context,omitempty"`
+}
+
+// FromParserError converts a parser error into a diagnostic
+func FromParserError(file string, err *parser.ParserError) Diagnostic {
+	return Diagnostic{
+		File:      file,
+		Line:      err.Position.Line,
+		Column:    err.Position.Column,
+		EndLine:   err.Position.Line,
+		EndColumn: err.Position.Column,
+		Severity:  SeverityError,
+		Message:   err.Message,
+	}
+}
+
+// FromTypeError converts a type error or warning into a diagnostic
+// covering its span
+func FromTypeError(file string, err *types.TypeError) Diagnostic {
+	end := err.Span.End
+	if end.Line == 0 {
+		end = err.Position
+	}
+	severity := SeverityError
+	if err.Severity == types.SeverityWarning {
+		severity = SeverityWarning
+	}
+	return Diagnostic{
+		File:       file,
+		Line:       err.Position.Line,
+		Column:     err.Position.Column,
+		EndLine:    end.Line,
+		EndColumn:  end.Column,
+		Severity:   severity,
+		Code:       string(err.Code),
+		Message:    err.Message,
+		Suggestion: err.Suggestion,
+		Context:    err.Context,
+	}
+}
+
+// Check parses and type checks source, returning all diagnostics with
+// warnings after errors. Type checking is skipped when the source has
+// syntax errors.
+func Check(file, source string) []Diagnostic {
+	diags := []Diagnostic{}
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errors := p.ParserErrors(); len(errors) > 0 {
+		for _, err := range errors {
+			diags = append(diags, FromParserError(file, err))
+		}
+		return diags
+	}
+
+	checker := types.NewTypeChecker()
+	for _, err := range checker.Check(program) {
+		diags = append(diags, FromTypeError(file, err))
+	}
+	for _, warning := range checker.Warnings() {
+		diags = append(diags, FromTypeError(file, warning))
+	}
+	return diags
+}
+
+// Split separates diagnostics into errors and warnings
+func Split(diags []Diagnostic) (errors, warnings []Diagnostic) {
+	for _, d := range diags {
+		if d.Severity == SeverityWarning {
+			warnings = append(warnings, d)
+		} else {
+			errors = append(errors, d)
+		}
+	}
+	return errors, warnings
+}
+
+// Underline renders the source line where span starts with carets under the
+// spanned columns. Spans that continue onto later lines are underlined to
+// the end of the first line; empty spans get a single caret.
+func Underline(source string, span lexer.Span) string {
+	lines := strings.Split(source, "\n")
+	if span.Start.Line < 1 || span.Start.Line > len(lines) {
+		return ""
+	}
+	line := strings.TrimRight(lines[span.Start.Line-1], "\r")
+
+	start := span.Start.Column
+	if start < 1 {
+		start = 1
+	}
+	end := span.End.Column
+	if span.End.Line != span.Start.Line {
+		end = len(line) + 1
+	}
+	if end <= start {
+		end = start + 1
+	}
+
+	// Keep tabs in the indentation so the carets line up
+	var indent strings.Builder
+	for i := 0; i < start-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+	return line + "\n" + indent.String() + strings.Repeat("^", end-start)
+}
+
+// WriteJSON writes diagnostics to w as a JSON array
+func WriteJSON(w io.Writer, diags []Diagnostic) error {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diags)
+}
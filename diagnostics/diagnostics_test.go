@@ -0,0 +1,113 @@
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/lexer"
+)
+
+func TestCheckReportsParserErrors(t *testing.T) {
+	diags := Check("bad.tg", "let x = ;")
+	if len(diags) == 0 {
+		t.Fatalf("expected diagnostics")
+	}
+
+	d := diags[0]
+	if d.File != "bad.tg" || d.Line != 1 || d.Column != 9 || d.Severity != SeverityError {
+		t.Errorf("wrong diagnostic: %+v", d)
+	}
+}
+
+func TestCheckReportsTypeErrors(t *testing.T) {
+	diags := Check("types.tg", "let x: int = 1\nlet y: string = x")
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %+v", len(diags), diags)
+	}
+
+	d := diags[0]
+	if d.Code != "E002" || d.Line != 2 || d.Message == "" || d.Suggestion == "" || d.Context == "" {
+		t.Errorf("wrong diagnostic: %+v", d)
+	}
+}
+
+func TestTypeErrorSpan(t *testing.T) {
+	source := "let a: int = 1\nlet b: string = \"x\"\nlet c = a - b"
+	diags := Check("span.tg", source)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %+v", len(diags), diags)
+	}
+
+	d := diags[0]
+	if d.Line != 3 || d.Column != 11 || d.EndLine != 3 || d.EndColumn != 12 {
+		t.Errorf("wrong range: %+v", d)
+	}
+}
+
+func TestUnderline(t *testing.T) {
+	source := "let x = 1\n\tlet y = a + b\n"
+	tests := []struct {
+		span     lexer.Span
+		expected string
+	}{
+		{lexer.Span{Start: lexer.Position{Line: 1, Column: 5}, End: lexer.Position{Line: 1, Column: 6}}, "let x = 1\n    ^"},
+		// Tabs are kept so the carets line up
+		{lexer.Span{Start: lexer.Position{Line: 2, Column: 10}, End: lexer.Position{Line: 2, Column: 15}}, "\tlet y = a + b\n\t        ^^^^^"},
+		// Empty spans get a single caret
+		{lexer.Span{Start: lexer.Position{Line: 1, Column: 9}, End: lexer.Position{Line: 1, Column: 9}}, "let x = 1\n        ^"},
+		// Multi-line spans are underlined to the end of the first line
+		{lexer.Span{Start: lexer.Position{Line: 1, Column: 9}, End: lexer.Position{Line: 2, Column: 3}}, "let x = 1\n        ^"},
+		{lexer.Span{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := Underline(source, tt.span); got != tt.expected {
+			t.Errorf("Underline(%s) = %q, want %q", tt.span, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, Check("ok.tg", "let x = 1")); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	if got := bytes.TrimSpace(buf.Bytes()); string(got) != "[]" {
+		t.Errorf("expected empty array, got %s", got)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, Check("types.tg", "let x: int = \"s\"")); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(decoded) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(decoded))
+	}
+	for _, field := range []string{"file", "line", "column", "endLine", "endColumn", "severity", "code", "message"} {
+		if _, ok := decoded[0][field]; !ok {
+			t.Errorf("diagnostic is missing field %q", field)
+		}
+	}
+}
+
+func TestCheckReportsWarnings(t *testing.T) {
+	diags := Check("warn.tg", "let x: string = 1\nfunction f(a: int): int {\n    return 0\n}")
+	errors, warnings := Split(diags)
+	if len(errors) != 1 || len(warnings) != 1 {
+		t.Fatalf("expected 1 error and 1 warning, got %+v", diags)
+	}
+	if diags[1] != warnings[0] {
+		t.Errorf("expected warnings after errors, got %+v", diags)
+	}
+
+	w := warnings[0]
+	if w.Code != "W001" || w.Line != 2 || w.Column != 12 || w.EndColumn != 13 {
+		t.Errorf("wrong warning: %+v", w)
+	}
+}
@@ -0,0 +1,177 @@
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/ast"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/types"
+)
+
+// Entry documents a single top-level declaration
+type Entry struct {
+	Kind      string `json:"kind"` // function, class, interface, enum, type or const
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Doc       string `json:"doc"`
+	Line      int    `json:"line"`
+}
+
+// Generate parses and type checks source and returns documentation for its
+// top-level declarations. Doc text comes from the comments directly above
+// each declaration, and signatures use the types resolved by the checker.
+func Generate(source string) ([]Entry, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) > 0 {
+		return nil, fmt.Errorf("parsing failed: %s", strings.Join(errors, "; "))
+	}
+
+	// Type errors don't prevent documentation; unresolved types are shown as-is
+	checker := types.NewTypeChecker()
+	checker.Check(program)
+	scope := checker.GetGlobalScope()
+
+	entries := []Entry{}
+	for _, stmt := range program.Body {
+		for _, entry := range describe(stmt, scope) {
+			entry.Doc = docText(program.LeadingComments(stmt))
+			entry.Line = stmt.Pos().Line
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// describe builds the entries for a statement, if it is a documented declaration
+func describe(stmt ast.Statement, scope *types.Scope) []Entry {
+	switch s := stmt.(type) {
+	case *ast.FunctionDeclaration:
+		return []Entry{{Kind: "function", Name: s.Name.Name, Signature: functionSignature(s, scope)}}
+	case *ast.ClassDeclaration:
+		signature := "class " + s.Name.Name
+		if s.SuperClass != nil {
+			signature += " extends " + s.SuperClass.String()
+		}
+		return []Entry{{Kind: "class", Name: s.Name.Name, Signature: signature}}
+	case *ast.InterfaceDeclaration:
+		return []Entry{{Kind: "interface", Name: s.Name.Name, Signature: interfaceSignature(s.Name.Name, scope)}}
+	case *ast.EnumDeclaration:
+		var members []string
+		for _, member := range s.Members {
+			members = append(members, member.String())
+		}
+		signature := fmt.Sprintf("enum %s { %s }", s.Name.Name, strings.Join(members, ", "))
+		return []Entry{{Kind: "enum", Name: s.Name.Name, Signature: signature}}
+	case *ast.TypeAliasDeclaration:
+		return []Entry{{Kind: "type", Name: s.Name.Name, Signature: s.String()}}
+	case *ast.ExportDeclaration:
+		return describe(s.Declaration, scope)
+	case *ast.VariableDeclaration:
+		if s.Kind != lexer.CONST {
+			return nil
+		}
+		var entries []Entry
+		for _, decl := range s.Declarations {
+			// A destructuring pattern documents each name it binds
+			for _, id := range ast.BindingNames(decl.Id) {
+				signature := "const " + id.Name
+				if symbol, ok := scope.LookupLocal(id.Name); ok {
+					signature += ": " + symbol.Type.String()
+				}
+				entries = append(entries, Entry{Kind: "const", Name: id.Name, Signature: signature})
+			}
+		}
+		return entries
+	default:
+		return nil
+	}
+}
+
+// functionSignature formats a function declaration using its resolved type
+func functionSignature(decl *ast.FunctionDeclaration, scope *types.Scope) string {
+	var funcType *types.FunctionType
+	if symbol, ok := scope.LookupLocal(decl.Name.Name); ok {
+		funcType, _ = symbol.Type.(*types.FunctionType)
+	}
+
+	var params []string
+	for i, param := range decl.Parameters {
+		paramType := "any"
+		if funcType != nil && i < len(funcType.Parameters) {
+			paramType = funcType.Parameters[i].String()
+		}
+		params = append(params, fmt.Sprintf("%s: %s", param.Name.Name, paramType))
+	}
+
+	returnType := "void"
+	if funcType != nil {
+		returnType = funcType.ReturnType.String()
+	}
+	var typeParams string
+	if len(decl.TypeParameters) > 0 {
+		var names []string
+		for _, param := range decl.TypeParameters {
+			names = append(names, param.String())
+		}
+		typeParams = "<" + strings.Join(names, ", ") + ">"
+	}
+	return fmt.Sprintf("function %s%s(%s): %s", decl.Name.Name, typeParams, strings.Join(params, ", "), returnType)
+}
+
+// interfaceSignature formats an interface using its resolved object type
+func interfaceSignature(name string, scope *types.Scope) string {
+	symbol, ok := scope.LookupLocal(name)
+	if !ok {
+		return "interface " + name
+	}
+	objType, ok := symbol.Type.(*types.ObjectType)
+	if !ok {
+		return "interface " + name
+	}
+
+	var members []string
+	for _, prop := range objType.PropertyNames() {
+		if objType.IsOptional(prop) {
+			members = append(members, fmt.Sprintf("%s?: %s", prop, objType.Properties[prop].String()))
+		} else {
+			members = append(members, fmt.Sprintf("%s: %s", prop, objType.Properties[prop].String()))
+		}
+	}
+	return fmt.Sprintf("interface %s { %s }", name, strings.Join(members, "; "))
+}
+
+// docText joins the contents of a comment group
+func docText(comments []*ast.Comment) string {
+	var lines []string
+	for _, comment := range comments {
+		lines = append(lines, comment.Content())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteMarkdown writes the documentation as Markdown
+func WriteMarkdown(w io.Writer, title string, entries []Entry) error {
+	var sb strings.Builder
+	sb.WriteString("# " + title + "\n")
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf("\n## %s %s\n\n", entry.Kind, entry.Name))
+		sb.WriteString("```typescript\n" + entry.Signature + "\n```\n")
+		if entry.Doc != "" {
+			sb.WriteString("\n" + entry.Doc + "\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteJSON writes the documentation as a JSON array
+func WriteJSON(w io.Writer, entries []Entry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
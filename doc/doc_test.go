@@ -0,0 +1,129 @@
+package doc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const documentedSource = `// Package comment is not attached to anything.
+
+let counter = 0 // trailing comments are ignored
+
+// add returns the sum of two integers.
+function add(a: int, b: int): int {
+    return a + b
+}
+
+/**
+ * greet builds a greeting
+ * for the given name.
+ */
+function greet(name: string): string {
+    return "hello " + name
+}
+
+// Shape describes something with an area.
+interface Shape {
+    area: float
+    label?: string
+}
+
+// LIMIT is the largest accepted value.
+const LIMIT = 100
+
+function undocumented() {
+}
+`
+
+func TestGenerate(t *testing.T) {
+	entries, err := Generate(documentedSource)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	tests := []struct {
+		kind      string
+		name      string
+		signature string
+		doc       string
+	}{
+		{"function", "add", "function add(a: int, b: int): int", "add returns the sum of two integers."},
+		{"function", "greet", "function greet(name: string): string", "greet builds a greeting\nfor the given name."},
+		{"interface", "Shape", "interface Shape { area: float; label?: string }", "Shape describes something with an area."},
+		{"const", "LIMIT", "const LIMIT: int", "LIMIT is the largest accepted value."},
+		{"function", "undocumented", "function undocumented(): void", ""},
+	}
+
+	if len(entries) != len(tests) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(tests), len(entries), entries)
+	}
+
+	for i, tt := range tests {
+		entry := entries[i]
+		if entry.Kind != tt.kind || entry.Name != tt.name {
+			t.Errorf("entry %d: expected %s %s, got %s %s", i, tt.kind, tt.name, entry.Kind, entry.Name)
+		}
+		if entry.Signature != tt.signature {
+			t.Errorf("entry %d: wrong signature. expected=%q, got=%q", i, tt.signature, entry.Signature)
+		}
+		if entry.Doc != tt.doc {
+			t.Errorf("entry %d: wrong doc. expected=%q, got=%q", i, tt.doc, entry.Doc)
+		}
+	}
+}
+
+func TestGenerateExports(t *testing.T) {
+	source := `// add returns the sum of two integers.
+export function add(a: int, b: int): int {
+    return a + b
+}
+
+// LIMIT is the largest accepted value.
+export const LIMIT = 100
+`
+	entries, err := Generate(source)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := []Entry{
+		{Kind: "function", Name: "add", Signature: "function add(a: int, b: int): int", Doc: "add returns the sum of two integers.", Line: 2},
+		{Kind: "const", Name: "LIMIT", Signature: "const LIMIT: int", Doc: "LIMIT is the largest accepted value.", Line: 7},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(entries), entries)
+	}
+	for i, entry := range entries {
+		if entry != expected[i] {
+			t.Errorf("entry %d: expected %+v, got %+v", i, expected[i], entry)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	entries, err := Generate(documentedSource)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, "lib.tg", entries); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"# lib.tg",
+		"## function add",
+		"function add(a: int, b: int): int",
+		"add returns the sum of two integers.",
+		"## function greet",
+		"function greet(name: string): string",
+		"greet builds a greeting",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("markdown output is missing %q:\n%s", expected, output)
+		}
+	}
+}
@@ -8,14 +8,25 @@ import (
 
 // Lexer represents the lexical analyzer
 type Lexer struct {
-	input        string   // the input source code
-	position     int      // current position in input (points to current char)
-	readPosition int      // current reading position in input (after current char)
-	ch           byte     // current char under examination
-	line         int      // current line number (1-based)
-	column       int      // current column number (1-based)
-	offset       int      // current byte offset (0-based)
-	errors       []string // collection of lexer errors
+	input        string        // the input source code
+	position     int           // current position in input (points to current char)
+	readPosition int           // current reading position in input (after current char)
+	ch           byte          // current char under examination
+	line         int           // current line number (1-based)
+	column       int           // current column number (1-based)
+	offset       int           // current byte offset (0-based)
+	errors       []*LexerError // collection of lexer errors
+}
+
+// LexerError represents a lexical error at a source position
+type LexerError struct {
+	Position Position
+	Message  string
+}
+
+// Error returns the error message with its position
+func (e *LexerError) Error() string {
+	return fmt.Sprintf("Lexer error at line %d, column %d: %s", e.Position.Line, e.Position.Column, e.Message)
 }
 
 // New creates a new lexer instance
@@ -25,7 +36,7 @@ func New(input string) *Lexer {
 		line:   1,
 		column: 0,
 		offset: 0,
-		errors: make([]string, 0),
+		errors: make([]*LexerError, 0),
 	}
 	l.readChar() // initialize the lexer by reading the first character
 	return l
@@ -79,16 +90,29 @@ func (l *Lexer) currentPosition() Position {
 
 // addError adds an error message to the lexer's error collection
 func (l *Lexer) addError(msg string) {
-	pos := l.currentPosition()
-	errorMsg := fmt.Sprintf("Lexer error at line %d, column %d: %s", pos.Line, pos.Column, msg)
-	l.errors = append(l.errors, errorMsg)
+	l.addErrorAt(l.currentPosition(), msg)
 }
 
-// GetErrors returns all lexer errors
-func (l *Lexer) GetErrors() []string {
+// addErrorAt adds an error message reported at the given position
+func (l *Lexer) addErrorAt(pos Position, msg string) {
+	l.errors = append(l.errors, &LexerError{Position: pos, Message: msg})
+}
+
+// GetErrors returns the lexer errors in source order
+func (l *Lexer) GetErrors() []*LexerError {
 	return l.errors
 }
 
+// ErrorStrings returns the lexer errors as messages prefixed with their
+// positions
+func (l *Lexer) ErrorStrings() []string {
+	messages := make([]string, len(l.errors))
+	for i, err := range l.errors {
+		messages[i] = err.Error()
+	}
+	return messages
+}
+
 // HasErrors returns true if there are any lexer errors
 func (l *Lexer) HasErrors() bool {
 	return len(l.errors) > 0
@@ -181,41 +205,31 @@ func (l *Lexer) readNumber() (string, Token) {
 			// Hexadecimal number
 			l.readChar() // consume '0'
 			l.readChar() // consume 'x' or 'X'
-			for isHexDigit(l.ch) {
-				l.readChar()
-			}
+			l.readRadixDigits(isHexDigit, "hexadecimal")
 			return l.input[position:l.position], INT
 		} else if l.peekChar() == 'b' || l.peekChar() == 'B' {
 			// Binary number
 			l.readChar() // consume '0'
 			l.readChar() // consume 'b' or 'B'
-			for isBinaryDigit(l.ch) {
-				l.readChar()
-			}
+			l.readRadixDigits(isBinaryDigit, "binary")
 			return l.input[position:l.position], INT
 		} else if l.peekChar() == 'o' || l.peekChar() == 'O' {
 			// Octal number
 			l.readChar() // consume '0'
 			l.readChar() // consume 'o' or 'O'
-			for isOctalDigit(l.ch) {
-				l.readChar()
-			}
+			l.readRadixDigits(isOctalDigit, "octal")
 			return l.input[position:l.position], INT
 		}
 	}
 
 	// Regular decimal number
-	for isDigit(l.ch) {
-		l.readChar()
-	}
+	l.readDigits(isDigit)
 
 	// Check for decimal point
 	if l.ch == '.' && isDigit(l.peekChar()) {
 		tokenType = FLOAT
 		l.readChar() // consume '.'
-		for isDigit(l.ch) {
-			l.readChar()
-		}
+		l.readDigits(isDigit)
 	}
 
 	// Check for scientific notation
@@ -225,24 +239,59 @@ func (l *Lexer) readNumber() (string, Token) {
 		if l.ch == '+' || l.ch == '-' {
 			l.readChar() // consume sign
 		}
-		for isDigit(l.ch) {
-			l.readChar()
-		}
+		l.readDigits(isDigit)
 	}
 
 	return l.input[position:l.position], tokenType
 }
 
+// readDigits reads a run of digits accepted by isValid. Underscores are
+// allowed as separators, but only singly and between two digits.
+func (l *Lexer) readDigits(isValid func(byte) bool) {
+	prevDigit, reported := false, false
+	for isValid(l.ch) || l.ch == '_' {
+		if l.ch == '_' {
+			if (!prevDigit || !isValid(l.peekChar())) && !reported {
+				l.addError("numeric separator '_' must be between digits")
+				reported = true
+			}
+			prevDigit = false
+		} else {
+			prevDigit = true
+		}
+		l.readChar()
+	}
+}
+
+// readRadixDigits reads the digits after a 0x, 0b or 0o prefix and reports
+// a malformed literal if there are none or an invalid digit follows them.
+// The invalid characters are kept in the literal so it stays one token.
+func (l *Lexer) readRadixDigits(isValid func(byte) bool, kind string) {
+	start := l.position
+	l.readDigits(isValid)
+	if l.position == start || isLetter(l.ch) || isDigit(l.ch) {
+		l.addError(fmt.Sprintf("malformed numeric literal: expected %s digit", kind))
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+}
+
 // readString reads a string literal (single or double quoted)
 func (l *Lexer) readString(delimiter byte) string {
+	start := l.currentPosition()
 	position := l.position + 1 // skip opening quote
 	for {
 		l.readChar()
-		if l.ch == delimiter || l.ch == 0 {
+		if l.ch == 0 {
+			l.addErrorAt(start, "unterminated string literal")
+			break
+		}
+		if l.ch == delimiter {
 			break
 		}
-		// Handle escape sequences
-		if l.ch == '\\' {
+		// Handle escape sequences, leaving a trailing backslash to hit EOF
+		if l.ch == '\\' && l.peekChar() != 0 {
 			l.readChar() // skip escape character
 		}
 	}
@@ -251,14 +300,19 @@ func (l *Lexer) readString(delimiter byte) string {
 
 // readTemplateString reads a template string literal (backtick quoted)
 func (l *Lexer) readTemplateString() string {
+	start := l.currentPosition()
 	position := l.position + 1 // skip opening backtick
 	for {
 		l.readChar()
-		if l.ch == '`' || l.ch == 0 {
+		if l.ch == 0 {
+			l.addErrorAt(start, "unterminated template literal")
+			break
+		}
+		if l.ch == '`' {
 			break
 		}
-		// Handle escape sequences
-		if l.ch == '\\' {
+		// Handle escape sequences, leaving a trailing backslash to hit EOF
+		if l.ch == '\\' && l.peekChar() != 0 {
 			l.readChar() // skip escape character
 		}
 		// Note: Template expressions ${...} would need special handling
@@ -398,7 +452,12 @@ func (l *Lexer) NextToken() TokenInfo {
 	case '&':
 		if l.peekChar() == '&' {
 			l.readChar()
-			tok = TokenInfo{Type: LOGICAL_AND, Literal: "&&", Position: tok.Position}
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = TokenInfo{Type: LOGICAL_AND_ASSIGN, Literal: "&&=", Position: tok.Position}
+			} else {
+				tok = TokenInfo{Type: LOGICAL_AND, Literal: "&&", Position: tok.Position}
+			}
 		} else if l.peekChar() == '=' {
 			l.readChar()
 			tok = TokenInfo{Type: BIT_AND_ASSIGN, Literal: "&=", Position: tok.Position}
@@ -408,7 +467,12 @@ func (l *Lexer) NextToken() TokenInfo {
 	case '|':
 		if l.peekChar() == '|' {
 			l.readChar()
-			tok = TokenInfo{Type: LOGICAL_OR, Literal: "||", Position: tok.Position}
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = TokenInfo{Type: LOGICAL_OR_ASSIGN, Literal: "||=", Position: tok.Position}
+			} else {
+				tok = TokenInfo{Type: LOGICAL_OR, Literal: "||", Position: tok.Position}
+			}
 		} else if l.peekChar() == '=' {
 			l.readChar()
 			tok = TokenInfo{Type: BIT_OR_ASSIGN, Literal: "|=", Position: tok.Position}
@@ -434,7 +498,12 @@ func (l *Lexer) NextToken() TokenInfo {
 	case '?':
 		if l.peekChar() == '?' {
 			l.readChar()
-			tok = TokenInfo{Type: NULLISH, Literal: "??", Position: tok.Position}
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = TokenInfo{Type: NULLISH_ASSIGN, Literal: "??=", Position: tok.Position}
+			} else {
+				tok = TokenInfo{Type: NULLISH, Literal: "??", Position: tok.Position}
+			}
 		} else if l.peekChar() == '.' {
 			l.readChar()
 			tok = TokenInfo{Type: OPTIONAL, Literal: "?.", Position: tok.Position}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -198,7 +200,7 @@ func TestLexerStrings(t *testing.T) {
 }
 
 func TestLexerOperators(t *testing.T) {
-	input := `++ -- += -= *= /= **= && || ?? ?. ... => ** >>> <<= >>= >>>= &= |= ^= %=`
+	input := `++ -- += -= *= /= **= && || ?? ?. ... => ** >>> <<= >>= >>>= &= |= ^= %= &&= ||= ??=`
 
 	tests := []struct {
 		expectedType    Token
@@ -226,6 +228,9 @@ func TestLexerOperators(t *testing.T) {
 		{BIT_OR_ASSIGN, "|="},
 		{BIT_XOR_ASSIGN, "^="},
 		{MOD_ASSIGN, "%="},
+		{LOGICAL_AND_ASSIGN, "&&="},
+		{LOGICAL_OR_ASSIGN, "||="},
+		{NULLISH_ASSIGN, "??="},
 		{EOF, ""},
 	}
 
@@ -325,21 +330,21 @@ func TestLexerErrors(t *testing.T) {
 	}
 
 	errors := l.GetErrors()
-	if len(errors) == 0 {
-		t.Error("Expected at least one error, got none")
+	if len(errors) != 1 {
+		t.Fatalf("Expected one error, got %v", errors)
 	}
 
-	// Check that error message contains information about the illegal character
-	found := false
-	for _, err := range errors {
-		if err != "" {
-			found = true
-			break
-		}
+	// The error points at the illegal character
+	err := errors[0]
+	if err.Position.Line != 1 || err.Position.Column != 9 || err.Position.Offset != 8 {
+		t.Errorf("Expected error at line 1, column 9, offset 8, got line %d, column %d, offset %d",
+			err.Position.Line, err.Position.Column, err.Position.Offset)
 	}
-
-	if !found {
-		t.Error("Expected error message about illegal character")
+	if !strings.Contains(err.Message, "@") || strings.Contains(err.Message, "line") {
+		t.Errorf("Expected a message about '@' without the position, got %q", err.Message)
+	}
+	if got := l.ErrorStrings()[0]; got != err.Error() || !strings.Contains(got, "line 1, column 9") {
+		t.Errorf("Expected the string form to include the position, got %q", got)
 	}
 }
 
@@ -433,7 +438,7 @@ func TestLexerUnterminatedComment(t *testing.T) {
 	// Check that error message contains information about unterminated comment
 	found := false
 	for _, err := range errors {
-		if err != "" {
+		if err.Message != "" {
 			found = true
 			break
 		}
@@ -442,4 +447,132 @@ func TestLexerUnterminatedComment(t *testing.T) {
 	if !found {
 		t.Error("Expected error message about unterminated comment")
 	}
-}
\ No newline at end of file
+}
+
+func TestUnterminatedString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = "abc`, "line 1, column 9: unterminated string literal"},
+		{"let s = 1\nlet t = 'abc", "line 2, column 9: unterminated string literal"},
+		{"let s = `abc\ndef", "line 1, column 9: unterminated template literal"},
+		{`let s = "abc\`, "line 1, column 9: unterminated string literal"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		}
+
+		if !l.HasErrors() {
+			t.Errorf("input %q: expected lexer errors, got none", tt.input)
+			continue
+		}
+		if errors := l.ErrorStrings(); !strings.Contains(errors[0], tt.expected) {
+			t.Errorf("input %q: expected error containing %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+
+	// Closed strings are not errors
+	l := New(`"abc" 'def' ` + "`ghi`")
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+	}
+	if l.HasErrors() {
+		t.Errorf("unexpected lexer errors: %v", l.GetErrors())
+	}
+}
+
+func TestNumericSeparators(t *testing.T) {
+	input := `1_000 0xFF_FF 0b1010_1010 0o7_7 1_000.000_1 1e1_0`
+
+	tests := []struct {
+		expectedType    Token
+		expectedLiteral string
+	}{
+		{INT, "1_000"},
+		{INT, "0xFF_FF"},
+		{INT, "0b1010_1010"},
+		{INT, "0o7_7"},
+		{FLOAT, "1_000.000_1"},
+		{FLOAT, "1e1_0"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+	if l.HasErrors() {
+		t.Errorf("unexpected lexer errors: %v", l.GetErrors())
+	}
+
+	// A leading underscore starts an identifier, not a number
+	if tok := New("_1").NextToken(); tok.Type != IDENT {
+		t.Errorf("expected _1 to be an identifier, got %s", tok.Type)
+	}
+}
+
+func TestInvalidNumericSeparators(t *testing.T) {
+	tests := []struct {
+		input  string
+		column int
+	}{
+		{"1__0", 2},
+		{"1_", 2},
+		{"0x_FF", 3},
+		{"1_.5", 2},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		}
+
+		errors := l.ErrorStrings()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error, got %v", tt.input, errors)
+			continue
+		}
+		expected := fmt.Sprintf("column %d: numeric separator", tt.column)
+		if !strings.Contains(errors[0], expected) {
+			t.Errorf("input %q: expected error containing %q, got %q", tt.input, expected, errors[0])
+		}
+	}
+}
+
+func TestMalformedRadixLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		literal  string
+		expected string
+	}{
+		{"0x", "0x", "column 3: malformed numeric literal: expected hexadecimal digit"},
+		{"0x;", "0x", "column 3: malformed numeric literal: expected hexadecimal digit"},
+		{"0b2", "0b2", "column 3: malformed numeric literal: expected binary digit"},
+		{"0b102", "0b102", "column 5: malformed numeric literal: expected binary digit"},
+		{"0o8", "0o8", "column 3: malformed numeric literal: expected octal digit"},
+		{"0xFG", "0xFG", "column 4: malformed numeric literal: expected hexadecimal digit"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != INT || tok.Literal != tt.literal {
+			t.Errorf("input %q: expected INT %q, got %s %q", tt.input, tt.literal, tok.Type, tok.Literal)
+		}
+
+		errors := l.ErrorStrings()
+		if len(errors) != 1 {
+			t.Errorf("input %q: expected 1 error, got %v", tt.input, errors)
+			continue
+		}
+		if !strings.Contains(errors[0], tt.expected) {
+			t.Errorf("input %q: expected error containing %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package lexer
 
-import "strconv"
+import (
+	"fmt"
+	"strconv"
+)
 
 // Token represents a token type in TG-Script
 type Token int
@@ -12,6 +15,17 @@ type Position struct {
 	Offset int // byte offset (0-based)
 }
 
+// Span is a range of source text, from Start up to but not including End
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// String returns the span as "line:column-line:column"
+func (s Span) String() string {
+	return fmt.Sprintf("%d:%d-%d:%d", s.Start.Line, s.Start.Column, s.End.Line, s.End.Column)
+}
+
 // TokenInfo contains token information including position
 type TokenInfo struct {
 	Type     Token
@@ -88,6 +102,11 @@ const (
 	RSHIFT_ASSIGN  // >>=
 	URSHIFT_ASSIGN // >>>=
 
+	// Logical assignment operators
+	LOGICAL_AND_ASSIGN // &&=
+	LOGICAL_OR_ASSIGN  // ||=
+	NULLISH_ASSIGN     // ??=
+
 	// Increment/Decrement
 	INCREMENT // ++
 	DECREMENT // --
@@ -171,6 +190,7 @@ const (
 	PRIVATE   // private
 	PROTECTED // protected
 	READONLY  // readonly
+	OVERRIDE  // override
 
 	// Type keywords
 	ANY       // any
@@ -263,6 +283,11 @@ var tokenNames = [...]string{
 	LSHIFT_ASSIGN:  "<<=",
 	RSHIFT_ASSIGN:  ">>=",
 	URSHIFT_ASSIGN: ">>>=",
+
+	LOGICAL_AND_ASSIGN: "&&=",
+	LOGICAL_OR_ASSIGN:  "||=",
+	NULLISH_ASSIGN:     "??=",
+
 	INCREMENT:      "++",
 	DECREMENT:      "--",
 	TYPEOF:         "typeof",
@@ -328,6 +353,7 @@ var tokenNames = [...]string{
 	PRIVATE:     "private",
 	PROTECTED:   "protected",
 	READONLY:    "readonly",
+	OVERRIDE:    "override",
 	ANY:         "any",
 	UNKNOWN:     "unknown",
 	NEVER:       "never",
@@ -431,7 +457,8 @@ func (tok Token) IsAssignment() bool {
 	switch tok {
 	case ASSIGN, ADD_ASSIGN, SUB_ASSIGN, MUL_ASSIGN, DIV_ASSIGN, MOD_ASSIGN,
 		POW_ASSIGN, BIT_AND_ASSIGN, BIT_OR_ASSIGN, BIT_XOR_ASSIGN,
-		LSHIFT_ASSIGN, RSHIFT_ASSIGN, URSHIFT_ASSIGN:
+		LSHIFT_ASSIGN, RSHIFT_ASSIGN, URSHIFT_ASSIGN,
+		LOGICAL_AND_ASSIGN, LOGICAL_OR_ASSIGN, NULLISH_ASSIGN:
 		return true
 	default:
 		return false
@@ -0,0 +1,233 @@
+// Package loader loads programs split into modules, files that import
+// declarations exported by other files, and checks, compiles and runs the
+// modules in dependency order.
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/ast"
+	"github.com/xingleixu/TG-Script/compiler"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/types"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+// Module is one source file of a program
+type Module struct {
+	Path        string // path of the file
+	Name        string // path relative to the entry module's directory, used in messages
+	Source      string
+	Program     *ast.Program
+	ParseErrors []*parser.ParserError
+	TypeErrors  []*types.TypeError // set by Check
+	Warnings    []*types.TypeError // set by Check
+	Function    *vm.Function       // set by Compile
+
+	imports   map[string]*Module       // imported modules, by the string naming them
+	exports   map[string]*types.Symbol // exported symbols, set by Check
+	namespace string                   // prefix of the module's globals, set by Compile
+}
+
+// Graph holds the modules of a program in dependency order: each module
+// comes after the modules it imports, and the entry module comes last.
+type Graph struct {
+	Modules    []*Module
+	NoOptimize bool // compile without the peephole pass
+}
+
+// Entry returns the module the program was loaded from
+func (g *Graph) Entry() *Module {
+	return g.Modules[len(g.Modules)-1]
+}
+
+// Load reads and parses the module at path and every module it imports,
+// directly or indirectly. A file imported by several modules is loaded
+// once. Import paths are relative to the importing file and must start
+// with "./" or "../"; the ".tg" extension may be left out.
+func Load(path string) (*Graph, error) {
+	l := &loader{
+		base:    filepath.Dir(path),
+		modules: make(map[string]*Module),
+		graph:   &Graph{},
+	}
+	if _, err := l.load(filepath.Clean(path)); err != nil {
+		return nil, err
+	}
+	return l.graph, nil
+}
+
+// loader tracks the modules loaded so far
+type loader struct {
+	base    string             // directory of the entry module
+	modules map[string]*Module // loaded modules, by path
+	loading []*Module          // modules whose imports are being loaded, outermost first
+	graph   *Graph
+}
+
+// load loads the module at path and the modules it imports
+func (l *loader) load(path string) (*Module, error) {
+	for i, m := range l.loading {
+		if m.Path == path {
+			var chain []string
+			for _, link := range l.loading[i:] {
+				chain = append(chain, link.Name)
+			}
+			return nil, fmt.Errorf("import cycle: %s -> %s", strings.Join(chain, " -> "), m.Name)
+		}
+	}
+	if m, ok := l.modules[path]; ok {
+		return m, nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Module{
+		Path:    path,
+		Name:    l.name(path),
+		Source:  string(source),
+		imports: make(map[string]*Module),
+	}
+	p := parser.New(lexer.New(m.Source))
+	m.Program = p.ParseProgram()
+	m.ParseErrors = p.ParserErrors()
+
+	l.loading = append(l.loading, m)
+	for _, stmt := range m.Program.Body {
+		decl, ok := stmt.(*ast.ImportDeclaration)
+		if !ok {
+			continue
+		}
+		if _, ok := m.imports[decl.Source.Value]; ok {
+			continue
+		}
+
+		depPath, err := resolve(path, decl.Source.Value)
+		if err == nil {
+			if _, statErr := os.Stat(depPath); statErr != nil {
+				err = fmt.Errorf("file %s not found", depPath)
+			}
+		}
+		if err != nil {
+			pos := decl.Source.Pos()
+			return nil, fmt.Errorf("%s:%d:%d: cannot import '%s': %v", m.Name, pos.Line, pos.Column, decl.Source.Value, err)
+		}
+
+		dep, err := l.load(depPath)
+		if err != nil {
+			return nil, err
+		}
+		m.imports[decl.Source.Value] = dep
+	}
+	l.loading = l.loading[:len(l.loading)-1]
+
+	l.modules[path] = m
+	l.graph.Modules = append(l.graph.Modules, m)
+	return m, nil
+}
+
+// name returns the name of the module at path used in messages
+func (l *loader) name(path string) string {
+	if rel, err := filepath.Rel(l.base, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// resolve returns the path of the module that the file at from imports
+// as source
+func resolve(from, source string) (string, error) {
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return "", fmt.Errorf("module paths must start with './' or '../'")
+	}
+	path := filepath.Join(filepath.Dir(from), filepath.FromSlash(source))
+	if filepath.Ext(path) == "" {
+		path += ".tg"
+	}
+	return path, nil
+}
+
+// HasParseErrors reports whether any module failed to parse
+func (g *Graph) HasParseErrors() bool {
+	for _, m := range g.Modules {
+		if len(m.ParseErrors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Check type checks the modules. Each module sees the exports of the
+// modules it imports. Errors and warnings are stored on the modules;
+// Check reports whether there were no errors.
+func (g *Graph) Check() bool {
+	ok := true
+	for _, m := range g.Modules {
+		checker := types.NewTypeChecker()
+		for source, dep := range m.imports {
+			checker.AddModule(source, dep.exports)
+		}
+		m.TypeErrors = checker.Check(m.Program)
+		m.Warnings = checker.Warnings()
+		m.exports = checker.Exports(m.Program)
+		if len(m.TypeErrors) > 0 {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// Compile compiles the modules, refusing to create globals for undeclared
+// variables. The top-level variables of the entry module stay local to
+// it; those of other modules are globals named after their module, so
+// the modules can share a VM.
+func (g *Graph) Compile() error {
+	for _, m := range g.Modules {
+		if m != g.Entry() {
+			m.namespace = m.Name + ":"
+		}
+
+		imports := make(map[string]string)
+		for _, stmt := range m.Program.Body {
+			decl, ok := stmt.(*ast.ImportDeclaration)
+			if !ok {
+				continue
+			}
+			dep := m.imports[decl.Source.Value]
+			for _, spec := range decl.Specifiers {
+				imports[spec.Local.Name] = dep.namespace + spec.Imported.Name
+			}
+		}
+
+		c := compiler.NewCompiler()
+		c.SetStrictMode(true)
+		c.SetOptimize(!g.NoOptimize)
+		c.SetModule(m.namespace, imports)
+		function, err := c.Compile(m.Program)
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Name, err)
+		}
+		m.Function = function
+	}
+	return nil
+}
+
+// Run runs the compiled modules on machine, each module after the modules
+// it imports, and returns the result of the entry module
+func (g *Graph) Run(machine *vm.VM) (vm.Value, error) {
+	var result vm.Value
+	for _, m := range g.Modules {
+		var err error
+		result, err = machine.Execute(vm.NewClosure(m.Function), []vm.Value{})
+		if err != nil {
+			return vm.NilValue, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package loader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+// writeFiles writes files, by path relative to a new temporary directory,
+// and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunModules(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.tg": `import { area, PI as pi, count } from "./lib/math.tg"
+import { twice } from "./util"
+let total: int = 0
+print(area(1.0), pi, twice(1.0), count(), total)`,
+		"util.tg": `import { area } from "./lib/math.tg"
+let total: float = 0.0
+export function twice(r: float): float {
+    total = total + area(r)
+    return area(r) * 2.0
+}`,
+		"lib/math.tg": `export const PI: float = 3.0
+let total: int = 0
+export function area(r: float): float {
+    total = total + 1
+    return PI * r * r
+}
+export function count(): int { return total }
+print("loaded math")`,
+	})
+
+	graph, err := Load(filepath.Join(dir, "main.tg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range graph.Modules {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "lib/math.tg,util.tg,main.tg" {
+		t.Fatalf("modules = %s", got)
+	}
+
+	if !graph.Check() {
+		for _, m := range graph.Modules {
+			t.Errorf("%s: %v", m.Name, m.TypeErrors)
+		}
+		t.FailNow()
+	}
+	if err := graph.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	machine := vm.NewVM()
+	machine.SetOutput(&out, &out)
+	if _, err := graph.Run(machine); err != nil {
+		t.Fatal(err)
+	}
+	// Each module runs once, and top-level names don't clash across modules
+	if got, want := out.String(), "loaded math\n3 3 6 3 0\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.tg":    `import { a } from "./a.tg"`,
+		"a.tg":       `import { b } from "./b.tg"` + "\nexport function a(): int { return 1 }",
+		"b.tg":       `import { a } from "./a.tg"` + "\nexport function b(): int { return 1 }",
+		"missing.tg": `import { x } from "./nowhere.tg"`,
+		"bare.tg":    `import { x } from "lib.tg"`,
+	})
+
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{"main.tg", "import cycle: a.tg -> b.tg -> a.tg"},
+		{"missing.tg", "missing.tg:1:19: cannot import './nowhere.tg'"},
+		{"bare.tg", "module paths must start with './' or '../'"},
+	}
+	for _, tt := range tests {
+		_, err := Load(filepath.Join(dir, tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.file, tt.expected, err)
+		}
+	}
+}
+
+func TestCheckAcrossModules(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.tg": `import { add, secret } from "./lib.tg"
+const s: string = add(1, 2)`,
+		"lib.tg": `export function add(a: int, b: int): int { return a + b }
+function secret(): int { return 42 }`,
+	})
+
+	graph, err := Load(filepath.Join(dir, "main.tg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Check() {
+		t.Fatal("expected type errors")
+	}
+
+	var messages []string
+	for _, err := range graph.Entry().TypeErrors {
+		messages = append(messages, err.Message)
+	}
+	got := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"Module './lib.tg' has no exported member 'secret'",
+		"Cannot assign value of type 'int' to variable of type 'string'",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected error %q, got:\n%s", want, got)
+		}
+	}
+}
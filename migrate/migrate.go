@@ -0,0 +1,409 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/ast"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/types"
+)
+
+// Issue is a construct that could not be migrated automatically
+type Issue struct {
+	Line    int    `json:"line"`   // line in the TypeScript source
+	Column  int    `json:"column"` // column in the TypeScript source
+	Message string `json:"message"`
+}
+
+// String returns the issue as "line N, column M: message"
+func (i Issue) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", i.Line, i.Column, i.Message)
+}
+
+// Change counts the applications of one kind of rewrite
+type Change struct {
+	Description string `json:"description"`
+	Count       int    `json:"count"`
+}
+
+// Result is the outcome of migrating a TypeScript source file
+type Result struct {
+	Output  string   // TG-Script source
+	Changes []Change // rewrites applied, in a fixed order
+	Issues  []Issue  // constructs needing manual attention, in source order
+}
+
+// Descriptions of the rewrites, in the order they are reported
+const (
+	changeNumberToFloat = "'number' annotations changed to 'float'"
+	changeNumberToInt   = "'number' annotations with integer initializers changed to 'int'"
+	changeVarToLet      = "'var' declarations changed to 'let'"
+	changeDefaultExport = "default exports changed to named exports"
+	changeModuleSyntax  = "unsupported import and export statements replaced with comments"
+	changeDecorator     = "decorators removed"
+)
+
+var changeOrder = []string{
+	changeNumberToFloat, changeNumberToInt, changeVarToLet,
+	changeDefaultExport, changeModuleSyntax, changeDecorator,
+}
+
+// declarationKeywords are the declarations TG-Script can export
+var declarationKeywords = []string{
+	"function", "class", "interface", "type", "enum", "const", "let", "var",
+}
+
+// namedImport matches an import of named bindings, capturing the module path
+var namedImport = regexp.MustCompile(`^import\s*\{[^}]*\}\s*from\s*["']([^"']*)["']\s*;?$`)
+
+// edit replaces source[start:end] with text
+type edit struct {
+	start, end int
+	text       string
+}
+
+// migration collects the edits and issues for one source file
+type migration struct {
+	source string
+	edits  []edit // sorted by start once collection is done
+	issues []Issue
+	counts map[string]int
+}
+
+// Migrate converts TypeScript source to TG-Script. Named imports of
+// relative paths and exported declarations are kept as TG-Script modules;
+// a default export of a named function or class becomes a named export,
+// and other import and export forms are replaced with comments. 'number'
+// annotations become 'float' (or 'int' when initialized with an integer
+// literal), 'var' becomes 'let' and decorators are removed. Namespaces and
+// generic classes, interfaces and type aliases are reported as issues, and
+// the result is parsed and type checked so that anything still unsupported
+// is reported with its position in the original source. Imported modules
+// aren't read, so imported names are checked as 'any'.
+func Migrate(source string) Result {
+	m := &migration{source: source, counts: make(map[string]int)}
+	m.rewriteModuleSyntax()
+	m.rewriteTokens()
+
+	sort.SliceStable(m.edits, func(i, j int) bool { return m.edits[i].start < m.edits[j].start })
+	output := m.apply()
+
+	// Report whatever the TG-Script front end still rejects
+	p := parser.New(lexer.New(output))
+	program := p.ParseProgram()
+	if errors := p.ParserErrors(); len(errors) > 0 {
+		for _, err := range errors {
+			m.addIssue(m.sourceOffset(err.Position.Offset), "unsupported syntax: "+err.Message)
+		}
+	} else {
+		checker := types.NewTypeChecker()
+		addImportedModules(checker, program)
+		for _, err := range checker.Check(program) {
+			m.addIssue(m.sourceOffset(err.Position.Offset),
+				fmt.Sprintf("type error [%s]: %s", err.Code, err.Message))
+		}
+	}
+
+	sort.SliceStable(m.issues, func(i, j int) bool {
+		a, b := m.issues[i], m.issues[j]
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+
+	var changes []Change
+	for _, description := range changeOrder {
+		if count := m.counts[description]; count > 0 {
+			changes = append(changes, Change{Description: description, Count: count})
+		}
+	}
+	return Result{Output: output, Changes: changes, Issues: m.issues}
+}
+
+// addImportedModules declares the modules program imports to checker, with
+// each imported name exported as 'any'
+func addImportedModules(checker *types.TypeChecker, program *ast.Program) {
+	exports := make(map[string]map[string]*types.Symbol)
+	for _, stmt := range program.Body {
+		decl, ok := stmt.(*ast.ImportDeclaration)
+		if !ok {
+			continue
+		}
+		if exports[decl.Source.Value] == nil {
+			exports[decl.Source.Value] = make(map[string]*types.Symbol)
+		}
+		for _, spec := range decl.Specifiers {
+			name := spec.Imported.Name
+			exports[decl.Source.Value][name] = &types.Symbol{Name: name, Type: types.AnyType, Kind: types.VariableSymbol}
+		}
+	}
+	for source, symbols := range exports {
+		checker.AddModule(source, symbols)
+	}
+}
+
+// rewriteModuleSyntax keeps the import and export statements TG-Script
+// supports, turns default exports of named functions and classes into named
+// exports and replaces other import and export statements with comments
+func (m *migration) rewriteModuleSyntax() {
+	lines := strings.Split(m.source, "\n")
+	offset := 0
+	lineStarts := make([]int, len(lines))
+	for i, line := range lines {
+		lineStarts[i] = offset
+		offset += len(line) + 1
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		start := lineStarts[i] + len(line) - len(strings.TrimLeft(line, " \t"))
+
+		last := skipBraces(lines, i)
+		var message string
+		switch {
+		case hasKeyword(trimmed, "import"):
+			statement := strings.TrimSpace(m.source[start : lineStarts[last]+len(lines[last])])
+			match := namedImport.FindStringSubmatch(statement)
+			switch {
+			case match == nil:
+				message = "import removed; only named imports such as 'import { a } from \"./a\"' are supported"
+			case !strings.HasPrefix(match[1], "./") && !strings.HasPrefix(match[1], "../"):
+				message = fmt.Sprintf("import from '%s' removed; only relative module paths starting with './' or '../' can be imported", match[1])
+			default:
+				i = last
+				continue
+			}
+
+		case hasKeyword(trimmed, "export"):
+			rest := strings.TrimSpace(strings.TrimPrefix(trimmed, "export"))
+			if startsDeclaration(rest) {
+				continue
+			}
+			if hasKeyword(rest, "default") {
+				decl := strings.TrimSpace(strings.TrimPrefix(rest, "default"))
+				if isNamedDeclaration(decl) {
+					// Drop 'default' and the space after it
+					defaultStart := start + strings.Index(line[start-lineStarts[i]:], rest)
+					end := defaultStart + strings.Index(rest, decl)
+					m.edits = append(m.edits, edit{start: defaultStart, end: end})
+					m.addIssue(start, "default export changed to a named export; import it by name")
+					m.counts[changeDefaultExport]++
+					continue
+				}
+			}
+			message = "export removed; only exported declarations such as 'export function f() {}' are supported"
+
+		default:
+			continue
+		}
+
+		m.addIssue(start, message)
+		end := lineStarts[last] + len(strings.TrimRight(lines[last], " \t\r"))
+		m.edits = append(m.edits, edit{start: start, end: end, text: "// migrate: " + message})
+		m.counts[changeModuleSyntax]++
+		i = last
+	}
+}
+
+// rewriteTokens applies the token level rewrites and reports unsupported
+// declarations. Tokens inside replaced statements are ignored.
+func (m *migration) rewriteTokens() {
+	var tokens []lexer.TokenInfo
+	l := lexer.New(m.source)
+	for tok := l.NextToken(); tok.Type != lexer.EOF; tok = l.NextToken() {
+		if tok.Type != lexer.COMMENT && !m.replaced(tok.Position.Offset) {
+			tokens = append(tokens, tok)
+		}
+	}
+
+	at := func(i int) lexer.Token {
+		if i < len(tokens) {
+			return tokens[i].Type
+		}
+		return lexer.EOF
+	}
+
+	for i, tok := range tokens {
+		offset := tok.Position.Offset
+		switch {
+		case tok.Type == lexer.NUMBER_T:
+			replacement, change := "float", changeNumberToFloat
+			if hasIntegerInitializer(tokens, i) {
+				replacement, change = "int", changeNumberToInt
+			}
+			m.edits = append(m.edits, edit{start: offset, end: offset + len(tok.Literal), text: replacement})
+			m.counts[change]++
+
+		case tok.Type == lexer.VAR:
+			m.edits = append(m.edits, edit{start: offset, end: offset + len(tok.Literal), text: "let"})
+			m.counts[changeVarToLet]++
+
+		case tok.Type == lexer.ILLEGAL && tok.Literal == "@" && at(i+1) == lexer.IDENT:
+			last := decoratorEnd(tokens, i)
+			end := tokens[last].Position.Offset + len(tokens[last].Literal)
+			m.addIssue(offset, fmt.Sprintf("decorator '%s' removed; decorators are not supported",
+				m.source[offset:tokens[i+1].Position.Offset+len(tokens[i+1].Literal)]))
+			m.edits = append(m.edits, edit{start: offset, end: end})
+			m.counts[changeDecorator]++
+
+		case (tok.Type == lexer.NAMESPACE || tok.Type == lexer.MODULE) &&
+			(at(i+1) == lexer.IDENT || at(i+1) == lexer.STRING):
+			m.addIssue(offset, fmt.Sprintf("%s '%s' is not supported; move its members to the top level",
+				tok.Literal, tokens[i+1].Literal))
+
+		case (tok.Type == lexer.CLASS || tok.Type == lexer.INTERFACE || tok.Type == lexer.TYPE) &&
+			at(i+1) == lexer.IDENT && at(i+2) == lexer.LT:
+			m.addIssue(tokens[i+2].Position.Offset, fmt.Sprintf(
+				"generic %s '%s' is not supported; replace its type parameters with concrete types",
+				tok.Literal, tokens[i+1].Literal))
+		}
+	}
+}
+
+// hasIntegerInitializer reports whether the 'number' annotation at token i
+// is followed by an initializer that is just an integer literal
+func hasIntegerInitializer(tokens []lexer.TokenInfo, i int) bool {
+	k := i + 1
+	if k >= len(tokens) || tokens[k].Type != lexer.ASSIGN {
+		return false
+	}
+	k++
+	if k < len(tokens) && tokens[k].Type == lexer.SUB {
+		k++
+	}
+	if k >= len(tokens) || tokens[k].Type != lexer.INT {
+		return false
+	}
+	if k+1 == len(tokens) || tokens[k+1].Position.Line > tokens[k].Position.Line {
+		return true
+	}
+	switch tokens[k+1].Type {
+	case lexer.SEMICOLON, lexer.COMMA, lexer.RPAREN, lexer.RBRACE:
+		return true
+	}
+	return false
+}
+
+// decoratorEnd returns the index of the last token of the decorator
+// starting at token i: '@', a dotted name and optional call arguments
+func decoratorEnd(tokens []lexer.TokenInfo, i int) int {
+	last := i + 1
+	for last+2 < len(tokens) && tokens[last+1].Type == lexer.DOT && tokens[last+2].Type == lexer.IDENT {
+		last += 2
+	}
+	if last+1 < len(tokens) && tokens[last+1].Type == lexer.LPAREN {
+		depth := 0
+		for k := last + 1; k < len(tokens); k++ {
+			switch tokens[k].Type {
+			case lexer.LPAREN:
+				depth++
+			case lexer.RPAREN:
+				depth--
+			}
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return last
+}
+
+// replaced reports whether offset lies within a statement that has been
+// replaced with a comment
+func (m *migration) replaced(offset int) bool {
+	for _, e := range m.edits {
+		if e.text != "" && offset >= e.start && offset < e.end {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the source with all edits applied
+func (m *migration) apply() string {
+	var sb strings.Builder
+	last := 0
+	for _, e := range m.edits {
+		sb.WriteString(m.source[last:e.start])
+		sb.WriteString(e.text)
+		last = e.end
+	}
+	sb.WriteString(m.source[last:])
+	return sb.String()
+}
+
+// sourceOffset maps an offset in the output back to the source. Offsets
+// within inserted text map to the start of the text it replaced.
+func (m *migration) sourceOffset(offset int) int {
+	shift := 0 // output offset minus source offset
+	for _, e := range m.edits {
+		if e.start+shift > offset {
+			break
+		}
+		if offset < e.start+shift+len(e.text) {
+			return e.start
+		}
+		shift += len(e.text) - (e.end - e.start)
+	}
+	return offset - shift
+}
+
+// addIssue records an issue at an offset in the source
+func (m *migration) addIssue(offset int, message string) {
+	if offset > len(m.source) {
+		offset = len(m.source)
+	}
+	before := m.source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+	m.issues = append(m.issues, Issue{Line: line, Column: column, Message: message})
+}
+
+// hasKeyword reports whether text starts with keyword as a whole word
+func hasKeyword(text, keyword string) bool {
+	if !strings.HasPrefix(text, keyword) {
+		return false
+	}
+	rest := text[len(keyword):]
+	return rest == "" || !isIdentChar(rest[0])
+}
+
+// startsDeclaration reports whether text begins with a declaration keyword
+func startsDeclaration(text string) bool {
+	for _, keyword := range declarationKeywords {
+		if hasKeyword(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamedDeclaration reports whether text begins a function or class
+// declaration with a name
+func isNamedDeclaration(text string) bool {
+	for _, keyword := range []string{"function", "class"} {
+		if hasKeyword(text, keyword) {
+			rest := strings.TrimSpace(text[len(keyword):])
+			return rest != "" && isIdentChar(rest[0]) && !hasKeyword(rest, "extends")
+		}
+	}
+	return false
+}
+
+// skipBraces returns the index of the last line of a statement starting at
+// line i, following an open brace list such as 'import {' onto later lines
+func skipBraces(lines []string, i int) int {
+	depth := strings.Count(lines[i], "{") - strings.Count(lines[i], "}")
+	for depth > 0 && i+1 < len(lines) {
+		i++
+		depth += strings.Count(lines[i], "{") - strings.Count(lines[i], "}")
+	}
+	return i
+}
+
+func isIdentChar(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
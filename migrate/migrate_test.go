@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		issues   []Issue
+	}{
+		{
+			name:     "number annotations",
+			input:    "let x: number = 1.5\nfunction half(n: number): number {\n    return n / 2\n}",
+			expected: "let x: float = 1.5\nfunction half(n: float): float {\n    return n / 2\n}",
+		},
+		{
+			name:     "number in composite types",
+			input:    "const xs: number[] = [1.5]\nlet y: number | string = \"a\"",
+			expected: "const xs: float[] = [1.5]\nlet y: float | string = \"a\"",
+		},
+		{
+			name:     "exported declarations are kept",
+			input:    "export const limit: number = 10.5\n    export interface Point { x: number }",
+			expected: "export const limit: float = 10.5\n    export interface Point { x: float }",
+		},
+		{
+			name:     "default exports become named exports",
+			input:    "export default function run() {\n}\nexport default class App {\n}",
+			expected: "export function run() {\n}\nexport class App {\n}",
+			issues: []Issue{
+				{Line: 1, Column: 1, Message: "default export changed to a named export; import it by name"},
+				{Line: 3, Column: 1, Message: "default export changed to a named export; import it by name"},
+			},
+		},
+		{
+			name:     "relative named imports are kept",
+			input:    "import { a } from \"./a\"\nimport {\n    b,\n    c as d,\n} from \"../b\";\nlet z: number = a + b + d",
+			expected: "import { a } from \"./a\"\nimport {\n    b,\n    c as d,\n} from \"../b\";\nlet z: float = a + b + d",
+		},
+		{
+			name:  "unresolvable imports are removed",
+			input: "import { a } from \"lodash\"\nimport b from \"./b\"\nimport * as c from \"./c\"",
+			expected: "// migrate: import from 'lodash' removed; only relative module paths starting with './' or '../' can be imported\n" +
+				"// migrate: import removed; only named imports such as 'import { a } from \"./a\"' are supported\n" +
+				"// migrate: import removed; only named imports such as 'import { a } from \"./a\"' are supported",
+			issues: []Issue{
+				{Line: 1, Column: 1, Message: "import from 'lodash' removed; only relative module paths starting with './' or '../' can be imported"},
+				{Line: 2, Column: 1, Message: "import removed; only named imports such as 'import { a } from \"./a\"' are supported"},
+				{Line: 3, Column: 1, Message: "import removed; only named imports such as 'import { a } from \"./a\"' are supported"},
+			},
+		},
+		{
+			name:     "export lists are removed",
+			input:    "const v = 1\nexport { v }\nexport * from \"./all\"",
+			expected: "const v = 1\n// migrate: export removed; only exported declarations such as 'export function f() {}' are supported\n// migrate: export removed; only exported declarations such as 'export function f() {}' are supported",
+			issues: []Issue{
+				{Line: 2, Column: 1, Message: "export removed; only exported declarations such as 'export function f() {}' are supported"},
+				{Line: 3, Column: 1, Message: "export removed; only exported declarations such as 'export function f() {}' are supported"},
+			},
+		},
+		{
+			name:     "identifiers and strings are untouched",
+			input:    "let numberOfItems = 1\nlet label = \"number: 1\"",
+			expected: "let numberOfItems = 1\nlet label = \"number: 1\"",
+		},
+		{
+			name:     "remaining errors map to source lines",
+			input:    "import x from \"x\"\nlet s: string = 1",
+			expected: "// migrate: import removed; only named imports such as 'import { a } from \"./a\"' are supported\nlet s: string = 1",
+			issues: []Issue{
+				{Line: 1, Column: 1, Message: "import removed; only named imports such as 'import { a } from \"./a\"' are supported"},
+				{Line: 2, Column: 5, Message: "type error [E002]: Cannot assign value of type 'int' to variable of type 'string'"},
+			},
+		},
+		{
+			name:     "integer initializers become int",
+			input:    "let count: number = 0;\nconst low: number = -1\nlet mid: number = 1 + 0.5\nfunction at(i: number = 2) {\n}",
+			expected: "let count: int = 0;\nconst low: int = -1\nlet mid: float = 1 + 0.5\nfunction at(i: int = 2) {\n}",
+		},
+		{
+			name:     "var becomes let",
+			input:    "var total = 1\nfor (var i = 0; i < 2; i++) {\n}",
+			expected: "let total = 1\nfor (let i = 0; i < 2; i++) {\n}",
+		},
+		{
+			name:     "columns account for earlier rewrites",
+			input:    "export let a: number = 1.5; let b: string = 2",
+			expected: "export let a: float = 1.5; let b: string = 2",
+			issues: []Issue{
+				{Line: 1, Column: 33, Message: "type error [E002]: Cannot assign value of type 'int' to variable of type 'string'"},
+			},
+		},
+		{
+			name:     "decorators are removed",
+			input:    "@sealed\nclass A {\n}\n@Component({ name: \"b\" }) class B {\n}",
+			expected: "\nclass A {\n}\n class B {\n}",
+			issues: []Issue{
+				{Line: 1, Column: 1, Message: "decorator '@sealed' removed; decorators are not supported"},
+				{Line: 4, Column: 1, Message: "decorator '@Component' removed; decorators are not supported"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Migrate(tt.input)
+			if result.Output != tt.expected {
+				t.Errorf("wrong output.\nexpected:\n%s\ngot:\n%s", tt.expected, result.Output)
+			}
+			if len(result.Issues) != len(tt.issues) {
+				t.Fatalf("expected %d issues, got %d: %v", len(tt.issues), len(result.Issues), result.Issues)
+			}
+			for i, issue := range result.Issues {
+				if issue != tt.issues[i] {
+					t.Errorf("issue %d: expected %v, got %v", i, tt.issues[i], issue)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateFlagsUnsupportedDeclarations(t *testing.T) {
+	result := Migrate("namespace Shapes {\n}\ntype Box<T> = T[]\nfunction first<T>(xs: T[]): T {\n    return xs[0]\n}")
+
+	expected := map[Issue]bool{
+		{Line: 1, Column: 1, Message: "namespace 'Shapes' is not supported; move its members to the top level"}:               true,
+		{Line: 3, Column: 9, Message: "generic type 'Box' is not supported; replace its type parameters with concrete types"}: true,
+	}
+	for _, issue := range result.Issues {
+		// Generic functions are supported
+		if issue.Line == 4 {
+			t.Errorf("unexpected issue %v", issue)
+		}
+		delete(expected, issue)
+	}
+	for issue := range expected {
+		t.Errorf("missing issue %v in %v", issue, result.Issues)
+	}
+}
+
+func TestMigrateChanges(t *testing.T) {
+	result := Migrate("import a from \"a\"\nexport default function f() {\n}\nexport var x: number = 1\nlet y: number = 1.5\nlet z: number = 2.5")
+
+	expected := []Change{
+		{Description: changeNumberToFloat, Count: 2},
+		{Description: changeNumberToInt, Count: 1},
+		{Description: changeVarToLet, Count: 1},
+		{Description: changeDefaultExport, Count: 1},
+		{Description: changeModuleSyntax, Count: 1},
+	}
+	if len(result.Changes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, result.Changes)
+	}
+	for i, change := range result.Changes {
+		if change != expected[i] {
+			t.Errorf("change %d: expected %v, got %v", i, expected[i], change)
+		}
+	}
+}
@@ -47,6 +47,13 @@ func (p *Parser) parseExpression(precedence Precedence) ast.Expression {
 			return leftExp
 		}
 
+		// A ++ or -- on the next line is a prefix operator starting a new
+		// statement, not a postfix operator on this expression
+		if (p.peekTokenIs(lexer.INCREMENT) || p.peekTokenIs(lexer.DECREMENT)) &&
+			p.currentToken.Position.Line < p.peekToken.Position.Line {
+			return leftExp
+		}
+
 		p.nextToken()
 		leftExp = infix(leftExp)
 	}
@@ -138,34 +145,50 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	// Try to parse as arrow function parameters first
 	if p.mightBeArrowFunctionParams() {
 		// Save current position for potential backtracking
-		savedCurrentToken := p.currentToken
-		savedPeekToken := p.peekToken
-		savedErrors := len(p.errors)
+		saved := p.saveState()
 
 		// Try to parse as arrow function parameters
 		params := p.parseArrowFunctionParameterList()
 		if params != nil && p.expectPeek(lexer.RPAREN) {
 			rparenPos := p.currentToken.Position
+
+			// Optional return type annotation: (x: int): int => ...
+			var returnType ast.TypeNode
+			if p.peekTokenIs(lexer.COLON) {
+				p.nextToken()
+				p.nextToken()
+				returnType = p.parseTypeAnnotation()
+			}
+
 			// Check if next token is '=>' to confirm this is arrow function params
 			if p.peekTokenIs(lexer.ARROW) {
 				return &ast.ArrowFunctionParams{
 					LParen:     lparenPos,
 					Parameters: params,
 					RParen:     rparenPos,
+					ReturnType: returnType,
 				}
 			}
 		}
 
 		// If not arrow function params, restore state and parse as regular expression
-		p.currentToken = savedCurrentToken
-		p.peekToken = savedPeekToken
-		// Remove any errors added during failed arrow function parsing
-		p.errors = p.errors[:savedErrors]
+		p.restoreState(saved)
 	}
 
 	// Parse as regular grouped expression
 	exp := p.parseExpression(LOWEST)
 
+	// Comma-separated expressions form a sequence: (a, b)
+	if p.peekTokenIs(lexer.COMMA) {
+		sequence := &ast.SequenceExpression{Expressions: []ast.Expression{exp}}
+		for p.peekTokenIs(lexer.COMMA) {
+			p.nextToken() // consume ','
+			p.nextToken() // move to next expression
+			sequence.Expressions = append(sequence.Expressions, p.parseExpression(LOWEST))
+		}
+		exp = sequence
+	}
+
 	if !p.expectPeek(lexer.RPAREN) {
 		return nil
 	}
@@ -227,6 +250,15 @@ func (p *Parser) parseObjectLiteral() ast.Expression {
 func (p *Parser) parseObjectProperty() *ast.Property {
 	prop := &ast.Property{}
 
+	// Spread property: { ...base }
+	if p.currentTokenIs(lexer.SPREAD) {
+		prop.Value = p.parseSpreadElement()
+		if prop.Value == nil {
+			return nil
+		}
+		return prop
+	}
+
 	// Parse key
 	switch p.currentToken.Type {
 	case lexer.IDENT:
@@ -248,9 +280,28 @@ func (p *Parser) parseObjectProperty() *ast.Property {
 		return nil
 	}
 
+	// Method shorthand: key(params) { body }
+	if p.peekTokenIs(lexer.LPAREN) {
+		prop.Method = true
+		prop.Value = p.parseMethodValue(prop.Key.Pos())
+		if prop.Value == nil {
+			return nil
+		}
+		return prop
+	}
+
+	// Shorthand property: { x } is { x: x }
+	if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed &&
+		(p.peekTokenIs(lexer.COMMA) || p.peekTokenIs(lexer.RBRACE)) {
+		prop.Shorthand = true
+		prop.Value = ident
+		return prop
+	}
+
 	if !p.expectPeek(lexer.COLON) {
 		return nil
 	}
+	prop.Colon = p.currentToken.Position
 
 	p.nextToken()
 	prop.Value = p.parseExpression(LOWEST)
@@ -258,6 +309,35 @@ func (p *Parser) parseObjectProperty() *ast.Property {
 	return prop
 }
 
+// parseMethodValue parses the parameters, return type and body of a method
+// shorthand property as a function expression starting at pos.
+func (p *Parser) parseMethodValue(pos lexer.Position) *ast.FunctionExpression {
+	fn := &ast.FunctionExpression{FunctionPos: pos}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	fn.LParen = p.currentToken.Position
+	fn.Parameters = p.parseParameterList()
+	if p.currentTokenIs(lexer.RPAREN) {
+		fn.RParen = p.currentToken.Position
+	}
+
+	// Optional return type annotation
+	if p.peekTokenIs(lexer.COLON) {
+		p.nextToken()
+		p.nextToken()
+		fn.ReturnType = p.parseTypeAnnotation()
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	fn.Body = p.parseBlockStatement()
+
+	return fn
+}
+
 // parseFunctionExpression parses a function expression.
 func (p *Parser) parseFunctionExpression() ast.Expression {
 	fn := &ast.FunctionExpression{
@@ -324,6 +404,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.currentPrecedence()
+	if p.currentTokenIs(lexer.POW) {
+		// ** is right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -410,7 +494,8 @@ func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression
 
 	exp.Colon = p.currentToken.Position
 	p.nextToken()
-	exp.Alternate = p.parseExpression(TERNARY)
+	// Parse below TERNARY so that nested conditionals associate to the right
+	exp.Alternate = p.parseExpression(ASSIGN)
 
 	return exp
 }
@@ -429,12 +514,16 @@ func (p *Parser) parseExpressionList(end lexer.Token) []ast.Expression {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseListElement())
 
 	for p.peekTokenIs(lexer.COMMA) {
 		p.nextToken()
+		// Allow a trailing comma before the closing delimiter
+		if p.peekTokenIs(end) {
+			break
+		}
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseListElement())
 	}
 
 	if !p.expectPeek(end) {
@@ -444,6 +533,28 @@ func (p *Parser) parseExpressionList(end lexer.Token) []ast.Expression {
 	return args
 }
 
+// parseListElement parses an element of an expression list, which may be
+// a spread element.
+func (p *Parser) parseListElement() ast.Expression {
+	if p.currentTokenIs(lexer.SPREAD) {
+		return p.parseSpreadElement()
+	}
+	return p.parseExpression(LOWEST)
+}
+
+// parseSpreadElement parses ...expr.
+func (p *Parser) parseSpreadElement() ast.Expression {
+	spread := &ast.SpreadElement{
+		Ellipsis: p.currentToken.Position,
+	}
+	p.nextToken()
+	spread.Argument = p.parseExpression(ASSIGN)
+	if spread.Argument == nil {
+		return nil
+	}
+	return spread
+}
+
 // parseParameterList parses a function parameter list.
 func (p *Parser) parseParameterList() []*ast.Parameter {
 	var params []*ast.Parameter
@@ -462,6 +573,10 @@ func (p *Parser) parseParameterList() []*ast.Parameter {
 
 	for p.peekTokenIs(lexer.COMMA) {
 		p.nextToken()
+		// Allow a trailing comma before ')'
+		if p.peekTokenIs(lexer.RPAREN) {
+			break
+		}
 		p.nextToken()
 		param := p.parseParameter()
 		if param != nil {
@@ -631,12 +746,21 @@ func (p *Parser) parseInstanceofExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.currentPrecedence()
+	if p.currentTokenIs(lexer.POW) {
+		// ** is right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+// parseAsExpression parses type assertions (value as Type)
+func (p *Parser) parseAsExpression(left ast.Expression) ast.Expression {
+	return p.parseTypeAssertion(left)
+}
+
 // parseInExpression parses in expressions
 func (p *Parser) parseInExpression(left ast.Expression) ast.Expression {
 	expression := &ast.BinaryExpression{
@@ -646,6 +770,10 @@ func (p *Parser) parseInExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.currentPrecedence()
+	if p.currentTokenIs(lexer.POW) {
+		// ** is right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -661,24 +789,41 @@ func (p *Parser) parseNullishCoalescingExpression(left ast.Expression) ast.Expre
 	}
 
 	precedence := p.currentPrecedence()
+	if p.currentTokenIs(lexer.POW) {
+		// ** is right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2)
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
-// parseOptionalChainingExpression parses ?. expressions
+// parseOptionalChainingExpression parses ?. expressions: obj?.prop,
+// obj?.[prop] and f?.(args)
 func (p *Parser) parseOptionalChainingExpression(left ast.Expression) ast.Expression {
-	expression := &ast.MemberExpression{
-		Object:   left,
-		Computed: false,
-		Dot:      p.currentToken.Position,
+	switch {
+	case p.peekTokenIs(lexer.LPAREN):
+		p.nextToken()
+		call := p.parseCallExpression(left).(*ast.CallExpression)
+		call.Optional = true
+		return call
+	case p.peekTokenIs(lexer.LBRACKET):
+		p.nextToken()
+		member, ok := p.parseIndexExpression(left).(*ast.MemberExpression)
+		if !ok {
+			return nil
+		}
+		member.Optional = true
+		return member
+	default:
+		member, ok := p.parseMemberExpression(left).(*ast.MemberExpression)
+		if !ok {
+			return nil
+		}
+		member.Optional = true
+		return member
 	}
-
-	p.nextToken()
-	expression.Property = p.parseExpression(MEMBER)
-
-	return expression
 }
 
 // parsePostfixIncrementExpression parses postfix ++ expressions
@@ -708,12 +853,13 @@ func (p *Parser) parsePostfixDecrementExpression(left ast.Expression) ast.Expres
 // parseArrowFunctionExpression parses arrow function expressions
 // mightBeArrowFunctionParams checks if the current position might be arrow function parameters
 func (p *Parser) mightBeArrowFunctionParams() bool {
-	// Simple heuristic: if we see an identifier followed by ':' or ',' or ')', it might be parameters
+	// Simple heuristic: if we see an identifier followed by ':', ',', ')' or a default '=', it might be parameters
 	if p.currentTokenIs(lexer.IDENT) {
-		return p.peekTokenIs(lexer.COLON) || p.peekTokenIs(lexer.COMMA) || p.peekTokenIs(lexer.RPAREN)
+		return p.peekTokenIs(lexer.COLON) || p.peekTokenIs(lexer.COMMA) || p.peekTokenIs(lexer.RPAREN) ||
+			p.peekTokenIs(lexer.ASSIGN)
 	}
-	// Empty parameter list
-	if p.currentTokenIs(lexer.RPAREN) {
+	// Empty parameter list, or a rest parameter, which can't start an expression
+	if p.currentTokenIs(lexer.RPAREN) || p.currentTokenIs(lexer.SPREAD) {
 		return true
 	}
 	return false
@@ -738,6 +884,9 @@ func (p *Parser) parseArrowFunctionParameterList() []*ast.Parameter {
 	// Parse remaining parameters
 	for p.peekTokenIs(lexer.COMMA) {
 		p.nextToken() // consume ','
+		if p.peekTokenIs(lexer.RPAREN) {
+			break // trailing comma
+		}
 		p.nextToken() // move to next parameter
 		
 		param := p.parseParameter()
@@ -768,6 +917,7 @@ func (p *Parser) parseArrowFunctionExpression(left ast.Expression) ast.Expressio
 		arrow.Parameters = leftExpr.Parameters
 		arrow.LParen = leftExpr.LParen
 		arrow.RParen = leftExpr.RParen
+		arrow.ReturnType = leftExpr.ReturnType
 	default:
 		// For now, we don't support other complex parameter forms
 		p.addErrorf("arrow function currently only supports identifier or parenthesized parameters: %T", left)
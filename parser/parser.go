@@ -1,8 +1,13 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
 
 	"github.com/xingleixu/TG-Script/ast"
 	"github.com/xingleixu/TG-Script/lexer"
@@ -12,17 +17,35 @@ import (
 type Parser struct {
 	lexer *lexer.Lexer
 
+	// tokens buffers everything read from the lexer so the parser can
+	// backtrack; position is the index of peekToken in tokens
+	tokens   []lexer.TokenInfo
+	position int
+
 	currentToken lexer.TokenInfo
 	peekToken    lexer.TokenInfo
 
-	errors []string
+	errors   []*ParserError
+	comments []*ast.Comment
+}
+
+// ParserError represents a syntax error at a source position.
+type ParserError struct {
+	Position lexer.Position
+	Message  string
+}
+
+// Error returns the error message with its position.
+func (e *ParserError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Position.Line, e.Position.Column, e.Message)
 }
 
 // New creates a new parser instance.
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		lexer:  l,
-		errors: []string{},
+		lexer:    l,
+		position: -1,
+		errors:   []*ParserError{},
 	}
 
 	// Read two tokens, so currentToken and peekToken are both set
@@ -85,12 +108,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(lexer.MUL_ASSIGN, p.parseAssignmentExpression)
 	p.registerInfix(lexer.DIV_ASSIGN, p.parseAssignmentExpression)
 	p.registerInfix(lexer.MOD_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(lexer.LOGICAL_AND_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(lexer.LOGICAL_OR_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(lexer.NULLISH_ASSIGN, p.parseAssignmentExpression)
 	p.registerInfix(lexer.LPAREN, p.parseCallExpression)
 	p.registerInfix(lexer.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(lexer.DOT, p.parseMemberExpression)
 	p.registerInfix(lexer.QUESTION, p.parseTernaryExpression)
 	p.registerInfix(lexer.INSTANCEOF, p.parseInstanceofExpression)
 	p.registerInfix(lexer.IN, p.parseInExpression)
+	p.registerInfix(lexer.AS, p.parseAsExpression)
 	p.registerInfix(lexer.NULLISH, p.parseNullishCoalescingExpression)
 	p.registerInfix(lexer.OPTIONAL, p.parseOptionalChainingExpression)
 	p.registerInfix(lexer.INCREMENT, p.parsePostfixIncrementExpression)
@@ -103,29 +130,83 @@ func New(l *lexer.Lexer) *Parser {
 // nextToken advances both currentToken and peekToken.
 func (p *Parser) nextToken() {
 	p.currentToken = p.peekToken
-	p.peekToken = p.lexer.NextToken()
-	
-	// Skip comments
-	for p.peekToken.Type == lexer.COMMENT {
-		p.peekToken = p.lexer.NextToken()
+	p.position++
+	p.peekToken = p.tokenAt(p.position)
+}
+
+// tokenAt returns the token at index i of the buffer, reading from the
+// lexer as needed.
+func (p *Parser) tokenAt(i int) lexer.TokenInfo {
+	for len(p.tokens) <= i {
+		if n := len(p.tokens); n > 0 && p.tokens[n-1].Type == lexer.EOF {
+			return p.tokens[n-1]
+		}
+		
+		tok := p.lexer.NextToken()
+		// Skip comments, keeping them for documentation tools
+		for tok.Type == lexer.COMMENT {
+			p.comments = append(p.comments, &ast.Comment{Slash: tok.Position, Text: tok.Literal})
+			tok = p.lexer.NextToken()
+		}
+		p.tokens = append(p.tokens, tok)
 	}
+	return p.tokens[i]
+}
+
+// parserState is a saved parser position used for backtracking.
+type parserState struct {
+	position int
+	errors   int
+}
+
+// saveState records the current position so a speculative parse can be undone.
+func (p *Parser) saveState() parserState {
+	return parserState{position: p.position, errors: len(p.errors)}
 }
 
-// Errors returns the list of parsing errors.
+// restoreState rewinds the parser to a saved position, discarding any
+// errors reported since.
+func (p *Parser) restoreState(state parserState) {
+	p.position = state.position
+	p.currentToken = p.tokenAt(state.position - 1)
+	p.peekToken = p.tokenAt(state.position)
+	p.errors = p.errors[:state.errors]
+}
+
+// Errors returns the list of parsing error messages.
 func (p *Parser) Errors() []string {
+	messages := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		messages[i] = err.Message
+	}
+	return messages
+}
+
+// ParserErrors returns the list of parsing errors with their positions.
+func (p *Parser) ParserErrors() []*ParserError {
 	return p.errors
 }
 
-// addError adds an error message to the parser's error list.
+// addError adds an error at the current token to the parser's error list.
 func (p *Parser) addError(msg string) {
-	p.errors = append(p.errors, msg)
+	p.addErrorAt(p.currentToken.Position, msg)
 }
 
-// addErrorf adds a formatted error message to the parser's error list.
+// addErrorf adds a formatted error at the current token to the parser's error list.
 func (p *Parser) addErrorf(format string, args ...interface{}) {
 	p.addError(fmt.Sprintf(format, args...))
 }
 
+// addErrorAt adds an error at the given position to the parser's error list.
+func (p *Parser) addErrorAt(pos lexer.Position, msg string) {
+	p.errors = append(p.errors, &ParserError{Position: pos, Message: msg})
+}
+
+// addPeekErrorf adds a formatted error at the peek token to the parser's error list.
+func (p *Parser) addPeekErrorf(format string, args ...interface{}) {
+	p.addErrorAt(p.peekToken.Position, fmt.Sprintf(format, args...))
+}
+
 // expectToken checks if the current token is of the expected type and advances.
 func (p *Parser) expectToken(tokenType lexer.Token) bool {
 	if p.currentToken.Type == tokenType {
@@ -142,7 +223,7 @@ func (p *Parser) expectPeek(tokenType lexer.Token) bool {
 		p.nextToken()
 		return true
 	}
-	p.addErrorf("expected next token to be %s, got %s", tokenType, p.peekToken.Type)
+	p.addPeekErrorf("expected next token to be %s, got %s", tokenType, p.peekToken.Type)
 	return false
 }
 
@@ -199,7 +280,7 @@ func (p *Parser) expectSemicolonOrASI() bool {
 		return true
 	}
 	
-	p.addErrorf("expected ';' or line break, got %s", p.peekToken.Type)
+	p.addPeekErrorf("expected ';' or line break, got %s", p.peekToken.Type)
 	return false
 }
 
@@ -247,6 +328,10 @@ var precedences = map[lexer.Token]Precedence{
 	lexer.DIV_ASSIGN:    ASSIGN,
 	lexer.MOD_ASSIGN:    ASSIGN,
 
+	lexer.LOGICAL_AND_ASSIGN: ASSIGN,
+	lexer.LOGICAL_OR_ASSIGN:  ASSIGN,
+	lexer.NULLISH_ASSIGN:     ASSIGN,
+
 	lexer.QUESTION:      TERNARY,
 
 	lexer.NULLISH: NULLISH,
@@ -269,6 +354,7 @@ var precedences = map[lexer.Token]Precedence{
 	lexer.GE:            RELATIONAL,
 	lexer.INSTANCEOF:    RELATIONAL,
 	lexer.IN:            RELATIONAL,
+	lexer.AS:            RELATIONAL,
 
 	lexer.BIT_LSHIFT:    SHIFT,
 	lexer.BIT_RSHIFT:    SHIFT,
@@ -318,19 +404,44 @@ func (p *Parser) ParseProgram() *ast.Program {
 	}
 
 	for !p.currentTokenIs(lexer.EOF) {
-		stmt := p.parseStatement()
+		stmt := p.parseModuleItem()
 		if stmt != nil {
 			program.Body = append(program.Body, stmt)
 		}
 		p.nextToken()
 	}
 
+	p.mergeLexerErrors()
+	program.Comments = p.comments
 	return program
 }
 
+// mergeLexerErrors adds the lexer's errors, such as unterminated strings,
+// to the parser's, keeping both in source order. They are merged once
+// parsing is done so that backtracking can't discard them.
+func (p *Parser) mergeLexerErrors() {
+	lexErrors := p.lexer.GetErrors()
+	if len(lexErrors) == 0 {
+		return
+	}
+	merged := make([]*ParserError, 0, len(p.errors)+len(lexErrors))
+	i := 0
+	for _, lexErr := range lexErrors {
+		for i < len(p.errors) && p.errors[i].Position.Offset <= lexErr.Position.Offset {
+			merged = append(merged, p.errors[i])
+			i++
+		}
+		merged = append(merged, &ParserError{Position: lexErr.Position, Message: lexErr.Message})
+	}
+	p.errors = append(merged, p.errors[i:]...)
+}
+
 // parseStatement parses a statement.
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currentToken.Type {
+	case lexer.IMPORT, lexer.EXPORT:
+		p.addErrorf("%s declarations are only allowed at the top level", p.currentToken.Literal)
+		return nil
 	case lexer.LET, lexer.CONST, lexer.VAR:
 		return p.parseVariableDeclaration()
 	case lexer.FUNCTION:
@@ -352,14 +463,22 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseIfStatement()
 	case lexer.WHILE:
 		return p.parseWhileStatement()
+	case lexer.DO:
+		return p.parseDoWhileStatement()
 	case lexer.FOR:
 		return p.parseForStatement()
+	case lexer.SWITCH:
+		return p.parseSwitchStatement()
 	case lexer.RETURN:
 		return p.parseReturnStatement()
 	case lexer.BREAK:
 		return p.parseBreakStatement()
 	case lexer.CONTINUE:
 		return p.parseContinueStatement()
+	case lexer.THROW:
+		return p.parseThrowStatement()
+	case lexer.TRY:
+		return p.parseTryStatement()
 	case lexer.LBRACE:
 		return p.parseBlockStatement()
 	case lexer.SEMICOLON:
@@ -369,6 +488,19 @@ func (p *Parser) parseStatement() ast.Statement {
 	}
 }
 
+// parseModuleItem parses a top-level statement, which may also be an
+// import or export declaration.
+func (p *Parser) parseModuleItem() ast.Statement {
+	switch p.currentToken.Type {
+	case lexer.IMPORT:
+		return p.parseImportDeclaration()
+	case lexer.EXPORT:
+		return p.parseExportDeclaration()
+	default:
+		return p.parseStatement()
+	}
+}
+
 // ============================================================================
 // UTILITY FUNCTIONS
 // ============================================================================
@@ -395,16 +527,40 @@ func (p *Parser) parseIntegerLiteral() *ast.IntegerLiteral {
 		Raw:      p.currentToken.Literal,
 	}
 
-	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
-	if err != nil {
-		p.addErrorf("could not parse %q as integer", p.currentToken.Literal)
+	digits, base := integerDigits(p.currentToken.Literal)
+	value, err := strconv.ParseInt(digits, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.addErrorf("integer literal %s is too large; the maximum is %d", p.currentToken.Literal, int64(math.MaxInt64))
 		return nil
 	}
+	if err != nil {
+		// The lexer has reported the malformed literal
+		return lit
+	}
 
 	lit.Value = value
 	return lit
 }
 
+// integerDigits returns the digits of an integer literal without its radix
+// prefix and separators, and the radix. Literals without a 0x, 0b or 0o
+// prefix are decimal, even with leading zeros.
+func integerDigits(literal string) (string, int) {
+	digits := strings.ReplaceAll(literal, "_", "")
+	if len(digits) < 2 || digits[0] != '0' {
+		return digits, 10
+	}
+	switch digits[1] {
+	case 'x', 'X':
+		return digits[2:], 16
+	case 'b', 'B':
+		return digits[2:], 2
+	case 'o', 'O':
+		return digits[2:], 8
+	}
+	return digits, 10
+}
+
 // parseFloatLiteral parses a float literal.
 func (p *Parser) parseFloatLiteral() *ast.FloatLiteral {
 	lit := &ast.FloatLiteral{
@@ -412,7 +568,7 @@ func (p *Parser) parseFloatLiteral() *ast.FloatLiteral {
 		Raw:      p.currentToken.Literal,
 	}
 
-	value, err := strconv.ParseFloat(p.currentToken.Literal, 64)
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.currentToken.Literal, "_", ""), 64)
 	if err != nil {
 		p.addErrorf("could not parse %q as float", p.currentToken.Literal)
 		return nil
@@ -424,11 +580,132 @@ func (p *Parser) parseFloatLiteral() *ast.FloatLiteral {
 
 // parseStringLiteral parses a string literal.
 func (p *Parser) parseStringLiteral() *ast.StringLiteral {
+	raw := p.currentToken.Literal
+	value, offset, err := unescapeString(raw)
+	if err != "" {
+		p.addErrorAt(escapePosition(p.currentToken.Position, raw, offset), err)
+	}
 	return &ast.StringLiteral{
 		ValuePos: p.currentToken.Position,
-		Value:    p.currentToken.Literal,
-		Raw:      p.currentToken.Literal,
+		Value:    value,
+		Raw:      raw,
+	}
+}
+
+// unescapeString decodes the escape sequences in the body of a string
+// literal. If an escape is invalid it returns an error message and the
+// offset of its backslash; the invalid escape is kept as written.
+func unescapeString(raw string) (string, int, string) {
+	if strings.IndexByte(raw, '\\') < 0 {
+		return raw, 0, ""
+	}
+
+	var sb strings.Builder
+	errOffset, errMsg := 0, ""
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		if ch != '\\' || i+1 >= len(raw) {
+			sb.WriteByte(ch)
+			continue
+		}
+
+		switch raw[i+1] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case '0':
+			sb.WriteByte(0)
+		case '\\', '"', '\'':
+			sb.WriteByte(raw[i+1])
+		case 'x':
+			if code, ok := hexEscape(raw, i+2, 2); ok {
+				sb.WriteRune(code)
+				i += 3
+				continue
+			}
+			if errMsg == "" {
+				errOffset, errMsg = i, "invalid hexadecimal escape sequence: expected \\x followed by 2 hex digits"
+			}
+			sb.WriteString(raw[i : i+2])
+		case 'u':
+			if code, size, ok := unicodeEscape(raw, i); ok {
+				sb.WriteRune(code)
+				i += size - 1
+				continue
+			}
+			if errMsg == "" {
+				errOffset, errMsg = i, "invalid unicode escape sequence: expected \\u followed by 4 hex digits or \\u{code point}"
+			}
+			sb.WriteString(raw[i : i+2])
+		default:
+			if errMsg == "" {
+				errOffset, errMsg = i, fmt.Sprintf("invalid escape sequence: \\%c", raw[i+1])
+			}
+			sb.WriteString(raw[i : i+2])
+		}
+		i++
+	}
+	return sb.String(), errOffset, errMsg
+}
+
+// unicodeEscape decodes the \uXXXX or \u{X...} escape whose backslash is at
+// raw[i], returning the code point and the length of the escape. A high
+// surrogate followed by a \uXXXX low surrogate decodes as one code point.
+func unicodeEscape(raw string, i int) (rune, int, bool) {
+	if i+2 < len(raw) && raw[i+2] == '{' {
+		end := strings.IndexByte(raw[i+3:], '}')
+		if end < 1 || end > 6 {
+			return 0, 0, false
+		}
+		code, ok := hexEscape(raw, i+3, end)
+		if !ok || code > unicode.MaxRune {
+			return 0, 0, false
+		}
+		return code, end + 4, true
+	}
+
+	code, ok := hexEscape(raw, i+2, 4)
+	if !ok {
+		return 0, 0, false
+	}
+	if utf16.IsSurrogate(code) && strings.HasPrefix(raw[i+6:], "\\u") {
+		if low, ok := hexEscape(raw, i+8, 4); ok {
+			if pair := utf16.DecodeRune(code, low); pair != unicode.ReplacementChar {
+				return pair, 12, true
+			}
+		}
+	}
+	return code, 6, true
+}
+
+// hexEscape parses the n hex digits at raw[start:]
+func hexEscape(raw string, start, n int) (rune, bool) {
+	if start+n > len(raw) {
+		return 0, false
+	}
+	code, err := strconv.ParseUint(raw[start:start+n], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return rune(code), true
+}
+
+// escapePosition returns the source position of the byte at offset in the
+// body of the string literal starting at pos
+func escapePosition(pos lexer.Position, raw string, offset int) lexer.Position {
+	pos.Offset += offset + 1 // skip opening quote
+	pos.Column++
+	for i := 0; i < offset; i++ {
+		if raw[i] == '\n' {
+			pos.Line++
+			pos.Column = 0
+		}
+		pos.Column++
 	}
+	return pos
 }
 
 // parseBooleanLiteral parses a boolean literal.
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/xingleixu/TG-Script/ast"
@@ -179,6 +182,99 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestNumericSeparatorLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1_000", int64(1000)},
+		{"0xFF_FF", int64(0xFFFF)},
+		{"0b1010_1010", int64(0xAA)},
+		{"0o7_7", int64(077)},
+		{"1_000.5", 1000.5},
+		{"1_000_000", int64(1000000)},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Body[0].(*ast.ExpressionStatement)
+		switch literal := stmt.Expression.(type) {
+		case *ast.IntegerLiteral:
+			if literal.Value != tt.expected {
+				t.Errorf("input %s: literal.Value not %v. got=%d", tt.input, tt.expected, literal.Value)
+			}
+		case *ast.FloatLiteral:
+			if literal.Value != tt.expected {
+				t.Errorf("input %s: literal.Value not %v. got=%g", tt.input, tt.expected, literal.Value)
+			}
+		default:
+			t.Errorf("input %s: unexpected expression %T", tt.input, stmt.Expression)
+		}
+	}
+}
+
+func TestIntegerLiteralRadixes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0x1F", 31},
+		{"0XfF", 255},
+		{"0b1010", 10},
+		{"0B1", 1},
+		{"0o777", 511},
+		{"0O17", 15},
+		{"010", 10}, // leading zeros don't make a literal octal
+		{"9223372036854775807", math.MaxInt64},
+		{"9_223_372_036_854_775_807", math.MaxInt64},
+		{"0x7FFF_FFFF_FFFF_FFFF", math.MaxInt64},
+		{"0b" + strings.Repeat("1", 63), math.MaxInt64},
+		{"0o777777777777777777777", math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		literal, ok := program.Body[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Errorf("input %s: expected an integer literal", tt.input)
+			continue
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("input %s: literal.Value not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+	}
+
+	overflows := []string{
+		"9223372036854775808",
+		"99999999999999999999",
+		"0x8000000000000000",
+		"0b1" + strings.Repeat("0", 63),
+		"0o1000000000000000000000",
+	}
+	for _, input := range overflows {
+		p := createParser(input)
+		p.ParseProgram()
+		expected := "integer literal " + input + " is too large; the maximum is 9223372036854775807"
+		if errors := p.Errors(); len(errors) != 1 || errors[0] != expected {
+			t.Errorf("input %s: expected error %q, got %v", input, expected, errors)
+		}
+	}
+
+	// A malformed literal is reported once, by the lexer
+	p := createParser("x = 0x")
+	p.ParseProgram()
+	expected := "malformed numeric literal: expected hexadecimal digit"
+	if errors := p.Errors(); len(errors) != 1 || errors[0] != expected {
+		t.Errorf("expected error %q, got %v", expected, errors)
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -276,6 +372,53 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 }
 
+func TestLogicalAssignmentExpressions(t *testing.T) {
+	tests := []struct {
+		input      string
+		leftValue  string
+		operator   string
+		rightValue interface{}
+	}{
+		{"a &&= b;", "a", "&&=", "b"},
+		{"a ||= 5;", "a", "||=", 5},
+		{"a ??= true;", "a", "??=", true},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Body) != 1 {
+			t.Fatalf("program.Body does not contain %d statements. got=%d\n",
+				1, len(program.Body))
+		}
+
+		stmt, ok := program.Body[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Body[0] is not ast.ExpressionStatement. got=%T",
+				program.Body[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignmentExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.AssignmentExpression. got=%T", stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Left, tt.leftValue) {
+			return
+		}
+
+		if exp.Operator.String() != tt.operator {
+			t.Fatalf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator.String())
+		}
+
+		if !testLiteralExpression(t, exp.Right, tt.rightValue) {
+			return
+		}
+	}
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -381,6 +524,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
 		{
 			"a * [1, 2, 3, 4][b * c] * d",
 			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
@@ -641,6 +792,129 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestStringLiteralEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"a\tb"`, "a\tb"},
+		{`"a\rb"`, "a\rb"},
+		{`"a\\b"`, "a\\b"},
+		{`"a\"b"`, "a\"b"},
+		{`'a\'b'`, "a'b"},
+		{`"a\0b"`, "a\x00b"},
+		{`"\u0041\u00e9\u4e2d"`, "A\u00e9\u4e2d"},
+		{`"\x41\x7e\xE9"`, "A~\u00e9"},
+		{`"\u{41}\u{1F600}\u{10FFFF}"`, "A\U0001F600\U0010FFFF"},
+		{`"\uD83D\uDE00!"`, "\U0001F600!"},
+		{`'say "hi"'`, `say "hi"`},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Body[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("input %s: literal.Value not %q. got=%q", tt.input, tt.expected, literal.Value)
+		}
+		if literal.Raw != tt.input[1:len(tt.input)-1] {
+			t.Errorf("input %s: literal.Raw not kept as written. got=%q", tt.input, literal.Raw)
+		}
+	}
+}
+
+func TestInvalidStringEscapes(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		column  int
+	}{
+		{`let s = "a\qb"`, "invalid escape sequence: \\q", 11},
+		{`let s = "\u12"`, "invalid unicode escape sequence", 10},
+		{`let s = "ok\u00zz"`, "invalid unicode escape sequence", 12},
+		{`let s = "\x4"`, "invalid hexadecimal escape sequence", 10},
+		{`let s = "\u{}"`, "invalid unicode escape sequence", 10},
+		{`let s = "\u{110000}"`, "invalid unicode escape sequence", 10},
+		{`let s = "\u{41"`, "invalid unicode escape sequence", 10},
+		{`let s = "abc`, "unterminated string literal", 9},
+		{"let s = 'abc\nlet t = 1", "unterminated string literal", 9},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		p.ParseProgram()
+
+		errors := p.ParserErrors()
+		if len(errors) != 1 {
+			t.Errorf("input %s: expected 1 error, got %d: %v", tt.input, len(errors), p.Errors())
+			continue
+		}
+		if !strings.Contains(errors[0].Message, tt.message) {
+			t.Errorf("input %s: expected error containing %q, got %q", tt.input, tt.message, errors[0].Message)
+		}
+		if errors[0].Position.Line != 1 || errors[0].Position.Column != tt.column {
+			t.Errorf("input %s: expected error at 1:%d, got %d:%d", tt.input, tt.column,
+				errors[0].Position.Line, errors[0].Position.Column)
+		}
+	}
+}
+
+func TestObjectPropertyShorthand(t *testing.T) {
+	input := `o = { x, y: 2, double(n: int): int { return n * 2 }, [key]() { return 1 } }`
+
+	p := createParser(input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Body[0].(*ast.ExpressionStatement)
+	assign := stmt.Expression.(*ast.AssignmentExpression)
+	obj, ok := assign.Right.(*ast.ObjectLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.ObjectLiteral. got=%T", assign.Right)
+	}
+
+	tests := []struct {
+		shorthand bool
+		method    bool
+		computed  bool
+		expected  string
+	}{
+		{true, false, false, "x"},
+		{false, false, false, "y: 2"},
+		{false, true, false, "double(n: int): int {\nreturn (n * 2);\n}"},
+		{false, true, true, "[key]() {\nreturn 1;\n}"},
+	}
+
+	if len(obj.Properties) != len(tests) {
+		t.Fatalf("expected %d properties, got %d", len(tests), len(obj.Properties))
+	}
+	for i, tt := range tests {
+		prop := obj.Properties[i]
+		if prop.Shorthand != tt.shorthand || prop.Method != tt.method || prop.Computed != tt.computed {
+			t.Errorf("property %d: flags shorthand=%v method=%v computed=%v, want %v %v %v",
+				i, prop.Shorthand, prop.Method, prop.Computed, tt.shorthand, tt.method, tt.computed)
+		}
+		if prop.String() != tt.expected {
+			t.Errorf("property %d: String() = %q, want %q", i, prop.String(), tt.expected)
+		}
+	}
+
+	// Shorthand values are the key identifier; method values are functions
+	if ident, ok := obj.Properties[0].Value.(*ast.Identifier); !ok || ident.Name != "x" {
+		t.Errorf("shorthand value not identifier x. got=%T", obj.Properties[0].Value)
+	}
+	if _, ok := obj.Properties[2].Value.(*ast.FunctionExpression); !ok {
+		t.Errorf("method value not *ast.FunctionExpression. got=%T", obj.Properties[2].Value)
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -683,4 +957,759 @@ func TestParsingIndexExpressions(t *testing.T) {
 	if !testInfixExpression(t, indexExp.Property, 1, "+", 1) {
 		return
 	}
-}
\ No newline at end of file
+}
+
+func TestOptionalChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a?.b", "a?.b"},
+		{"a?.b?.c", "a?.b?.c"},
+		{"a?.b.c", "a?.b.c"},
+		{"a?.[i]", "a?.[i]"},
+		{"f?.(1, 2)", "f?.(1, 2)"},
+		{"a.f?.(x).y", "a.f?.(x).y"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Body[0].(*ast.ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+		if !ast.IsOptionalChain(stmt.Expression) {
+			t.Errorf("%q is not an optional chain", tt.input)
+		}
+	}
+
+	p := createParser("f?.()")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	call, ok := program.Body[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if !ok || !call.Optional {
+		t.Fatalf("expected optional *ast.CallExpression. got=%T", program.Body[0].(*ast.ExpressionStatement).Expression)
+	}
+}
+
+func TestArrowFunctionReturnType(t *testing.T) {
+	p := createParser("const f = (x: int): int => x * 2;")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl, ok := program.Body[0].(*ast.VariableDeclaration)
+	if !ok {
+		t.Fatalf("program.Body[0] is not ast.VariableDeclaration. got=%T", program.Body[0])
+	}
+
+	arrow, ok := decl.Declarations[0].Init.(*ast.ArrowFunctionExpression)
+	if !ok {
+		t.Fatalf("init is not ast.ArrowFunctionExpression. got=%T", decl.Declarations[0].Init)
+	}
+
+	if len(arrow.Parameters) != 1 {
+		t.Fatalf("arrow function should have 1 parameter. got=%d", len(arrow.Parameters))
+	}
+
+	if arrow.ReturnType == nil || arrow.ReturnType.String() != "int" {
+		t.Fatalf("arrow.ReturnType is not 'int'. got=%v", arrow.ReturnType)
+	}
+}
+
+func TestArrowFunctionRestParameter(t *testing.T) {
+	p := createParser("const f = (...xs: int[]) => xs;")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := program.Body[0].(*ast.VariableDeclaration)
+	arrow, ok := decl.Declarations[0].Init.(*ast.ArrowFunctionExpression)
+	if !ok {
+		t.Fatalf("init is not ast.ArrowFunctionExpression. got=%T", decl.Declarations[0].Init)
+	}
+
+	if len(arrow.Parameters) != 1 || !arrow.Parameters[0].Rest {
+		t.Fatalf("arrow function should have 1 rest parameter. got=%v", arrow.Parameters)
+	}
+}
+
+func TestArrowFunctionDefaultParameter(t *testing.T) {
+	p := createParser("const f = (x = 7, y: int = x + 1) => x + y;\n(x = 1);")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := program.Body[0].(*ast.VariableDeclaration)
+	arrow, ok := decl.Declarations[0].Init.(*ast.ArrowFunctionExpression)
+	if !ok {
+		t.Fatalf("init is not ast.ArrowFunctionExpression. got=%T", decl.Declarations[0].Init)
+	}
+	if len(arrow.Parameters) != 2 || arrow.Parameters[0].DefaultValue == nil || arrow.Parameters[1].DefaultValue == nil {
+		t.Fatalf("arrow function should have 2 parameters with defaults. got=%v", arrow.Parameters)
+	}
+
+	// Without an arrow, the parentheses hold an assignment
+	stmt := program.Body[1].(*ast.ExpressionStatement)
+	if _, ok := stmt.Expression.(*ast.AssignmentExpression); !ok {
+		t.Fatalf("expected an assignment expression. got=%T", stmt.Expression)
+	}
+}
+
+func TestDoWhileStatement(t *testing.T) {
+	p := createParser("do { x = x + 1; } while (x < 10);")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Body) != 1 {
+		t.Fatalf("program.Body does not contain 1 statement. got=%d", len(program.Body))
+	}
+
+	stmt, ok := program.Body[0].(*ast.DoWhileStatement)
+	if !ok {
+		t.Fatalf("program.Body[0] is not ast.DoWhileStatement. got=%T", program.Body[0])
+	}
+
+	if !testInfixExpression(t, stmt.Test, "x", "<", 10) {
+		return
+	}
+
+	body, ok := stmt.Body.(*ast.BlockStatement)
+	if !ok {
+		t.Fatalf("stmt.Body is not ast.BlockStatement. got=%T", stmt.Body)
+	}
+
+	if len(body.Body) != 1 {
+		t.Fatalf("body does not contain 1 statement. got=%d", len(body.Body))
+	}
+}
+
+func TestDoWhileStatementSemicolon(t *testing.T) {
+	// The semicolon after do-while is optional, even on the same line
+	tests := []string{
+		"do { x = x + 1 } while (x < 10)\ny = 1",
+		"do { x = x + 1 } while (x < 10); y = 1",
+		"do { x = x + 1 } while (x < 10) y = 1",
+	}
+
+	for _, input := range tests {
+		p := createParser(input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Body) != 2 {
+			t.Fatalf("%q: expected 2 statements. got=%d", input, len(program.Body))
+		}
+		if _, ok := program.Body[0].(*ast.DoWhileStatement); !ok {
+			t.Errorf("%q: program.Body[0] is not ast.DoWhileStatement. got=%T", input, program.Body[0])
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedLine   int
+		expectedColumn int
+		expectedMsg    string
+	}{
+		{"let x = ;", 1, 9, "no prefix parse function for ; found"},
+		{"let a = 1\nlet b = (2 + 3", 2, 15, "expected next token to be ), got EOF"},
+		{"let y = 1 2", 1, 11, "expected ';' or line break after variable declaration, got INT"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.ParserErrors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+
+		err := errors[0]
+		if err.Message != tt.expectedMsg {
+			t.Errorf("wrong message for %q. expected=%q, got=%q", tt.input, tt.expectedMsg, err.Message)
+		}
+		if err.Position.Line != tt.expectedLine || err.Position.Column != tt.expectedColumn {
+			t.Errorf("wrong position for %q. expected=%d:%d, got=%d:%d", tt.input,
+				tt.expectedLine, tt.expectedColumn, err.Position.Line, err.Position.Column)
+		}
+
+		// The string API reports the same messages
+		if p.Errors()[0] != err.Message {
+			t.Errorf("Errors() and ParserErrors() disagree: %q vs %q", p.Errors()[0], err.Message)
+		}
+	}
+}
+
+func TestCommentsAreRetained(t *testing.T) {
+	input := `// first
+let x = 1 /* inline */
+/* block
+   comment */
+let y = 2`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{"// first", "/* inline */", "/* block\n   comment */"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("expected %d comments, got %d", len(expected), len(program.Comments))
+	}
+	for i, text := range expected {
+		if program.Comments[i].Text != text {
+			t.Errorf("comment %d wrong. expected=%q, got=%q", i, text, program.Comments[i].Text)
+		}
+	}
+
+	leading := program.LeadingComments(program.Body[1])
+	if len(leading) != 1 || leading[0].Content() != "block\ncomment" {
+		t.Errorf("wrong leading comments for second statement: %v", leading)
+	}
+}
+
+func TestNestedConditionalExpression(t *testing.T) {
+	input := `a ? b : c ? d : e`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Body[0].(*ast.ExpressionStatement)
+	outer, ok := stmt.Expression.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("expected *ast.ConditionalExpression, got %T", stmt.Expression)
+	}
+	if outer.Test.String() != "a" || outer.Consequent.String() != "b" {
+		t.Fatalf("wrong outer conditional: %s", outer.String())
+	}
+
+	inner, ok := outer.Alternate.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("expected alternate to be *ast.ConditionalExpression, got %T", outer.Alternate)
+	}
+	if inner.String() != "c ? d : e" {
+		t.Fatalf("wrong inner conditional: %s", inner.String())
+	}
+}
+
+func TestParenthesizedExpressionBacktracking(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType string
+		expected     string
+	}{
+		{"(a, b) => a + b", "*ast.ArrowFunctionExpression", ""},
+		{"(a: int, b) => a", "*ast.ArrowFunctionExpression", ""},
+		{"(a)", "*ast.Identifier", "a"},
+		{"(a) * c", "*ast.BinaryExpression", "(a * c)"},
+		{"(a + b)", "*ast.BinaryExpression", "(a + b)"},
+		{"(a + b) * c", "*ast.BinaryExpression", "((a + b) * c)"},
+		{"(x, y)", "*ast.SequenceExpression", "(x, y)"},
+		{"(x, y) + z", "*ast.BinaryExpression", "((x, y) + z)"},
+		{"((x) => x)(1)", "*ast.CallExpression", ""},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Body) != 1 {
+			t.Fatalf("%q: expected 1 statement, got %d", tt.input, len(program.Body))
+		}
+		expr := program.Body[0].(*ast.ExpressionStatement).Expression
+		if actual := fmt.Sprintf("%T", expr); actual != tt.expectedType {
+			t.Errorf("%q: expected %s, got %s", tt.input, tt.expectedType, actual)
+			continue
+		}
+		if tt.expected != "" && expr.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, expr.String())
+		}
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	tests := []struct {
+		input        string
+		hasParam     bool
+		hasHandler   bool
+		hasFinalizer bool
+	}{
+		{`try { throw "boom" } catch (e) { print(e) }`, true, true, false},
+		{`try { f() } finally { done() }`, false, false, true},
+		{`try { f() } catch { g() } finally { done() }`, false, true, true},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Body[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Body[0] is not ast.TryStatement. got=%T", program.Body[0])
+		}
+		if (stmt.Handler != nil) != tt.hasHandler {
+			t.Errorf("%q: expected handler=%t", tt.input, tt.hasHandler)
+		}
+		if stmt.Handler != nil && (stmt.Handler.Param != nil) != tt.hasParam {
+			t.Errorf("%q: expected catch param=%t", tt.input, tt.hasParam)
+		}
+		if (stmt.Finalizer != nil) != tt.hasFinalizer {
+			t.Errorf("%q: expected finalizer=%t", tt.input, tt.hasFinalizer)
+		}
+	}
+
+	throwStmt, ok := createParser(`try { throw "boom" } catch (e) {}`).ParseProgram().Body[0].(*ast.TryStatement).Block.Body[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("expected ast.ThrowStatement in try block")
+	}
+	if throwStmt.Argument.String() != "boom" {
+		t.Errorf("wrong throw argument: %s", throwStmt.Argument.String())
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedMsg string
+	}{
+		{"try { f() }", "expected catch or finally after try block"},
+		{"throw\nx", "expected expression after throw"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(errors[0], tt.expectedMsg) {
+			t.Errorf("%q: expected error containing %q, got %q", tt.input, tt.expectedMsg, errors[0])
+		}
+	}
+}
+
+func TestClassMemberModifiers(t *testing.T) {
+	input := `class B extends A {
+    override greet(name: string): string { return name }
+    static override readonly y = 2
+    count: int = 0
+}`
+	p := createParser(input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	class, ok := program.Body[0].(*ast.ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Body[0] is not ast.ClassDeclaration. got=%T", program.Body[0])
+	}
+	if len(class.Body) != 3 {
+		t.Fatalf("expected 3 class members, got=%d", len(class.Body))
+	}
+
+	method, ok := class.Body[0].(*ast.MethodDefinition)
+	if !ok {
+		t.Fatalf("class.Body[0] is not ast.MethodDefinition. got=%T", class.Body[0])
+	}
+	if !method.Override || method.Static {
+		t.Errorf("expected override instance method, got override=%t static=%t", method.Override, method.Static)
+	}
+	if method.Value.ReturnType == nil {
+		t.Errorf("expected method return type")
+	}
+
+	prop, ok := class.Body[1].(*ast.PropertyDefinition)
+	if !ok {
+		t.Fatalf("class.Body[1] is not ast.PropertyDefinition. got=%T", class.Body[1])
+	}
+	if !prop.Override || !prop.Static || !prop.Readonly || len(prop.Modifiers) != 3 {
+		t.Errorf("wrong property modifiers: %s", prop.String())
+	}
+
+	typed, ok := class.Body[2].(*ast.PropertyDefinition)
+	if !ok || typed.TypeAnnotation == nil {
+		t.Errorf("expected property with type annotation, got %T", class.Body[2])
+	}
+}
+
+func TestSwitchStatement(t *testing.T) {
+	input := `switch (x) {
+    case 1:
+    case 2:
+        y = 1
+        break
+    default:
+        y = 2
+    case 3:
+}`
+	p := createParser(input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Body[0].(*ast.SwitchStatement)
+	if !ok {
+		t.Fatalf("program.Body[0] is not ast.SwitchStatement. got=%T", program.Body[0])
+	}
+	if stmt.Discriminant.String() != "x" {
+		t.Errorf("wrong discriminant: %s", stmt.Discriminant.String())
+	}
+
+	expected := []struct {
+		test       string
+		consequent int
+	}{
+		{"1", 0},
+		{"2", 2},
+		{"", 1},
+		{"3", 0},
+	}
+	if len(stmt.Cases) != len(expected) {
+		t.Fatalf("expected %d cases, got=%d", len(expected), len(stmt.Cases))
+	}
+	for i, tt := range expected {
+		clause := stmt.Cases[i]
+		test := ""
+		if clause.Test != nil {
+			test = clause.Test.String()
+		}
+		if test != tt.test {
+			t.Errorf("case %d: expected test %q, got %q", i, tt.test, test)
+		}
+		if len(clause.Consequent) != tt.consequent {
+			t.Errorf("case %d: expected %d statements, got %d", i, tt.consequent, len(clause.Consequent))
+		}
+	}
+}
+
+func TestSwitchStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"switch (x) { default: a(); default: b() }", "multiple default clauses"},
+		{"switch (x) { y = 1 }", "expected case or default"},
+		{"switch (x) { case 1 y() }", "expected next token to be :"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expected parser errors", tt.input)
+			continue
+		}
+		if !strings.Contains(errors[0], tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestBinaryExpressionSpan(t *testing.T) {
+	p := createParser("let total = price * count + 1")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	decl := program.Body[0].(*ast.VariableDeclaration)
+	expr, ok := decl.Declarations[0].Init.(*ast.BinaryExpression)
+	if !ok {
+		t.Fatalf("init is not ast.BinaryExpression. got=%T", decl.Declarations[0].Init)
+	}
+
+	span := ast.SpanOf(expr)
+	if span.Start != expr.Left.Pos() || span.End != expr.Right.End() {
+		t.Errorf("span %s does not cover operands %v to %v", span, expr.Left.Pos(), expr.Right.End())
+	}
+	if span.String() != "1:13-1:30" {
+		t.Errorf("wrong span: %s", span)
+	}
+}
+
+func TestDestructuringDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = pair;", "[a, b]"},
+		{"let [a, , b = 1] = items;", "[a, , b = 1]"},
+		{"const {x, y: z, w = 2} = point;", "{x, y: z, w = 2}"},
+		{"const {a: [b, {c}], [key]: d} = data;", "{a: [b, {c}], [key]: d}"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		decl, ok := program.Body[0].(*ast.VariableDeclaration)
+		if !ok {
+			t.Fatalf("%q: statement not *ast.VariableDeclaration. got=%T", tt.input, program.Body[0])
+		}
+		id := decl.Declarations[0].Id
+		if _, ok := id.(ast.Pattern); !ok {
+			t.Fatalf("%q: id not ast.Pattern. got=%T", tt.input, id)
+		}
+		if id.String() != tt.expected {
+			t.Errorf("%q: String() = %q, want %q", tt.input, id.String(), tt.expected)
+		}
+	}
+
+	// Nested targets and defaults keep their structure
+	p := createParser("const {a: [b, {c = 3}]} = data;")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	obj := program.Body[0].(*ast.VariableDeclaration).Declarations[0].Id.(*ast.ObjectPattern)
+	arr, ok := obj.Properties[0].Value.(*ast.ArrayPattern)
+	if !ok {
+		t.Fatalf("property value not *ast.ArrayPattern. got=%T", obj.Properties[0].Value)
+	}
+	inner := arr.Elements[1].(*ast.ObjectPattern)
+	def, ok := inner.Properties[0].Value.(*ast.AssignmentPattern)
+	if !ok || !inner.Properties[0].Shorthand {
+		t.Fatalf("expected shorthand property with default, got %T", inner.Properties[0].Value)
+	}
+
+	names := ast.BindingNames(obj)
+	if len(names) != 2 || names[0].Name != "b" || names[1].Name != "c" || def.Right.String() != "3" {
+		t.Errorf("unexpected bindings %v with default %s", names, def.Right)
+	}
+}
+
+func TestSpreadElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = [...a, 4]", "[...a, 4]"},
+		{"x = [1, ...f(), ...[2, 3]]", "[1, ...f(), ...[2, 3]]"},
+		{"x = {...base, x: 1}", "{...base, x: 1}"},
+		{"x = {a: 1, ...other}", "{a: 1, ...other}"},
+		{"x = f(...args)", "f(...args)"},
+		{"x = f(1, ...args, 2)", "f(1, ...args, 2)"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Body[0].(*ast.ExpressionStatement)
+		literal := stmt.Expression.(*ast.AssignmentExpression).Right
+		if literal.String() != tt.expected {
+			t.Errorf("%q: String() = %q, want %q", tt.input, literal.String(), tt.expected)
+		}
+	}
+
+	p := createParser("x = {...base}")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	obj := program.Body[0].(*ast.ExpressionStatement).Expression.(*ast.AssignmentExpression).Right.(*ast.ObjectLiteral)
+	prop := obj.Properties[0]
+	spread, ok := prop.Value.(*ast.SpreadElement)
+	if !ok || prop.Key != nil {
+		t.Fatalf("expected spread property without key, got key=%v value=%T", prop.Key, prop.Value)
+	}
+	if spread.Argument.String() != "base" || prop.Pos() != spread.Ellipsis {
+		t.Errorf("wrong spread property: %s at %v", spread, prop.Pos())
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(a, b,)", "f(a, b)"},
+		{"x = [1, 2,]", "x = [1, 2]"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Body[0].(*ast.ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("%q: String() = %q, want %q", tt.input, stmt.Expression.String(), tt.expected)
+		}
+	}
+
+	paramTests := []struct {
+		input  string
+		params int
+	}{
+		{"function add(a: int, b: int,): int { return a + b; }", 2},
+		{"let f = (a, b,) => a + b;", 2},
+		{"let g = (a: int,) => a;", 1},
+	}
+
+	for _, tt := range paramTests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		var params []*ast.Parameter
+		switch stmt := program.Body[0].(type) {
+		case *ast.FunctionDeclaration:
+			params = stmt.Parameters
+		case *ast.VariableDeclaration:
+			params = stmt.Declarations[0].Init.(*ast.ArrowFunctionExpression).Parameters
+		default:
+			t.Fatalf("%q: unexpected statement %T", tt.input, stmt)
+		}
+		if len(params) != tt.params {
+			t.Errorf("%q: got %d parameters, want %d", tt.input, len(params), tt.params)
+		}
+	}
+}
+
+func TestIncrementOnNewLine(t *testing.T) {
+	p := createParser("a\n++b\nc--\n--d")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []string{"a", "(++b)", "c--", "(--d)"}
+	if len(program.Body) != len(expected) {
+		t.Fatalf("expected %d statements, got %d", len(expected), len(program.Body))
+	}
+	for i, stmt := range program.Body {
+		expr := stmt.(*ast.ExpressionStatement).Expression
+		if expr.String() != expected[i] {
+			t.Errorf("statement %d: expected %q, got %q", i, expected[i], expr.String())
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: number = 1", "number"},
+		{"let xs: int[][] = []", "int[][]"},
+		{"let u: int | string | null = 1", "int | string | null"},
+		{"let u: (int | string)[] = []", "(int | string)[]"},
+		{"let p: (int) = 1", "int"},
+		{"let o: { x: int; y: string } = o", "{ x: int; y: string }"},
+		{"let o: { x: int, y?: string[] } = o", "{ x: int; y?: string[] }"},
+		{"let f: (x: int) => int = f", "(x: int) => int"},
+		{"let f: () => void = f", "() => void"},
+		{"let f: (x: int, ...rest: string[]) => int | null = f", "(x: int, ...rest: string[]) => int | null"},
+		{"let fs: ((x: int) => int)[] = []", "((x: int) => int)[]"},
+		{"let g: (f: (x: int) => int) => { n: int } = g", "(f: (x: int) => int) => { n: int }"},
+	}
+
+	for _, tt := range tests {
+		p := createParser(tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		decl := program.Body[0].(*ast.VariableDeclaration)
+		annotation := decl.Declarations[0].TypeAnnotation
+		if annotation == nil {
+			t.Errorf("%q: missing type annotation", tt.input)
+			continue
+		}
+		if annotation.String() != tt.expected {
+			t.Errorf("%q: String() = %q, want %q", tt.input, annotation.String(), tt.expected)
+		}
+	}
+
+	p := createParser("let f: (x: int) => int = f")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	fn, ok := program.Body[0].(*ast.VariableDeclaration).Declarations[0].TypeAnnotation.(*ast.FunctionType)
+	if !ok {
+		t.Fatalf("expected *ast.FunctionType")
+	}
+	if len(fn.Parameters) != 1 || fn.Parameters[0].Name.Name != "x" || fn.ReturnType.String() != "int" {
+		t.Errorf("wrong function type: %s", fn)
+	}
+}
+
+func TestModuleDeclarations(t *testing.T) {
+	input := `import { add, PI as pi } from "./math.tg"
+import { Point } from './geometry';
+export const E = 2.7
+export function twice(x: int): int { return x * 2 }
+export interface Shape { name: string }`
+
+	p := createParser(input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Body) != 5 {
+		t.Fatalf("expected 5 statements, got %d", len(program.Body))
+	}
+	imp, ok := program.Body[0].(*ast.ImportDeclaration)
+	if !ok {
+		t.Fatalf("expected *ast.ImportDeclaration, got %T", program.Body[0])
+	}
+	if imp.Source.Value != "./math.tg" || len(imp.Specifiers) != 2 {
+		t.Fatalf("wrong import: %s", imp)
+	}
+	if spec := imp.Specifiers[1]; spec.Imported.Name != "PI" || spec.Local.Name != "pi" {
+		t.Errorf("expected PI as pi, got %s", spec)
+	}
+	if got := imp.String(); got != `import { add, PI as pi } from "./math.tg";` {
+		t.Errorf("String() = %q", got)
+	}
+
+	var exported []string
+	for _, stmt := range program.Body[2:] {
+		export, ok := stmt.(*ast.ExportDeclaration)
+		if !ok {
+			t.Fatalf("expected *ast.ExportDeclaration, got %T", stmt)
+		}
+		for _, name := range ast.DeclaredNames(export) {
+			exported = append(exported, name.Name)
+		}
+	}
+	if strings.Join(exported, ",") != "E,twice,Shape" {
+		t.Errorf("exported names = %v", exported)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"export 5", "expected declaration after export, got INT"},
+		{"if (x) { import { a } from \"./a.tg\" }", "import declarations are only allowed at the top level"},
+		{"function f() { export const x = 1 }", "export declarations are only allowed at the top level"},
+		{"import { a } \"./a.tg\"", "expected next token to be from, got STRING"},
+	}
+	for _, tt := range errorTests {
+		p := createParser(tt.input)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || !strings.Contains(errors[0], tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestGenericFunctionDeclaration(t *testing.T) {
+	p := createParser("function longest<T extends string, U>(a: T, b: U): T { return a }")
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn, ok := program.Body[0].(*ast.FunctionDeclaration)
+	if !ok {
+		t.Fatalf("expected *ast.FunctionDeclaration, got %T", program.Body[0])
+	}
+	if len(fn.TypeParameters) != 2 || fn.TypeParameters[0].String() != "T extends string" || fn.TypeParameters[1].String() != "U" {
+		t.Fatalf("wrong type parameters: %v", fn.TypeParameters)
+	}
+	if !strings.HasPrefix(fn.String(), "function longest<T extends string, U>(a: T, b: U): T") {
+		t.Errorf("String() = %q", fn.String())
+	}
+}
@@ -21,7 +21,7 @@ func (p *Parser) parseExpressionStatement() ast.Statement {
 		stmt.Semicolon = p.currentToken.Position
 	} else if !p.canInsertSemicolon() {
 		// Only report error if ASI is not applicable
-		p.addErrorf("expected ';' or line break after expression, got %s", p.peekToken.Type)
+		p.addPeekErrorf("expected ';' or line break after expression, got %s", p.peekToken.Type)
 	}
 
 	return stmt
@@ -64,7 +64,7 @@ func (p *Parser) parseVariableDeclaration() ast.Statement {
 		Kind:    p.currentToken.Type,
 	}
 
-	if !p.expectPeek(lexer.IDENT) {
+	if !p.expectBindingTarget() {
 		return nil
 	}
 
@@ -76,7 +76,7 @@ func (p *Parser) parseVariableDeclaration() ast.Statement {
 	// Handle multiple declarations separated by commas
 	for p.peekTokenIs(lexer.COMMA) {
 		p.nextToken()
-		if !p.expectPeek(lexer.IDENT) {
+		if !p.expectBindingTarget() {
 			return nil
 		}
 		declarator := p.parseVariableDeclarator()
@@ -91,7 +91,7 @@ func (p *Parser) parseVariableDeclaration() ast.Statement {
 		stmt.Semicolon = p.currentToken.Position
 	} else if !p.canInsertSemicolon() {
 		// Only report error if ASI is not applicable
-		p.addErrorf("expected ';' or line break after variable declaration, got %s", p.peekToken.Type)
+		p.addPeekErrorf("expected ';' or line break after variable declaration, got %s", p.peekToken.Type)
 	}
 
 	return stmt
@@ -100,7 +100,10 @@ func (p *Parser) parseVariableDeclaration() ast.Statement {
 // parseVariableDeclarator parses a single variable declarator.
 func (p *Parser) parseVariableDeclarator() *ast.VariableDeclarator {
 	declarator := &ast.VariableDeclarator{
-		Id: p.parseIdentifier(),
+		Id: p.parseBindingTarget(),
+	}
+	if declarator.Id == nil {
+		return nil
 	}
 
 	// Optional type annotation
@@ -120,6 +123,163 @@ func (p *Parser) parseVariableDeclarator() *ast.VariableDeclarator {
 	return declarator
 }
 
+// expectBindingTarget advances to the next token if it can start a binding
+// target: an identifier or an array or object pattern.
+func (p *Parser) expectBindingTarget() bool {
+	switch p.peekToken.Type {
+	case lexer.IDENT, lexer.LBRACKET, lexer.LBRACE:
+		p.nextToken()
+		return true
+	}
+	p.addPeekErrorf("expected identifier or destructuring pattern, got %s", p.peekToken.Type)
+	return false
+}
+
+// parseBindingTarget parses an identifier or a destructuring pattern.
+func (p *Parser) parseBindingTarget() ast.BindingTarget {
+	switch p.currentToken.Type {
+	case lexer.LBRACKET:
+		return p.parseArrayPattern()
+	case lexer.LBRACE:
+		return p.parseObjectPattern()
+	}
+	if ident := p.parseIdentifier(); ident != nil {
+		return ident
+	}
+	return nil
+}
+
+// parseBindingElement parses a binding target with an optional default value.
+func (p *Parser) parseBindingElement() ast.BindingTarget {
+	target := p.parseBindingTarget()
+	if target == nil {
+		return nil
+	}
+	return p.parseBindingDefault(target)
+}
+
+// parseBindingDefault wraps target in an assignment pattern if a default
+// value follows it.
+func (p *Parser) parseBindingDefault(target ast.BindingTarget) ast.BindingTarget {
+	if !p.peekTokenIs(lexer.ASSIGN) {
+		return target
+	}
+	p.nextToken()
+	pattern := &ast.AssignmentPattern{
+		Left:   target,
+		Assign: p.currentToken.Position,
+	}
+	p.nextToken()
+	pattern.Right = p.parseExpression(ASSIGN)
+	if pattern.Right == nil {
+		return nil
+	}
+	return pattern
+}
+
+// parseArrayPattern parses an array destructuring pattern like [a, , b = 1].
+func (p *Parser) parseArrayPattern() ast.BindingTarget {
+	pattern := &ast.ArrayPattern{
+		LBracket: p.currentToken.Position,
+	}
+
+	for !p.peekTokenIs(lexer.RBRACKET) {
+		// A bare comma leaves a hole
+		if p.peekTokenIs(lexer.COMMA) {
+			p.nextToken()
+			pattern.Elements = append(pattern.Elements, nil)
+			continue
+		}
+		if !p.expectBindingTarget() {
+			return nil
+		}
+		elem := p.parseBindingElement()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, elem)
+
+		if !p.peekTokenIs(lexer.RBRACKET) && !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.RBracket = p.currentToken.Position
+	return pattern
+}
+
+// parseObjectPattern parses an object destructuring pattern like
+// {a, b: c, [key]: d = 1}.
+func (p *Parser) parseObjectPattern() ast.BindingTarget {
+	pattern := &ast.ObjectPattern{
+		LBrace: p.currentToken.Position,
+	}
+
+	for !p.peekTokenIs(lexer.RBRACE) {
+		p.nextToken()
+		prop := p.parsePatternProperty()
+		if prop == nil {
+			return nil
+		}
+		pattern.Properties = append(pattern.Properties, prop)
+
+		if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.RBrace = p.currentToken.Position
+	return pattern
+}
+
+// parsePatternProperty parses a single property of an object pattern.
+func (p *Parser) parsePatternProperty() *ast.PatternProperty {
+	prop := &ast.PatternProperty{}
+
+	switch p.currentToken.Type {
+	case lexer.IDENT:
+		prop.Key = p.parseIdentifierExpression()
+	case lexer.STRING:
+		prop.Key = p.parseStringLiteralExpression()
+	case lexer.INT:
+		prop.Key = p.parseIntegerLiteralExpression()
+	case lexer.LBRACKET:
+		prop.Computed = true
+		p.nextToken()
+		prop.Key = p.parseExpression(LOWEST)
+		if !p.expectPeek(lexer.RBRACKET) {
+			return nil
+		}
+	default:
+		p.addErrorf("expected property name in object pattern, got %s", p.currentToken.Type)
+		return nil
+	}
+
+	// Shorthand property: { x } binds x, { x = 1 } binds x with a default
+	if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed && !p.peekTokenIs(lexer.COLON) {
+		prop.Shorthand = true
+		prop.Value = p.parseBindingDefault(ident)
+		if prop.Value == nil {
+			return nil
+		}
+		return prop
+	}
+
+	if !p.expectPeek(lexer.COLON) {
+		return nil
+	}
+	if !p.expectBindingTarget() {
+		return nil
+	}
+	prop.Value = p.parseBindingElement()
+	if prop.Value == nil {
+		return nil
+	}
+	return prop
+}
+
 // parseIfStatement parses an if statement.
 func (p *Parser) parseIfStatement() ast.Statement {
 	stmt := &ast.IfStatement{
@@ -194,6 +354,46 @@ func (p *Parser) parseWhileStatement() ast.Statement {
 	return stmt
 }
 
+// parseDoWhileStatement parses a do-while statement.
+func (p *Parser) parseDoWhileStatement() ast.Statement {
+	stmt := &ast.DoWhileStatement{
+		DoPos: p.currentToken.Position,
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if !p.expectPeek(lexer.WHILE) {
+		return nil
+	}
+
+	stmt.WhilePos = p.currentToken.Position
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+
+	stmt.LParen = p.currentToken.Position
+	p.nextToken()
+	stmt.Test = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	stmt.RParen = p.currentToken.Position
+
+	// The semicolon after a do-while statement is always optional
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // parseForStatement parses a for statement.
 func (p *Parser) parseForStatement() ast.Statement {
 	forPos := p.currentToken.Position
@@ -396,6 +596,78 @@ func (p *Parser) parseForStatement() ast.Statement {
 	}
 }
 
+// parseSwitchStatement parses a switch statement.
+func (p *Parser) parseSwitchStatement() ast.Statement {
+	stmt := &ast.SwitchStatement{
+		SwitchPos: p.currentToken.Position,
+	}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+
+	stmt.LParen = p.currentToken.Position
+	p.nextToken()
+	stmt.Discriminant = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	stmt.RParen = p.currentToken.Position
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+
+	stmt.LBrace = p.currentToken.Position
+	p.nextToken()
+
+	hasDefault := false
+	for !p.currentTokenIs(lexer.RBRACE) && !p.currentTokenIs(lexer.EOF) {
+		clause := &ast.SwitchCase{CasePos: p.currentToken.Position}
+
+		switch p.currentToken.Type {
+		case lexer.CASE:
+			p.nextToken()
+			clause.Test = p.parseExpression(LOWEST)
+		case lexer.DEFAULT:
+			if hasDefault {
+				p.addErrorf("multiple default clauses in switch statement")
+			}
+			hasDefault = true
+		default:
+			p.addErrorf("expected case or default in switch statement, got %s", p.currentToken.Type)
+			return nil
+		}
+
+		if !p.expectPeek(lexer.COLON) {
+			return nil
+		}
+		clause.Colon = p.currentToken.Position
+		p.nextToken()
+
+		// Statements run until the next clause or the end of the switch
+		for !p.currentTokenIs(lexer.CASE) && !p.currentTokenIs(lexer.DEFAULT) &&
+			!p.currentTokenIs(lexer.RBRACE) && !p.currentTokenIs(lexer.EOF) {
+			if consequent := p.parseStatement(); consequent != nil {
+				clause.Consequent = append(clause.Consequent, consequent)
+			}
+			p.nextToken()
+		}
+
+		stmt.Cases = append(stmt.Cases, clause)
+	}
+
+	if !p.currentTokenIs(lexer.RBRACE) {
+		p.addErrorf("expected '}' to close switch statement, got %s", p.currentToken.Type)
+		return nil
+	}
+	stmt.RBrace = p.currentToken.Position
+
+	return stmt
+}
+
 // parseReturnStatement parses a return statement.
 func (p *Parser) parseReturnStatement() ast.Statement {
 	stmt := &ast.ReturnStatement{
@@ -423,7 +695,7 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 		stmt.Semicolon = p.currentToken.Position
 	} else if !p.canInsertSemicolon() {
 		// Only report error if ASI is not applicable
-		p.addErrorf("expected ';' or line break after return statement, got %s", p.peekToken.Type)
+		p.addPeekErrorf("expected ';' or line break after return statement, got %s", p.peekToken.Type)
 	}
 
 	return stmt
@@ -447,7 +719,7 @@ func (p *Parser) parseBreakStatement() ast.Statement {
 		stmt.Semicolon = p.currentToken.Position
 	} else if !p.canInsertSemicolon() {
 		// Only report error if ASI is not applicable
-		p.addErrorf("expected ';' or line break after break statement, got %s", p.peekToken.Type)
+		p.addPeekErrorf("expected ';' or line break after break statement, got %s", p.peekToken.Type)
 	}
 
 	return stmt
@@ -471,7 +743,158 @@ func (p *Parser) parseContinueStatement() ast.Statement {
 		stmt.Semicolon = p.currentToken.Position
 	} else if !p.canInsertSemicolon() {
 		// Only report error if ASI is not applicable
-		p.addErrorf("expected ';' or line break after continue statement, got %s", p.peekToken.Type)
+		p.addPeekErrorf("expected ';' or line break after continue statement, got %s", p.peekToken.Type)
+	}
+
+	return stmt
+}
+
+// parseImportDeclaration parses an import declaration like
+// import { add, PI as pi } from "./math.tg".
+func (p *Parser) parseImportDeclaration() ast.Statement {
+	stmt := &ast.ImportDeclaration{
+		ImportPos: p.currentToken.Position,
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(lexer.RBRACE) {
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		spec := &ast.ImportSpecifier{Imported: p.parseIdentifier()}
+		spec.Local = spec.Imported
+		if p.peekTokenIs(lexer.AS) {
+			p.nextToken()
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			spec.Local = p.parseIdentifier()
+		}
+		stmt.Specifiers = append(stmt.Specifiers, spec)
+
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(lexer.RBRACE) || !p.expectPeek(lexer.FROM) || !p.expectPeek(lexer.STRING) {
+		return nil
+	}
+	stmt.Source = p.parseStringLiteral()
+
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+		stmt.Semicolon = p.currentToken.Position
+	} else if !p.canInsertSemicolon() {
+		p.addPeekErrorf("expected ';' or line break after import declaration, got %s", p.peekToken.Type)
+	}
+
+	return stmt
+}
+
+// parseExportDeclaration parses 'export' followed by a variable, function,
+// class, interface, type alias or enum declaration.
+func (p *Parser) parseExportDeclaration() ast.Statement {
+	stmt := &ast.ExportDeclaration{
+		ExportPos: p.currentToken.Position,
+	}
+
+	switch p.peekToken.Type {
+	case lexer.LET, lexer.CONST, lexer.VAR, lexer.FUNCTION, lexer.CLASS, lexer.INTERFACE, lexer.TYPE, lexer.ENUM:
+		p.nextToken()
+	default:
+		p.addPeekErrorf("expected declaration after export, got %s", p.peekToken.Type)
+		return nil
+	}
+
+	stmt.Declaration = p.parseStatement()
+	if stmt.Declaration == nil {
+		return nil
+	}
+	if _, ok := stmt.Declaration.(*ast.ExpressionStatement); ok {
+		p.addErrorAt(stmt.ExportPos, "expected declaration after export, got function expression")
+		return nil
+	}
+	return stmt
+}
+
+// parseThrowStatement parses a throw statement.
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{
+		ThrowPos: p.currentToken.Position,
+	}
+
+	// No line break is allowed between 'throw' and its argument
+	if p.canInsertSemicolon() {
+		p.addPeekErrorf("expected expression after throw, got %s", p.peekToken.Type)
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Argument = p.parseExpression(LOWEST)
+	if stmt.Argument == nil {
+		return nil
+	}
+
+	// Use ASI logic for optional semicolon
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+		stmt.Semicolon = p.currentToken.Position
+	} else if !p.canInsertSemicolon() {
+		// Only report error if ASI is not applicable
+		p.addPeekErrorf("expected ';' or line break after throw statement, got %s", p.peekToken.Type)
+	}
+
+	return stmt
+}
+
+// parseTryStatement parses a try statement with catch and/or finally clauses.
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{
+		TryPos: p.currentToken.Position,
+	}
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(lexer.CATCH) {
+		p.nextToken()
+		handler := &ast.CatchClause{CatchPos: p.currentToken.Position}
+
+		// The binding is optional: catch { ... }
+		if p.peekTokenIs(lexer.LPAREN) {
+			p.nextToken()
+			if !p.expectPeek(lexer.IDENT) {
+				return nil
+			}
+			handler.Param = p.parseIdentifier()
+			if !p.expectPeek(lexer.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(lexer.LBRACE) {
+			return nil
+		}
+		handler.Body = p.parseBlockStatement()
+		stmt.Handler = handler
+	}
+
+	if p.peekTokenIs(lexer.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(lexer.LBRACE) {
+			return nil
+		}
+		stmt.Finalizer = p.parseBlockStatement()
+	}
+
+	if stmt.Handler == nil && stmt.Finalizer == nil {
+		p.addPeekErrorf("expected catch or finally after try block, got %s", p.peekToken.Type)
+		return nil
 	}
 
 	return stmt
@@ -503,6 +926,12 @@ func (p *Parser) parseFunctionDeclaration() ast.Statement {
 
 	fn.Name = p.parseIdentifier()
 
+	// Optional generic type parameters
+	if p.peekTokenIs(lexer.LT) {
+		p.nextToken()
+		fn.TypeParameters = p.parseTypeParameterList()
+	}
+
 	if !p.expectPeek(lexer.LPAREN) {
 		return nil
 	}
@@ -582,11 +1011,22 @@ func (p *Parser) parseClassMember() ast.Node {
 	// - computed property names
 	// - etc.
 
+	modifiers := p.parseClassModifiers()
+
 	if p.currentTokenIs(lexer.IDENT) && p.peekTokenIs(lexer.LPAREN) {
 		// Method
 		method := &ast.MethodDefinition{
-			Key:  p.parseIdentifierExpression(),
-			Kind: "method",
+			Key:       p.parseIdentifierExpression(),
+			Kind:      "method",
+			Modifiers: modifiers,
+		}
+		for _, modifier := range modifiers {
+			switch modifier.Kind {
+			case "static":
+				method.Static = true
+			case "override":
+				method.Override = true
+			}
 		}
 
 		// Create a function expression for the method
@@ -605,6 +1045,13 @@ func (p *Parser) parseClassMember() ast.Node {
 			fn.RParen = p.currentToken.Position
 		}
 
+		// Optional return type annotation
+		if p.peekTokenIs(lexer.COLON) {
+			p.nextToken()
+			p.nextToken()
+			fn.ReturnType = p.parseTypeAnnotation()
+		}
+
 		if !p.expectPeek(lexer.LBRACE) {
 			return nil
 		}
@@ -618,7 +1065,25 @@ func (p *Parser) parseClassMember() ast.Node {
 	// Property (simplified)
 	if p.currentTokenIs(lexer.IDENT) {
 		prop := &ast.PropertyDefinition{
-			Key: p.parseIdentifierExpression(),
+			Key:       p.parseIdentifierExpression(),
+			Modifiers: modifiers,
+		}
+		for _, modifier := range modifiers {
+			switch modifier.Kind {
+			case "static":
+				prop.Static = true
+			case "readonly":
+				prop.Readonly = true
+			case "override":
+				prop.Override = true
+			}
+		}
+
+		// Optional type annotation
+		if p.peekTokenIs(lexer.COLON) {
+			p.nextToken()
+			p.nextToken()
+			prop.TypeAnnotation = p.parseTypeAnnotation()
 		}
 
 		if p.peekTokenIs(lexer.ASSIGN) {
@@ -633,6 +1098,24 @@ func (p *Parser) parseClassMember() ast.Node {
 	return nil
 }
 
+// parseClassModifiers parses the modifiers before a class member.
+func (p *Parser) parseClassModifiers() []*ast.Modifier {
+	var modifiers []*ast.Modifier
+	for {
+		switch p.currentToken.Type {
+		case lexer.STATIC, lexer.ABSTRACT, lexer.PUBLIC, lexer.PRIVATE,
+			lexer.PROTECTED, lexer.READONLY, lexer.OVERRIDE:
+			modifiers = append(modifiers, &ast.Modifier{
+				Kind: p.currentToken.Literal,
+				Pos:  p.currentToken.Position,
+			})
+			p.nextToken()
+		default:
+			return modifiers
+		}
+	}
+}
+
 // parseInterfaceDeclaration parses an interface declaration.
 func (p *Parser) parseInterfaceDeclaration() ast.Statement {
 	iface := &ast.InterfaceDeclaration{
@@ -137,8 +137,32 @@ func (p *Parser) parseTypeReference() ast.TypeNode {
 	}
 }
 
-// parseGroupedType parses a grouped type (parenthesized type).
+// parseGroupedType parses a grouped type (parenthesized type) or an arrow
+// function type such as (x: int) => string.
 func (p *Parser) parseGroupedType() ast.TypeNode {
+	lparen := p.currentToken.Position
+
+	// Try a parameter list first; without a following '=>' the parentheses
+	// only group a type
+	saved := p.saveState()
+	params := p.parseParameterList()
+	if len(p.errors) == saved.errors && p.currentTokenIs(lexer.RPAREN) && p.peekTokenIs(lexer.ARROW) {
+		fn := &ast.FunctionType{
+			LParen:     lparen,
+			Parameters: params,
+			RParen:     p.currentToken.Position,
+		}
+		p.nextToken()
+		fn.Arrow = p.currentToken.Position
+		p.nextToken()
+		fn.ReturnType = p.parseTypeAnnotation()
+		if fn.ReturnType == nil {
+			return nil
+		}
+		return fn
+	}
+	p.restoreState(saved)
+
 	p.nextToken() // consume '('
 	typ := p.parseTypeAnnotation()
 	if !p.expectPeek(lexer.RPAREN) {
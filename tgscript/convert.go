@@ -0,0 +1,18 @@
+package tgscript
+
+import "github.com/xingleixu/TG-Script/vm"
+
+// ToValue converts a Go value to a script value. Supported types are nil,
+// bool, the integer and float types, string, []interface{} and
+// map[string]interface{}, nested to any depth, and vm.Value itself. Map
+// keys become object properties in sorted order.
+func ToValue(v interface{}) (vm.Value, error) {
+	return vm.ValueOf(v)
+}
+
+// FromValue converts a script value to a Go value: nil, bool, int64,
+// float64, string, []interface{} or map[string]interface{}. Functions and
+// other values with no Go equivalent are an error.
+func FromValue(v vm.Value) (interface{}, error) {
+	return v.ToGo()
+}
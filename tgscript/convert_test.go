@@ -0,0 +1,72 @@
+package tgscript
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+func TestValueRoundTrip(t *testing.T) {
+	tests := []interface{}{
+		nil,
+		true,
+		int64(42),
+		3.5,
+		"hello",
+		[]interface{}{int64(1), "two", 3.0, nil},
+		map[string]interface{}{
+			"name": "tg",
+			"tags": []interface{}{"a", "b"},
+			"meta": map[string]interface{}{"ok": false},
+		},
+	}
+
+	for _, tt := range tests {
+		value, err := ToValue(tt)
+		if err != nil {
+			t.Fatalf("ToValue(%v) failed: %v", tt, err)
+		}
+		back, err := FromValue(value)
+		if err != nil {
+			t.Fatalf("FromValue(%s) failed: %v", value.ToString(), err)
+		}
+		if !reflect.DeepEqual(back, tt) {
+			t.Errorf("round trip of %#v gave %#v", tt, back)
+		}
+	}
+}
+
+func TestToValueWidensNumbers(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected vm.Value
+	}{
+		{7, vm.NewIntValue(7)},
+		{int32(-7), vm.NewIntValue(-7)},
+		{uint8(255), vm.NewIntValue(255)},
+		{float32(0.5), vm.NewFloatValue(0.5)},
+	}
+
+	for _, tt := range tests {
+		value, err := ToValue(tt.input)
+		if err != nil {
+			t.Fatalf("ToValue(%v) failed: %v", tt.input, err)
+		}
+		if !value.Equals(tt.expected) || value.Type != tt.expected.Type {
+			t.Errorf("ToValue(%T %v) = %s (%s)", tt.input, tt.input, value.ToString(), value.TypeName())
+		}
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	if _, err := ToValue(struct{}{}); err == nil {
+		t.Errorf("expected error converting a struct")
+	}
+	if _, err := ToValue([]interface{}{1, make(chan int)}); err == nil {
+		t.Errorf("expected error converting a nested channel")
+	}
+	if _, err := FromValue(vm.NewFunctionValue(vm.NewFunction("f"))); err == nil {
+		t.Errorf("expected error converting a function")
+	}
+}
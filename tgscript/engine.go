@@ -0,0 +1,188 @@
+// Package tgscript embeds TG-Script in Go programs. An Engine runs scripts,
+// exposes Go functions to them and converts values in both directions.
+package tgscript
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/ast"
+	"github.com/xingleixu/TG-Script/compiler"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/types"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+// HostFunc is a Go function that scripts can call. Its arguments and
+// result are converted with FromValue and ToValue.
+type HostFunc func(args ...interface{}) (interface{}, error)
+
+// Engine runs TG-Script source. Top-level declarations made by Run are
+// globals, visible to later runs and through Get, Set and Call.
+type Engine struct {
+	machine    *vm.VM
+	signatures map[string]*types.FunctionType // declared host function types
+}
+
+// New creates an engine with the built-in functions registered
+func New() *Engine {
+	return &Engine{
+		machine:    vm.NewVM(),
+		signatures: make(map[string]*types.FunctionType),
+	}
+}
+
+// SetOutput sends the output of print and console.log to stdout, and that
+// of console.error and console.warn to stderr
+func (e *Engine) SetOutput(stdout, stderr io.Writer) {
+	e.machine.SetOutput(stdout, stderr)
+}
+
+// SetMaxSteps bounds the instructions each Run or Call may execute, so a
+// runaway script fails instead of hanging the host; 0 means no limit
+func (e *Engine) SetMaxSteps(steps int64) {
+	e.machine.MaxSteps = steps
+}
+
+// RegisterFunc makes fn callable from scripts under name, with any number
+// of arguments until DeclareFunc gives it a type
+func (e *Engine) RegisterFunc(name string, fn HostFunc) {
+	delete(e.signatures, name)
+	e.machine.SetGlobalFunc(name, vm.GoFunc(fn))
+}
+
+// DeclareFunc gives the host function registered under name a type, so
+// that calls with the wrong number or types of arguments are type errors.
+// The argument count is also enforced when the function is called.
+func (e *Engine) DeclareFunc(name string, fnType *types.FunctionType) error {
+	native, ok := e.machine.NativeFunctions[name]
+	if !ok {
+		return fmt.Errorf("undefined function: %s", name)
+	}
+	e.signatures[name] = fnType
+
+	native.MinArgs = fnType.MinArgs()
+	native.MaxArgs = len(fnType.Parameters)
+	if fnType.Variadic {
+		native.MaxArgs = -1
+	}
+	return nil
+}
+
+// Check parses and type checks source against the host functions and
+// globals defined so far, without running it
+func (e *Engine) Check(source string) error {
+	_, err := e.check(source)
+	return err
+}
+
+// check parses and type checks source, returning the parsed program
+func (e *Engine) check(source string) (*ast.Program, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errors := p.ParserErrors(); len(errors) > 0 {
+		messages := make([]string, len(errors))
+		for i, err := range errors {
+			messages[i] = err.Error()
+		}
+		return nil, fmt.Errorf("syntax errors: %s", strings.Join(messages, "; "))
+	}
+
+	checker := types.NewTypeChecker()
+	e.declareGlobals(checker)
+	if errors := checker.Check(program); len(errors) > 0 {
+		messages := make([]string, len(errors))
+		for i, err := range errors {
+			messages[i] = err.Error()
+		}
+		return nil, fmt.Errorf("type errors: %s", strings.Join(messages, "; "))
+	}
+	return program, nil
+}
+
+// Run parses, type checks, compiles and executes source. If the source
+// ends with an expression statement, its value is returned.
+func (e *Engine) Run(source string) (interface{}, error) {
+	program, err := e.check(source)
+	if err != nil {
+		return nil, err
+	}
+
+	function, err := compiler.CompileREPL(program)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := e.machine.Execute(vm.NewClosure(function), []vm.Value{})
+	if err != nil {
+		return nil, err
+	}
+	return FromValue(result)
+}
+
+// declareGlobals makes host functions and the globals defined so far known
+// to the type checker. Host functions without a declared type take their
+// registered number of 'any' arguments; other script types are 'any'.
+func (e *Engine) declareGlobals(checker *types.TypeChecker) {
+	scope := checker.GetGlobalScope()
+	for name, native := range e.machine.NativeFunctions {
+		if fnType, ok := e.signatures[name]; ok {
+			checker.DeclareFunction(name, fnType)
+		} else if _, exists := scope.Symbols[name]; !exists {
+			checker.DeclareFunction(name, types.NewNativeFunctionType(native.MinArgs, native.MaxArgs, types.AnyType))
+		}
+	}
+
+	hostFunc := types.NewVariadicFunctionType([]types.Type{}, types.AnyType)
+	for name, value := range e.machine.Globals() {
+		if _, exists := scope.Symbols[name]; exists {
+			continue
+		}
+		if value.IsCallable() {
+			scope.Define(name, &types.Symbol{Name: name, Type: hostFunc, Kind: types.FunctionSymbol})
+		} else {
+			scope.Define(name, &types.Symbol{Name: name, Type: types.AnyType, Kind: types.VariableSymbol})
+		}
+	}
+}
+
+// Get returns the value of a global variable
+func (e *Engine) Get(name string) (interface{}, error) {
+	return e.machine.GetGlobalValue(name)
+}
+
+// Set assigns a global variable, defining it if needed
+func (e *Engine) Set(name string, value interface{}) error {
+	v, err := ToValue(value)
+	if err != nil {
+		return err
+	}
+	e.machine.SetGlobal(name, v)
+	return nil
+}
+
+// Call calls the script function stored in global name and returns its
+// result
+func (e *Engine) Call(name string, args ...interface{}) (interface{}, error) {
+	callee, ok := e.machine.GetGlobal(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined function: %s", name)
+	}
+
+	values := make([]vm.Value, len(args))
+	for i, arg := range args {
+		value, err := ToValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		values[i] = value
+	}
+
+	result, err := e.machine.Call(callee, values)
+	if err != nil {
+		return nil, err
+	}
+	return FromValue(result)
+}
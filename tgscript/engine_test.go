@@ -0,0 +1,207 @@
+package tgscript
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/types"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+func TestEngineRun(t *testing.T) {
+	engine := New()
+
+	result, err := engine.Run("let x = 20\nx + 22")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result != int64(42) {
+		t.Errorf("expected 42, got %#v", result)
+	}
+
+	// Declarations persist between runs
+	result, err = engine.Run("function double(n: int): int {\n    return n * 2\n}\ndouble(x)")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result != int64(40) {
+		t.Errorf("expected 40, got %#v", result)
+	}
+
+	// No trailing expression gives no result
+	if result, err := engine.Run("let y = 1"); err != nil || result != nil {
+		t.Errorf("expected nil result, got %#v (%v)", result, err)
+	}
+}
+
+func TestEngineSetOutput(t *testing.T) {
+	var stdout, stderr strings.Builder
+	engine := New()
+	engine.SetOutput(&stdout, &stderr)
+	if _, err := engine.Run(`print("a", 1)
+console.log("b")
+console.warn("c")`); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stdout.String() != "a 1\nb\n" || stderr.String() != "c\n" {
+		t.Errorf("got stdout %q and stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"let = 1", "syntax errors"},
+		{"let s: string = 1", "type errors"},
+		{"undefinedThing + 1", "type errors"},
+		{"1 / 0", "division by zero"},
+		{"assert(1 > 2, \"math is broken\")", "assertion failed: math is broken"},
+		{"assert(1)", "type errors"},
+	}
+
+	for _, tt := range tests {
+		_, err := engine.Run(tt.source)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("source %q: expected error containing %q, got %v", tt.source, tt.expected, err)
+		}
+	}
+}
+
+func TestEngineMaxSteps(t *testing.T) {
+	engine := New()
+	engine.SetMaxSteps(1000)
+
+	if _, err := engine.Run("while (true) {}"); err == nil || !strings.Contains(err.Error(), "step limit exceeded") {
+		t.Fatalf("expected step limit error, got %v", err)
+	}
+
+	// The budget applies to each run separately
+	result, err := engine.Run("1 + 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != int64(3) {
+		t.Errorf("expected 3, got %v", result)
+	}
+}
+
+func TestEngineHostFunctions(t *testing.T) {
+	engine := New()
+
+	var received []interface{}
+	engine.RegisterFunc("collect", func(args ...interface{}) (interface{}, error) {
+		received = args
+		return map[string]interface{}{"count": len(args)}, nil
+	})
+	engine.RegisterFunc("fail", func(args ...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("host failure")
+	})
+
+	result, err := engine.Run(`let r = collect(1, 2.5, "s", true, [1, 2])
+r.count`)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if result != int64(5) {
+		t.Errorf("expected 5, got %#v", result)
+	}
+
+	expected := []interface{}{int64(1), 2.5, "s", true, []interface{}{int64(1), int64(2)}}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("host received %#v, want %#v", received, expected)
+	}
+
+	if _, err := engine.Run("fail()"); err == nil || !strings.Contains(err.Error(), "fail: host failure") {
+		t.Errorf("expected host error, got %v", err)
+	}
+}
+
+func TestEngineGlobals(t *testing.T) {
+	engine := New()
+
+	if err := engine.Set("limit", 10); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if _, err := engine.Run("let total = limit * 3"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	total, err := engine.Get("total")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if total != int64(30) {
+		t.Errorf("expected 30, got %#v", total)
+	}
+
+	if _, err := engine.Get("missing"); err == nil {
+		t.Errorf("expected error getting an undefined global")
+	}
+	if err := engine.Set("bad", struct{}{}); err == nil {
+		t.Errorf("expected error setting an unconvertible value")
+	}
+}
+
+func TestEngineCall(t *testing.T) {
+	engine := New()
+	if _, err := engine.Run("function greet(name: string): string {\n    return \"hello \" + name\n}"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	result, err := engine.Call("greet", "go")
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if result != "hello go" {
+		t.Errorf("expected %q, got %#v", "hello go", result)
+	}
+
+	if _, err := engine.Call("missing"); err == nil {
+		t.Errorf("expected error calling an undefined function")
+	}
+}
+
+func TestEngineDeclareFunc(t *testing.T) {
+	engine := New()
+	engine.RegisterFunc("square", func(args ...interface{}) (interface{}, error) {
+		n := args[0].(int64)
+		return n * n, nil
+	})
+
+	// Undeclared host functions accept any arguments
+	if err := engine.Check(`square(1, "extra")`); err != nil {
+		t.Errorf("unexpected error before declaring a type: %v", err)
+	}
+
+	square := types.NewFunctionType([]types.Type{types.IntType}, types.IntType)
+	if err := engine.DeclareFunc("square", square); err != nil {
+		t.Fatalf("declare failed: %v", err)
+	}
+	for _, source := range []string{`square("2")`, `square(1, 2)`} {
+		if err := engine.Check(source); err == nil || !strings.Contains(err.Error(), "type errors") {
+			t.Errorf("%s: expected a type error, got %v", source, err)
+		}
+	}
+
+	result, err := engine.Run("let n: int = square(7)\nn")
+	if err != nil || result != int64(49) {
+		t.Errorf("expected 49, got %#v (%v)", result, err)
+	}
+
+	// The declared arity is enforced at runtime too
+	native := vm.NewNativeFunctionValue(engine.machine.NativeFunctions["square"])
+	if _, err := engine.machine.Call(native, []vm.Value{vm.NewIntValue(1), vm.NewIntValue(2)}); err == nil ||
+		!strings.Contains(err.Error(), "expects 1 argument") {
+		t.Errorf("expected an argument count error, got %v", err)
+	}
+
+	if err := engine.DeclareFunc("missing", square); err == nil {
+		t.Errorf("expected an error declaring an unregistered function")
+	}
+}
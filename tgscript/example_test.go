@@ -0,0 +1,36 @@
+package tgscript_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xingleixu/TG-Script/tgscript"
+)
+
+// A Go host exposes a function to a script, then calls a script function
+// and receives its result as a Go value.
+func Example() {
+	engine := tgscript.New()
+	engine.RegisterFunc("upper", func(args ...interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	})
+
+	_, err := engine.Run(`
+function greet(name: string): string {
+    return "Hello, " + upper(name) + "!"
+}
+`)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	result, err := engine.Call("greet", "ada")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	greeting := result.(string)
+	fmt.Println(greeting)
+	// Output: Hello, ADA!
+}
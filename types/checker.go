@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/xingleixu/TG-Script/ast"
 	"github.com/xingleixu/TG-Script/lexer"
@@ -25,19 +27,46 @@ const (
 	ConstReassignmentError       ErrorCode = "E011"
 	ArrowFunctionAssignmentError ErrorCode = "E012"
 	LetRedeclarationError        ErrorCode = "E013"
+	InvalidTypeAliasError        ErrorCode = "E014"
+	InvalidEnumMemberError       ErrorCode = "E015"
+	NotIterableError             ErrorCode = "E016"
+	InvalidOverrideError         ErrorCode = "E017"
+	AssignmentToUndeclaredError  ErrorCode = "E018"
+	InvalidJumpError             ErrorCode = "E019"
+	MissingInitializerError      ErrorCode = "E020"
+	UnresolvedImportError        ErrorCode = "E021"
+
+	// Warnings report suspicious code that is still valid
+	UnusedVariableWarning  ErrorCode = "W001"
+	UnreachableCodeWarning ErrorCode = "W002"
+	NegativeIndexWarning   ErrorCode = "W003"
+)
+
+// Severity distinguishes type errors from warnings
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
 )
 
 type TypeError struct {
-	Position   lexer.Position
-	Message    string
-	Code       ErrorCode
-	Suggestion string
-	Context    string
+	Position    lexer.Position
+	EndPosition lexer.Position // end of the offending code, just past its last character
+	Span        lexer.Span     // source range of the offending code, from Position to EndPosition
+	Message     string
+	Code        ErrorCode
+	Suggestion  string
+	Context     string
+	Severity    Severity
 }
 
 func (e *TypeError) Error() string {
-	result := fmt.Sprintf("[%s] Type error at line %d, column %d: %s",
-		e.Code, e.Position.Line, e.Position.Column, e.Message)
+	kind := "Type error"
+	if e.Severity == SeverityWarning {
+		kind = "Warning"
+	}
+	result := fmt.Sprintf("[%s] %s at %s: %s", e.Code, kind, e.location(), e.Message)
 
 	if e.Context != "" {
 		result += fmt.Sprintf("\n  Context: %s", e.Context)
@@ -50,12 +79,34 @@ func (e *TypeError) Error() string {
 	return result
 }
 
+// location describes where the error is, as "line 3, column 7" or, when
+// the error spans several characters, "line 3, columns 7-9" or
+// "line 3, column 7 to line 4, column 2"
+func (e *TypeError) location() string {
+	start, end := e.Position, e.EndPosition
+	if end.Line == 0 || end.Offset <= start.Offset+1 {
+		return fmt.Sprintf("line %d, column %d", start.Line, start.Column)
+	}
+	// EndPosition is exclusive; report the last column covered
+	if end.Line == start.Line {
+		return fmt.Sprintf("line %d, columns %d-%d", start.Line, start.Column, end.Column-1)
+	}
+	return fmt.Sprintf("line %d, column %d to line %d, column %d", start.Line, start.Column, end.Line, max(end.Column-1, 1))
+}
+
 // TypeChecker performs static type checking
 type TypeChecker struct {
-	resolver   *Resolver
-	inferrer   *TypeInferrer
-	errors     []*TypeError
-	strictMode bool
+	resolver    *Resolver
+	inferrer    *TypeInferrer
+	errors      []*TypeError
+	warnings    []*TypeError
+	strictMode  bool
+	returnTypes []Type   // declared return types of enclosing functions (nil if not annotated)
+	jumpTargets []bool   // enclosing loops (true) and switches (false) of the current function
+	outerJumps  [][]bool // jump targets of the enclosing functions
+
+	classes            map[string]*ast.ClassDeclaration // classes declared so far, by name
+	noImplicitOverride bool                             // require 'override' on overriding members
 }
 
 // NewTypeChecker creates a new type checker
@@ -73,6 +124,11 @@ func NewTypeChecker() *TypeChecker {
 // Check performs type checking on a program
 func (tc *TypeChecker) Check(program *ast.Program) []*TypeError {
 	tc.errors = nil
+	tc.warnings = nil
+	tc.returnTypes = nil
+	tc.jumpTargets = nil
+	tc.outerJumps = nil
+	tc.classes = make(map[string]*ast.ClassDeclaration)
 
 	// First pass: resolve symbols and build symbol table
 	tc.resolver.ResolveProgram(program)
@@ -86,12 +142,89 @@ func (tc *TypeChecker) Check(program *ast.Program) []*TypeError {
 		}
 	}
 
-	// Second pass: type check all statements
-	for _, stmt := range program.Body {
+	// Second pass: type check all statements. Scopes created from here on
+	// hold the locals whose reads are tracked for unused variable warnings.
+	globalScope := tc.resolver.GetGlobalScope()
+	firstScope := len(globalScope.Children)
+	tc.checkStatementList(program.Body)
+	tc.warnUnusedSymbols(globalScope.Children[firstScope:])
+	sort.SliceStable(tc.warnings, func(i, j int) bool {
+		return tc.warnings[i].Position.Offset < tc.warnings[j].Position.Offset
+	})
+
+	return tc.errors
+}
+
+// checkStatementList type checks the statements of a block and warns about
+// the first statement after a return, break, continue or throw
+func (tc *TypeChecker) checkStatementList(stmts []ast.Statement) {
+	reported := false
+	for i, stmt := range stmts {
+		if i > 0 && !reported {
+			if exit := exitKeyword(stmts[i-1]); exit != "" {
+				tc.addNodeWarning(stmt,
+					"Unreachable code detected",
+					UnreachableCodeWarning,
+					fmt.Sprintf("Remove the code or move it before the '%s'", exit),
+					fmt.Sprintf("Statement follows a '%s' in the same block", exit))
+				reported = true
+			}
+		}
 		tc.checkStatement(stmt)
 	}
+}
 
-	return tc.errors
+// exitKeyword returns the keyword of a statement that always leaves its
+// block, or "" for any other statement
+func exitKeyword(stmt ast.Statement) string {
+	switch stmt.(type) {
+	case *ast.ReturnStatement:
+		return "return"
+	case *ast.BreakStatement:
+		return "break"
+	case *ast.ContinueStatement:
+		return "continue"
+	case *ast.ThrowStatement:
+		return "throw"
+	}
+	return ""
+}
+
+// warnUnusedSymbols warns about the variables and parameters in scopes
+// that were never read. Names starting with '_' are exempt.
+func (tc *TypeChecker) warnUnusedSymbols(scopes []*Scope) {
+	var unused []*Symbol
+	var collect func(scope *Scope)
+	collect = func(scope *Scope) {
+		for _, symbol := range scope.Symbols {
+			if (symbol.Kind == VariableSymbol || symbol.Kind == ParameterSymbol) &&
+				!symbol.Used && !strings.HasPrefix(symbol.Name, "_") {
+				unused = append(unused, symbol)
+			}
+		}
+		for _, child := range scope.Children {
+			collect(child)
+		}
+	}
+	for _, scope := range scopes {
+		collect(scope)
+	}
+
+	for _, symbol := range unused {
+		end := symbol.Position
+		end.Column += len(symbol.Name)
+		end.Offset += len(symbol.Name)
+		tc.warnings = append(tc.warnings, &TypeError{
+			Position:    symbol.Position,
+			EndPosition: end,
+			Span:        lexer.Span{Start: symbol.Position, End: end},
+			Message:     fmt.Sprintf("'%s' is declared but its value is never read", symbol.Name),
+			Code:        UnusedVariableWarning,
+			Suggestion:  fmt.Sprintf("Remove the %s or prefix its name with '_'", symbol.Kind),
+			Context:     fmt.Sprintf("Unused %s '%s'", symbol.Kind, symbol.Name),
+			Severity:    SeverityWarning,
+		})
+	}
 }
 
 // checkStatement type checks a statement
@@ -109,10 +242,38 @@ func (tc *TypeChecker) checkStatement(stmt ast.Statement) {
 		tc.checkIfStatement(s)
 	case *ast.WhileStatement:
 		tc.checkWhileStatement(s)
+	case *ast.DoWhileStatement:
+		tc.checkDoWhileStatement(s)
 	case *ast.ForStatement:
 		tc.checkForStatement(s)
+	case *ast.ForOfStatement:
+		tc.checkForOfStatement(s)
+	case *ast.ForInStatement:
+		tc.checkForInStatement(s)
+	case *ast.SwitchStatement:
+		tc.checkSwitchStatement(s)
+	case *ast.TryStatement:
+		tc.checkTryStatement(s)
+	case *ast.ClassDeclaration:
+		tc.checkClassDeclaration(s)
+	case *ast.ThrowStatement:
+		tc.checkExpression(s.Argument)
 	case *ast.ReturnStatement:
 		tc.checkReturnStatement(s)
+	case *ast.BreakStatement:
+		tc.checkBreakStatement(s)
+	case *ast.ContinueStatement:
+		tc.checkContinueStatement(s)
+	case *ast.InterfaceDeclaration:
+		tc.checkInterfaceDeclaration(s)
+	case *ast.TypeAliasDeclaration:
+		tc.checkTypeAliasDeclaration(s)
+	case *ast.EnumDeclaration:
+		tc.checkEnumDeclaration(s)
+	case *ast.ExportDeclaration:
+		tc.checkStatement(s.Declaration)
+	case *ast.ImportDeclaration:
+		// Imported names are bound by the resolver
 	}
 }
 
@@ -129,14 +290,14 @@ func (tc *TypeChecker) checkVariableDeclaration(decl *ast.VariableDeclaration) {
 
 		// Check initializer if present
 		if declarator.Init != nil {
-			initType := tc.checkExpression(declarator.Init)
+			initType := tc.checkValue(declarator.Init, declaredType)
 
 			// Check if arrow function is assigned to non-const variable
 			if _, isArrowFunction := declarator.Init.(*ast.ArrowFunctionExpression); isArrowFunction {
 				if decl.Kind != lexer.CONST {
 					if id, ok := declarator.Id.(*ast.Identifier); ok {
-						tc.addDetailedError(
-							declarator.Init.Pos(),
+						tc.addNodeError(
+							declarator.Id,
 							fmt.Sprintf("Arrow functions can only be assigned to 'const' variables, not '%s'", decl.Kind.String()),
 							ArrowFunctionAssignmentError,
 							"Change the variable declaration to 'const' to ensure arrow function immutability",
@@ -149,16 +310,17 @@ func (tc *TypeChecker) checkVariableDeclaration(decl *ast.VariableDeclaration) {
 
 			// If we have both type annotation and initializer, check compatibility
 			if declarator.TypeAnnotation != nil {
-				if !tc.isAssignable(initType, declaredType) {
-					tc.addDetailedError(
-						declarator.Init.Pos(),
+				if !tc.isAssignableExpr(declarator.Init, initType, declaredType) {
+					tc.addNodeError(
+						declarator.Id,
 						fmt.Sprintf("Cannot assign value of type '%s' to variable of type '%s'",
 							initType.String(), declaredType.String()),
 						TypeMismatchError,
 						fmt.Sprintf("Change the initializer to match type '%s' or remove the type annotation to allow type inference",
 							declaredType.String()),
-						fmt.Sprintf("Variable '%s' is declared with type '%s' but initialized with incompatible type '%s'",
-							declarator.Id.String(), declaredType.String(), initType.String()),
+						fmt.Sprintf("Variable '%s' is declared with type '%s' but initialized with incompatible type '%s'%s",
+							declarator.Id.String(), declaredType.String(), initType.String(),
+							tc.mismatchDetail(initType, declaredType)),
 					)
 				}
 				finalType = declaredType
@@ -167,11 +329,21 @@ func (tc *TypeChecker) checkVariableDeclaration(decl *ast.VariableDeclaration) {
 				finalType = initType
 
 			}
+		} else if _, isPattern := declarator.Id.(ast.Pattern); decl.Kind == lexer.CONST && !isPattern {
+			// A const can never be assigned later, so it needs its value now
+			tc.addNodeError(
+				declarator.Id,
+				fmt.Sprintf("Const declaration '%s' must be initialized", declarator.Id.String()),
+				MissingInitializerError,
+				fmt.Sprintf("Provide a value (e.g., 'const %s = ...') or declare it with 'let'", declarator.Id.String()),
+				fmt.Sprintf("Const '%s' has no initializer", declarator.Id.String()),
+			)
+			finalType = declaredType
 		} else if declarator.TypeAnnotation == nil {
 			// No type annotation and no initializer - this should be an error in strict mode
 			if tc.strictMode {
-				tc.addDetailedError(
-					declarator.Id.Pos(),
+				tc.addNodeError(
+					declarator.Id,
 					"Variable declaration must have either a type annotation or an initializer",
 					TypeMismatchError,
 					"Add a type annotation (e.g., ': string') or provide an initializer (e.g., '= \"value\"')",
@@ -183,57 +355,352 @@ func (tc *TypeChecker) checkVariableDeclaration(decl *ast.VariableDeclaration) {
 			finalType = declaredType
 		}
 
-		// Update variable type in symbol table (it was already defined during resolution)
-		if id, ok := declarator.Id.(*ast.Identifier); ok {
-			if err := tc.resolver.UpdateType(id.Name, finalType); err != nil {
-				// If update fails, try to define it (fallback)
-				tc.resolver.DefineWithDeclarationKind(id.Name, finalType, VariableSymbol, decl.Kind, id.Pos())
-			}
+		// Patterns read from the initializer, so they can't do without one
+		if _, isPattern := declarator.Id.(ast.Pattern); isPattern && declarator.Init == nil {
+			tc.addNodeError(
+				declarator.Id,
+				"Destructuring declaration must have an initializer",
+				TypeMismatchError,
+				"Provide the value to destructure (e.g., '= [1, 2]')",
+				fmt.Sprintf("Pattern '%s' has no value to read from", declarator.Id.String()),
+			)
 		}
+
+		// Update variable types in symbol table (they were already defined during resolution)
+		tc.checkBindingTarget(declarator.Id, finalType, decl.Kind)
 	}
 }
 
-// checkFunctionDeclaration type checks a function declaration
-func (tc *TypeChecker) checkFunctionDeclaration(decl *ast.FunctionDeclaration) {
-	// Collect parameter types
-	var paramTypes []Type
-	for _, param := range decl.Parameters {
-		var paramType Type = AnyType // Default to AnyType like TypeScript
-		if param.TypeAnnotation != nil {
-			paramType = tc.resolveTypeAnnotation(param.TypeAnnotation)
+// checkBindingTarget gives each name bound by target its part of valueType.
+// Parts that can't be determined are 'any', or the type of their default.
+func (tc *TypeChecker) checkBindingTarget(target ast.BindingTarget, valueType Type, kind lexer.Token) {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		// let and const bind in the current block, shadowing any outer
+		// variable of the same name instead of retyping it
+		if kind != lexer.VAR {
+			if symbol, ok := tc.resolver.LookupLocal(t.Name); ok {
+				symbol.Type = valueType
+			} else {
+				tc.resolver.DefineWithDeclarationKind(t.Name, valueType, VariableSymbol, kind, t.Pos())
+			}
+		} else if err := tc.resolver.UpdateType(t.Name, valueType); err != nil {
+			// If update fails, try to define it (fallback)
+			tc.resolver.DefineWithDeclarationKind(t.Name, valueType, VariableSymbol, kind, t.Pos())
+		}
+
+	case *ast.AssignmentPattern:
+		defaultType := tc.checkExpression(t.Right)
+		if isUnknownType(valueType) || valueType.Equals(NullType) {
+			valueType = defaultType
+		} else if !tc.isAssignableExpr(t.Right, defaultType, valueType) {
+			tc.addNodeError(t.Right,
+				fmt.Sprintf("Default value of type '%s' is not assignable to type '%s'",
+					defaultType.String(), valueType.String()),
+				TypeMismatchError,
+				fmt.Sprintf("Use a default value of type '%s'", valueType.String()),
+				fmt.Sprintf("Default for '%s'%s", t.Left.String(), tc.mismatchDetail(defaultType, valueType)))
+		}
+		tc.checkBindingTarget(t.Left, valueType, kind)
+
+	case *ast.ArrayPattern:
+		var elementType Type = AnyType
+		tuple, isTuple := valueType.(*TupleType)
+		if arrayType, ok := valueType.(*ArrayType); ok {
+			elementType = arrayType.ElementType
+		} else if !isTuple && !isUnknownType(valueType) {
+			tc.addNodeError(t,
+				fmt.Sprintf("Cannot destructure value of type '%s' as an array", valueType.String()),
+				TypeMismatchError,
+				"Only arrays can be destructured with '[...]'",
+				fmt.Sprintf("Destructuring '%s' from type '%s'", t.String(), valueType.String()))
+		}
+		for i, elem := range t.Elements {
+			if elem != nil {
+				// Each position of a tuple has its own type
+				if isTuple {
+					elementType = UndefinedType
+					if i < len(tuple.ElementTypes) {
+						elementType = tuple.ElementTypes[i]
+					}
+				}
+				tc.checkBindingTarget(elem, elementType, kind)
+			}
+		}
+
+	case *ast.ObjectPattern:
+		objType, isObject := valueType.(*ObjectType)
+		if !isObject && !isUnknownType(valueType) {
+			tc.addNodeError(t,
+				fmt.Sprintf("Cannot destructure value of type '%s' as an object", valueType.String()),
+				TypeMismatchError,
+				"Only objects can be destructured with '{...}'",
+				fmt.Sprintf("Destructuring '%s' from type '%s'", t.String(), valueType.String()))
+		}
+		for _, prop := range t.Properties {
+			var propType Type = AnyType
+			if prop.Computed {
+				tc.checkExpression(prop.Key)
+			} else if isObject {
+				name := patternKeyName(prop.Key)
+				if known, exists := objType.Properties[name]; exists {
+					propType = known
+				} else if tc.strictMode {
+					tc.addNodeError(prop.Key,
+						fmt.Sprintf("Property '%s' does not exist on object", name),
+						InvalidMemberAccessError,
+						fmt.Sprintf("Check if property '%s' exists or verify the object type", name),
+						fmt.Sprintf("Destructuring property '%s' from object of type '%s'", name, valueType.String()))
+				}
+			}
+			tc.checkBindingTarget(prop.Value, propType, kind)
 		}
-		paramTypes = append(paramTypes, paramType)
 	}
+}
 
-	// Determine return type
-	var returnType Type = UndefinedType
-	if decl.ReturnType != nil {
-		returnType = tc.resolveTypeAnnotation(decl.ReturnType)
+// isUnknownType reports whether nothing is known about a value's type
+func isUnknownType(t Type) bool {
+	return t.Equals(AnyType) || t.Equals(UndefinedType)
+}
+
+// disjointTypes reports whether no value can have both types, which makes
+// a strict equality between them constant. Null is never reported. Integer
+// and float types are disjoint, since strict equality compares the value's
+// runtime type and 1 === 1.0 is false.
+func (tc *TypeChecker) disjointTypes(left, right Type) bool {
+	if isUnknownType(left) || isUnknownType(right) {
+		return false
+	}
+	if left.Equals(NullType) || right.Equals(NullType) {
+		return false
 	}
+	if IsNumericType(left) && IsNumericType(right) {
+		_, leftFloat := numericWidth(left)
+		_, rightFloat := numericWidth(right)
+		return leftFloat != rightFloat
+	}
+	return !tc.isAssignable(left, right) && !tc.isAssignable(right, left)
+}
 
-	// Create function type and register it in the symbol table
-	funcType := &FunctionType{
-		Parameters: paramTypes,
-		ReturnType: returnType,
-		Variadic:   false,
+// patternKeyName returns the property name a non-computed pattern key reads
+func patternKeyName(key ast.Expression) string {
+	if str, ok := key.(*ast.StringLiteral); ok {
+		return str.Value
 	}
-	tc.resolver.Define(decl.Name.Name, funcType, FunctionSymbol, decl.Name.Pos())
+	return key.String()
+}
 
-	// Enter function scope
+// checkInterfaceDeclaration registers interfaces declared in nested scopes.
+// Top-level interfaces are already registered by the resolver.
+func (tc *TypeChecker) checkInterfaceDeclaration(decl *ast.InterfaceDeclaration) {
+	if _, exists := tc.resolver.LookupLocal(decl.Name.Name); !exists {
+		tc.resolver.resolveInterfaceDeclaration(decl)
+	}
+}
+
+// checkTypeAliasDeclaration registers type aliases declared in nested scopes.
+// Top-level aliases are already registered by the resolver.
+func (tc *TypeChecker) checkTypeAliasDeclaration(decl *ast.TypeAliasDeclaration) {
+	if _, exists := tc.resolver.LookupLocal(decl.Name.Name); !exists {
+		tc.resolver.resolveTypeAliasDeclaration(decl)
+	}
+}
+
+// checkEnumDeclaration validates enum members and registers enums declared
+// in nested scopes. Top-level enums are already registered by the resolver.
+func (tc *TypeChecker) checkEnumDeclaration(decl *ast.EnumDeclaration) {
+	if _, exists := tc.resolver.LookupLocal(decl.Name.Name); !exists {
+		tc.resolver.resolveEnumDeclaration(decl)
+	}
+	
+	seen := make(map[string]bool)
+	autoIncrement := true // false after a string member
+	for _, member := range decl.Members {
+		name := member.Name.Name
+		if seen[name] {
+			tc.addNodeError(member,
+				fmt.Sprintf("Duplicate enum member '%s'", name),
+				InvalidEnumMemberError,
+				"Give each enum member a unique name",
+				fmt.Sprintf("Enum '%s' already declares a member named '%s'", decl.Name.Name, name))
+		}
+		seen[name] = true
+		
+		switch value := member.Value.(type) {
+		case nil:
+			if !autoIncrement {
+				tc.addNodeError(member,
+					fmt.Sprintf("Enum member '%s' must have an initializer", name),
+					InvalidEnumMemberError,
+					fmt.Sprintf("Initialize '%s' with a number or string", name),
+					"Members following a string member cannot be auto-incremented")
+			}
+		case *ast.StringLiteral:
+			autoIncrement = false
+		default:
+			if !isConstantEnumValue(value) {
+				tc.addNodeError(member,
+					fmt.Sprintf("Enum member '%s' must be initialized with a constant number or string", name),
+					InvalidEnumMemberError,
+					"Use an integer or string literal as the member value",
+					fmt.Sprintf("Initializer '%s' is not a constant", value.String()))
+			}
+			autoIncrement = true
+		}
+	}
+}
+
+// isConstantEnumValue reports whether expr is a valid numeric enum initializer
+func isConstantEnumValue(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return true
+	case *ast.UnaryExpression:
+		_, ok := e.Operand.(*ast.IntegerLiteral)
+		return ok && e.Operator == lexer.SUB
+	default:
+		return false
+	}
+}
+
+// checkFunctionDeclaration type checks a function declaration
+func (tc *TypeChecker) checkFunctionDeclaration(decl *ast.FunctionDeclaration) {
+	// Enter function scope, where the signature can refer to the type
+	// parameters
 	tc.resolver.EnterScope()
 	defer tc.resolver.ExitScope()
+	funcType, paramTypes := tc.resolver.functionSignature(decl, UndefinedType)
+	returnType := funcType.ReturnType
+
+	// Register the function in the enclosing scope
+	tc.resolver.currentScope.Parent.Define(decl.Name.Name, &Symbol{
+		Name:     decl.Name.Name,
+		Type:     funcType,
+		Kind:     FunctionSymbol,
+		Position: decl.Name.Pos(),
+	})
 
 	// Add parameters to scope
 	for i, param := range decl.Parameters {
 		tc.resolver.Define(param.Name.Name, paramTypes[i], ParameterSymbol, param.Name.Pos())
 	}
+	tc.checkParameterDefaults(decl.Parameters, paramTypes)
+
+	// Track the declared return type while checking the body
+	var declaredReturnType Type
+	if decl.ReturnType != nil {
+		declaredReturnType = returnType
+	}
+	tc.pushReturnType(declaredReturnType)
+	defer tc.popReturnType()
 
 	// Check function body
 	if decl.Body != nil {
 		tc.checkBlockStatement(decl.Body)
+		if decl.ReturnType != nil {
+			tc.checkMissingReturn(decl.Body, decl.ReturnType, returnType)
+		}
+	}
+}
+
+// checkMissingReturn reports a function body that can end without a return
+// statement when its declared return type doesn't accept undefined
+func (tc *TypeChecker) checkMissingReturn(body *ast.BlockStatement, annotation ast.TypeNode, returnType Type) {
+	if acceptsUndefined(returnType) || !fallsThrough(body) {
+		return
+	}
+	tc.addNodeError(annotation,
+		fmt.Sprintf("Function lacks ending return statement and return type '%s' does not include 'undefined'", returnType.String()),
+		InvalidReturnTypeError,
+		fmt.Sprintf("Return a value of type '%s' at the end of the function", returnType.String()),
+		"Control can reach the end of the function body without returning a value")
+}
+
+// acceptsUndefined reports whether a function returning t may end without
+// returning a value
+func acceptsUndefined(t Type) bool {
+	if union, ok := t.(*UnionType); ok {
+		for _, member := range union.Types {
+			if acceptsUndefined(member) {
+				return true
+			}
+		}
+		return false
+	}
+	return t.Equals(VoidType) || t.Equals(UndefinedType) || t.Equals(AnyType)
+}
+
+// fallsThrough reports whether control can run past the end of stmt. A
+// break or continue leaves the enclosing loop or switch, which then falls
+// through; loops are assumed to end.
+func fallsThrough(stmt ast.Statement) bool {
+	switch s := stmt.(type) {
+	case *ast.BlockStatement:
+		return fallsThroughStatements(s.Body)
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return false
+	case *ast.IfStatement:
+		return s.Alternate == nil || fallsThrough(s.Consequent) || fallsThrough(s.Alternate)
+	case *ast.TryStatement:
+		if s.Finalizer != nil && !fallsThrough(s.Finalizer) {
+			return false
+		}
+		return fallsThrough(s.Block) || (s.Handler != nil && fallsThrough(s.Handler.Body))
+	case *ast.SwitchStatement:
+		// Without a default no case may match; otherwise every case runs
+		// on into the cases after it
+		hasDefault := false
+		for i, c := range s.Cases {
+			hasDefault = hasDefault || c.Test == nil
+			var stmts []ast.Statement
+			for _, next := range s.Cases[i:] {
+				stmts = append(stmts, next.Consequent...)
+			}
+			if fallsThroughStatements(stmts) {
+				return true
+			}
+		}
+		return !hasDefault
+	}
+	return true
+}
+
+// fallsThroughStatements reports whether control can run past the end of
+// a list of statements
+func fallsThroughStatements(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		if ast.TerminatesBlock(stmt) {
+			return fallsThrough(stmt)
+		}
+		if !fallsThrough(stmt) {
+			return false
+		}
 	}
+	return true
+}
 
-	// TODO: Check return type compatibility
+// checkParameterDefaults checks that the default value of each annotated
+// parameter is assignable to its type. Defaults may refer to the parameters
+// already in scope.
+func (tc *TypeChecker) checkParameterDefaults(params []*ast.Parameter, paramTypes []Type) {
+	for i, param := range params {
+		if param.DefaultValue == nil {
+			continue
+		}
+		if param.TypeAnnotation == nil {
+			tc.checkExpression(param.DefaultValue)
+			continue
+		}
+		defaultType := tc.checkValue(param.DefaultValue, paramTypes[i])
+		if !tc.isAssignableExpr(param.DefaultValue, defaultType, paramTypes[i]) {
+			tc.addNodeError(param.DefaultValue,
+				fmt.Sprintf("Default value of type '%s' is not assignable to parameter of type '%s'",
+					defaultType.String(), paramTypes[i].String()),
+				TypeMismatchError,
+				fmt.Sprintf("Use a default value of type '%s'", paramTypes[i].String()),
+				fmt.Sprintf("Parameter '%s' is declared as '%s'", param.Name.Name, paramTypes[i].String()))
+		}
+	}
 }
 
 // checkExpression type checks an expression
@@ -251,8 +718,31 @@ func (tc *TypeChecker) checkExpression(expr ast.Expression) Type {
 		return tc.checkAssignmentExpression(e)
 	case *ast.ArrayLiteral:
 		return tc.checkArrayLiteral(e)
+	case *ast.ObjectLiteral:
+		return tc.checkObjectLiteral(e, nil)
 	case *ast.ArrowFunctionExpression:
 		return tc.checkArrowFunctionExpression(e)
+	case *ast.FunctionExpression:
+		return tc.checkFunctionExpression(e)
+	case *ast.ConditionalExpression:
+		return tc.checkConditionalExpression(e)
+	case *ast.SpreadElement:
+		// Literals and calls check their spread elements themselves
+		tc.checkExpression(e.Argument)
+		tc.addNodeError(e,
+			"Spread syntax is only supported in array literals, object literals and argument lists",
+			InvalidCallError,
+			"Remove the '...' or move it into a literal or call",
+			fmt.Sprintf("Spreading '%s'", e.Argument.String()))
+		return UndefinedType
+	case *ast.SequenceExpression:
+		var result Type = UndefinedType
+		for _, expr := range e.Expressions {
+			result = tc.checkExpression(expr)
+		}
+		return result
+	case *ast.TypeAssertion:
+		return tc.checkTypeAssertion(e)
 	case *ast.Identifier:
 		return tc.checkIdentifier(e)
 	default:
@@ -268,6 +758,85 @@ func (tc *TypeChecker) checkBinaryExpression(expr *ast.BinaryExpression) Type {
 	operator := expr.Operator.String()
 
 	// Type compatibility checks
+	switch operator {
+	case "+", "-", "*", "/", "%", "**":
+		// Literals take the type of a sized numeric operand they fit in
+		if IsNumericType(leftType) && IsNumericType(rightType) {
+			if tc.isAssignableExpr(expr.Right, rightType, leftType) {
+				rightType = leftType
+			} else if tc.isAssignableExpr(expr.Left, leftType, rightType) {
+				leftType = rightType
+			}
+		}
+		resultType := tc.checkArithmeticOperation(expr, operator, leftType, rightType)
+		if operator == "**" && IsIntegerType(resultType) {
+			if exponent, ok := constantInteger(expr.Right); ok && exponent < 0 {
+				tc.addNodeError(expr.Right,
+					fmt.Sprintf("Negative exponent %d cannot be applied to an integer base", exponent),
+					InvalidOperatorError,
+					"Use a float base, e.g. '2.0 ** -1', for fractional results",
+					fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String()))
+			}
+		}
+		return resultType
+
+	case "&", "|", "^", "<<", ">>", ">>>":
+		if IsIntegerType(leftType) && IsIntegerType(rightType) {
+			if tc.isAssignableExpr(expr.Right, rightType, leftType) {
+				rightType = leftType
+			} else if tc.isAssignableExpr(expr.Left, leftType, rightType) {
+				leftType = rightType
+			}
+		}
+		return tc.checkBitwiseOperation(expr, operator, leftType, rightType)
+
+	case "==", "!=":
+		// Allow comparison of any types
+		return BooleanType
+
+	case "===", "!==":
+		if tc.disjointTypes(leftType, rightType) {
+			result := "false"
+			if operator == "!==" {
+				result = "true"
+			}
+			tc.addOperatorError(expr,
+				fmt.Sprintf("This comparison always returns %s since types '%s' and '%s' have no overlap",
+					result, leftType.String(), rightType.String()),
+				TypeMismatchError,
+				"Compare values of the same type, or convert one operand first",
+				fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String()))
+		}
+		return BooleanType
+
+	case "<", ">", "<=", ">=":
+		if !IsNumericType(leftType) || !IsNumericType(rightType) {
+			suggestion := "Use numeric types (int or float) for comparison operations"
+			context := fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String())
+			tc.addOperatorError(expr,
+				fmt.Sprintf("Cannot compare non-numeric types '%s' and '%s'",
+					leftType.String(), rightType.String()),
+				InvalidOperatorError,
+				suggestion,
+				context)
+		}
+		return BooleanType
+
+	case "&&", "||":
+		return BooleanType
+
+	case "??":
+		return nullishFallbackType(leftType, rightType)
+
+	default:
+		return tc.inferrer.InferType(expr)
+	}
+}
+
+// checkArithmeticOperation checks the operand types of an arithmetic operator
+// and returns the result type. It is shared by binary expressions and
+// compound assignments.
+func (tc *TypeChecker) checkArithmeticOperation(node ast.Node, operator string, leftType, rightType Type) Type {
 	switch operator {
 	case "+":
 		// If either operand is AnyType, allow the operation (TypeScript behavior)
@@ -279,14 +848,11 @@ func (tc *TypeChecker) checkBinaryExpression(expr *ast.BinaryExpression) Type {
 			return StringType
 		}
 		if IsNumericType(leftType) && IsNumericType(rightType) {
-			if leftType.Equals(FloatType) || rightType.Equals(FloatType) {
-				return FloatType
-			}
-			return IntType
+			return arithmeticResultType(leftType, rightType)
 		}
 		suggestion := fmt.Sprintf("Use numeric types (int or float) with operator '%s'", operator)
 		context := fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String())
-		tc.addDetailedError(expr.Pos(),
+		tc.addOperatorError(node,
 			fmt.Sprintf("Cannot apply operator '%s' to types '%s' and '%s'",
 				operator, leftType.String(), rightType.String()),
 			InvalidOperatorError,
@@ -294,7 +860,7 @@ func (tc *TypeChecker) checkBinaryExpression(expr *ast.BinaryExpression) Type {
 			context)
 		return UndefinedType
 
-	case "-", "*", "/", "%":
+	case "-", "*", "/", "%", "**":
 		// If either operand is AnyType, allow the operation (TypeScript behavior)
 		if leftType.Equals(AnyType) || rightType.Equals(AnyType) {
 			return AnyType
@@ -302,7 +868,7 @@ func (tc *TypeChecker) checkBinaryExpression(expr *ast.BinaryExpression) Type {
 		if !IsNumericType(leftType) || !IsNumericType(rightType) {
 			suggestion := fmt.Sprintf("Convert operands to numeric types (int or float) before using '%s'", operator)
 			context := fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String())
-			tc.addDetailedError(expr.Pos(),
+			tc.addOperatorError(node,
 				fmt.Sprintf("Cannot apply operator '%s' to non-numeric types '%s' and '%s'",
 					operator, leftType.String(), rightType.String()),
 				InvalidOperatorError,
@@ -310,34 +876,63 @@ func (tc *TypeChecker) checkBinaryExpression(expr *ast.BinaryExpression) Type {
 				context)
 			return UndefinedType
 		}
-		if leftType.Equals(FloatType) || rightType.Equals(FloatType) {
-			return FloatType
-		}
-		return IntType
-
-	case "==", "!=":
-		// Allow comparison of any types
-		return BooleanType
+		return arithmeticResultType(leftType, rightType)
+	}
+	return UndefinedType
+}
 
-	case "<", ">", "<=", ">=":
-		if !IsNumericType(leftType) || !IsNumericType(rightType) {
-			suggestion := "Use numeric types (int or float) for comparison operations"
-			context := fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String())
-			tc.addDetailedError(expr.Pos(),
-				fmt.Sprintf("Cannot compare non-numeric types '%s' and '%s'",
-					leftType.String(), rightType.String()),
-				InvalidOperatorError,
-				suggestion,
-				context)
+// checkBitwiseOperation checks that both operands of a bitwise or shift
+// operator are integers and returns the result type. Shifts keep the type of
+// the left operand.
+func (tc *TypeChecker) checkBitwiseOperation(node ast.Node, operator string, leftType, rightType Type) Type {
+	// If either operand is AnyType, allow the operation (TypeScript behavior)
+	if leftType.Equals(AnyType) || rightType.Equals(AnyType) {
+		return AnyType
+	}
+	if !IsIntegerType(leftType) || !IsIntegerType(rightType) {
+		suggestion := fmt.Sprintf("Use integer operands with '%s'", operator)
+		if IsNumericType(leftType) && IsNumericType(rightType) {
+			suggestion = fmt.Sprintf("Convert float operands explicitly, e.g. 'x as int', before using '%s'", operator)
 		}
-		return BooleanType
-
-	case "&&", "||":
-		return BooleanType
+		context := fmt.Sprintf("Left operand: %s, Right operand: %s", leftType.String(), rightType.String())
+		tc.addOperatorError(node,
+			fmt.Sprintf("Cannot apply operator '%s' to types '%s' and '%s'; bitwise operators require integers",
+				operator, leftType.String(), rightType.String()),
+			InvalidOperatorError,
+			suggestion,
+			context)
+		return UndefinedType
+	}
+	switch operator {
+	case "<<", ">>", ">>>":
+		return leftType
+	}
+	return arithmeticResultType(leftType, rightType)
+}
 
-	default:
-		return tc.inferrer.InferType(expr)
+// arithmeticResultType returns the type of an arithmetic operation on two
+// numeric operands: the wider of the two, or float if either is a float type
+func arithmeticResultType(leftType, rightType Type) Type {
+	if leftType.Equals(rightType) {
+		return leftType
+	}
+	
+	leftBits, leftFloat := numericWidth(leftType)
+	rightBits, rightFloat := numericWidth(rightType)
+	if leftFloat != rightFloat {
+		return FloatType
+	}
+	if leftBits == 64 && rightBits == 64 {
+		// int and int64 (or float and float64) mix as the plain type
+		if leftFloat {
+			return FloatType
+		}
+		return IntType
 	}
+	if leftBits >= rightBits {
+		return leftType
+	}
+	return rightType
 }
 
 // checkUnaryExpression type checks a unary expression
@@ -354,7 +949,7 @@ func (tc *TypeChecker) checkUnaryExpression(expr *ast.UnaryExpression) Type {
 		if !IsNumericType(operandType) {
 			suggestion := fmt.Sprintf("Use numeric types (int or float) with unary operator '%s'", operator)
 			context := fmt.Sprintf("Operand type: %s", operandType.String())
-			tc.addDetailedError(expr.Pos(),
+			tc.addOperatorError(expr,
 				fmt.Sprintf("Cannot apply unary operator '%s' to non-numeric type '%s'",
 					operator, operandType.String()),
 				InvalidOperatorError,
@@ -367,6 +962,29 @@ func (tc *TypeChecker) checkUnaryExpression(expr *ast.UnaryExpression) Type {
 	case "!":
 		return BooleanType
 
+	case "typeof":
+		return StringType
+
+	case "~":
+		// If operand is AnyType, allow the operation (TypeScript behavior)
+		if operandType.Equals(AnyType) {
+			return AnyType
+		}
+		if !IsIntegerType(operandType) {
+			suggestion := "Use an integer operand with unary operator '~'"
+			if IsNumericType(operandType) {
+				suggestion = "Convert the operand explicitly, e.g. 'x as int', before using '~'"
+			}
+			context := fmt.Sprintf("Operand type: %s", operandType.String())
+			tc.addOperatorError(expr,
+				fmt.Sprintf("Cannot apply unary operator '~' to non-integer type '%s'", operandType.String()),
+				InvalidOperatorError,
+				suggestion,
+				context)
+			return UndefinedType
+		}
+		return operandType
+
 	case "++", "--":
 		// If operand is AnyType, allow the operation (TypeScript behavior)
 		if operandType.Equals(AnyType) {
@@ -375,7 +993,7 @@ func (tc *TypeChecker) checkUnaryExpression(expr *ast.UnaryExpression) Type {
 		if !IsNumericType(operandType) {
 			suggestion := fmt.Sprintf("Use numeric types (int or float) with operator '%s'", operator)
 			context := fmt.Sprintf("Operand type: %s", operandType.String())
-			tc.addDetailedError(expr.Pos(),
+			tc.addOperatorError(expr,
 				fmt.Sprintf("Cannot apply operator '%s' to non-numeric type '%s'",
 					operator, operandType.String()),
 				InvalidOperatorError,
@@ -392,65 +1010,376 @@ func (tc *TypeChecker) checkUnaryExpression(expr *ast.UnaryExpression) Type {
 
 // checkCallExpression type checks a call expression
 func (tc *TypeChecker) checkCallExpression(expr *ast.CallExpression) Type {
-	calleeType := tc.checkExpression(expr.Callee)
-
-	if funcType, ok := calleeType.(*FunctionType); ok {
-		// Check argument count for non-variadic functions
-		if !funcType.Variadic {
-			if len(expr.Arguments) != len(funcType.Parameters) {
-				suggestion := fmt.Sprintf("Provide exactly %d arguments to match function signature", len(funcType.Parameters))
-				context := fmt.Sprintf("Function signature requires %d parameters", len(funcType.Parameters))
-				tc.addDetailedError(expr.Pos(),
-					fmt.Sprintf("Expected %d arguments, got %d",
-						len(funcType.Parameters), len(expr.Arguments)),
-					ArgumentCountMismatchError,
-					suggestion,
-					context)
-			}
-		} else {
-			// For variadic functions, check minimum argument count
-			if len(expr.Arguments) < len(funcType.Parameters) {
-				suggestion := fmt.Sprintf("Provide at least %d arguments for this variadic function", len(funcType.Parameters))
-				context := fmt.Sprintf("Variadic function requires minimum %d parameters", len(funcType.Parameters))
-				tc.addDetailedError(expr.Pos(),
-					fmt.Sprintf("Expected at least %d arguments, got %d",
-						len(funcType.Parameters), len(expr.Arguments)),
-					ArgumentCountMismatchError,
-					suggestion,
-					context)
+	calleeType := tc.arrayBuiltinType(expr, tc.checkExpression(expr.Callee))
+	
+	// An optional chain stops at a null or undefined link, so the call only
+	// happens on the rest and may produce undefined instead
+	if expr.Optional || ast.IsOptionalChain(expr.Callee) {
+		calleeType = withoutNullish(calleeType)
+		if isUnknownType(calleeType) {
+			for _, arg := range expr.Arguments {
+				if spread, ok := arg.(*ast.SpreadElement); ok {
+					tc.checkArraySpread(spread)
+				} else {
+					tc.checkExpression(arg)
+				}
 			}
+			return calleeType
 		}
+		return orUndefined(tc.checkCall(expr, calleeType))
+	}
+	return tc.checkCall(expr, calleeType)
+}
 
-		// Check argument types
-		for i, arg := range expr.Arguments {
-			argType := tc.checkExpression(arg)
+// arrayBuiltinType specializes the type of a call to the push and pop
+// builtins to the element type of the array they're given
+func (tc *TypeChecker) arrayBuiltinType(expr *ast.CallExpression, calleeType Type) Type {
+	builtin, ok := calleeType.(*FunctionType)
+	if !ok || (builtin != pushBuiltinType && builtin != popBuiltinType) || len(expr.Arguments) == 0 {
+		return calleeType
+	}
+	arrayType, ok := tc.inferrer.InferType(expr.Arguments[0]).(*ArrayType)
+	if !ok {
+		return calleeType
+	}
+	if builtin == popBuiltinType {
+		return NewFunctionType([]Type{arrayType}, arrayType.ElementType)
+	}
+	return &FunctionType{Parameters: []Type{arrayType}, ReturnType: IntType, Variadic: true, RestType: arrayType.ElementType}
+}
 
-			if i < len(funcType.Parameters) {
-				// Check regular parameters
+// instantiate infers the type arguments of a call to a generic function
+// from the types of its arguments and returns the function's type with
+// them substituted for its type parameters. A type parameter no argument
+// determines is its constraint, or 'any'.
+func (tc *TypeChecker) instantiate(expr *ast.CallExpression, fn *FunctionType) *FunctionType {
+	bindings := make(map[*TypeVariable]Type)
+	for _, param := range fn.TypeParameters {
+		bindings[param] = nil
+	}
+	// sources records the argument each type parameter was last bound from
+	sources := make(map[*TypeVariable]ast.Expression)
+	for i, arg := range expr.Arguments {
+		if _, ok := arg.(*ast.SpreadElement); ok {
+			break
+		}
+		paramType, ok := parameterAt(fn, i)
+		if !ok {
+			break
+		}
+		before := make(map[*TypeVariable]Type, len(bindings))
+		for param, bound := range bindings {
+			before[param] = bound
+		}
+		tc.inferTypeArguments(paramType, tc.argumentType(arg), bindings)
+		for param, bound := range bindings {
+			if bound != before[param] {
+				sources[param] = arg
+			}
+		}
+	}
+	
+	for _, param := range fn.TypeParameters {
+		bound := bindings[param]
+		if bound == nil {
+			bindings[param] = typeParameterBound(param)
+			continue
+		}
+		if param.Constraint != nil && !tc.isAssignable(bound, param.Constraint) {
+			tc.addNodeError(sources[param],
+				fmt.Sprintf("Type '%s' does not satisfy the constraint '%s' of type parameter '%s'",
+					bound.String(), param.Constraint.String(), param.Name),
+				TypeMismatchError,
+				fmt.Sprintf("Pass arguments whose type is assignable to '%s'", param.Constraint.String()),
+				fmt.Sprintf("'%s' is inferred as '%s' from the arguments of '%s'", param.Name, bound.String(), expr.Callee.String()))
+		}
+	}
+	return substitute(fn, bindings).(*FunctionType)
+}
+
+// argumentType returns the type of a call argument without reporting the
+// errors in it, which are reported when the call's arguments are checked.
+// Function literals aren't checked twice; their type is only inferred.
+func (tc *TypeChecker) argumentType(arg ast.Expression) Type {
+	switch arg.(type) {
+	case *ast.ArrowFunctionExpression, *ast.FunctionExpression:
+		return tc.inferrer.InferType(arg)
+	}
+	errors, warnings := len(tc.errors), len(tc.warnings)
+	argType := tc.checkExpression(arg)
+	tc.errors, tc.warnings = tc.errors[:errors], tc.warnings[:warnings]
+	return argType
+}
+
+// inferTypeArguments binds the type parameters in bindings that appear in
+// a parameter's type to the matching parts of an argument's type. A later
+// argument may widen a binding, as in pair(1, 2.5) binding T to float.
+func (tc *TypeChecker) inferTypeArguments(paramType, argType Type, bindings map[*TypeVariable]Type) {
+	switch param := paramType.(type) {
+	case *TypeVariable:
+		bound, ok := bindings[param]
+		if !ok || argType.Equals(UndefinedType) {
+			return
+		}
+		if bound == nil || (tc.isAssignable(bound, argType) && !tc.isAssignable(argType, bound)) {
+			bindings[param] = argType
+		}
+	case *ArrayType:
+		switch arg := argType.(type) {
+		case *ArrayType:
+			tc.inferTypeArguments(param.ElementType, arg.ElementType, bindings)
+		case *TupleType:
+			tc.inferTypeArguments(param.ElementType, arg.ElementType(), bindings)
+		}
+	case *TupleType:
+		if arg, ok := argType.(*TupleType); ok && len(arg.ElementTypes) == len(param.ElementTypes) {
+			for i, element := range param.ElementTypes {
+				tc.inferTypeArguments(element, arg.ElementTypes[i], bindings)
+			}
+		}
+	case *FunctionType:
+		if arg, ok := argType.(*FunctionType); ok {
+			for i, paramType := range param.Parameters {
+				if i < len(arg.Parameters) {
+					tc.inferTypeArguments(paramType, arg.Parameters[i], bindings)
+				}
+			}
+			tc.inferTypeArguments(param.ReturnType, arg.ReturnType, bindings)
+		}
+	case *ObjectType:
+		if arg, ok := argType.(*ObjectType); ok {
+			for name, propType := range param.Properties {
+				if argProp, ok := arg.Properties[name]; ok {
+					tc.inferTypeArguments(propType, argProp, bindings)
+				}
+			}
+		}
+	}
+}
+
+// typeParameterBound returns the type a type parameter stands for when
+// nothing binds it: its constraint, or 'any'
+func typeParameterBound(param *TypeVariable) Type {
+	if param.Constraint != nil {
+		return param.Constraint
+	}
+	return AnyType
+}
+
+// substitute returns t with the type variables in bindings replaced by
+// their bound types. Types that mention none of them are returned as is.
+func substitute(t Type, bindings map[*TypeVariable]Type) Type {
+	switch t := t.(type) {
+	case *TypeVariable:
+		if bound, ok := bindings[t]; ok && bound != nil {
+			return bound
+		}
+	case *ArrayType:
+		if element := substitute(t.ElementType, bindings); element != t.ElementType {
+			return NewArrayType(element)
+		}
+	case *TupleType:
+		elements, changed := substituteAll(t.ElementTypes, bindings)
+		if changed {
+			return NewTupleType(elements...)
+		}
+	case *UnionType:
+		members, changed := substituteAll(t.Types, bindings)
+		if changed {
+			return NewUnionType(members...)
+		}
+	case *FunctionType:
+		params, changed := substituteAll(t.Parameters, bindings)
+		returnType := substitute(t.ReturnType, bindings)
+		var restType Type
+		if t.RestType != nil {
+			restType = substitute(t.RestType, bindings)
+		}
+		if changed || returnType != t.ReturnType || restType != t.RestType {
+			instance := *t
+			instance.Parameters = params
+			instance.ReturnType = returnType
+			instance.RestType = restType
+			instance.TypeParameters = nil
+			return &instance
+		}
+	case *ObjectType:
+		var instance *ObjectType
+		for name, propType := range t.Properties {
+			if substituted := substitute(propType, bindings); substituted != propType {
+				if instance == nil {
+					instance = NewObjectType(make(map[string]Type))
+					instance.Name = t.Name
+					for name, propType := range t.Properties {
+						instance.Properties[name] = propType
+						instance.Optional[name] = t.Optional[name]
+					}
+				}
+				instance.Properties[name] = substituted
+			}
+		}
+		if instance != nil {
+			return instance
+		}
+	}
+	return t
+}
+
+// substituteAll substitutes bindings in each of types and reports whether
+// any of them changed
+func substituteAll(types []Type, bindings map[*TypeVariable]Type) ([]Type, bool) {
+	result := make([]Type, len(types))
+	changed := false
+	for i, t := range types {
+		result[i] = substitute(t, bindings)
+		if result[i] != t {
+			changed = true
+		}
+	}
+	return result, changed
+}
+
+// checkCall checks a call's arguments against the callee's type and returns
+// the call's result type
+func (tc *TypeChecker) checkCall(expr *ast.CallExpression, calleeType Type) Type {
+	if funcType, ok := calleeType.(*FunctionType); ok {
+		if len(funcType.TypeParameters) > 0 {
+			funcType = tc.instantiate(expr, funcType)
+		}
+		
+		// A spread argument supplies an unknown number of values, so only the
+		// fixed arguments can be counted
+		fixedArgs := 0
+		for _, arg := range expr.Arguments {
+			if _, ok := arg.(*ast.SpreadElement); !ok {
+				fixedArgs++
+			}
+		}
+		
+		// Check argument count for non-variadic functions
+		minArgs := funcType.MinArgs()
+		if fixedArgs < len(expr.Arguments) {
+			if !funcType.Variadic && fixedArgs > len(funcType.Parameters) {
+				tc.addNodeError(expr,
+					fmt.Sprintf("Expected at most %d arguments, got %d before spreading",
+						len(funcType.Parameters), fixedArgs),
+					ArgumentCountMismatchError,
+					fmt.Sprintf("Pass at most %d arguments to match function signature", len(funcType.Parameters)),
+					fmt.Sprintf("Function signature has %d parameters", len(funcType.Parameters)))
+			}
+		} else if !funcType.Variadic && funcType.Optional == 0 {
+			if len(expr.Arguments) != len(funcType.Parameters) {
+				suggestion := fmt.Sprintf("Provide exactly %d arguments to match function signature", len(funcType.Parameters))
+				context := fmt.Sprintf("Function signature requires %d parameters", len(funcType.Parameters))
+				tc.addNodeError(expr,
+					fmt.Sprintf("Expected %d arguments, got %d",
+						len(funcType.Parameters), len(expr.Arguments)),
+					ArgumentCountMismatchError,
+					suggestion,
+					context)
+			}
+		} else if !funcType.Variadic {
+			// Trailing optional parameters may be omitted
+			if len(expr.Arguments) < minArgs || len(expr.Arguments) > len(funcType.Parameters) {
+				suggestion := fmt.Sprintf("Provide between %d and %d arguments to match function signature", minArgs, len(funcType.Parameters))
+				context := fmt.Sprintf("Function signature has %d required and %d optional parameters", minArgs, funcType.Optional)
+				tc.addNodeError(expr,
+					fmt.Sprintf("Expected %d-%d arguments, got %d",
+						minArgs, len(funcType.Parameters), len(expr.Arguments)),
+					ArgumentCountMismatchError,
+					suggestion,
+					context)
+			}
+		} else {
+			// For variadic functions, check minimum argument count
+			if len(expr.Arguments) < minArgs {
+				suggestion := fmt.Sprintf("Provide at least %d arguments for this variadic function", minArgs)
+				context := fmt.Sprintf("Variadic function requires minimum %d parameters", minArgs)
+				tc.addNodeError(expr,
+					fmt.Sprintf("Expected at least %d arguments, got %d",
+						minArgs, len(expr.Arguments)),
+					ArgumentCountMismatchError,
+					suggestion,
+					context)
+			}
+		}
+
+		// Check argument types
+		for i, arg := range expr.Arguments {
+			if spread, ok := arg.(*ast.SpreadElement); ok {
+				// Every element may land in any of the remaining parameters,
+				// and later arguments no longer have a known position
+				if i >= len(funcType.Parameters) && fixedArgs <= len(funcType.Parameters) && !funcType.Variadic {
+					tc.addNodeError(spread,
+						fmt.Sprintf("Spread argument has no parameter to fill; the function takes %d arguments",
+							len(funcType.Parameters)),
+						ArgumentCountMismatchError,
+						"Remove the spread or pass it to a function with a rest parameter",
+						fmt.Sprintf("Spreading '%s' into a call to '%s'", spread.Argument.String(), expr.Callee.String()))
+				}
+				params := funcType.Parameters[min(i, len(funcType.Parameters)):]
+				if funcType.RestType != nil {
+					params = append(params[:len(params):len(params)], funcType.RestType)
+				}
+				tc.checkSpreadArgument(expr, spread, params)
+				for _, rest := range expr.Arguments[i+1:] {
+					if spread, ok := rest.(*ast.SpreadElement); ok {
+						tc.checkArraySpread(spread)
+					} else {
+						tc.checkExpression(rest)
+					}
+				}
+				break
+			}
+			paramType, _ := parameterAt(funcType, i)
+			argType := tc.checkValue(arg, paramType)
+
+			if i < len(funcType.Parameters) {
+				// Check regular parameters
 				expectedType := funcType.Parameters[i]
-				if !tc.isAssignable(argType, expectedType) {
+				if !tc.isAssignableExpr(arg, argType, expectedType) {
 					suggestion := fmt.Sprintf("Convert argument %d to type '%s' or check function signature", i+1, expectedType.String())
 					context := fmt.Sprintf("Function expects parameter %d of type '%s', but got '%s'", i+1, expectedType.String(), argType.String())
-					tc.addDetailedError(expr.Pos(),
-						fmt.Sprintf("Argument %d: cannot assign type '%s' to parameter of type '%s'",
-							i+1, argType.String(), expectedType.String()),
+					tc.addNodeError(arg,
+						fmt.Sprintf("Argument %d: cannot assign type '%s' to parameter of type '%s'%s",
+							i+1, argType.String(), expectedType.String(), tc.mismatchDetail(argType, expectedType)),
 						ArgumentCountMismatchError,
 						suggestion,
 						context)
 				}
-			} else if funcType.Variadic {
-				// For variadic arguments, we accept any type for now
-				// In a more sophisticated implementation, we would check against the variadic parameter type
-				continue
+			} else if funcType.Variadic && funcType.RestType != nil {
+				// Surplus arguments are collected by the rest parameter
+				if !tc.isAssignableExpr(arg, argType, funcType.RestType) {
+					tc.addNodeError(arg,
+						fmt.Sprintf("Argument %d: cannot assign type '%s' to rest parameter of type '%s'",
+							i+1, argType.String(), NewArrayType(funcType.RestType).String()),
+						ArgumentCountMismatchError,
+						fmt.Sprintf("Pass values of type '%s' to the rest parameter", funcType.RestType.String()),
+						fmt.Sprintf("Function collects arguments from %d on into '%s'", len(funcType.Parameters)+1,
+							NewArrayType(funcType.RestType).String()))
+				}
 			}
 		}
 
 		return funcType.ReturnType
 	}
 
+	for _, arg := range expr.Arguments {
+		if spread, ok := arg.(*ast.SpreadElement); ok {
+			tc.checkArraySpread(spread)
+		} else {
+			tc.checkExpression(arg)
+		}
+	}
+	
+	// If callee is AnyType, allow the call (TypeScript behavior)
+	if calleeType.Equals(AnyType) {
+		return AnyType
+	}
+	
 	suggestion := "Ensure the expression evaluates to a function before calling it"
 	context := fmt.Sprintf("Attempting to call expression of type '%s'", calleeType.String())
-	tc.addDetailedError(expr.Pos(),
+	tc.addNodeError(expr,
 		fmt.Sprintf("Cannot call non-function type '%s'", calleeType.String()),
 		InvalidCallError,
 		suggestion,
@@ -461,13 +1390,14 @@ func (tc *TypeChecker) checkCallExpression(expr *ast.CallExpression) Type {
 // checkIdentifier type checks an identifier and reports undefined variables/functions
 func (tc *TypeChecker) checkIdentifier(expr *ast.Identifier) Type {
 	if symbol, exists := tc.resolver.Lookup(expr.Name); exists {
+		symbol.Used = true
 		return symbol.Type
 	}
 	// In strict mode, report undefined identifiers as errors
 	if tc.strictMode {
 		suggestion := fmt.Sprintf("Declare '%s' before using it, or check for typos", expr.Name)
 		context := fmt.Sprintf("Identifier '%s' is not defined in the current scope", expr.Name)
-		tc.addDetailedError(expr.Pos(),
+		tc.addNodeError(expr,
 			fmt.Sprintf("Undefined identifier '%s'", expr.Name),
 			UndefinedIdentifierError,
 			suggestion,
@@ -480,6 +1410,45 @@ func (tc *TypeChecker) checkIdentifier(expr *ast.Identifier) Type {
 // checkMemberExpression type checks a member expression
 func (tc *TypeChecker) checkMemberExpression(expr *ast.MemberExpression) Type {
 	objectType := tc.checkExpression(expr.Object)
+	
+	// An optional chain stops at a null or undefined link, so the member is
+	// read from the rest and may be undefined instead
+	if expr.Optional || ast.IsOptionalChain(expr.Object) {
+		return orUndefined(tc.memberType(expr, withoutNullish(objectType)))
+	}
+	return tc.memberType(expr, objectType)
+}
+
+// memberType checks the property of a member expression and returns the
+// type of the member read from a value of objectType
+func (tc *TypeChecker) memberType(expr *ast.MemberExpression, objectType Type) Type {
+	// Values of a type parameter have the members of its constraint
+	if variable, ok := objectType.(*TypeVariable); ok && variable.Constraint != nil {
+		objectType = variable.Constraint
+	}
+	if tuple, ok := objectType.(*TupleType); ok && expr.Computed {
+		indexType := tc.checkExpression(expr.Property)
+		if !IsNumericType(indexType) {
+			tc.addNodeError(expr,
+				fmt.Sprintf("Tuple index must be numeric, got '%s'", indexType.String()),
+				InvalidArrayElementError,
+				"Use numeric types (int or float) for tuple indexing",
+				fmt.Sprintf("Index type: %s", indexType.String()))
+		}
+		// A constant index selects the type of its position
+		if index, ok := constantInteger(expr.Property); ok {
+			if index < 0 || index >= int64(len(tuple.ElementTypes)) {
+				tc.addNodeError(expr,
+					fmt.Sprintf("Tuple index %d is out of range for '%s'", index, tuple.String()),
+					InvalidArrayElementError,
+					fmt.Sprintf("Use an index below %d", len(tuple.ElementTypes)),
+					fmt.Sprintf("Tuple '%s' has %d elements", expr.Object.String(), len(tuple.ElementTypes)))
+				return UndefinedType
+			}
+			return tuple.ElementTypes[index]
+		}
+		return tuple.ElementType()
+	}
 
 	if arrayType, ok := objectType.(*ArrayType); ok {
 		if expr.Computed {
@@ -488,16 +1457,33 @@ func (tc *TypeChecker) checkMemberExpression(expr *ast.MemberExpression) Type {
 			if !IsNumericType(indexType) {
 				suggestion := "Use numeric types (int or float) for array indexing"
 				context := fmt.Sprintf("Index type: %s", indexType.String())
-				tc.addDetailedError(expr.Pos(),
+				tc.addNodeError(expr,
 					fmt.Sprintf("Array index must be numeric, got '%s'", indexType.String()),
 					InvalidArrayElementError,
 					suggestion,
 					context)
 			}
+			tc.checkNegativeIndex(expr)
 			return arrayType.ElementType
 		}
 	}
 
+	// Indexing a string yields a one-character string
+	if IsStringType(objectType) && expr.Computed {
+		indexType := tc.checkExpression(expr.Property)
+		if !IsNumericType(indexType) {
+			suggestion := "Use numeric types (int or float) for string indexing"
+			context := fmt.Sprintf("Index type: %s", indexType.String())
+			tc.addNodeError(expr,
+				fmt.Sprintf("String index must be numeric, got '%s'", indexType.String()),
+				InvalidMemberAccessError,
+				suggestion,
+				context)
+		}
+		tc.checkNegativeIndex(expr)
+		return StringType
+	}
+
 	// Handle object property access
 	if objType, ok := objectType.(*ObjectType); ok {
 		if !expr.Computed {
@@ -509,7 +1495,7 @@ func (tc *TypeChecker) checkMemberExpression(expr *ast.MemberExpression) Type {
 				if tc.strictMode {
 					suggestion := fmt.Sprintf("Check if property '%s' exists or verify the object type", propIdent.Name)
 					context := fmt.Sprintf("Accessing property '%s' on object of type '%s'", propIdent.Name, objectType.String())
-					tc.addDetailedError(expr.Pos(),
+					tc.addNodeError(expr,
 						fmt.Sprintf("Property '%s' does not exist on object", propIdent.Name),
 						InvalidMemberAccessError,
 						suggestion,
@@ -517,29 +1503,65 @@ func (tc *TypeChecker) checkMemberExpression(expr *ast.MemberExpression) Type {
 				}
 			}
 		} else {
-			// Computed property access like obj[prop]
+			// Computed property access like obj[prop]; numbers are converted to strings at runtime
 			propType := tc.checkExpression(expr.Property)
-			if !IsStringType(propType) {
-				suggestion := "Use string type for object property keys"
+			if !IsStringType(propType) && !IsNumericType(propType) {
+				suggestion := "Use string or numeric types for object property keys"
 				context := fmt.Sprintf("Property key type: %s", propType.String())
-				tc.addDetailedError(expr.Pos(),
-					fmt.Sprintf("Object property key must be string, got '%s'", propType.String()),
+				tc.addNodeError(expr,
+					fmt.Sprintf("Object property key must be string or number, got '%s'", propType.String()),
 					InvalidMemberAccessError,
 					suggestion,
 					context)
 			}
-			// For computed access, we can't determine the exact property type at compile time
-			return UndefinedType
+			// A literal key names a known property; otherwise the property
+			// can't be determined at compile time (e.g. keys from for...in)
+			if key, ok := expr.Property.(*ast.StringLiteral); ok {
+				if propType, exists := objType.Properties[key.Value]; exists {
+					return propType
+				}
+			}
+			return AnyType
 		}
 	}
 
 	return UndefinedType
 }
 
+// checkAssignmentTarget type checks the left side of an assignment. A plain
+// assignment writes a variable without reading it. In strict mode the
+// variable must have been declared.
+func (tc *TypeChecker) checkAssignmentTarget(expr *ast.AssignmentExpression) Type {
+	if id, ok := expr.Left.(*ast.Identifier); ok {
+		symbol, exists := tc.resolver.Lookup(id.Name)
+		if !exists {
+			if tc.strictMode {
+				suggestion := fmt.Sprintf("Declare '%s' with 'let' or 'const' before assigning to it, or check for typos", id.Name)
+				context := fmt.Sprintf("Identifier '%s' is not defined in the current scope", id.Name)
+				tc.addNodeError(id,
+					fmt.Sprintf("Cannot assign to undeclared variable '%s'", id.Name),
+					AssignmentToUndeclaredError,
+					suggestion,
+					context)
+			}
+			// The assignment creates a global of any type
+			return AnyType
+		}
+		if expr.Operator == lexer.ASSIGN {
+			// A narrowed variable can still be assigned any value of its declared type
+			if symbol.DeclaredType != nil {
+				return symbol.DeclaredType
+			}
+			return symbol.Type
+		}
+	}
+	return tc.checkExpression(expr.Left)
+}
+
 // checkAssignmentExpression type checks an assignment expression
 func (tc *TypeChecker) checkAssignmentExpression(expr *ast.AssignmentExpression) Type {
-	leftType := tc.checkExpression(expr.Left)
-	rightType := tc.checkExpression(expr.Right)
+	leftType := tc.checkAssignmentTarget(expr)
+	rightType := tc.checkValue(expr.Right, leftType)
 
 	// Check if we're trying to reassign a const variable
 	if id, ok := expr.Left.(*ast.Identifier); ok {
@@ -547,7 +1569,7 @@ func (tc *TypeChecker) checkAssignmentExpression(expr *ast.AssignmentExpression)
 			if symbol.DeclarationKind == lexer.CONST {
 				suggestion := "Use 'let' or 'var' instead of 'const' if you need to reassign the variable"
 				context := fmt.Sprintf("Variable '%s' was declared with 'const' and cannot be reassigned", id.Name)
-				tc.addDetailedError(expr.Pos(),
+				tc.addNodeError(expr,
 					fmt.Sprintf("Cannot assign to const variable '%s'", id.Name),
 					ConstReassignmentError,
 					suggestion,
@@ -557,10 +1579,21 @@ func (tc *TypeChecker) checkAssignmentExpression(expr *ast.AssignmentExpression)
 		}
 	}
 
-	if !tc.isAssignable(rightType, leftType) {
+	// Compound assignments (x += y) apply the arithmetic operator first
+	switch operator := expr.Operator.String(); operator {
+	case "+=", "-=", "*=", "/=", "%=":
+		rightType = tc.checkArithmeticOperation(expr, strings.TrimSuffix(operator, "="), leftType, rightType)
+		if rightType.Equals(UndefinedType) {
+			// Operator error already reported
+			return rightType
+		}
+	}
+
+	if !tc.isAssignableExpr(expr.Right, rightType, leftType) {
 		suggestion := fmt.Sprintf("Convert the value to type '%s' or change the variable type", leftType.String())
-		context := fmt.Sprintf("Assigning value of type '%s' to variable of type '%s'", rightType.String(), leftType.String())
-		tc.addDetailedError(expr.Pos(),
+		context := fmt.Sprintf("Assigning value of type '%s' to variable of type '%s'%s",
+			rightType.String(), leftType.String(), tc.mismatchDetail(rightType, leftType))
+		tc.addNodeError(expr,
 			fmt.Sprintf("Cannot assign type '%s' to type '%s'",
 				rightType.String(), leftType.String()),
 			InvalidAssignmentError,
@@ -577,24 +1610,23 @@ func (tc *TypeChecker) checkArrayLiteral(expr *ast.ArrayLiteral) Type {
 		return NewArrayType(UndefinedType)
 	}
 
-	// Check all elements and find common type
+	// Elements of different types make a union element type, so [1, "a"]
+	// is an (int | string)[]
 	var elementType Type
-	for i, element := range expr.Elements {
+	for _, element := range expr.Elements {
 		if element != nil {
-			elemType := tc.checkExpression(element)
-			if i == 0 {
+			var elemType Type
+			if spread, ok := element.(*ast.SpreadElement); ok {
+				elemType = tc.checkArraySpread(spread)
+			} else {
+				elemType = tc.checkExpression(element)
+			}
+			if elementType == nil {
 				elementType = elemType
-			} else if !elementType.Equals(elemType) {
-				// TODO: In a more sophisticated implementation,
-				// we would create union types or find common supertypes
-				suggestion := fmt.Sprintf("Ensure all array elements have the same type '%s'", elementType.String())
-				context := fmt.Sprintf("Element %d has type '%s', but expected '%s'", i, elemType.String(), elementType.String())
-				tc.addDetailedError(expr.Pos(),
-					"Array elements must have the same type",
-					TypeMismatchError,
-					suggestion,
-					context)
-				break
+			} else if elementType.Equals(AnyType) || elemType.Equals(AnyType) {
+				elementType = AnyType
+			} else {
+				elementType = unionOf(elementType, elemType)
 			}
 		}
 	}
@@ -606,6 +1638,367 @@ func (tc *TypeChecker) checkArrayLiteral(expr *ast.ArrayLiteral) Type {
 	return NewArrayType(elementType)
 }
 
+// checkValue type checks expr as a value for a target of type target, which
+// is nil when there is none. An array literal for a tuple is typed as a
+// tuple, so that it can be compared position by position. The properties of
+// an object literal for an object type are values for the types of those
+// properties, and in strict mode the literal may only have the properties
+// of that type.
+func (tc *TypeChecker) checkValue(expr ast.Expression, target Type) Type {
+	switch target := target.(type) {
+	case *TupleType:
+		if literal, ok := expr.(*ast.ArrayLiteral); ok {
+			return tc.checkTupleLiteral(literal, target)
+		}
+	case *ObjectType:
+		if literal, ok := expr.(*ast.ObjectLiteral); ok {
+			objType := tc.checkObjectLiteral(literal, target)
+			if tc.strictMode {
+				tc.checkExcessProperties(literal, target)
+			}
+			return objType
+		}
+	}
+	return tc.checkExpression(expr)
+}
+
+// checkExcessProperties reports the properties of an object literal that
+// target doesn't declare. Spread and computed properties aren't checked;
+// nested literals are checked as values of their property's type.
+func (tc *TypeChecker) checkExcessProperties(literal *ast.ObjectLiteral, target *ObjectType) {
+	for _, prop := range literal.Properties {
+		if prop.Key == nil || prop.Computed {
+			continue
+		}
+		var name string
+		switch key := prop.Key.(type) {
+		case *ast.Identifier:
+			name = key.Name
+		case *ast.StringLiteral:
+			name = key.Value
+		default:
+			continue
+		}
+		
+		if _, exists := target.Properties[name]; !exists {
+			tc.addNodeError(prop.Key,
+				fmt.Sprintf("Object literal may only specify known properties, and '%s' does not exist in type '%s'",
+					name, target.String()),
+				TypeMismatchError,
+				fmt.Sprintf("Remove '%s' or declare it in '%s'", name, target.String()),
+				fmt.Sprintf("Known properties: %s", strings.Join(target.PropertyNames(), ", ")))
+		}
+	}
+}
+
+// checkTupleLiteral types an array literal as a tuple of its element types.
+// An element takes the tuple's type for its position when it fits, as the
+// literal 1 does for int8. Spread elements make the length unknown, so such
+// a literal is an array.
+func (tc *TypeChecker) checkTupleLiteral(expr *ast.ArrayLiteral, tuple *TupleType) Type {
+	for _, element := range expr.Elements {
+		if _, ok := element.(*ast.SpreadElement); ok {
+			return tc.checkArrayLiteral(expr)
+		}
+	}
+	
+	elementTypes := make([]Type, len(expr.Elements))
+	for i, element := range expr.Elements {
+		if element == nil {
+			elementTypes[i] = UndefinedType
+			continue
+		}
+		elementType := tc.checkExpression(element)
+		if i < len(tuple.ElementTypes) && tc.isAssignableExpr(element, elementType, tuple.ElementTypes[i]) {
+			elementType = tuple.ElementTypes[i]
+		}
+		elementTypes[i] = elementType
+	}
+	return NewTupleType(elementTypes...)
+}
+
+// checkArraySpread type checks ...expr in an array literal and returns the
+// type of the elements it contributes
+func (tc *TypeChecker) checkArraySpread(spread *ast.SpreadElement) Type {
+	argType := tc.checkExpression(spread.Argument)
+	if arrayType, ok := argType.(*ArrayType); ok {
+		return arrayType.ElementType
+	}
+	if tuple, ok := argType.(*TupleType); ok {
+		return tuple.ElementType()
+	}
+	if IsStringType(argType) {
+		return StringType
+	}
+	if isUnknownType(argType) {
+		return AnyType
+	}
+	
+	tc.addNodeError(spread,
+		fmt.Sprintf("Cannot spread value of type '%s' into an array", argType.String()),
+		NotIterableError,
+		"Only arrays and strings can be spread into an array",
+		fmt.Sprintf("Spreading '%s' of type '%s'", spread.Argument.String(), argType.String()))
+	return AnyType
+}
+
+// checkSpreadArgument checks that the elements of a spread argument are
+// assignable to each of the parameters they may fill
+func (tc *TypeChecker) checkSpreadArgument(call *ast.CallExpression, spread *ast.SpreadElement, params []Type) {
+	elementType := tc.checkArraySpread(spread)
+	for _, param := range params {
+		if !tc.isAssignable(elementType, param) {
+			tc.addNodeError(spread,
+				fmt.Sprintf("Spread argument: cannot assign element type '%s' to parameter of type '%s'",
+					elementType.String(), param.String()),
+				TypeMismatchError,
+				fmt.Sprintf("Spread an array of '%s' or pass the arguments individually", param.String()),
+				fmt.Sprintf("Spreading '%s' into a call to '%s'", spread.Argument.String(), call.Callee.String()))
+			return
+		}
+	}
+}
+
+// checkObjectLiteral type checks an object literal and builds its object
+// type. When the literal is a value for target, which may be nil, its
+// properties are values for the types target gives them.
+func (tc *TypeChecker) checkObjectLiteral(expr *ast.ObjectLiteral, target *ObjectType) Type {
+	properties := make(map[string]Type)
+	for _, prop := range expr.Properties {
+		if spread, ok := prop.Value.(*ast.SpreadElement); ok && prop.Key == nil {
+			// Spread properties are copied; later properties override them
+			tc.checkObjectSpread(spread, properties)
+			continue
+		}
+		if prop.Computed {
+			tc.checkExpression(prop.Value)
+			continue
+		}
+		var name string
+		switch key := prop.Key.(type) {
+		case *ast.Identifier:
+			name = key.Name
+		case *ast.StringLiteral:
+			name = key.Value
+		default:
+			tc.checkExpression(prop.Value)
+			continue
+		}
+		var propType Type
+		if target != nil {
+			propType = target.Properties[name]
+		}
+		properties[name] = tc.checkValue(prop.Value, propType)
+	}
+	return NewObjectType(properties)
+}
+
+// checkObjectSpread type checks ...expr in an object literal and adds the
+// properties it copies to properties
+func (tc *TypeChecker) checkObjectSpread(spread *ast.SpreadElement, properties map[string]Type) {
+	argType := tc.checkExpression(spread.Argument)
+	if objType, ok := argType.(*ObjectType); ok {
+		for name, propType := range objType.Properties {
+			properties[name] = propType
+		}
+		return
+	}
+	if isUnknownType(argType) || argType.Equals(NullType) {
+		return
+	}
+	
+	tc.addNodeError(spread,
+		fmt.Sprintf("Cannot spread value of type '%s' into an object", argType.String()),
+		TypeMismatchError,
+		"Only objects can be spread into an object literal",
+		fmt.Sprintf("Spreading '%s' of type '%s'", spread.Argument.String(), argType.String()))
+}
+
+// checkTypeAssertion type checks an 'as' cast. Any numeric type can be cast
+// to any other numeric type; other casts must be compatible in one direction.
+func (tc *TypeChecker) checkTypeAssertion(expr *ast.TypeAssertion) Type {
+	sourceType := tc.checkExpression(expr.Expression)
+	targetType := tc.resolveTypeAnnotation(expr.Type)
+	
+	if IsNumericType(sourceType) && IsNumericType(targetType) {
+		return targetType
+	}
+	
+	if !tc.isAssignable(sourceType, targetType) && !tc.isAssignable(targetType, sourceType) {
+		tc.addDetailedError(expr.AsPos,
+			fmt.Sprintf("Cannot convert type '%s' to type '%s'", sourceType.String(), targetType.String()),
+			TypeMismatchError,
+			"Only cast between compatible types or between numeric types",
+			fmt.Sprintf("Casting expression '%s' of type '%s' with 'as %s'",
+				expr.Expression.String(), sourceType.String(), targetType.String()))
+	}
+	return targetType
+}
+
+// checkCondition reports a condition of type condType that can't decide
+// between branches. In strict mode conditions must be boolean. Otherwise any
+// type whose values can be both truthy and falsy is allowed, as in
+// JavaScript: numbers, strings and nullable types, but not objects, which
+// are always truthy, or void, which is always falsy. The any type is allowed
+// in both modes.
+func (tc *TypeChecker) checkCondition(node ast.Node, condType Type, construct string, examples string) {
+	if IsBooleanType(condType) || condType.Equals(AnyType) {
+		return
+	}
+	title := strings.ToUpper(construct[:1]) + construct[1:]
+	context := fmt.Sprintf("Condition type: %s", condType.String())
+	
+	if tc.strictMode {
+		tc.addNodeError(node,
+			fmt.Sprintf("%s condition must be boolean, got '%s'", title, condType.String()),
+			InvalidConditionError,
+			fmt.Sprintf("Use boolean expressions in %s conditions (e.g., %s)", construct, examples),
+			context)
+		return
+	}
+	
+	truthy, falsy := truthiness(condType)
+	if truthy && falsy {
+		return
+	}
+	always := "truthy"
+	if falsy {
+		always = "falsy"
+	}
+	tc.addNodeError(node,
+		fmt.Sprintf("%s condition of type '%s' is always %s", title, condType.String(), always),
+		InvalidConditionError,
+		fmt.Sprintf("Compare the value explicitly in %s conditions (e.g., %s)", construct, examples),
+		context)
+}
+
+// truthiness reports whether values of type t can be truthy and whether
+// they can be falsy
+func truthiness(t Type) (truthy bool, falsy bool) {
+	switch t := t.(type) {
+	case *UnionType:
+		for _, member := range t.Types {
+			memberTruthy, memberFalsy := truthiness(member)
+			truthy = truthy || memberTruthy
+			falsy = falsy || memberFalsy
+		}
+		return truthy, falsy
+	case *ObjectType, *ArrayType, *FunctionType:
+		return true, false
+	}
+	if t.Equals(NullType) || t.Equals(UndefinedType) || t.Equals(VoidType) {
+		return false, true
+	}
+	return true, true
+}
+
+// checkConditionalExpression type checks a ternary expression. Its type is
+// the common type of both branches.
+func (tc *TypeChecker) checkConditionalExpression(expr *ast.ConditionalExpression) Type {
+	condType := tc.checkExpression(expr.Test)
+	tc.checkCondition(expr, condType, "ternary", "x > 0, x === true")
+	
+	consequentType := tc.checkExpression(expr.Consequent)
+	alternateType := tc.checkExpression(expr.Alternate)
+	return commonType(consequentType, alternateType)
+}
+
+// withoutNullish returns t without its null and undefined members. A type
+// that is only nullish is returned unchanged.
+func withoutNullish(t Type) Type {
+	union, ok := t.(*UnionType)
+	if !ok {
+		return t
+	}
+	var members []Type
+	for _, member := range union.Types {
+		if !member.Equals(NullType) && !member.Equals(UndefinedType) {
+			members = append(members, member)
+		}
+	}
+	switch len(members) {
+	case 0:
+		return t
+	case 1:
+		return members[0]
+	}
+	return NewUnionType(members...)
+}
+
+// nullishFallbackType returns the type of left ?? right: the right operand
+// replaces a null or undefined left operand, so only the rest of the left
+// type remains
+func nullishFallbackType(left, right Type) Type {
+	if left.Equals(NullType) || left.Equals(UndefinedType) {
+		return right
+	}
+	return commonType(withoutNullish(left), right)
+}
+
+// orUndefined returns a type that also covers undefined
+func orUndefined(t Type) Type {
+	if isUnknownType(t) {
+		return t
+	}
+	return commonType(t, UndefinedType)
+}
+
+// commonType returns a type that covers values of both a and b
+func commonType(a, b Type) Type {
+	if a.Equals(b) {
+		return a
+	}
+	if a.Equals(AnyType) || b.Equals(AnyType) {
+		return AnyType
+	}
+	if IsNumericType(a) && IsNumericType(b) {
+		return arithmeticResultType(a, b)
+	}
+	return unionOf(a, b)
+}
+
+// unionOf returns the flattened union of a and b without duplicate members.
+// Unlike commonType it keeps distinct numeric types apart.
+func unionOf(a, b Type) Type {
+	if a.Equals(b) {
+		return a
+	}
+	var members []Type
+	for _, t := range []Type{a, b} {
+		parts := []Type{t}
+		if union, ok := t.(*UnionType); ok {
+			parts = union.Types
+		}
+		for _, part := range parts {
+			duplicate := false
+			for _, member := range members {
+				if member.Equals(part) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				members = append(members, part)
+			}
+		}
+	}
+	return NewUnionType(members...)
+}
+
+// checkFunctionExpression type checks a function expression, such as the
+// value of a method shorthand property. Without 'this' binding it checks
+// like an arrow function with a block body.
+func (tc *TypeChecker) checkFunctionExpression(expr *ast.FunctionExpression) Type {
+	return tc.checkArrowFunctionExpression(&ast.ArrowFunctionExpression{
+		LParen:     expr.LParen,
+		Parameters: expr.Parameters,
+		RParen:     expr.RParen,
+		Body:       expr.Body,
+		ReturnType: expr.ReturnType,
+		Async:      expr.Async,
+	})
+}
+
 // checkArrowFunctionExpression type checks an arrow function expression
 func (tc *TypeChecker) checkArrowFunctionExpression(expr *ast.ArrowFunctionExpression) Type {
 
@@ -618,15 +2011,14 @@ func (tc *TypeChecker) checkArrowFunctionExpression(expr *ast.ArrowFunctionExpre
 	var paramsNeedInference []int // Track which parameters need type inference
 
 	for i, param := range expr.Parameters {
-		var paramType Type = AnyType // Default to AnyType like TypeScript
-		if param.TypeAnnotation != nil {
-			paramType = tc.resolveTypeAnnotation(param.TypeAnnotation)
-		} else {
+		paramType := tc.resolver.parameterType(param)
+		if param.TypeAnnotation == nil && !param.Rest {
 			paramsNeedInference = append(paramsNeedInference, i)
 		}
 		paramTypes = append(paramTypes, paramType)
 		tc.resolver.Define(param.Name.Name, paramType, ParameterSymbol, param.Name.Pos())
 	}
+	tc.checkParameterDefaults(expr.Parameters, paramTypes)
 
 	// Determine return type
 	var returnType Type = UndefinedType
@@ -653,7 +2045,16 @@ func (tc *TypeChecker) checkArrowFunctionExpression(expr *ast.ArrowFunctionExpre
 	if expr.Body != nil {
 		switch body := expr.Body.(type) {
 		case *ast.BlockStatement:
+			var declaredReturnType Type
+			if expr.ReturnType != nil {
+				declaredReturnType = returnType
+			}
+			tc.pushReturnType(declaredReturnType)
 			tc.checkBlockStatement(body)
+			tc.popReturnType()
+			if expr.ReturnType != nil {
+				tc.checkMissingReturn(body, expr.ReturnType, returnType)
+			}
 
 			// For arrow functions with expression bodies (wrapped in BlockStatement with ReturnStatement),
 			// we need to infer the return type from the return statement
@@ -672,7 +2073,7 @@ func (tc *TypeChecker) checkArrowFunctionExpression(expr *ast.ArrowFunctionExpre
 				if !returnType.Equals(exprType) {
 					suggestion := fmt.Sprintf("Change return type to '%s' or modify expression", exprType.String())
 					context := fmt.Sprintf("Expression returns '%s', but function expects '%s'", exprType.String(), returnType.String())
-					tc.addDetailedError(expr.Pos(),
+					tc.addNodeError(expr,
 						"Arrow function expression type doesn't match declared return type",
 						TypeMismatchError,
 						suggestion,
@@ -693,7 +2094,7 @@ func (tc *TypeChecker) checkArrowFunctionExpression(expr *ast.ArrowFunctionExpre
 		ReturnType: returnType,
 		Variadic:   false,
 	}
-	return funcType
+	return withParameterModifiers(funcType, expr.Parameters)
 }
 
 // checkBlockStatement type checks a block statement
@@ -701,50 +2102,168 @@ func (tc *TypeChecker) checkBlockStatement(stmt *ast.BlockStatement) {
 	tc.resolver.EnterScope()
 	defer tc.resolver.ExitScope()
 
-	for _, s := range stmt.Body {
-		tc.checkStatement(s)
-	}
+	tc.checkStatementList(stmt.Body)
 }
 
 // checkIfStatement type checks an if statement
 func (tc *TypeChecker) checkIfStatement(stmt *ast.IfStatement) {
 	// Check condition
 	condType := tc.checkExpression(stmt.Test)
-	if tc.strictMode && !IsBooleanType(condType) {
-		suggestion := "Use boolean expressions in if conditions (e.g., x > 0, x === true)"
-		context := fmt.Sprintf("Condition type: %s", condType.String())
-		tc.addDetailedError(stmt.Pos(),
-			fmt.Sprintf("If condition must be boolean, got '%s'", condType.String()),
-			InvalidConditionError,
-			suggestion,
-			context)
-	}
+	tc.checkCondition(stmt, condType, "if", "x > 0, x === true")
+
+	// A typeof guard narrows a union typed variable in each branch
+	symbol, thenType, elseType := tc.typeofGuard(stmt.Test)
 
 	// Check consequent
-	tc.checkStatement(stmt.Consequent)
+	tc.checkNarrowed(stmt.Consequent, symbol, thenType)
 
 	// Check alternate if present
 	if stmt.Alternate != nil {
-		tc.checkStatement(stmt.Alternate)
+		tc.checkNarrowed(stmt.Alternate, symbol, elseType)
 	}
 }
 
-// checkWhileStatement type checks a while statement
-func (tc *TypeChecker) checkWhileStatement(stmt *ast.WhileStatement) {
-	// Check condition
-	condType := tc.checkExpression(stmt.Test)
-	if tc.strictMode && !IsBooleanType(condType) {
-		suggestion := "Use boolean expressions in while conditions (e.g., x > 0, x !== null)"
-		context := fmt.Sprintf("Condition type: %s", condType.String())
-		tc.addDetailedError(stmt.Pos(),
-			fmt.Sprintf("While condition must be boolean, got '%s'", condType.String()),
-			InvalidConditionError,
-			suggestion,
-			context)
+// checkNarrowed checks stmt with symbol narrowed to t, restoring its type
+// afterwards. A nil symbol checks stmt unchanged.
+func (tc *TypeChecker) checkNarrowed(stmt ast.Statement, symbol *Symbol, t Type) {
+	if symbol == nil {
+		tc.checkStatement(stmt)
+		return
 	}
+	
+	saved, savedDeclared := symbol.Type, symbol.DeclaredType
+	if symbol.DeclaredType == nil {
+		symbol.DeclaredType = symbol.Type
+	}
+	symbol.Type = t
+	tc.checkStatement(stmt)
+	symbol.Type, symbol.DeclaredType = saved, savedDeclared
+}
+
+// typeofGuard recognizes a condition comparing 'typeof x' with a string,
+// such as typeof x === "string", where x has a union type. It returns the
+// symbol of x with its type when the condition holds and when it doesn't,
+// or a nil symbol for any other condition.
+func (tc *TypeChecker) typeofGuard(test ast.Expression) (*Symbol, Type, Type) {
+	binary, ok := test.(*ast.BinaryExpression)
+	if !ok {
+		return nil, nil, nil
+	}
+	negated := false
+	switch binary.Operator {
+	case lexer.EQ, lexer.STRICT_EQ:
+	case lexer.NE, lexer.STRICT_NE:
+		negated = true
+	default:
+		return nil, nil, nil
+	}
+	
+	operand, name := typeofComparison(binary.Left, binary.Right)
+	if operand == nil {
+		operand, name = typeofComparison(binary.Right, binary.Left)
+	}
+	if operand == nil {
+		return nil, nil, nil
+	}
+	symbol, exists := tc.resolver.Lookup(operand.Name)
+	if !exists {
+		return nil, nil, nil
+	}
+	union, ok := symbol.Type.(*UnionType)
+	if !ok {
+		return nil, nil, nil
+	}
+	
+	// Members whose typeof result is unknown may take either branch
+	var matching, others []Type
+	for _, member := range union.Types {
+		result := typeofResult(member)
+		if result == name || result == "" {
+			matching = append(matching, member)
+		}
+		if result != name {
+			others = append(others, member)
+		}
+	}
+	if negated {
+		matching, others = others, matching
+	}
+	return symbol, narrowedUnion(union, matching), narrowedUnion(union, others)
+}
+
+// typeofComparison returns the identifier x and the string s when left is
+// 'typeof x' and right is the string literal s
+func typeofComparison(left, right ast.Expression) (*ast.Identifier, string) {
+	unary, ok := left.(*ast.UnaryExpression)
+	if !ok || unary.Operator != lexer.TYPEOF {
+		return nil, ""
+	}
+	id, ok := unary.Operand.(*ast.Identifier)
+	if !ok {
+		return nil, ""
+	}
+	literal, ok := right.(*ast.StringLiteral)
+	if !ok {
+		return nil, ""
+	}
+	return id, literal.Value
+}
+
+// typeofResult returns what typeof evaluates to for values of type t, or
+// "" if it can't be told from the type
+func typeofResult(t Type) string {
+	switch t.(type) {
+	case *FunctionType:
+		return "function"
+	case *ArrayType, *ObjectType:
+		return "object"
+	}
+	switch {
+	case IsStringType(t):
+		return "string"
+	case IsNumericType(t):
+		return "number"
+	case IsBooleanType(t):
+		return "boolean"
+	case t.Equals(NullType):
+		return "object"
+	case t.Equals(UndefinedType):
+		return "undefined"
+	}
+	return ""
+}
+
+// narrowedUnion returns the members of union left after narrowing. A
+// branch no member can reach keeps the whole union.
+func narrowedUnion(union *UnionType, members []Type) Type {
+	switch len(members) {
+	case 0:
+		return union
+	case 1:
+		return members[0]
+	default:
+		return NewUnionType(members...)
+	}
+}
+
+// checkWhileStatement type checks a while statement
+func (tc *TypeChecker) checkWhileStatement(stmt *ast.WhileStatement) {
+	// Check condition
+	condType := tc.checkExpression(stmt.Test)
+	tc.checkCondition(stmt, condType, "while", "x > 0, x !== null")
 
 	// Check body
-	tc.checkStatement(stmt.Body)
+	tc.checkLoopBody(stmt.Body)
+}
+
+// checkDoWhileStatement type checks a do-while statement
+func (tc *TypeChecker) checkDoWhileStatement(stmt *ast.DoWhileStatement) {
+	// Check body
+	tc.checkLoopBody(stmt.Body)
+
+	// Check condition
+	condType := tc.checkExpression(stmt.Test)
+	tc.checkCondition(stmt.Test, condType, "do-while", "x > 0, x !== null")
 }
 
 // checkForStatement type checks a for statement
@@ -760,15 +2279,7 @@ func (tc *TypeChecker) checkForStatement(stmt *ast.ForStatement) {
 	// Check test
 	if stmt.Test != nil {
 		condType := tc.checkExpression(stmt.Test)
-		if tc.strictMode && !IsBooleanType(condType) {
-			suggestion := "Use boolean expressions in for conditions (e.g., i < 10, x !== null)"
-			context := fmt.Sprintf("Condition type: %s", condType.String())
-			tc.addDetailedError(stmt.Pos(),
-				fmt.Sprintf("For condition must be boolean, got '%s'", condType.String()),
-				InvalidConditionError,
-				suggestion,
-				context)
-		}
+		tc.checkCondition(stmt, condType, "for", "i < 10, x !== null")
 	}
 
 	// Check update
@@ -777,66 +2288,377 @@ func (tc *TypeChecker) checkForStatement(stmt *ast.ForStatement) {
 	}
 
 	// Check body
-	tc.checkStatement(stmt.Body)
+	tc.checkLoopBody(stmt.Body)
+}
+
+// checkForOfStatement type checks a for-of statement. Arrays and strings are
+// iterable; the loop variable takes the element type.
+func (tc *TypeChecker) checkForOfStatement(stmt *ast.ForOfStatement) {
+	tc.resolver.EnterScope()
+	defer tc.resolver.ExitScope()
+
+	iterableType := tc.checkExpression(stmt.Right)
+	var elementType Type
+	switch t := iterableType.(type) {
+	case *ArrayType:
+		elementType = t.ElementType
+	case *TupleType:
+		elementType = t.ElementType()
+	default:
+		if IsStringType(iterableType) {
+			elementType = StringType
+		} else if iterableType.Equals(AnyType) {
+			elementType = AnyType
+		} else {
+			tc.addNodeError(stmt.Right,
+				fmt.Sprintf("Type '%s' is not iterable", iterableType.String()),
+				NotIterableError,
+				"Iterate over an array or a string with for...of",
+				fmt.Sprintf("Iterating over '%s' of type '%s'", stmt.Right.String(), iterableType.String()))
+			elementType = AnyType
+		}
+	}
+
+	if id, ok := stmt.Left.(*ast.Identifier); ok {
+		tc.resolver.Define(id.Name, elementType, VariableSymbol, id.Pos())
+	}
+
+	// Check body
+	tc.checkLoopBody(stmt.Body)
+}
+
+// checkForInStatement type checks a for-in statement. It iterates over the
+// keys of an object (or the indices of an array), which are always strings.
+func (tc *TypeChecker) checkForInStatement(stmt *ast.ForInStatement) {
+	tc.resolver.EnterScope()
+	defer tc.resolver.ExitScope()
+
+	objectType := tc.checkExpression(stmt.Right)
+	switch objectType.(type) {
+	case *ObjectType, *ArrayType:
+	default:
+		if !objectType.Equals(AnyType) {
+			tc.addNodeError(stmt.Right,
+				fmt.Sprintf("Cannot iterate over the keys of type '%s'", objectType.String()),
+				NotIterableError,
+				"Iterate over the keys of an object with for...in",
+				fmt.Sprintf("Iterating over '%s' of type '%s'", stmt.Right.String(), objectType.String()))
+		}
+	}
+
+	if id, ok := stmt.Left.(*ast.Identifier); ok {
+		tc.resolver.Define(id.Name, StringType, VariableSymbol, id.Pos())
+	}
+
+	// Check body
+	tc.checkLoopBody(stmt.Body)
+}
+
+// checkClassDeclaration checks the members of a class against its base
+// class. Members marked 'override' must override a base member, and
+// overriding members must have a compatible type.
+func (tc *TypeChecker) checkClassDeclaration(decl *ast.ClassDeclaration) {
+	tc.classes[decl.Name.Name] = decl
+
+	var base *ast.ClassDeclaration
+	if decl.SuperClass != nil {
+		superName, ok := decl.SuperClass.(*ast.Identifier)
+		if !ok {
+			return
+		}
+		if base, ok = tc.classes[superName.Name]; !ok {
+			// The base class isn't known, so overrides can't be verified
+			return
+		}
+	}
+
+	for _, member := range decl.Body {
+		name, memberType, override, pos := tc.classMemberInfo(member)
+		if name == "" {
+			continue
+		}
+
+		if base == nil {
+			if override {
+				tc.addDetailedError(pos,
+					fmt.Sprintf("Member '%s' cannot be marked override because class '%s' does not extend another class",
+						name, decl.Name.Name),
+					InvalidOverrideError,
+					"Remove the 'override' modifier or extend a base class",
+					fmt.Sprintf("Class '%s'", decl.Name.Name))
+			}
+			continue
+		}
+
+		baseType, owner, found := tc.findClassMember(base, name)
+		if !found {
+			if override {
+				tc.addDetailedError(pos,
+					fmt.Sprintf("Member '%s' is marked override but is not declared in base class '%s'",
+						name, base.Name.Name),
+					InvalidOverrideError,
+					"Remove the 'override' modifier or check the member name",
+					fmt.Sprintf("Class '%s' extends '%s'", decl.Name.Name, base.Name.Name))
+			}
+			continue
+		}
+
+		if !override && tc.noImplicitOverride {
+			tc.addDetailedError(pos,
+				fmt.Sprintf("Member '%s' overrides a member of base class '%s' and must be marked override",
+					name, owner),
+				InvalidOverrideError,
+				fmt.Sprintf("Add the 'override' modifier to '%s'", name),
+				fmt.Sprintf("Class '%s' extends '%s'", decl.Name.Name, base.Name.Name))
+		}
+
+		if !tc.isOverrideCompatible(memberType, baseType) {
+			tc.addDetailedError(pos,
+				fmt.Sprintf("Member '%s' of type '%s' is not compatible with '%s' in base class '%s'",
+					name, memberType.String(), baseType.String(), owner),
+				InvalidOverrideError,
+				fmt.Sprintf("Change '%s' to match the signature in '%s'", name, owner),
+				fmt.Sprintf("Class '%s' extends '%s'", decl.Name.Name, base.Name.Name))
+		}
+	}
+}
+
+// classMemberInfo returns the name, type, override flag and position of
+// an instance member. Static and computed members yield an empty name.
+func (tc *TypeChecker) classMemberInfo(member ast.Node) (string, Type, bool, lexer.Position) {
+	switch m := member.(type) {
+	case *ast.MethodDefinition:
+		key, ok := m.Key.(*ast.Identifier)
+		if !ok || m.Static || m.Computed {
+			return "", nil, false, lexer.Position{}
+		}
+		params := make([]Type, len(m.Value.Parameters))
+		for i, param := range m.Value.Parameters {
+			params[i] = AnyType
+			if param.TypeAnnotation != nil {
+				params[i] = tc.resolveTypeAnnotation(param.TypeAnnotation)
+			}
+		}
+		var returnType Type = AnyType
+		if m.Value.ReturnType != nil {
+			returnType = tc.resolveTypeAnnotation(m.Value.ReturnType)
+		}
+		return key.Name, NewFunctionType(params, returnType), m.Override, key.Pos()
+	case *ast.PropertyDefinition:
+		key, ok := m.Key.(*ast.Identifier)
+		if !ok || m.Static || m.Computed {
+			return "", nil, false, lexer.Position{}
+		}
+		var propType Type = AnyType
+		if m.TypeAnnotation != nil {
+			propType = tc.resolveTypeAnnotation(m.TypeAnnotation)
+		}
+		return key.Name, propType, m.Override, key.Pos()
+	}
+	return "", nil, false, lexer.Position{}
+}
+
+// findClassMember looks up an instance member in a class and its base
+// classes, returning its type and the name of the class declaring it
+func (tc *TypeChecker) findClassMember(class *ast.ClassDeclaration, name string) (Type, string, bool) {
+	for class != nil {
+		for _, member := range class.Body {
+			if memberName, memberType, _, _ := tc.classMemberInfo(member); memberName == name {
+				return memberType, class.Name.Name, true
+			}
+		}
+
+		superName, ok := class.SuperClass.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		class = tc.classes[superName.Name]
+	}
+	return nil, "", false
+}
+
+// isOverrideCompatible reports whether a member of type derived can
+// override a base member of type base. Like TypeScript methods, parameters
+// are compared bivariantly; the override may take fewer parameters.
+func (tc *TypeChecker) isOverrideCompatible(derived, base Type) bool {
+	derivedFunc, derivedIsFunc := derived.(*FunctionType)
+	baseFunc, baseIsFunc := base.(*FunctionType)
+	if derivedIsFunc != baseIsFunc {
+		return derived.Equals(AnyType) || base.Equals(AnyType)
+	}
+	if !derivedIsFunc {
+		return tc.isAssignable(derived, base)
+	}
+
+	if len(derivedFunc.Parameters) > len(baseFunc.Parameters) {
+		return false
+	}
+	for i, param := range derivedFunc.Parameters {
+		baseParam := baseFunc.Parameters[i]
+		if !tc.isAssignable(baseParam, param) && !tc.isAssignable(param, baseParam) {
+			return false
+		}
+	}
+	return tc.isAssignable(derivedFunc.ReturnType, baseFunc.ReturnType)
+}
+
+// checkSwitchStatement type checks a switch statement. Each case expression
+// must be comparable with the discriminant.
+func (tc *TypeChecker) checkSwitchStatement(stmt *ast.SwitchStatement) {
+	discriminantType := tc.checkExpression(stmt.Discriminant)
+
+	tc.resolver.EnterScope()
+	tc.jumpTargets = append(tc.jumpTargets, false)
+	for _, clause := range stmt.Cases {
+		if clause.Test != nil {
+			caseType := tc.checkExpression(clause.Test)
+			if !tc.isComparable(clause.Test, caseType, discriminantType) {
+				tc.addNodeError(clause.Test,
+					fmt.Sprintf("Case of type '%s' is not comparable to switch value of type '%s'",
+						caseType.String(), discriminantType.String()),
+					TypeMismatchError,
+					fmt.Sprintf("Use a case value of type '%s'", discriminantType.String()),
+					fmt.Sprintf("switch (%s)", stmt.Discriminant.String()))
+			}
+		}
+		tc.checkStatementList(clause.Consequent)
+	}
+	tc.jumpTargets = tc.jumpTargets[:len(tc.jumpTargets)-1]
+	tc.resolver.ExitScope()
+}
+
+// isComparable reports whether a case value can ever equal the discriminant
+func (tc *TypeChecker) isComparable(expr ast.Expression, caseType, discriminantType Type) bool {
+	if IsNumericType(caseType) && IsNumericType(discriminantType) {
+		return true
+	}
+	return tc.isAssignableExpr(expr, caseType, discriminantType) || tc.isAssignable(discriminantType, caseType)
+}
+
+// checkTryStatement type checks a try statement. Any value can be thrown,
+// so the catch parameter has type any.
+func (tc *TypeChecker) checkTryStatement(stmt *ast.TryStatement) {
+	tc.checkBlockStatement(stmt.Block)
+
+	if stmt.Handler != nil {
+		tc.resolver.EnterScope()
+		if param := stmt.Handler.Param; param != nil {
+			tc.resolver.Define(param.Name, AnyType, VariableSymbol, param.Pos())
+			// Catching an error without inspecting it is fine
+			if symbol, ok := tc.resolver.LookupLocal(param.Name); ok {
+				symbol.Used = true
+			}
+		}
+		tc.checkBlockStatement(stmt.Handler.Body)
+		tc.resolver.ExitScope()
+	}
+
+	if stmt.Finalizer != nil {
+		tc.checkBlockStatement(stmt.Finalizer)
+	}
 }
 
 // checkReturnStatement type checks a return statement
 func (tc *TypeChecker) checkReturnStatement(stmt *ast.ReturnStatement) {
+	var expected Type
+	if len(tc.returnTypes) > 0 {
+		expected = tc.returnTypes[len(tc.returnTypes)-1]
+	}
+	var argType Type
 	if stmt.Argument != nil {
-		tc.checkExpression(stmt.Argument)
+		argType = tc.checkValue(stmt.Argument, expected)
 	}
-	// TODO: Check return type compatibility with function signature
-}
 
-// resolveTypeAnnotation resolves a type annotation to a Type
-func (tc *TypeChecker) resolveTypeAnnotation(annotation ast.TypeNode) Type {
-	switch t := annotation.(type) {
-	case *ast.BasicType:
-		switch t.Kind {
-		case lexer.NUMBER_T:
-			return FloatType
-		case lexer.INT_T:
-			return IntType
-		case lexer.FLOAT_T:
-			return FloatType
-		case lexer.STRING_T:
-			return StringType
-		case lexer.BOOLEAN_T:
-			return BooleanType
-		case lexer.VOID:
-			return VoidType
-		case lexer.NULL:
-			return NullType
-		case lexer.UNDEFINED:
-			return UndefinedType
-		// Extended numeric types
-		case lexer.INT8_T:
-			return Int8Type
-		case lexer.INT16_T:
-			return Int16Type
-		case lexer.INT32_T:
-			return Int32Type
-		case lexer.INT64_T:
-			return Int64Type
-		case lexer.FLOAT32_T:
-			return Float32Type
-		case lexer.FLOAT64_T:
-			return Float64Type
-		default:
-			return UndefinedType
+	// Only check returns inside functions with a declared return type
+	if expected == nil {
+		return
+	}
+
+	if expected.Equals(VoidType) {
+		if argType != nil {
+			tc.addNodeError(stmt,
+				"Cannot return a value from a function with return type 'void'",
+				InvalidReturnTypeError,
+				"Remove the return value or change the function's return type",
+				fmt.Sprintf("Function is declared to return 'void', but returns '%s'", argType.String()))
 		}
-	case *ast.ArrayType:
-		elementType := tc.resolveTypeAnnotation(t.ElementType)
-		return NewArrayType(elementType)
-	case *ast.UnionType:
-		var types []Type
-		for _, typeNode := range t.Types {
-			types = append(types, tc.resolveTypeAnnotation(typeNode))
+		return
+	}
+
+	if argType == nil {
+		tc.addNodeError(stmt,
+			fmt.Sprintf("Missing return value, expected a value of type '%s'", expected.String()),
+			InvalidReturnTypeError,
+			fmt.Sprintf("Return a value of type '%s'", expected.String()),
+			fmt.Sprintf("Function is declared to return '%s', but returns nothing", expected.String()))
+		return
+	}
+
+	if !tc.isAssignableExpr(stmt.Argument, argType, expected) {
+		tc.addNodeError(stmt,
+			fmt.Sprintf("Cannot return type '%s' from a function with return type '%s'",
+				argType.String(), expected.String()),
+			InvalidReturnTypeError,
+			fmt.Sprintf("Return a value of type '%s' or change the function's return type", expected.String()),
+			fmt.Sprintf("Function is declared to return '%s', but returns '%s'", expected.String(), argType.String()))
+	}
+}
+
+// pushReturnType enters a function body with the given declared return type.
+// A nil type means the function has no return type annotation. Loops and
+// switches outside the function are not jump targets inside it.
+func (tc *TypeChecker) pushReturnType(t Type) {
+	tc.returnTypes = append(tc.returnTypes, t)
+	tc.outerJumps = append(tc.outerJumps, tc.jumpTargets)
+	tc.jumpTargets = nil
+}
+
+// popReturnType leaves the innermost function body
+func (tc *TypeChecker) popReturnType() {
+	tc.returnTypes = tc.returnTypes[:len(tc.returnTypes)-1]
+	tc.jumpTargets = tc.outerJumps[len(tc.outerJumps)-1]
+	tc.outerJumps = tc.outerJumps[:len(tc.outerJumps)-1]
+}
+
+// checkLoopBody checks the body of a loop, which break and continue
+// statements can target
+func (tc *TypeChecker) checkLoopBody(body ast.Statement) {
+	tc.jumpTargets = append(tc.jumpTargets, true)
+	tc.checkStatement(body)
+	tc.jumpTargets = tc.jumpTargets[:len(tc.jumpTargets)-1]
+}
+
+// checkBreakStatement checks that a break is inside a loop or switch
+func (tc *TypeChecker) checkBreakStatement(stmt *ast.BreakStatement) {
+	if stmt.Label == nil && len(tc.jumpTargets) == 0 {
+		tc.addNodeError(stmt,
+			"'break' can only be used inside a loop or switch",
+			InvalidJumpError,
+			"Remove the break or move it into a loop or switch",
+			"break outside of a loop or switch")
+	}
+}
+
+// checkContinueStatement checks that a continue is inside a loop
+func (tc *TypeChecker) checkContinueStatement(stmt *ast.ContinueStatement) {
+	if stmt.Label != nil {
+		return
+	}
+	for _, loop := range tc.jumpTargets {
+		if loop {
+			return
 		}
-		return NewUnionType(types...)
-	default:
-		return UndefinedType
 	}
+	tc.addNodeError(stmt,
+		"'continue' can only be used inside a loop",
+		InvalidJumpError,
+		"Remove the continue or move it into a loop",
+		"continue outside of a loop")
+}
+
+// resolveTypeAnnotation resolves a type annotation to a Type
+func (tc *TypeChecker) resolveTypeAnnotation(annotation ast.TypeNode) Type {
+	return tc.resolver.resolveTypeAnnotation(annotation)
 }
 
 // isAssignable checks if source type can be assigned to target type
@@ -856,9 +2678,52 @@ func (tc *TypeChecker) isAssignable(source, target Type) bool {
 		return true
 	}
 
-	// Numeric type compatibility
+	// A type parameter's values satisfy its constraint
+	if variable, ok := source.(*TypeVariable); ok && variable.Constraint != nil {
+		return tc.isAssignable(variable.Constraint, target)
+	}
+
+	// Numeric types may only be widened implicitly
 	if IsNumericType(source) && IsNumericType(target) {
-		return true
+		return isNumericWidening(source, target)
+	}
+
+	// Arrays are compatible when their elements are, which also admits the
+	// empty literal '[]' (undefined[])
+	if sourceArr, ok := source.(*ArrayType); ok {
+		if targetArr, ok := target.(*ArrayType); ok {
+			return tc.isAssignable(sourceArr.ElementType, targetArr.ElementType)
+		}
+	}
+
+	// Tuples are compatible with tuples of the same length and with arrays
+	// when their elements are
+	if sourceTuple, ok := source.(*TupleType); ok {
+		switch target := target.(type) {
+		case *TupleType:
+			return tc.tupleMismatch(sourceTuple, target) == ""
+		case *ArrayType:
+			for _, element := range sourceTuple.ElementTypes {
+				if !tc.isAssignable(element, target.ElementType) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	// Functions are compatible when they accept every call target allows
+	if sourceFunc, ok := source.(*FunctionType); ok {
+		if targetFunc, ok := target.(*FunctionType); ok {
+			return tc.functionMismatch(sourceFunc, targetFunc) == ""
+		}
+	}
+
+	// Structural compatibility between object types
+	if sourceObj, ok := source.(*ObjectType); ok {
+		if targetObj, ok := target.(*ObjectType); ok {
+			return tc.objectMismatch(sourceObj, targetObj) == ""
+		}
 	}
 
 	// Union type handling
@@ -873,23 +2738,306 @@ func (tc *TypeChecker) isAssignable(source, target Type) bool {
 	return false
 }
 
+// isNumericWidening reports whether a numeric value of type source can be
+// implicitly converted to target without losing range. Integers convert to
+// any float type; narrowing requires an explicit 'as' cast.
+func isNumericWidening(source, target Type) bool {
+	sourceBits, sourceFloat := numericWidth(source)
+	targetBits, targetFloat := numericWidth(target)
+	
+	if sourceFloat != targetFloat {
+		return !sourceFloat
+	}
+	return sourceBits <= targetBits
+}
+
+// isAssignableExpr is like isAssignable, but also accepts numeric literals
+// whose value fits in the target type (e.g. 'let x: int8 = 100')
+func (tc *TypeChecker) isAssignableExpr(expr ast.Expression, source, target Type) bool {
+	if tc.isAssignable(source, target) {
+		return true
+	}
+	
+	bits, isFloat := numericWidth(target)
+	if bits == 0 {
+		return false
+	}
+	
+	if isFloat {
+		return isNumericConstant(expr)
+	}
+	value, ok := constantInteger(expr)
+	if !ok {
+		return false
+	}
+	if bits == 64 {
+		return true
+	}
+	limit := int64(1) << (bits - 1)
+	return value >= -limit && value < limit
+}
+
+// constantInteger evaluates an integer constant expression built from
+// integer literals and arithmetic operators
+func constantInteger(expr ast.Expression) (int64, bool) {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return e.Value, true
+	case *ast.UnaryExpression:
+		value, ok := constantInteger(e.Operand)
+		if !ok || e.Operator != lexer.SUB {
+			return 0, false
+		}
+		return -value, true
+	case *ast.BinaryExpression:
+		left, ok := constantInteger(e.Left)
+		if !ok {
+			return 0, false
+		}
+		right, ok := constantInteger(e.Right)
+		if !ok {
+			return 0, false
+		}
+		switch e.Operator {
+		case lexer.ADD:
+			return left + right, true
+		case lexer.SUB:
+			return left - right, true
+		case lexer.MUL:
+			return left * right, true
+		case lexer.DIV:
+			if right == 0 || left%right != 0 {
+				return 0, false
+			}
+			return left / right, true
+		}
+	}
+	return 0, false
+}
+
+// isNumericConstant reports whether expr is built only from numeric
+// literals and arithmetic operators
+func isNumericConstant(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral:
+		return true
+	case *ast.UnaryExpression:
+		return e.Operator == lexer.SUB && isNumericConstant(e.Operand)
+	case *ast.BinaryExpression:
+		switch e.Operator {
+		case lexer.ADD, lexer.SUB, lexer.MUL, lexer.DIV:
+			return isNumericConstant(e.Left) && isNumericConstant(e.Right)
+		}
+	}
+	return false
+}
+
+// objectMismatch describes why source is not structurally compatible with target.
+// It returns an empty string if every required property of target is present
+// in source with an assignable type.
+func (tc *TypeChecker) objectMismatch(source, target *ObjectType) string {
+	for _, name := range target.PropertyNames() {
+		expectedType := target.Properties[name]
+		actualType, exists := source.Properties[name]
+		if !exists {
+			if target.IsOptional(name) {
+				continue
+			}
+			return fmt.Sprintf("property '%s' is missing", name)
+		}
+		if !tc.isAssignable(actualType, expectedType) {
+			return fmt.Sprintf("property '%s' has type '%s', expected '%s'",
+				name, actualType.String(), expectedType.String())
+		}
+	}
+	return ""
+}
+
+// tupleMismatch returns why source is not assignable to target, or "" if it
+// is
+func (tc *TypeChecker) tupleMismatch(source, target *TupleType) string {
+	if len(source.ElementTypes) != len(target.ElementTypes) {
+		return fmt.Sprintf("%d elements, expected %d", len(source.ElementTypes), len(target.ElementTypes))
+	}
+	for i, expectedType := range target.ElementTypes {
+		if actualType := source.ElementTypes[i]; !tc.isAssignable(actualType, expectedType) {
+			return fmt.Sprintf("element %d has type '%s', expected '%s'",
+				i, actualType.String(), expectedType.String())
+		}
+	}
+	return ""
+}
+
+// functionMismatch returns why a function of type source can't be used
+// where target is expected, or "" if it can. Every call target allows must
+// suit source: source may not require more arguments than target takes,
+// each argument type of target must be assignable to the matching parameter
+// of source, and source must return a type assignable to target's return
+// type unless target returns void.
+func (tc *TypeChecker) functionMismatch(source, target *FunctionType) string {
+	// A generic function is compatible when it is for any type arguments
+	if len(source.TypeParameters) > 0 {
+		bindings := make(map[*TypeVariable]Type)
+		for _, param := range source.TypeParameters {
+			bindings[param] = typeParameterBound(param)
+		}
+		source = substitute(source, bindings).(*FunctionType)
+	}
+	
+	if !target.Variadic && source.MinArgs() > len(target.Parameters) {
+		return fmt.Sprintf("requires %d arguments, expected at most %d", source.MinArgs(), len(target.Parameters))
+	}
+	
+	// Parameters beyond target's receive its rest arguments
+	count := len(target.Parameters)
+	if target.Variadic && len(source.Parameters) > count {
+		count = len(source.Parameters)
+	}
+	for i := 0; i < count; i++ {
+		paramType, ok := parameterAt(source, i)
+		if !ok {
+			break
+		}
+		argType, _ := parameterAt(target, i)
+		if !tc.isAssignable(argType, paramType) {
+			return fmt.Sprintf("parameter %d has type '%s', expected '%s'",
+				i+1, paramType.String(), argType.String())
+		}
+	}
+	
+	if target.ReturnType.Equals(VoidType) || tc.isAssignable(source.ReturnType, target.ReturnType) {
+		return ""
+	}
+	return fmt.Sprintf("returns '%s', expected '%s'", source.ReturnType.String(), target.ReturnType.String())
+}
+
+// parameterAt returns the type of argument i of a call to fn, or false if
+// fn takes fewer arguments. Untyped rest arguments are 'any'.
+func parameterAt(fn *FunctionType, i int) (Type, bool) {
+	if i < len(fn.Parameters) {
+		return fn.Parameters[i], true
+	}
+	if !fn.Variadic {
+		return nil, false
+	}
+	if fn.RestType == nil {
+		return AnyType, true
+	}
+	return fn.RestType, true
+}
+
+// mismatchDetail returns a short explanation for incompatible object, tuple
+// and function types
+func (tc *TypeChecker) mismatchDetail(source, target Type) string {
+	reason := ""
+	switch source := source.(type) {
+	case *TupleType:
+		if target, ok := target.(*TupleType); ok {
+			reason = tc.tupleMismatch(source, target)
+		}
+	case *ObjectType:
+		if target, ok := target.(*ObjectType); ok {
+			reason = tc.objectMismatch(source, target)
+		}
+	case *FunctionType:
+		if target, ok := target.(*FunctionType); ok {
+			reason = tc.functionMismatch(source, target)
+		}
+	}
+	if reason == "" {
+		return ""
+	}
+	return " (" + reason + ")"
+}
+
 // addError adds a type error with basic information
 func (tc *TypeChecker) addError(pos lexer.Position, message string) {
 	tc.errors = append(tc.errors, &TypeError{
-		Position: pos,
-		Message:  message,
-		Code:     TypeMismatchError, // Default error code
+		Position:    pos,
+		EndPosition: pos,
+		Span:        lexer.Span{Start: pos, End: pos},
+		Message:     message,
+		Code:        TypeMismatchError, // Default error code
 	})
 }
 
 // addDetailedError adds a type error with detailed information
 func (tc *TypeChecker) addDetailedError(pos lexer.Position, message string, code ErrorCode, suggestion string, context string) {
+	tc.addSpanError(lexer.Span{Start: pos, End: pos}, message, code, suggestion, context)
+}
+
+// addNodeError adds a detailed type error spanning the source of node
+func (tc *TypeChecker) addNodeError(node ast.Node, message string, code ErrorCode, suggestion string, context string) {
+	tc.addSpanError(ast.SpanOf(node), message, code, suggestion, context)
+}
+
+// addOperatorError adds a detailed type error at the operator of a unary,
+// binary or assignment expression
+func (tc *TypeChecker) addOperatorError(node ast.Node, message string, code ErrorCode, suggestion string, context string) {
+	tc.addSpanError(operatorSpan(node), message, code, suggestion, context)
+}
+
+// addSpanError adds a detailed type error covering span
+func (tc *TypeChecker) addSpanError(span lexer.Span, message string, code ErrorCode, suggestion string, context string) {
 	tc.errors = append(tc.errors, &TypeError{
-		Position:   pos,
-		Message:    message,
-		Code:       code,
-		Suggestion: suggestion,
-		Context:    context,
+		Position:    span.Start,
+		EndPosition: span.End,
+		Span:        span,
+		Message:     message,
+		Code:        code,
+		Suggestion:  suggestion,
+		Context:     context,
+	})
+}
+
+// operatorSpan returns the span of the operator token of a unary, binary
+// or assignment expression, or the span of node if it has none
+func operatorSpan(node ast.Node) lexer.Span {
+	var pos lexer.Position
+	var operator lexer.Token
+	switch n := node.(type) {
+	case *ast.BinaryExpression:
+		pos, operator = n.OpPos, n.Operator
+	case *ast.UnaryExpression:
+		pos, operator = n.OpPos, n.Operator
+	case *ast.AssignmentExpression:
+		pos, operator = n.OpPos, n.Operator
+	}
+	if pos.Line == 0 {
+		return ast.SpanOf(node)
+	}
+	end := pos
+	end.Column += len(operator.String())
+	end.Offset += len(operator.String())
+	return lexer.Span{Start: pos, End: end}
+}
+
+// checkNegativeIndex warns about a constant negative array or string index,
+// which fails at runtime
+func (tc *TypeChecker) checkNegativeIndex(expr *ast.MemberExpression) {
+	index, ok := constantInteger(expr.Property)
+	if !ok || index >= 0 {
+		return
+	}
+	tc.addNodeWarning(expr,
+		fmt.Sprintf("Negative index %d is a runtime error", index),
+		NegativeIndexWarning,
+		fmt.Sprintf("Count from the end with 'len(%s) - %d'", expr.Object.String(), -index),
+		"Indexes start at 0 and can't be negative")
+}
+
+// addNodeWarning adds a warning spanning the source of node
+func (tc *TypeChecker) addNodeWarning(node ast.Node, message string, code ErrorCode, suggestion string, context string) {
+	span := ast.SpanOf(node)
+	tc.warnings = append(tc.warnings, &TypeError{
+		Position:    span.Start,
+		EndPosition: span.End,
+		Span:        span,
+		Message:     message,
+		Code:        code,
+		Suggestion:  suggestion,
+		Context:     context,
+		Severity:    SeverityWarning,
 	})
 }
 
@@ -898,11 +3046,59 @@ func (tc *TypeChecker) GetErrors() []*TypeError {
 	return tc.errors
 }
 
+// Warnings returns the warnings found by the last Check, in source order
+func (tc *TypeChecker) Warnings() []*TypeError {
+	return tc.warnings
+}
+
+// GetGlobalScope returns the global scope populated by the last Check
+func (tc *TypeChecker) GetGlobalScope() *Scope {
+	return tc.resolver.GetGlobalScope()
+}
+
+// DeclareFunction makes a function defined outside the program, such as a
+// native function registered with the VM, known to later checks
+func (tc *TypeChecker) DeclareFunction(name string, fnType *FunctionType) {
+	tc.resolver.GetGlobalScope().Define(name, &Symbol{Name: name, Type: fnType, Kind: FunctionSymbol})
+}
+
+// AddModule makes the exports of another checked module importable from
+// source, the string an import declaration names the module by
+func (tc *TypeChecker) AddModule(source string, exports map[string]*Symbol) {
+	tc.resolver.AddModule(source, exports)
+}
+
+// Exports returns the symbols a checked program exports, by name.
+// Classes, which have no symbol of their own, are exported as 'any'.
+func (tc *TypeChecker) Exports(program *ast.Program) map[string]*Symbol {
+	exports := make(map[string]*Symbol)
+	for _, stmt := range program.Body {
+		export, ok := stmt.(*ast.ExportDeclaration)
+		if !ok {
+			continue
+		}
+		for _, name := range ast.DeclaredNames(export.Declaration) {
+			symbol, ok := tc.resolver.GetGlobalScope().LookupLocal(name.Name)
+			if !ok {
+				symbol = &Symbol{Name: name.Name, Type: AnyType, Kind: VariableSymbol, Position: name.Pos()}
+			}
+			exports[name.Name] = symbol
+		}
+	}
+	return exports
+}
+
 // SetStrictMode enables or disables strict type checking
 func (tc *TypeChecker) SetStrictMode(strict bool) {
 	tc.strictMode = strict
 }
 
+// SetNoImplicitOverride requires members that override a base class member
+// to be marked 'override'
+func (tc *TypeChecker) SetNoImplicitOverride(enabled bool) {
+	tc.noImplicitOverride = enabled
+}
+
 // inferParameterType attempts to infer the type of a parameter from its usage in the function body
 func (tc *TypeChecker) inferParameterType(paramName string, body ast.Node) Type {
 	// This is a simple implementation that looks for numeric operations
@@ -0,0 +1,1089 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+)
+
+// Helper function to parse and type check source code
+func checkSource(t *testing.T, tc *TypeChecker, input string) []*TypeError {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) > 0 {
+		t.Fatalf("parser errors: %v", errors)
+	}
+	return tc.Check(program)
+}
+
+// expectErrorCodes checks that type checking input reports errors with
+// exactly the given codes, in order
+func expectErrorCodes(t *testing.T, input string, codes ...ErrorCode) {
+	t.Helper()
+	expectCheckerErrorCodes(t, NewTypeChecker(), input, codes...)
+}
+
+// expectCheckerErrorCodes is expectErrorCodes with a configured checker
+func expectCheckerErrorCodes(t *testing.T, tc *TypeChecker, input string, codes ...ErrorCode) {
+	t.Helper()
+	if errors := checkSource(t, tc, input); !sameCodes(errors, codes) {
+		t.Errorf("%q: expected errors %v, got %v", input, codes, errors)
+	}
+}
+
+// sameCodes reports whether errors have exactly the given codes
+func sameCodes(errors []*TypeError, codes []ErrorCode) bool {
+	if len(errors) != len(codes) {
+		return false
+	}
+	for i, err := range errors {
+		if err.Code != codes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const overrideBase = `
+class Animal {
+    name: string = "animal"
+    speak(loud: boolean): string {
+        return "..."
+    }
+}
+`
+
+func TestOverrideChecking(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors int
+	}{
+		// Genuine override with the same signature
+		{`class Dog extends Animal {
+    override speak(loud: boolean): string { return "woof" }
+    override name: string = "dog"
+}`, 0},
+		// Overrides may drop trailing parameters
+		{`class Dog extends Animal {
+    override speak(): string { return "woof" }
+}`, 0},
+		// No base member to override
+		{`class Dog extends Animal {
+    override fetch(): int { return 1 }
+}`, 1},
+		// Incompatible parameter and return types
+		{`class Dog extends Animal {
+    override speak(times: int): string { return "woof" }
+}`, 1},
+		{`class Dog extends Animal {
+    override speak(loud: boolean): int { return 1 }
+}`, 1},
+		// Members inherited through an intermediate class
+		{`class Dog extends Animal {}
+class Puppy extends Dog {
+    override speak(loud: boolean): string { return "yip" }
+}`, 0},
+		// No base class at all
+		{`class Rock {
+    override speak(): string { return "" }
+}`, 1},
+	}
+
+	for _, tt := range tests {
+		errors := checkSource(t, NewTypeChecker(), overrideBase+tt.input)
+		if len(errors) != tt.errors {
+			t.Errorf("%q: expected %d errors, got %d: %v", tt.input, tt.errors, len(errors), errors)
+			continue
+		}
+		for _, err := range errors {
+			if err.Code != InvalidOverrideError {
+				t.Errorf("%q: expected %s, got %s", tt.input, InvalidOverrideError, err.Code)
+			}
+		}
+	}
+}
+
+func TestNoImplicitOverride(t *testing.T) {
+	input := overrideBase + `
+class Dog extends Animal {
+    speak(loud: boolean): string { return "woof" }
+}`
+
+	if errors := checkSource(t, NewTypeChecker(), input); len(errors) != 0 {
+		t.Fatalf("expected implicit override to be allowed by default, got %v", errors)
+	}
+
+	tc := NewTypeChecker()
+	tc.SetNoImplicitOverride(true)
+	errors := checkSource(t, tc, input)
+	if len(errors) != 1 || errors[0].Code != InvalidOverrideError {
+		t.Fatalf("expected one %s error, got %v", InvalidOverrideError, errors)
+	}
+}
+
+func TestSwitchCaseComparability(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors int
+	}{
+		{`let n: int = 1
+switch (n) {
+    case 1:
+    case 2.5:
+        break
+    default:
+}`, 0},
+		{`let s: string = "a"
+switch (s) {
+    case "a":
+    case 1:
+}`, 1},
+		{`let b: boolean = true
+switch (b) {
+    case "yes":
+    case 0:
+}`, 2},
+		// Declarations in one clause are visible in later clauses
+		{`let n: int = 1
+switch (n) {
+    case 1:
+        let label: string = "one"
+    case 2:
+        label = "two"
+}`, 0},
+	}
+
+	for _, tt := range tests {
+		errors := checkSource(t, NewTypeChecker(), tt.input)
+		if len(errors) != tt.errors {
+			t.Errorf("%q: expected %d errors, got %d: %v", tt.input, tt.errors, len(errors), errors)
+			continue
+		}
+		for _, err := range errors {
+			if err.Code != TypeMismatchError {
+				t.Errorf("%q: expected %s, got %s", tt.input, TypeMismatchError, err.Code)
+			}
+		}
+	}
+}
+
+func TestTypeErrorSpan(t *testing.T) {
+	errors := checkSource(t, NewTypeChecker(), "let a: int = 1\nlet b: string = \"x\"\nlet c = a - b")
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+
+	// The error covers the operator of 'a - b'
+	err := errors[0]
+	if err.Span.String() != "3:11-3:12" {
+		t.Errorf("wrong span: %s", err.Span)
+	}
+	if err.Position != err.Span.Start || err.EndPosition != err.Span.End {
+		t.Errorf("positions %v-%v do not match span %s", err.Position, err.EndPosition, err.Span)
+	}
+}
+
+func TestTypeErrorPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		start    string
+		end      string
+		location string
+	}{
+		{"binary operator", "let s: string = \"x\"\nlet n = s -\n  1", "2:11", "2:12", "line 2, column 11"},
+		{"compound operator", "let s: string = \"x\"\nlet n: int = 1\nn -= s", "3:3", "3:5", "line 3, columns 3-4"},
+		{"equality operator", "let s: string = \"x\"\nlet b = 1 === s", "2:11", "2:14", "line 2, columns 11-13"},
+		{"unary operator", "let s: string = \"x\"\nlet n = -s", "2:9", "2:10", "line 2, column 9"},
+		{"comparison operator", "let b: boolean = true\nlet c = 1 >= b", "2:11", "2:13", "line 2, columns 11-12"},
+		{"argument", "function f(a: int, b: string) {\n}\nf(1, 2 + 3)", "3:6", "3:11", "line 3, columns 6-10"},
+		{"declared name", "\nlet total: string = 1", "2:5", "2:10", "line 2, columns 5-9"},
+		{"constraint", "function longest<T extends int>(a: T, b: T): T { return a }\nlongest(1, 2.5)", "2:12", "2:15", "line 2, columns 12-14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := checkSource(t, NewTypeChecker(), tt.input)
+			if len(errors) != 1 {
+				t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+			}
+			err := errors[0]
+			start := fmt.Sprintf("%d:%d", err.Position.Line, err.Position.Column)
+			end := fmt.Sprintf("%d:%d", err.EndPosition.Line, err.EndPosition.Column)
+			if start != tt.start || end != tt.end {
+				t.Errorf("expected %s-%s, got %s-%s", tt.start, tt.end, start, end)
+			}
+			if !strings.Contains(err.Error(), " at "+tt.location+": ") {
+				t.Errorf("expected location %q in %q", tt.location, err.Error())
+			}
+		})
+	}
+}
+
+func TestDoWhileCondition(t *testing.T) {
+	input := "let n: int = 3\ndo {\n    n = n - 1\n} while (n)"
+
+	// Strict mode is on by default
+	errors := checkSource(t, NewTypeChecker(), input)
+	if len(errors) != 1 || errors[0].Code != InvalidConditionError {
+		t.Fatalf("expected one %s error, got %v", InvalidConditionError, errors)
+	}
+
+	tc := NewTypeChecker()
+	tc.SetStrictMode(false)
+	if errors := checkSource(t, tc, input); len(errors) != 0 {
+		t.Fatalf("expected non-boolean condition to be allowed outside strict mode, got %v", errors)
+	}
+}
+
+func TestDestructuringTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		// Each name gets its part of the initializer's type
+		{"const {x, y: [a, b = \"d\"]} = {x: 1, y: [\"s\"]}\nlet n: int = x\nlet s: string = a + b", nil},
+		{"const [a] = [1]\nlet s: string = a", []ErrorCode{TypeMismatchError}},
+		// Defaults must match the part they stand in for
+		{"const [a = \"d\"] = [1]", []ErrorCode{TypeMismatchError}},
+		{"const {z} = {x: 1}", []ErrorCode{InvalidMemberAccessError}},
+		{"const [a] = {x: 1}", []ErrorCode{TypeMismatchError}},
+		{"const {x} = [1]", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestDeclaredNativeFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"clamp(1)\nclamp(1, 2, 3)", nil},
+		{"clamp()", []ErrorCode{ArgumentCountMismatchError}},
+		{"clamp(1, 2, 3, 4)", []ErrorCode{ArgumentCountMismatchError}},
+		{"log(1)\nlog(1, \"a\", true)", nil},
+		{"log()", []ErrorCode{ArgumentCountMismatchError}},
+		{"square(2)", nil},
+		{"square(\"2\")", []ErrorCode{ArgumentCountMismatchError}},
+		// Built-in natives match the VM's
+		{"let n: int = len(\"abc\") + len([1, 2])", nil},
+	}
+
+	for _, tt := range tests {
+		tc := NewTypeChecker()
+		tc.DeclareFunction("clamp", NewNativeFunctionType(1, 3, AnyType))
+		tc.DeclareFunction("log", NewNativeFunctionType(1, -1, VoidType))
+		tc.DeclareFunction("square", NewFunctionType([]Type{IntType}, IntType))
+
+		expectCheckerErrorCodes(t, tc, tt.input, tt.codes...)
+	}
+
+	if got := NewNativeFunctionType(1, 3, AnyType).String(); got != "(any, any?, any?) => any" {
+		t.Errorf("wrong native function type: %s", got)
+	}
+}
+
+func TestSpreadTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"const a = [1, 2]\nconst b: int[] = [...a, 3]", nil},
+		{"const a = [1, 2]\nconst b: string[] = [...a]", []ErrorCode{TypeMismatchError}},
+		{"const base = {x: 1}\nconst o = {...base, y: \"s\"}\nlet n: int = o.x\nlet s: string = o.y", nil},
+		{"const n = 1\nconst a = [...n]", []ErrorCode{NotIterableError}},
+		{"const n = 1\nconst o = {...n}", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestSpreadArgumentTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"const items = [\"one\", \"two\"]\nprint(...items)", nil},
+		{"const items = [1, 2]\nprint(\"items:\", ...items, \"end\")", nil},
+		{"function add(a: int, b: int): int { return a + b }\nconst xs = [1, 2]\nconst n: int = add(...xs)", nil},
+		{"function add(a: int, b: int): int { return a + b }\nconst xs = [\"a\"]\nadd(1, ...xs)", []ErrorCode{TypeMismatchError}},
+		{"function add(a: int, b: int): int { return a + b }\nconst xs = [1]\nadd(1, 2, 3, ...xs)", []ErrorCode{ArgumentCountMismatchError}},
+		{"function add(a: int, b: int): int { return a + b }\nconst xs = [1]\nadd(1, 2, ...xs)", []ErrorCode{ArgumentCountMismatchError}},
+		{"const n = 1\nprint(...n)", []ErrorCode{NotIterableError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestRestParameterTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"function sum(...nums: int[]): int {\n    let total: int = 0\n    for (const n of nums) {\n        total = total + n\n    }\n    return total\n}\nsum()\nsum(1, 2, 3)", nil},
+		{"function sum(...nums: int[]): int { return len(nums) }\nsum(1, \"x\")", []ErrorCode{ArgumentCountMismatchError}},
+		{"function sum(...nums: int[]): int { return len(nums) }\nconst xs = [\"a\"]\nsum(...xs)", []ErrorCode{TypeMismatchError}},
+		{"function label(name: string, ...rest: int[]): string { return name }\nlabel()", []ErrorCode{ArgumentCountMismatchError}},
+		{"function label(name: string, ...rest: int[]): string { return name }\nlabel(\"a\", 1, 2)", nil},
+		{"const f = (...xs: string[]): int => len(xs)\nf(\"a\", \"b\")\nf(1)", []ErrorCode{ArgumentCountMismatchError}},
+		{"function collect(...xs) { return xs }\ncollect(1, \"a\", true)", nil},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestParameterDefaultTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"function greet(name: string = \"world\"): string { return name }\ngreet()\ngreet(\"you\")", nil},
+		{"function f(a: int, b: int = a + 1): int { return a + b }\nf(1)\nf(1, 2)", nil},
+		{"function f(a: int, b: int = a + 1): int { return a + b }\nf()", []ErrorCode{ArgumentCountMismatchError}},
+		{"function f(a: int, b: int = a + 1): int { return a + b }\nf(1, 2, 3)", []ErrorCode{ArgumentCountMismatchError}},
+		{"function f(a: int = \"x\"): int { return a }", []ErrorCode{TypeMismatchError}},
+		{"const g = (x: int = 7): int => x\ng()", nil},
+		{"const g = (x: int = true): int => x", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestTypeofNarrowing(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"function f(x: string | int): string {\n    if (typeof x === \"string\") {\n        let s: string = x\n        return s\n    } else {\n        let n: int = x\n        return \"number\"\n    }\n}", nil},
+		{"function f(x: string | int): int {\n    if (typeof x !== \"string\") {\n        return x * 2\n    }\n    return 0\n}", nil},
+		{"function f(x: string | int | boolean): int {\n    if (\"number\" == typeof x) {\n        return x\n    } else if (typeof x === \"boolean\") {\n        let b: boolean = x\n    } else {\n        let s: string = x\n    }\n    return 0\n}", nil},
+		// A narrowed variable can still be assigned its declared type
+		{"function f(x: string | int): void {\n    if (typeof x === \"string\") {\n        x = 5\n    }\n}", nil},
+		// Narrowing ends with the branch
+		{"function f(x: string | int): int {\n    if (typeof x === \"number\") {\n        let n: int = x\n    }\n    let m: int = x\n    return m\n}", []ErrorCode{TypeMismatchError}},
+		{"function f(x: string | int): void {\n    if (typeof x === \"string\") {\n        let n: int = x\n    }\n}", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestConditionTypes(t *testing.T) {
+	tests := []struct {
+		condition string
+		strict    []ErrorCode
+		loose     []ErrorCode
+	}{
+		{"flag", nil, nil},
+		{"value", nil, nil},
+		{"count", []ErrorCode{InvalidConditionError}, nil},
+		{"name", []ErrorCode{InvalidConditionError}, nil},
+		{"maybe", []ErrorCode{InvalidConditionError}, nil},
+		{"items", []ErrorCode{InvalidConditionError}, []ErrorCode{InvalidConditionError}},
+		{"point", []ErrorCode{InvalidConditionError}, []ErrorCode{InvalidConditionError}},
+		{"nothing()", []ErrorCode{InvalidConditionError}, []ErrorCode{InvalidConditionError}},
+	}
+	declarations := "let flag: boolean = true\nlet value: any = 1\nlet count: int = 0\nlet name: string = \"\"\n" +
+		"let maybe: string | null = null\nlet items: int[] = [1]\nlet point = {x: 1}\nfunction nothing(): void {}\n"
+
+	for _, tt := range tests {
+		for _, construct := range []string{"if (COND) {}", "while (COND) {}", "let r = COND ? 1 : 2"} {
+			input := declarations + strings.ReplaceAll(construct, "COND", tt.condition)
+			if errors := checkSource(t, NewTypeChecker(), input); !sameCodes(errors, tt.strict) {
+				t.Errorf("%q in strict mode: expected %v, got %v", tt.condition, tt.strict, errors)
+			}
+
+			tc := NewTypeChecker()
+			tc.SetStrictMode(false)
+			if errors := checkSource(t, tc, input); !sameCodes(errors, tt.loose) {
+				t.Errorf("%q outside strict mode: expected %v, got %v", tt.condition, tt.loose, errors)
+			}
+		}
+	}
+}
+
+func TestStrictEqualityTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let a = 1\nlet b = a === 2", nil},
+		{"let a: int8 = 1\nlet b = a === 2", nil},
+		{"let a = 1.5\nlet b = a !== 2.5", nil},
+		// Integers and floats are never strictly equal
+		{"let a = 1\nlet b = a === 2.5", []ErrorCode{TypeMismatchError}},
+		{"let a: float32 = 1\nlet b = a !== 1", []ErrorCode{TypeMismatchError}},
+		{"let a = 1\nlet b = a === \"1\"", []ErrorCode{TypeMismatchError}},
+		{"let a = \"x\"\nlet b = a !== true", []ErrorCode{TypeMismatchError}},
+		{"let a = \"x\"\nlet b = a == 1", nil},
+		{"let a: string | null = null\nlet b = a === null", nil},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestCallTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"function f(): int { return 1 }\nlet r = f()", nil},
+		{"let f: any = 1\nlet r: int = f(1, \"a\")", nil},
+		{"let f = 1\nlet r = f()", []ErrorCode{InvalidCallError}},
+		{"let f = \"s\"\nlet r = f()", []ErrorCode{InvalidCallError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestBitwiseTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let a = 6\nlet b: int = (a & 3) | (a << 2) ^ ~a", nil},
+		{"let a = -1\nlet b: int = a >>> 60", nil},
+		{"let a: int8 = 3\nlet b: int8 = a << 1", nil},
+		{"let a = 2.5\nlet b = a & 1", []ErrorCode{InvalidOperatorError}},
+		{"let a = 2.5\nlet b = ~a", []ErrorCode{InvalidOperatorError}},
+		{"let s = \"x\"\nlet b = s >> 1", []ErrorCode{InvalidOperatorError}},
+		{"let a = 2.5\nlet b = (a as int) & 1", nil},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+
+	errors := checkSource(t, NewTypeChecker(), "let a = 2.5\nlet b = a | 1")
+	if len(errors) != 1 || !strings.Contains(errors[0].Suggestion, "as int") {
+		t.Errorf("expected a suggestion to convert the float operand, got %v", errors)
+	}
+}
+
+func TestExponentiationTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let a: int = 2 ** 10", nil},
+		{"let a: float = 2.0 ** 0.5", nil},
+		{"let a: float = 2 ** 0.5", nil},
+		{"let a: int = 2 ** 0.5", []ErrorCode{TypeMismatchError}},
+		{"let a = 2 ** -1", []ErrorCode{InvalidOperatorError}},
+		{"let a: float = 2.0 ** -1", nil},
+		{"let a = \"x\" ** 2", []ErrorCode{InvalidOperatorError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestDivisionTypes(t *testing.T) {
+	// Division and modulo follow the VM: two integers give an integer, and
+	// any float operand gives a float
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let a: int = 6 / 2", nil},
+		{"let a: int = 7 / 2", nil},
+		{"let n: int8 = 9\nlet a: int8 = n / 2", nil},
+		{"let a: float = 7 / 2.0", nil},
+		{"let a: int = 7 / 2.0", []ErrorCode{TypeMismatchError}},
+		{"let a: int = 7.0 / 2", []ErrorCode{TypeMismatchError}},
+		{"let a: int = -7 % 3", nil},
+		{"let a: int = 7.5 % 2", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestMathTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let x = 2\nlet r: float = Math.sqrt(x) * Math.PI", nil},
+		{"const xs = [1, 2, 3]\nlet i: int = Math.floor(Math.random() * len(xs))\nlet v = xs[i]", nil},
+		{"let m: float = Math.max(1, 2.5, 3)", nil},
+		{"let r: int = Math.sqrt(4)", []ErrorCode{TypeMismatchError}},
+		{"let r = Math.abs(\"x\")", []ErrorCode{ArgumentCountMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestTypeAnnotationForms(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let xs: int[][] = [[1, 2], [3]]\nlet n: int = xs[0][1]", nil},
+		{"let xs: int[][] = [1, 2]", []ErrorCode{TypeMismatchError}},
+		{"let u: int | string | null = null\nu = \"a\"\nu = 2", nil},
+		{"let u: int | string | null = null\nu = true", []ErrorCode{InvalidAssignmentError}},
+		{"let g: (int | string)[] = []", nil},
+		{"let p: (int) = 1", nil},
+		{"let o: { x: int; y: string } = {x: 1, y: \"a\"}\nlet n: int = o.x", nil},
+		{"let o: { x: int; y?: string } = {x: 1}", nil},
+		{"let o: { x: int; y: string } = {x: 1}", []ErrorCode{TypeMismatchError}},
+		{"let p: { x: int; y: int } = {x: 1, y: 2}\np.x = p.y + 1", nil},
+		{"let p: { x: int; y: int } = {x: 1, y: 2}\np.x = \"s\"", []ErrorCode{InvalidAssignmentError}},
+		{"let p: { x: int; y: int } = {x: 1, y: 2}\nlet z = p.z", []ErrorCode{InvalidMemberAccessError}},
+		{"let p: { pos: { x: int } } = {pos: {x: 1}}\nlet n: int = p.pos.x", nil},
+		{"let t: [int, string] = [1, \"a\"]\nlet v: int | string = t[0]", nil},
+		{"let t: [int, string] = [1, true]", []ErrorCode{TypeMismatchError}},
+		{"const f: (x: int) => int = (x: int): int => x * 2\nlet n: int = f(2)", nil},
+		{"const f: (x: int) => int = (s: string): string => s", []ErrorCode{TypeMismatchError}},
+		{"function apply(cb: (n: int) => string, v: int): string { return cb(v) }", nil},
+		{"function apply(cb: (n: int) => string): int { return cb(1) }", []ErrorCode{InvalidReturnTypeError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestJSONTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let s: string = JSON.stringify({a: [1, 2]})\nlet v = JSON.parse(s)", nil},
+		{"let n: int = JSON.stringify(1)", []ErrorCode{TypeMismatchError}},
+		{"let v = JSON.parse(1)", []ErrorCode{ArgumentCountMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestAssertTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"assert(1 < 2)\nassert(true, \"message\")", nil},
+		{"assert()", []ErrorCode{ArgumentCountMismatchError}},
+		{"assert(1)", []ErrorCode{ArgumentCountMismatchError}},
+		{"assert(true, 1)", []ErrorCode{ArgumentCountMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestStringIndexTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let s: string = \"abc\"\nlet c: string = s[0]", nil},
+		{"let s: string = \"abc\"\nlet n: int = s[1]", []ErrorCode{TypeMismatchError}},
+		{"let s: string = \"abc\"\nlet c = s[\"0\"]", []ErrorCode{InvalidMemberAccessError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestAssignmentToUndeclared(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let counter: int = 0\ncounter = 5", nil},
+		{"let counter: int = 0\ncountre = 5", []ErrorCode{AssignmentToUndeclaredError}},
+		{"total += 1", []ErrorCode{AssignmentToUndeclaredError}},
+		{"function f(): void {\n    missing = 1\n}", []ErrorCode{AssignmentToUndeclaredError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+
+	// Outside strict mode the assignment creates a global, as before
+	tc := NewTypeChecker()
+	tc.SetStrictMode(false)
+	if errors := checkSource(t, tc, "countre = 5"); len(errors) != 0 {
+		t.Errorf("expected no errors outside strict mode, got %v", errors)
+	}
+}
+
+func TestConstInitializer(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"const x;", []ErrorCode{MissingInitializerError}},
+		{"const x: int;", []ErrorCode{MissingInitializerError}},
+		{"const x = 1;", nil},
+		{"let y: int;", nil},
+		// let keeps the strict-mode rule for untyped declarations
+		{"let y;", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestBlockShadowing(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let x = 1\n{\n    let x = \"s\"\n}\nx = 2", nil},
+		{"const k = 1\n{\n    let k = 2\n    k = 3\n}", nil},
+		{"let x = 1\n{\n    const x = 2\n}\nx = 3", nil},
+		{"function f(): void {\n    let y = 1\n    {\n        let y = 2\n    }\n    y = 3\n}", nil},
+		{"const k = 1\n{\n    k = 2\n}", []ErrorCode{ConstReassignmentError}},
+		{"let x = 1\n{\n    const x = 2\n    x = 3\n}", []ErrorCode{ConstReassignmentError}},
+		{"let x = 1\nlet x = 2", []ErrorCode{LetRedeclarationError}},
+		{"{\n    let x = 1\n    let x = 2\n}", []ErrorCode{LetRedeclarationError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestJumpOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"while (true) {\n    break\n}", nil},
+		{"for (let i = 0; i < 3; i++) {\n    continue\n}", nil},
+		{"let x: int = 1\nswitch (x) {\ncase 1:\n    break\n}", nil},
+		{"for (let i = 0; i < 3; i++) {\n    switch (i) {\n    case 1:\n        continue\n    }\n}", nil},
+		{"break", []ErrorCode{InvalidJumpError}},
+		{"continue", []ErrorCode{InvalidJumpError}},
+		{"let x: int = 1\nswitch (x) {\ncase 1:\n    continue\n}", []ErrorCode{InvalidJumpError}},
+		// A function body does not inherit the loops around it
+		{"while (true) {\n    let f = function(): void {\n        break\n    }\n    break\n}", []ErrorCode{InvalidJumpError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestMissingReturn(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"function f(x: boolean): int {\n    if (x) { return 1 }\n    return 0\n}", nil},
+		{"function f(x: boolean): int {\n    if (x) { return 1 } else { return 0 }\n}", nil},
+		{"function f(x: boolean): int {\n    if (x) { return 1 }\n    throw \"no\"\n}", nil},
+		{"function f(x: int): int {\n    switch (x) {\n    case 1:\n    default:\n        return 0\n    }\n}", nil},
+		{"function f(): int {\n    try {\n        return 1\n    } catch (e) {\n        return 2\n    }\n}", nil},
+		{"function f(x: boolean): void {\n    if (x) { return }\n}", nil},
+		{"function f(x: boolean): int | undefined {\n    if (x) { return 1 }\n}", nil},
+		{"function f(x: boolean): int {\n    if (x) { return 1 }\n}", []ErrorCode{InvalidReturnTypeError}},
+		{"function f(): int {\n}", []ErrorCode{InvalidReturnTypeError}},
+		{"function f(x: int): int {\n    switch (x) {\n    case 1:\n        return 1\n    }\n}", []ErrorCode{InvalidReturnTypeError}},
+		{"function f(x: int): int {\n    switch (x) {\n    case 1:\n        break\n    default:\n        return 0\n    }\n}", []ErrorCode{InvalidReturnTypeError}},
+		{"function f(x: int): int {\n    while (x > 0) {\n        return x\n    }\n}", []ErrorCode{InvalidReturnTypeError}},
+		{"const f = (x: boolean): string => {\n    if (x) { return \"a\" }\n}", []ErrorCode{InvalidReturnTypeError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestTupleTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let t: [string, int] = [\"a\", 1]\nlet s: string = t[0]\nlet n: int = t[1]", nil},
+		{"let t: [int8, string] = [1, \"a\"]", nil},
+		{"let t: [string, int] = [\"a\", 1]\nt = [\"b\", 2]", nil},
+		{"let t: [string, int] = [\"a\", 1]\nlet [s, n] = t\nlet m: int = n", nil},
+		{"let t: [string, int] = [\"a\", 1]\nlet xs: (string | int)[] = t", nil},
+		{"let t: [string, int] = [\"a\", 1]\nfor (const v of t) {\n    print(v)\n}", nil},
+		{"let t: [string, int] = [\"a\"]", []ErrorCode{TypeMismatchError}},
+		{"let t: [string, int] = [\"a\", 1, 2]", []ErrorCode{TypeMismatchError}},
+		{"let t: [string, int] = [1, \"a\"]", []ErrorCode{TypeMismatchError}},
+		{"let t: [int8, string] = [300, \"a\"]", []ErrorCode{TypeMismatchError}},
+		{"let xs = [\"a\", 1]\nlet t: [string, int] = xs", []ErrorCode{TypeMismatchError}},
+		{"let t: [string, int] = [\"a\", 1]\nt = [\"b\"]", []ErrorCode{InvalidAssignmentError}},
+		{"let t: [string, int] = [\"a\", 1]\nt[1] = \"s\"", []ErrorCode{InvalidAssignmentError}},
+		{"let t: [string, int] = [\"a\", 1]\nlet x = t[2]", []ErrorCode{InvalidArrayElementError}},
+		{"function g(): [string, int] { return [\"c\", 3] }", nil},
+		{"function g(): [string, int] { return [3, \"c\"] }", []ErrorCode{InvalidReturnTypeError}},
+		{"function g(p: [string, int] = [\"a\", 1]): int { return p[1] }", nil},
+		{"function g(p: [string, int] = [\"a\"]): int { return p[1] }", []ErrorCode{TypeMismatchError}},
+		{"let o: { pair: [string, int] } = { pair: [\"a\", 1] }", nil},
+		{"let o: { pair: [string, int] } = { pair: [1, \"a\"] }", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+
+	errors := checkSource(t, NewTypeChecker(), "let t: [string, int] = [\"a\"]")
+	if len(errors) != 1 || !strings.Contains(errors[0].Context, "1 elements, expected 2") {
+		t.Errorf("expected the length mismatch to be explained, got %v", errors)
+	}
+}
+
+func TestFunctionTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"const f: (x: int) => int = (x: int): int => x + 1\nlet n: int = f(1)", nil},
+		{"const f: (x: int) => float = (x: float): int => 1", nil},
+		{"const f: (x: int, y: int) => int = (x: int): int => x", nil},
+		{"const f: (x: int) => int = (x) => x", nil},
+		{"const f: (s: string) => void = (s: string): int => len(s)", nil},
+		{"function id(a: int): int { return a }\nconst f: (x: int) => int = id", nil},
+		{"const f: (...xs: int[]) => int = (a: int, b: int): int => a + b", nil},
+		{"function apply(f: (x: int) => int, v: int): int { return f(v) }\napply((n: int): int => n * 2, 3)", nil},
+		{"const f: (x: int) => int = (s: string): int => len(s)", []ErrorCode{TypeMismatchError}},
+		{"const f: (x: float) => int = (x: int): int => x", []ErrorCode{TypeMismatchError}},
+		{"const f: (x: int) => int = (x: int): string => \"a\"", []ErrorCode{TypeMismatchError}},
+		{"const f: (x: int) => int = (x: int, y: int): int => x", []ErrorCode{TypeMismatchError}},
+		{"const f: (...xs: string[]) => int = (a: int): int => a", []ErrorCode{TypeMismatchError}},
+		{"function apply(f: (x: int) => int, v: int): int { return f(v) }\napply((s: string): string => s, 3)", []ErrorCode{ArgumentCountMismatchError}},
+		{"const f: (x: int) => int = (x: int): int => x\nf(\"a\")", []ErrorCode{ArgumentCountMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+
+	errors := checkSource(t, NewTypeChecker(), "const f: (x: int) => int = (x: int, y: int): int => x")
+	if len(errors) != 1 || !strings.Contains(errors[0].Context, "requires 2 arguments, expected at most 1") {
+		t.Errorf("expected the argument count mismatch to be explained, got %v", errors)
+	}
+}
+
+func TestArrayBuiltinTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"const xs: int[] = [1]\nlet n: int = push(xs, 2, 3)\nlet last: int = pop(xs)", nil},
+		{"const names = [\"a\"]\nlet name: string = pop(names)", nil},
+		{"const xs: int[] = [1]\npush(xs, \"a\")", []ErrorCode{ArgumentCountMismatchError}},
+		{"const xs: int[] = [1]\nlet s: string = pop(xs)", []ErrorCode{TypeMismatchError}},
+		{"push(1, 2)", []ErrorCode{ArgumentCountMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestInterfaceConformance(t *testing.T) {
+	decls := "interface Named { name: string; nick?: string }\ninterface Person extends Named { age: int }\n"
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"const p: Person = { name: \"a\", age: 1 }", nil},
+		{"const p: Person = { name: \"a\", age: 1, nick: \"b\" }", nil},
+		{"const p: Person = { age: 1 }", []ErrorCode{TypeMismatchError}},
+		{"const p: Person = { name: \"a\", age: \"1\" }", []ErrorCode{TypeMismatchError}},
+		{"const p: Person = { name: \"a\", age: 1, nick: 2 }", []ErrorCode{TypeMismatchError}},
+		{"const p: Person = { name: \"a\", age: 1, color: \"red\" }", []ErrorCode{TypeMismatchError}},
+		{"let p: Person = { name: \"a\", age: 1 }\np = { name: \"b\", age: 2, extra: true }", []ErrorCode{TypeMismatchError}},
+		{"function greet(p: Person): string { return p.name }\ngreet({ name: \"a\", age: 1, color: 1 })", []ErrorCode{TypeMismatchError}},
+		{"const o = { name: \"a\", age: 1, color: \"red\" }\nconst p: Person = o", nil},
+		{"interface Team { lead: Person }\nconst t: Team = { lead: { name: \"a\", age: 1, extra: 1 } }", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, decls+tt.input, tt.codes...)
+	}
+
+	// Unknown properties are only rejected in strict mode
+	tc := NewTypeChecker()
+	tc.SetStrictMode(false)
+	if errors := checkSource(t, tc, decls+"const p: Person = { name: \"a\", age: 1, color: \"red\" }"); len(errors) != 0 {
+		t.Errorf("expected no errors outside strict mode, got %v", errors)
+	}
+}
+
+func TestConditionalExpressionTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let x: int = 1\nlet r: int = x > 0 ? 1 : 2", nil},
+		{"let x: int = 1\nlet r: float = x > 0 ? 1 : 2.5", nil},
+		{"let x: int = 1\nlet r: int | string = x > 0 ? 1 : \"a\"", nil},
+		{"let x: int = 1\nlet r: string = x > 0 ? \"a\" : x < 0 ? \"b\" : \"c\"", nil},
+		{"let x: int = 1\nlet r: int = x > 0 ? 1 : \"a\"", []ErrorCode{TypeMismatchError}},
+		{"let x: int = 1\nlet r: int = x > 0 ? 1 : 2.5", []ErrorCode{TypeMismatchError}},
+		// Sized integers widen to the wider type in either order
+		{"let a: int8 = 1\nlet b: int32 = 2\nlet r: int32 = a > 0 ? a : b", nil},
+		{"let a: int8 = 1\nlet b: int32 = 2\nlet r: int32 = a > 0 ? b : a", nil},
+		{"let a: int8 = 1\nlet b: int32 = 2\nlet r: int8 = a > 0 ? a : b", []ErrorCode{TypeMismatchError}},
+		{"let a: int8 = 1\nlet b: int32 = 2\nlet r: int8 = a > 0 ? b : a", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestCommonType(t *testing.T) {
+	tests := []struct {
+		a, b     Type
+		expected Type
+	}{
+		{Int8Type, Int32Type, Int32Type},
+		{Int32Type, Float32Type, FloatType},
+		{IntType, Int64Type, IntType},
+		{Float32Type, FloatType, FloatType},
+	}
+
+	// The result doesn't depend on the order of the operands
+	for _, tt := range tests {
+		for _, pair := range [][2]Type{{tt.a, tt.b}, {tt.b, tt.a}} {
+			if got := commonType(pair[0], pair[1]); !got.Equals(tt.expected) {
+				t.Errorf("commonType(%s, %s) = %s, expected %s", pair[0], pair[1], got, tt.expected)
+			}
+		}
+	}
+}
+
+func TestArrayLiteralTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let xs = [1, 2, 3]", "int[]"},
+		{"let xs = [1, \"a\"]", "(int | string)[]"},
+		{"let xs = [1, \"a\", 2, true]", "(int | string | boolean)[]"},
+		{"let xs = [\"a\", ...[1, 2]]", "(string | int)[]"},
+		{"let xs = [[1], [\"a\"]]", "(int[] | string[])[]"},
+	}
+
+	for _, tt := range tests {
+		tc := NewTypeChecker()
+		if errors := checkSource(t, tc, tt.input); len(errors) > 0 {
+			t.Errorf("%q: unexpected errors %v", tt.input, errors)
+			continue
+		}
+		symbol, ok := tc.resolver.Lookup("xs")
+		if !ok {
+			t.Fatalf("%q: xs is not defined", tt.input)
+		}
+		if symbol.Type.String() != tt.expected {
+			t.Errorf("%q: expected type %s, got %s", tt.input, tt.expected, symbol.Type.String())
+		}
+	}
+
+	errors := checkSource(t, NewTypeChecker(), "let xs = [1, \"a\"]\nlet ys: int[] = xs")
+	if len(errors) != 1 || errors[0].Code != TypeMismatchError {
+		t.Errorf("expected a type mismatch for a mixed array, got %v", errors)
+	}
+}
+
+func TestNullishCoalescingTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let x: int | null = null\nlet y: int = x ?? 0", nil},
+		{"let s: string = null ?? \"x\"", nil},
+		{"let x: int | undefined = 1\nlet y: int | string = x ?? \"none\"", nil},
+		{"let x: int | null = null\nlet y: string = x ?? \"s\"", []ErrorCode{TypeMismatchError}},
+		{"let x: int | null = null\nlet y: int = x ?? null", []ErrorCode{TypeMismatchError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestOptionalChainTypes(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		{"let o = {a: {b: 1}}\nlet x: int | undefined = o?.a.b", nil},
+		{"let o = {a: {b: 1}}\nlet x: int = o?.a?.b ?? 0", nil},
+		{"const f = (x: int): int => x\nlet y: int = f?.(1) ?? 0", nil},
+		{"let o = {a: 1}\nlet x: int = o?.a", []ErrorCode{TypeMismatchError}},
+		{"const f = (x: int): int => x\nlet y: int = f?.(1)", []ErrorCode{TypeMismatchError}},
+		{"const f = (x: int): int => x\nf?.(\"s\")", []ErrorCode{ArgumentCountMismatchError}},
+		{"let o = {a: 1}\no?.b", []ErrorCode{InvalidMemberAccessError}},
+	}
+
+	for _, tt := range tests {
+		expectErrorCodes(t, tt.input, tt.codes...)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		input string
+		codes []ErrorCode
+	}{
+		// Globals are never reported
+		{"let x = 1", nil},
+		{"function f(a: int): int {\n    let b = 2\n    return a\n}", []ErrorCode{UnusedVariableWarning}},
+		{"function f(a: int, _b: int): int {\n    return a\n}", nil},
+		// Assigning a variable doesn't read it
+		{"function f(): int {\n    let x = 0\n    x = 1\n    return 0\n}", []ErrorCode{UnusedVariableWarning}},
+		{"function f(): int {\n    let x = 0\n    x += 1\n    return x\n}", nil},
+		{"function f(): void {\n    try {\n        print(1)\n    } catch (e) {\n        print(2)\n    }\n}", nil},
+		{"function f(a: int): int {\n    return a\n    print(a)\n    print(a)\n}", []ErrorCode{UnreachableCodeWarning}},
+		{"function f(a: int): void {\n    switch (a) {\n    case 1:\n        break\n        print(a)\n    }\n}", []ErrorCode{UnreachableCodeWarning}},
+		{"function f(a: int, b: int): int {\n    throw a\n    return 0\n}", []ErrorCode{UnusedVariableWarning, UnreachableCodeWarning}},
+		{"const xs = [1, 2]\nlet x = xs[-1]", []ErrorCode{NegativeIndexWarning}},
+		{"const s = \"abc\"\nlet c = s[0 - 2]", []ErrorCode{NegativeIndexWarning}},
+		{"const xs = [1, 2]\nlet x = xs[len(xs) - 1]", nil},
+	}
+
+	for _, tt := range tests {
+		tc := NewTypeChecker()
+		if errors := checkSource(t, tc, tt.input); len(errors) > 0 {
+			t.Errorf("%q: unexpected errors %v", tt.input, errors)
+			continue
+		}
+		warnings := tc.Warnings()
+		if len(warnings) != len(tt.codes) {
+			t.Errorf("%q: expected %d warnings, got %v", tt.input, len(tt.codes), warnings)
+			continue
+		}
+		for i, warning := range warnings {
+			if warning.Code != tt.codes[i] || warning.Severity != SeverityWarning {
+				t.Errorf("%q: expected warning %s, got %s", tt.input, tt.codes[i], warning.Error())
+			}
+		}
+	}
+}
+
+func TestModuleImports(t *testing.T) {
+	lib := NewTypeChecker()
+	p := parser.New(lexer.New(`export const PI: float = 3.14
+export function add(a: int, b: int): int { return a + b }
+export interface Point { x: int; y: int }
+function helper(): int { return 1 }`))
+	libProgram := p.ParseProgram()
+	if errors := lib.Check(libProgram); len(errors) > 0 {
+		t.Fatalf("unexpected errors in library: %v", errors)
+	}
+	exports := lib.Exports(libProgram)
+	if len(exports) != 3 {
+		t.Fatalf("expected 3 exports, got %d", len(exports))
+	}
+
+	tests := []struct {
+		input    string
+		expected string // substring of the first error, "" for no errors
+	}{
+		{`import { add, PI as pi, Point } from "./lib.tg"
+const p: Point = { x: add(1, 2), y: 0 }
+const f: float = pi`, ""},
+		{`import { add } from "./lib.tg"
+const s: string = add(1, 2)`, "Cannot assign value of type 'int'"},
+		{`import { helper } from "./lib.tg"`, "Module './lib.tg' has no exported member 'helper'"},
+		{`import { add } from "./other.tg"`, "Cannot find module './other.tg'"},
+		{`import { PI } from "./lib.tg"
+PI = 3.0`, "Cannot assign to const variable 'PI'"},
+		{`import { add } from "./lib.tg"
+add(1, 2)
+export function add3(a: int): int { return add(a, 3) }`, ""},
+	}
+
+	for _, tt := range tests {
+		tc := NewTypeChecker()
+		tc.AddModule("./lib.tg", exports)
+		errors := checkSource(t, tc, tt.input)
+		if tt.expected == "" {
+			if len(errors) > 0 {
+				t.Errorf("%q: unexpected errors: %v", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) == 0 || !strings.Contains(errors[0].Message, tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestGenericFunctions(t *testing.T) {
+	const generics = `function identity<T>(x: T): T { return x }
+function first<T>(xs: T[]): T { return xs[0] }
+function pair<T>(a: T, b: T): T[] { return [a, b] }
+function longest<T extends string>(a: T, b: T): T { return a }
+function nameOf<T extends { name: string }>(x: T): string { return x.name }
+`
+	tests := []struct {
+		input    string
+		expected string // substring of the first error, "" for no errors
+	}{
+		// T is inferred separately at each call site
+		{`let n: int = identity(5)
+let s: string = identity("hi")
+let xs: int[] = identity([1, 2])`, ""},
+		{`let s: string = identity(5)`, "Cannot assign value of type 'int' to variable of type 'string'"},
+		{`let f: float = first([1.5, 2.5])`, ""},
+		{`let b: boolean = first([1, 2])`, "Cannot assign value of type 'int' to variable of type 'boolean'"},
+		{`let n: int = identity(identity(5))`, ""},
+		// Later arguments may widen the inferred type
+		{`let fs: float[] = pair(1, 2.5)`, ""},
+		{`let ns: int[] = pair(1, 2.5)`, "Cannot assign value of type 'float[]' to variable of type 'int[]'"},
+		// Constraints
+		{`let s: string = longest("a", "bb")`, ""},
+		{`longest(1, 2)`, "Type 'int' does not satisfy the constraint 'string' of type parameter 'T'"},
+		{`let s: string = nameOf({ name: "x", age: 3 })`, ""},
+		{`nameOf({ age: 3 })`, "does not satisfy the constraint '{ name: string }'"},
+		// Inside the function, T is only assignable to itself and its constraint
+		{`function bad<T>(x: T): T { return 5 }`, "Cannot return type 'int' from a function with return type 'T'"},
+		{`function widen<T extends int>(x: T): float { return x }`, ""},
+		{`function narrow<T>(x: T): int { return x }`, "Cannot return type 'T' from a function with return type 'int'"},
+		// A generic function is assignable to any of its instances
+		{`const f: (x: int) => int = identity`, ""},
+	}
+
+	for _, tt := range tests {
+		errors := checkSource(t, NewTypeChecker(), generics+tt.input)
+		if tt.expected == "" {
+			if len(errors) > 0 {
+				t.Errorf("%q: unexpected errors: %v", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) == 0 || !strings.Contains(errors[0].Message, tt.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
@@ -58,12 +58,18 @@ func (ti *TypeInferrer) inferArrayLiteralType(expr *ast.ArrayLiteral) Type {
 		return NewArrayType(UndefinedType)
 	}
 	
-	// Infer type from first non-null element
+	// Elements of different types make a union element type
 	var elementType Type
 	for _, element := range expr.Elements {
 		if element != nil {
-			elementType = ti.InferType(element)
-			break
+			elemType := ti.InferType(element)
+			if elementType == nil {
+				elementType = elemType
+			} else if elementType.Equals(AnyType) || elemType.Equals(AnyType) {
+				elementType = AnyType
+			} else {
+				elementType = unionOf(elementType, elemType)
+			}
 		}
 	}
 	
@@ -71,17 +77,13 @@ func (ti *TypeInferrer) inferArrayLiteralType(expr *ast.ArrayLiteral) Type {
 		elementType = UndefinedType
 	}
 	
-	// TODO: In a more sophisticated implementation, we would:
-	// 1. Check all elements for type compatibility
-	// 2. Find the common supertype if elements have different types
-	// 3. Create union types for incompatible types
-	
 	return NewArrayType(elementType)
 }
 
 // inferIdentifierType infers the type of an identifier
 func (ti *TypeInferrer) inferIdentifierType(expr *ast.Identifier) Type {
 	if symbol, exists := ti.resolver.Lookup(expr.Name); exists {
+		symbol.Used = true
 		return symbol.Type
 	}
 	return UndefinedType
@@ -95,13 +97,15 @@ func (ti *TypeInferrer) inferBinaryExpressionType(expr *ast.BinaryExpression) Ty
 	switch expr.Operator.String() {
 	case "+":
 		return ti.inferArithmeticType(leftType, rightType)
-	case "-", "*", "/", "%":
+	case "-", "*", "/", "%", "**":
 		return ti.inferArithmeticType(leftType, rightType)
-	case "==", "!=", "<", ">", "<=", ">=":
+	case "==", "!=", "===", "!==", "<", ">", "<=", ">=":
 		return BooleanType
 	case "&&", "||":
 		return BooleanType
-	case "&", "|", "^", "<<", ">>":
+	case "??":
+		return nullishFallbackType(leftType, rightType)
+	case "&", "|", "^", "<<", ">>", ">>>":
 		return ti.inferBitwiseType(leftType, rightType)
 	default:
 		return UndefinedType
@@ -188,6 +192,14 @@ func (ti *TypeInferrer) inferCallExpressionType(expr *ast.CallExpression) Type {
 func (ti *TypeInferrer) inferMemberExpressionType(expr *ast.MemberExpression) Type {
 	objectType := ti.InferType(expr.Object)
 	
+	// Tuple element access
+	if tuple, ok := objectType.(*TupleType); ok && expr.Computed {
+		if index, ok := constantInteger(expr.Property); ok && index >= 0 && index < int64(len(tuple.ElementTypes)) {
+			return tuple.ElementTypes[index]
+		}
+		return tuple.ElementType()
+	}
+	
 	// Array element access
 	if arrayType, ok := objectType.(*ArrayType); ok {
 		if expr.Computed {
@@ -196,6 +208,11 @@ func (ti *TypeInferrer) inferMemberExpressionType(expr *ast.MemberExpression) Ty
 		}
 	}
 	
+	// String character access
+	if IsStringType(objectType) && expr.Computed {
+		return StringType
+	}
+	
 	// TODO: Object property access would be handled here
 	// For now, we return undefined for unknown member access
 	return UndefinedType
@@ -15,6 +15,8 @@ type Symbol struct {
 	DeclarationKind lexer.Token // LET, CONST, VAR for variables
 	Position        lexer.Position
 	Scope           *Scope
+	Used            bool // read at least once during type checking
+	DeclaredType    Type // type before narrowing by a typeof guard (nil if not narrowed)
 }
 
 type SymbolKind int
@@ -94,9 +96,12 @@ func (s *Scope) LookupLocal(name string) (*Symbol, bool) {
 
 // Resolver handles symbol resolution and scope management
 type Resolver struct {
-	currentScope *Scope
-	globalScope  *Scope
-	errors       []error
+	currentScope     *Scope
+	globalScope      *Scope
+	errors           []error
+	pendingAliases   map[string]*ast.TypeAliasDeclaration // top-level aliases not yet resolved
+	resolvingAliases map[string]bool                      // aliases currently being resolved (cycle detection)
+	modules          map[string]map[string]*Symbol        // exports of the modules that can be imported, by source
 }
 
 // NewResolver creates a new resolver
@@ -105,21 +110,36 @@ func NewResolver() *Resolver {
 	
 	// Define built-in types and functions
 	resolver := &Resolver{
-		currentScope: globalScope,
-		globalScope:  globalScope,
+		currentScope:     globalScope,
+		globalScope:      globalScope,
+		pendingAliases:   make(map[string]*ast.TypeAliasDeclaration),
+		resolvingAliases: make(map[string]bool),
+		modules:          make(map[string]map[string]*Symbol),
 	}
 	
 	resolver.defineBuiltins()
 	return resolver
 }
 
+// Types of the push and pop builtins; calls specialize them to the element
+// type of their array argument
+var (
+	pushBuiltinType = NewVariadicFunctionType([]Type{NewArrayType(AnyType)}, IntType)
+	popBuiltinType  = NewFunctionType([]Type{NewArrayType(AnyType)}, AnyType)
+)
+
 // defineBuiltins defines built-in symbols
 func (r *Resolver) defineBuiltins() {
-	// Built-in functions
+	// Built-in functions, matching the natives registered by the VM
 	builtins := map[string]Type{
-		"print":  NewVariadicFunctionType([]Type{}, VoidType), // print accepts any number of arguments of any type
-		"len":    NewFunctionType([]Type{NewArrayType(StringType)}, IntType),
-		"typeof": NewFunctionType([]Type{StringType}, StringType),
+		"print":     NewVariadicFunctionType([]Type{}, VoidType), // print accepts any number of arguments of any type
+		"len":       NewFunctionType([]Type{AnyType}, IntType),   // strings, arrays and objects
+		"type":      NewFunctionType([]Type{AnyType}, StringType),
+		"assert":    &FunctionType{Parameters: []Type{BooleanType, StringType}, ReturnType: VoidType, Optional: 1},
+		"push":      pushBuiltinType,
+		"pop":       popBuiltinType,
+		"deepEqual": NewFunctionType([]Type{AnyType, AnyType}, BooleanType),
+		"json":      NewFunctionType([]Type{AnyType}, StringType),
 	}
 	
 	for name, typ := range builtins {
@@ -131,10 +151,12 @@ func (r *Resolver) defineBuiltins() {
 		r.globalScope.Define(name, symbol)
 	}
 	
-	// Define console object with log method
+	// Define console object with log, error and warn methods
 	consoleType := &ObjectType{
 		Properties: map[string]Type{
-			"log": NewVariadicFunctionType([]Type{}, VoidType), // console.log accepts any number of arguments
+			"log":   NewVariadicFunctionType([]Type{}, VoidType), // console methods accept any number of arguments
+			"error": NewVariadicFunctionType([]Type{}, VoidType),
+			"warn":  NewVariadicFunctionType([]Type{}, VoidType),
 		},
 	}
 	
@@ -144,6 +166,43 @@ func (r *Resolver) defineBuiltins() {
 		Kind: VariableSymbol,
 	}
 	r.globalScope.Define("console", consoleSymbol)
+	
+	// Define Math object, matching the one created by the VM
+	mathType := &ObjectType{
+		Properties: map[string]Type{
+			"PI":     FloatType,
+			"E":      FloatType,
+			"abs":    NewFunctionType([]Type{FloatType}, FloatType),
+			"floor":  NewFunctionType([]Type{FloatType}, IntType),
+			"ceil":   NewFunctionType([]Type{FloatType}, IntType),
+			"round":  NewFunctionType([]Type{FloatType}, IntType),
+			"sqrt":   NewFunctionType([]Type{FloatType}, FloatType),
+			"pow":    NewFunctionType([]Type{FloatType, FloatType}, FloatType),
+			"min":    NewVariadicFunctionType([]Type{FloatType}, FloatType),
+			"max":    NewVariadicFunctionType([]Type{FloatType}, FloatType),
+			"random": NewFunctionType([]Type{}, FloatType),
+		},
+	}
+	
+	r.globalScope.Define("Math", &Symbol{
+		Name: "Math",
+		Type: mathType,
+		Kind: VariableSymbol,
+	})
+	
+	// Define JSON object; parsed values are only known at runtime
+	jsonType := &ObjectType{
+		Properties: map[string]Type{
+			"parse":     NewFunctionType([]Type{StringType}, AnyType),
+			"stringify": NewFunctionType([]Type{AnyType}, StringType),
+		},
+	}
+	
+	r.globalScope.Define("JSON", &Symbol{
+		Name: "JSON",
+		Type: jsonType,
+		Kind: VariableSymbol,
+	})
 }
 
 // EnterScope creates and enters a new scope
@@ -204,6 +263,16 @@ func (r *Resolver) UpdateType(name string, typ Type) error {
 func (r *Resolver) ResolveProgram(program *ast.Program) error {
 	r.errors = nil
 	
+	// Collect top-level type aliases first so they can be referenced before their declaration
+	for _, stmt := range program.Body {
+		if export, ok := stmt.(*ast.ExportDeclaration); ok {
+			stmt = export.Declaration
+		}
+		if alias, ok := stmt.(*ast.TypeAliasDeclaration); ok {
+			r.pendingAliases[alias.Name.Name] = alias
+		}
+	}
+	
 	for _, stmt := range program.Body {
 		r.resolveStatement(stmt)
 	}
@@ -230,13 +299,81 @@ func (r *Resolver) resolveStatement(stmt ast.Statement) {
 		r.resolveIfStatement(s)
 	case *ast.WhileStatement:
 		r.resolveWhileStatement(s)
+	case *ast.DoWhileStatement:
+		r.resolveDoWhileStatement(s)
 	case *ast.ForStatement:
 		r.resolveForStatement(s)
+	case *ast.ForOfStatement:
+		r.resolveForOfStatement(s)
+	case *ast.ForInStatement:
+		r.resolveForInStatement(s)
+	case *ast.SwitchStatement:
+		r.resolveSwitchStatement(s)
+	case *ast.TryStatement:
+		r.resolveTryStatement(s)
+	case *ast.ThrowStatement:
+		r.resolveExpression(s.Argument)
 	case *ast.ReturnStatement:
 		r.resolveReturnStatement(s)
+	case *ast.InterfaceDeclaration:
+		r.resolveInterfaceDeclaration(s)
+	case *ast.TypeAliasDeclaration:
+		r.resolveTypeAliasDeclaration(s)
+	case *ast.EnumDeclaration:
+		r.resolveEnumDeclaration(s)
+	case *ast.ImportDeclaration:
+		r.resolveImportDeclaration(s)
+	case *ast.ExportDeclaration:
+		r.resolveStatement(s.Declaration)
+	}
+}
+
+// resolveImportDeclaration binds the imported names to the symbols exported
+// by the module. Imported bindings are read-only.
+func (r *Resolver) resolveImportDeclaration(stmt *ast.ImportDeclaration) {
+	exports, ok := r.modules[stmt.Source.Value]
+	if !ok {
+		r.addError(&TypeError{
+			Position:    stmt.Source.Pos(),
+			EndPosition: ast.SpanOf(stmt.Source).End,
+			Span:        ast.SpanOf(stmt.Source),
+			Message:     fmt.Sprintf("Cannot find module '%s'", stmt.Source.Value),
+			Code:        UnresolvedImportError,
+			Suggestion:  "Check the path of the module, relative to the importing file",
+			Context:     "Modules are loaded by 'tg run' and 'tg check'",
+		})
+		return
+	}
+
+	for _, spec := range stmt.Specifiers {
+		symbol, ok := exports[spec.Imported.Name]
+		if !ok {
+			r.addError(&TypeError{
+				Position:    spec.Imported.Pos(),
+				EndPosition: ast.SpanOf(spec.Imported).End,
+				Span:        ast.SpanOf(spec.Imported),
+				Message:     fmt.Sprintf("Module '%s' has no exported member '%s'", stmt.Source.Value, spec.Imported.Name),
+				Code:        UnresolvedImportError,
+				Suggestion:  fmt.Sprintf("Add 'export' to the declaration of '%s' in '%s'", spec.Imported.Name, stmt.Source.Value),
+				Context:     "Only exported declarations can be imported",
+			})
+			continue
+		}
+
+		declKind := lexer.CONST
+		if symbol.Kind == TypeSymbol {
+			declKind = symbol.DeclarationKind
+		}
+		r.DefineWithDeclarationKind(spec.Local.Name, symbol.Type, symbol.Kind, declKind, spec.Local.Pos())
 	}
 }
 
+// AddModule makes the exports of a module importable from source, the
+// string an import declaration names the module by
+func (r *Resolver) AddModule(source string, exports map[string]*Symbol) {
+	r.modules[source] = exports
+}
+
 // resolveVariableDeclaration resolves a variable declaration
 func (r *Resolver) resolveVariableDeclaration(stmt *ast.VariableDeclaration) {
 	for _, decl := range stmt.Declarations {
@@ -245,20 +382,23 @@ func (r *Resolver) resolveVariableDeclaration(stmt *ast.VariableDeclaration) {
 			r.resolveExpression(decl.Init)
 		}
 		
-		// For now, we'll use a simple approach for variable names
-		// In a full implementation, we'd need to handle destructuring patterns
-		if id, ok := decl.Id.(*ast.Identifier); ok {
+		// Destructuring patterns may contain defaults and computed keys
+		r.resolveBindingExpressions(decl.Id)
+		
+		for _, id := range ast.BindingNames(decl.Id) {
 			// Check for let redeclaration in the same scope
 			if stmt.Kind == lexer.LET {
 				if symbol, exists := r.currentScope.LookupLocal(id.Name); exists {
 					// Only report error if the existing symbol is also a let variable
 					if symbol.DeclarationKind == lexer.LET {
 						typeErr := &TypeError{
-							Position:   id.NamePos,
-							Message:    fmt.Sprintf("Identifier '%s' has already been declared", id.Name),
-							Code:       LetRedeclarationError,
-							Suggestion: "Use a different variable name or remove the duplicate declaration",
-							Context:    fmt.Sprintf("Previous declaration was at line %d", symbol.Position.Line),
+							Position:    id.NamePos,
+							EndPosition: ast.SpanOf(id).End,
+							Span:        ast.SpanOf(id),
+							Message:     fmt.Sprintf("Identifier '%s' has already been declared", id.Name),
+							Code:        LetRedeclarationError,
+							Suggestion:  "Use a different variable name or remove the duplicate declaration",
+							Context:     fmt.Sprintf("Previous declaration was at line %d", symbol.Position.Line),
 						}
 						r.addError(typeErr)
 						continue // Skip defining this variable
@@ -271,31 +411,46 @@ func (r *Resolver) resolveVariableDeclaration(stmt *ast.VariableDeclaration) {
 	}
 }
 
-// resolveFunctionDeclaration resolves a function declaration
-func (r *Resolver) resolveFunctionDeclaration(stmt *ast.FunctionDeclaration) {
-	// Resolve parameter types
-	var paramTypes []Type
-	for _, param := range stmt.Parameters {
-		var paramType Type = AnyType // Default to AnyType for unannotated parameters
-		if param.TypeAnnotation != nil {
-			paramType = r.resolveTypeAnnotation(param.TypeAnnotation)
+// resolveBindingExpressions resolves the default values and computed keys
+// within a destructuring pattern
+func (r *Resolver) resolveBindingExpressions(target ast.BindingTarget) {
+	switch t := target.(type) {
+	case *ast.AssignmentPattern:
+		r.resolveBindingExpressions(t.Left)
+		r.resolveExpression(t.Right)
+	case *ast.ArrayPattern:
+		for _, elem := range t.Elements {
+			if elem != nil {
+				r.resolveBindingExpressions(elem)
+			}
+		}
+	case *ast.ObjectPattern:
+		for _, prop := range t.Properties {
+			if prop.Computed {
+				r.resolveExpression(prop.Key)
+			}
+			r.resolveBindingExpressions(prop.Value)
 		}
-		paramTypes = append(paramTypes, paramType)
 	}
+}
+
+// resolveFunctionDeclaration resolves a function declaration
+func (r *Resolver) resolveFunctionDeclaration(stmt *ast.FunctionDeclaration) {
+	// Enter function scope, where the signature can refer to the type
+	// parameters
+	r.EnterScope()
+	funcType, paramTypes := r.functionSignature(stmt, VoidType)
 	
-	// Resolve return type
-	var returnType Type = VoidType
-	if stmt.ReturnType != nil {
-		returnType = r.resolveTypeAnnotation(stmt.ReturnType)
+	// Define function in the enclosing scope
+	if err := r.currentScope.Parent.Define(stmt.Name.Name, &Symbol{
+		Name:     stmt.Name.Name,
+		Type:     funcType,
+		Kind:     FunctionSymbol,
+		Position: stmt.Name.NamePos,
+	}); err != nil {
+		r.addError(err)
 	}
 	
-	// Define function in current scope
-	funcType := NewFunctionType(paramTypes, returnType)
-	r.Define(stmt.Name.Name, funcType, FunctionSymbol, stmt.Name.NamePos)
-	
-	// Enter function scope
-	r.EnterScope()
-	
 	// Define parameters with their resolved types
 	for i, param := range stmt.Parameters {
 		r.Define(param.Name.Name, paramTypes[i], ParameterSymbol, param.Name.NamePos)
@@ -308,6 +463,137 @@ func (r *Resolver) resolveFunctionDeclaration(stmt *ast.FunctionDeclaration) {
 	r.ExitScope()
 }
 
+// functionSignature resolves the type of a function declaration, whose
+// return type is defaultReturn if it isn't annotated, and the types its
+// parameters bind. The current scope must be the function's own: its type
+// parameters are defined there.
+func (r *Resolver) functionSignature(stmt *ast.FunctionDeclaration, defaultReturn Type) (*FunctionType, []Type) {
+	var typeParams []*TypeVariable
+	for _, param := range stmt.TypeParameters {
+		variable := &TypeVariable{Name: param.Name.Name}
+		if param.Constraint != nil {
+			variable.Constraint = r.resolveTypeAnnotation(param.Constraint)
+		}
+		r.Define(variable.Name, variable, TypeSymbol, param.Name.NamePos)
+		typeParams = append(typeParams, variable)
+	}
+	
+	var paramTypes []Type
+	for _, param := range stmt.Parameters {
+		paramTypes = append(paramTypes, r.parameterType(param))
+	}
+	
+	returnType := defaultReturn
+	if stmt.ReturnType != nil {
+		returnType = r.resolveTypeAnnotation(stmt.ReturnType)
+	}
+	
+	funcType := withParameterModifiers(NewFunctionType(paramTypes, returnType), stmt.Parameters)
+	funcType.TypeParameters = typeParams
+	return funcType, paramTypes
+}
+
+// resolveInterfaceDeclaration registers an interface as a type symbol
+func (r *Resolver) resolveInterfaceDeclaration(stmt *ast.InterfaceDeclaration) {
+	r.Define(stmt.Name.Name, r.buildInterfaceType(stmt), TypeSymbol, stmt.Name.NamePos)
+}
+
+// buildInterfaceType builds the object type described by an interface declaration,
+// including members inherited from extended interfaces
+func (r *Resolver) buildInterfaceType(stmt *ast.InterfaceDeclaration) *ObjectType {
+	objType := NewObjectType(make(map[string]Type))
+	objType.Name = stmt.Name.Name
+
+	// Inherited members come first so that own members can override them
+	for _, ext := range stmt.Extends {
+		if base, ok := r.resolveTypeAnnotation(ext).(*ObjectType); ok {
+			for name, typ := range base.Properties {
+				objType.Properties[name] = typ
+				objType.Optional[name] = base.IsOptional(name)
+			}
+		}
+	}
+
+	r.addTypeMembers(objType, stmt.Body)
+	return objType
+}
+
+// addTypeMembers adds the members of an interface body or object type
+// literal to objType
+func (r *Resolver) addTypeMembers(objType *ObjectType, members []*ast.TypeMember) {
+	for _, member := range members {
+		var name string
+		switch key := member.Key.(type) {
+		case *ast.Identifier:
+			name = key.Name
+		case *ast.StringLiteral:
+			name = key.Value
+		default:
+			continue
+		}
+
+		var memberType Type = AnyType
+		if member.Type != nil {
+			memberType = r.resolveTypeAnnotation(member.Type)
+		}
+		objType.Properties[name] = memberType
+		objType.Optional[name] = member.Optional
+	}
+}
+
+// resolveEnumDeclaration registers an enum as a constant object whose
+// members are numbers or strings
+func (r *Resolver) resolveEnumDeclaration(stmt *ast.EnumDeclaration) {
+	objType := NewObjectType(make(map[string]Type))
+	objType.Name = stmt.Name.Name
+	
+	for _, member := range stmt.Members {
+		var memberType Type = IntType
+		if _, ok := member.Value.(*ast.StringLiteral); ok {
+			memberType = StringType
+		}
+		objType.Properties[member.Name.Name] = memberType
+	}
+	
+	r.DefineWithDeclarationKind(stmt.Name.Name, objType, VariableSymbol, lexer.CONST, stmt.Name.NamePos)
+}
+
+// resolveTypeAliasDeclaration registers a type alias as a type symbol
+func (r *Resolver) resolveTypeAliasDeclaration(stmt *ast.TypeAliasDeclaration) {
+	name := stmt.Name.Name
+	
+	// The alias may already have been resolved through a forward reference
+	if symbol, exists := r.currentScope.LookupLocal(name); exists && symbol.Kind == TypeSymbol {
+		return
+	}
+	
+	r.Define(name, r.resolveTypeAlias(stmt), TypeSymbol, stmt.Name.NamePos)
+}
+
+// resolveTypeAlias resolves the type an alias refers to
+func (r *Resolver) resolveTypeAlias(stmt *ast.TypeAliasDeclaration) Type {
+	name := stmt.Name.Name
+	delete(r.pendingAliases, name)
+	
+	if len(stmt.TypeParameters) > 0 {
+		r.addError(&TypeError{
+			Position:    stmt.Name.NamePos,
+			EndPosition: ast.SpanOf(stmt.Name).End,
+			Span:        ast.SpanOf(stmt.Name),
+			Message:     fmt.Sprintf("Cannot declare generic type alias '%s': generics not yet supported", name),
+			Code:        InvalidTypeAliasError,
+			Suggestion:  "Remove the type parameters and use a concrete type",
+			Context:     fmt.Sprintf("Type alias '%s' declares %d type parameter(s)", name, len(stmt.TypeParameters)),
+		})
+		return AnyType
+	}
+	
+	r.resolvingAliases[name] = true
+	defer delete(r.resolvingAliases, name)
+	
+	return r.resolveTypeAnnotation(stmt.Type)
+}
+
 // resolveExpression resolves an expression
 func (r *Resolver) resolveExpression(expr ast.Expression) {
 	switch e := expr.(type) {
@@ -325,6 +611,8 @@ func (r *Resolver) resolveExpression(expr ast.Expression) {
 		r.resolveAssignmentExpression(e)
 	case *ast.ArrayLiteral:
 		r.resolveArrayLiteral(e)
+	case *ast.SpreadElement:
+		r.resolveExpression(e.Argument)
 	}
 }
 
@@ -406,6 +694,12 @@ func (r *Resolver) resolveWhileStatement(stmt *ast.WhileStatement) {
 	r.resolveStatement(stmt.Body)
 }
 
+// resolveDoWhileStatement resolves a do-while statement
+func (r *Resolver) resolveDoWhileStatement(stmt *ast.DoWhileStatement) {
+	r.resolveStatement(stmt.Body)
+	r.resolveExpression(stmt.Test)
+}
+
 // resolveForStatement resolves a for statement
 func (r *Resolver) resolveForStatement(stmt *ast.ForStatement) {
 	r.EnterScope()
@@ -427,6 +721,65 @@ func (r *Resolver) resolveForStatement(stmt *ast.ForStatement) {
 	r.ExitScope()
 }
 
+// resolveForOfStatement resolves a for-of statement
+func (r *Resolver) resolveForOfStatement(stmt *ast.ForOfStatement) {
+	r.resolveExpression(stmt.Right)
+	
+	r.EnterScope()
+	if id, ok := stmt.Left.(*ast.Identifier); ok {
+		r.Define(id.Name, AnyType, VariableSymbol, id.Pos())
+	}
+	r.resolveStatement(stmt.Body)
+	r.ExitScope()
+}
+
+// resolveForInStatement resolves a for-in statement
+func (r *Resolver) resolveForInStatement(stmt *ast.ForInStatement) {
+	r.resolveExpression(stmt.Right)
+	
+	r.EnterScope()
+	if id, ok := stmt.Left.(*ast.Identifier); ok {
+		r.Define(id.Name, StringType, VariableSymbol, id.Pos())
+	}
+	r.resolveStatement(stmt.Body)
+	r.ExitScope()
+}
+
+// resolveTryStatement resolves a try statement
+func (r *Resolver) resolveTryStatement(stmt *ast.TryStatement) {
+	r.resolveBlockStatement(stmt.Block)
+	
+	if stmt.Handler != nil {
+		r.EnterScope()
+		if stmt.Handler.Param != nil {
+			r.Define(stmt.Handler.Param.Name, AnyType, VariableSymbol, stmt.Handler.Param.Pos())
+		}
+		r.resolveBlockStatement(stmt.Handler.Body)
+		r.ExitScope()
+	}
+	
+	if stmt.Finalizer != nil {
+		r.resolveBlockStatement(stmt.Finalizer)
+	}
+}
+
+// resolveSwitchStatement resolves a switch statement. All clauses share
+// one scope, like the body of a block.
+func (r *Resolver) resolveSwitchStatement(stmt *ast.SwitchStatement) {
+	r.resolveExpression(stmt.Discriminant)
+	
+	r.EnterScope()
+	for _, clause := range stmt.Cases {
+		if clause.Test != nil {
+			r.resolveExpression(clause.Test)
+		}
+		for _, consequent := range clause.Consequent {
+			r.resolveStatement(consequent)
+		}
+	}
+	r.ExitScope()
+}
+
 // resolveReturnStatement resolves a return statement
 func (r *Resolver) resolveReturnStatement(stmt *ast.ReturnStatement) {
 	if stmt.Argument != nil {
@@ -470,6 +823,8 @@ func (r *Resolver) resolveTypeAnnotation(annotation ast.TypeNode) Type {
 			return NullType
 		case lexer.UNDEFINED:
 			return UndefinedType
+		case lexer.ANY, lexer.UNKNOWN:
+			return AnyType
 		// Extended numeric types
 		case lexer.INT8_T:
 			return Int8Type
@@ -495,7 +850,125 @@ func (r *Resolver) resolveTypeAnnotation(annotation ast.TypeNode) Type {
 			types = append(types, r.resolveTypeAnnotation(typeNode))
 		}
 		return NewUnionType(types...)
+	case *ast.ObjectType:
+		objType := NewObjectType(make(map[string]Type))
+		r.addTypeMembers(objType, t.Members)
+		return objType
+	case *ast.TupleType:
+		var elementTypes []Type
+		for _, typeNode := range t.Elements {
+			elementTypes = append(elementTypes, r.resolveTypeAnnotation(typeNode))
+		}
+		return NewTupleType(elementTypes...)
+	case *ast.FunctionType:
+		return r.resolveFunctionType(t)
+	case *ast.TypeReference:
+		return r.resolveTypeReference(t)
 	default:
 		return UndefinedType
 	}
+}
+
+// parameterType resolves the type a parameter binds. Unannotated
+// parameters are 'any', and unannotated rest parameters 'any[]'.
+func (r *Resolver) parameterType(param *ast.Parameter) Type {
+	if param.TypeAnnotation != nil {
+		return r.resolveTypeAnnotation(param.TypeAnnotation)
+	}
+	if param.Rest {
+		return NewArrayType(AnyType)
+	}
+	return AnyType
+}
+
+// withParameterModifiers makes the trailing parameters with defaults of
+// funcType optional. When the last of params is a rest parameter, it is
+// taken out of the fixed parameters and makes funcType variadic.
+func withParameterModifiers(funcType *FunctionType, params []*ast.Parameter) *FunctionType {
+	if len(params) == 0 || !params[len(params)-1].Rest {
+		funcType.Optional = optionalParameterCount(params)
+		return funcType
+	}
+	funcType.Optional = optionalParameterCount(params[:len(params)-1])
+	last := len(funcType.Parameters) - 1
+	funcType.Variadic = true
+	funcType.RestType = AnyType
+	if array, ok := funcType.Parameters[last].(*ArrayType); ok {
+		funcType.RestType = array.ElementType
+	}
+	funcType.Parameters = funcType.Parameters[:last]
+	return funcType
+}
+
+// optionalParameterCount counts the parameters with defaults at the end of
+// params
+func optionalParameterCount(params []*ast.Parameter) int {
+	count := 0
+	for i := len(params) - 1; i >= 0 && params[i].DefaultValue != nil; i-- {
+		count++
+	}
+	return count
+}
+
+// resolveFunctionType resolves a function type annotation. Parameters with
+// defaults may be omitted and a rest parameter makes the type variadic.
+func (r *Resolver) resolveFunctionType(fn *ast.FunctionType) *FunctionType {
+	funcType := NewFunctionType(nil, VoidType)
+	for _, param := range fn.Parameters {
+		if param.Rest {
+			funcType.Variadic = true
+			if array, ok := r.parameterType(param).(*ArrayType); ok {
+				funcType.RestType = array.ElementType
+			}
+			break
+		}
+		var paramType Type = AnyType
+		if param.TypeAnnotation != nil {
+			paramType = r.resolveTypeAnnotation(param.TypeAnnotation)
+		}
+		funcType.Parameters = append(funcType.Parameters, paramType)
+		if param.DefaultValue != nil {
+			funcType.Optional++
+		}
+	}
+	if fn.ReturnType != nil {
+		funcType.ReturnType = r.resolveTypeAnnotation(fn.ReturnType)
+	}
+	return funcType
+}
+
+// resolveTypeReference resolves a reference to a declared type by name
+func (r *Resolver) resolveTypeReference(ref *ast.TypeReference) Type {
+	name := ref.Name.Name
+	
+	if r.resolvingAliases[name] {
+		r.addError(&TypeError{
+			Position:    ref.Name.Pos(),
+			EndPosition: ast.SpanOf(ref.Name).End,
+			Span:        ast.SpanOf(ref.Name),
+			Message:     fmt.Sprintf("Type alias '%s' circularly references itself", name),
+			Code:        InvalidTypeAliasError,
+			Suggestion:  "Break the cycle by referring to a concrete type",
+			Context:     fmt.Sprintf("Resolving '%s' requires resolving '%s' again", name, name),
+		})
+		return AnyType
+	}
+	
+	if symbol, exists := r.Lookup(name); exists && symbol.Kind == TypeSymbol {
+		return symbol.Type
+	}
+	
+	// Forward reference to a top-level alias
+	if alias, ok := r.pendingAliases[name]; ok {
+		typ := r.resolveTypeAlias(alias)
+		r.globalScope.Define(name, &Symbol{
+			Name:     name,
+			Type:     typ,
+			Kind:     TypeSymbol,
+			Position: alias.Name.NamePos,
+		})
+		return typ
+	}
+	
+	return UndefinedType
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -135,6 +136,10 @@ type ArrayType struct {
 }
 
 func (a *ArrayType) String() string {
+	switch a.ElementType.(type) {
+	case *UnionType, *FunctionType:
+		return fmt.Sprintf("(%s)[]", a.ElementType.String())
+	}
 	return fmt.Sprintf("%s[]", a.ElementType.String())
 }
 
@@ -152,6 +157,74 @@ func (a *ArrayType) IsAssignableTo(other Type) bool {
 	return false
 }
 
+// ============================================================================
+// TUPLE TYPE
+// ============================================================================
+
+// TupleType represents fixed-length arrays with a type for each position
+// ([T1, T2]). Tuples are arrays at runtime.
+type TupleType struct {
+	ElementTypes []Type
+}
+
+func (t *TupleType) String() string {
+	var elements []string
+	for _, element := range t.ElementTypes {
+		elements = append(elements, element.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (t *TupleType) Equals(other Type) bool {
+	otherTuple, ok := other.(*TupleType)
+	if !ok || len(t.ElementTypes) != len(otherTuple.ElementTypes) {
+		return false
+	}
+	for i, element := range t.ElementTypes {
+		if !element.Equals(otherTuple.ElementTypes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *TupleType) IsAssignableTo(other Type) bool {
+	switch target := other.(type) {
+	case *TupleType:
+		if len(t.ElementTypes) != len(target.ElementTypes) {
+			return false
+		}
+		for i, element := range t.ElementTypes {
+			if !element.IsAssignableTo(target.ElementTypes[i]) {
+				return false
+			}
+		}
+		return true
+	case *ArrayType:
+		for _, element := range t.ElementTypes {
+			if !element.IsAssignableTo(target.ElementType) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ElementType returns the type of an element at an unknown position: the
+// union of the element types
+func (t *TupleType) ElementType() Type {
+	var elementType Type = UndefinedType
+	for i, element := range t.ElementTypes {
+		if i == 0 {
+			elementType = element
+		} else {
+			elementType = unionOf(elementType, element)
+		}
+	}
+	return elementType
+}
+
 // ============================================================================
 // FUNCTION TYPE
 // ============================================================================
@@ -161,22 +234,44 @@ type FunctionType struct {
 	Parameters []Type
 	ReturnType Type
 	Variadic   bool // true if the function accepts variable number of arguments
+	Optional   int  // number of trailing parameters that may be omitted
+	RestType   Type // type of each variadic argument (nil accepts any)
+	TypeParameters []*TypeVariable // type parameters of a generic function
+}
+
+// MinArgs returns the number of arguments a call must pass
+func (f *FunctionType) MinArgs() int {
+	return len(f.Parameters) - f.Optional
 }
 
 func (f *FunctionType) String() string {
 	var params []string
-	for _, param := range f.Parameters {
+	for i, param := range f.Parameters {
+		if i >= f.MinArgs() {
+			params = append(params, param.String()+"?")
+			continue
+		}
 		params = append(params, param.String())
 	}
-	if f.Variadic {
+	if f.Variadic && f.RestType != nil {
+		params = append(params, "..."+NewArrayType(f.RestType).String())
+	} else if f.Variadic {
 		params = append(params, "...")
 	}
-	return fmt.Sprintf("(%s) => %s", strings.Join(params, ", "), f.ReturnType.String())
+	signature := fmt.Sprintf("(%s) => %s", strings.Join(params, ", "), f.ReturnType.String())
+	if len(f.TypeParameters) > 0 {
+		var typeParams []string
+		for _, param := range f.TypeParameters {
+			typeParams = append(typeParams, param.Declaration())
+		}
+		signature = "<" + strings.Join(typeParams, ", ") + ">" + signature
+	}
+	return signature
 }
 
 func (f *FunctionType) Equals(other Type) bool {
 	if otherFunc, ok := other.(*FunctionType); ok {
-		if len(f.Parameters) != len(otherFunc.Parameters) {
+		if len(f.Parameters) != len(otherFunc.Parameters) || f.Optional != otherFunc.Optional {
 			return false
 		}
 		
@@ -217,17 +312,26 @@ func (f *FunctionType) IsAssignableTo(other Type) bool {
 
 // ObjectType represents an object with properties
 type ObjectType struct {
+	Name       string          // declared name for interface types (empty for anonymous objects)
 	Properties map[string]Type
+	Optional   map[string]bool // properties that may be omitted
 }
 
 func (o *ObjectType) String() string {
+	if o.Name != "" {
+		return o.Name
+	}
 	if len(o.Properties) == 0 {
 		return "object"
 	}
 	
 	var props []string
-	for name, typ := range o.Properties {
-		props = append(props, fmt.Sprintf("%s: %s", name, typ.String()))
+	for _, name := range o.PropertyNames() {
+		if o.IsOptional(name) {
+			props = append(props, fmt.Sprintf("%s?: %s", name, o.Properties[name].String()))
+		} else {
+			props = append(props, fmt.Sprintf("%s: %s", name, o.Properties[name].String()))
+		}
 	}
 	return fmt.Sprintf("{ %s }", strings.Join(props, ", "))
 }
@@ -241,6 +345,9 @@ func (o *ObjectType) Equals(other Type) bool {
 			if otherType, exists := otherObj.Properties[name]; !exists || !typ.Equals(otherType) {
 				return false
 			}
+			if o.IsOptional(name) != otherObj.IsOptional(name) {
+				return false
+			}
 		}
 		return true
 	}
@@ -251,7 +358,14 @@ func (o *ObjectType) IsAssignableTo(other Type) bool {
 	if otherObj, ok := other.(*ObjectType); ok {
 		// Structural typing: this object is assignable to other if it has all required properties
 		for name, expectedType := range otherObj.Properties {
-			if actualType, exists := o.Properties[name]; !exists || !actualType.IsAssignableTo(expectedType) {
+			actualType, exists := o.Properties[name]
+			if !exists {
+				if otherObj.IsOptional(name) {
+					continue
+				}
+				return false
+			}
+			if !actualType.IsAssignableTo(expectedType) {
 				return false
 			}
 		}
@@ -260,6 +374,57 @@ func (o *ObjectType) IsAssignableTo(other Type) bool {
 	return false
 }
 
+// IsOptional returns true if the named property may be omitted
+func (o *ObjectType) IsOptional(name string) bool {
+	return o.Optional[name]
+}
+
+// PropertyNames returns the property names in sorted order
+func (o *ObjectType) PropertyNames() []string {
+	names := make([]string, 0, len(o.Properties))
+	for name := range o.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ============================================================================
+// TYPE VARIABLES
+// ============================================================================
+
+// TypeVariable is a type parameter of a generic function, such as T in
+// 'function identity<T>(x: T): T'. Each call binds it to a type inferred
+// from the arguments.
+type TypeVariable struct {
+	Name       string
+	Constraint Type // type from the 'extends' clause (nil if unconstrained)
+}
+
+func (v *TypeVariable) String() string {
+	return v.Name
+}
+
+// Declaration returns the type parameter as declared, with its constraint
+func (v *TypeVariable) Declaration() string {
+	if v.Constraint != nil {
+		return v.Name + " extends " + v.Constraint.String()
+	}
+	return v.Name
+}
+
+func (v *TypeVariable) Equals(other Type) bool {
+	return v == other
+}
+
+func (v *TypeVariable) IsAssignableTo(other Type) bool {
+	if v.Equals(other) || other.Equals(AnyType) {
+		return true
+	}
+	// Whatever type a call binds, it satisfies the constraint
+	return v.Constraint != nil && v.Constraint.IsAssignableTo(other)
+}
+
 // ============================================================================
 // UNION TYPES
 // ============================================================================
@@ -342,6 +507,11 @@ func NewArrayType(elementType Type) *ArrayType {
 	return &ArrayType{ElementType: elementType}
 }
 
+// NewTupleType creates a new tuple type
+func NewTupleType(elementTypes ...Type) *TupleType {
+	return &TupleType{ElementTypes: elementTypes}
+}
+
 // NewFunctionType creates a new function type
 func NewFunctionType(parameters []Type, returnType Type) *FunctionType {
 	return &FunctionType{Parameters: parameters, ReturnType: returnType, Variadic: false}
@@ -352,6 +522,37 @@ func NewVariadicFunctionType(parameters []Type, returnType Type) *FunctionType {
 	return &FunctionType{Parameters: parameters, ReturnType: returnType, Variadic: true}
 }
 
+// NewNativeFunctionType creates the type of a native function taking
+// between minArgs and maxArgs arguments of any type (maxArgs -1 for no limit)
+func NewNativeFunctionType(minArgs, maxArgs int, returnType Type) *FunctionType {
+	if minArgs < 0 {
+		minArgs = 0
+	}
+	if maxArgs >= 0 && minArgs > maxArgs {
+		minArgs = maxArgs
+	}
+	if maxArgs < 0 {
+		parameters := make([]Type, minArgs)
+		for i := range parameters {
+			parameters[i] = AnyType
+		}
+		return NewVariadicFunctionType(parameters, returnType)
+	}
+	
+	parameters := make([]Type, maxArgs)
+	for i := range parameters {
+		parameters[i] = AnyType
+	}
+	fnType := NewFunctionType(parameters, returnType)
+	fnType.Optional = maxArgs - minArgs
+	return fnType
+}
+
+// NewObjectType creates a new anonymous object type
+func NewObjectType(properties map[string]Type) *ObjectType {
+	return &ObjectType{Properties: properties, Optional: make(map[string]bool)}
+}
+
 // NewUnionType creates a new union type
 func NewUnionType(types ...Type) *UnionType {
 	return &UnionType{Types: types}
@@ -368,6 +569,34 @@ func IsNumericType(t Type) bool {
 	return false
 }
 
+// IsIntegerType checks if a type is one of the integer types
+func IsIntegerType(t Type) bool {
+	bits, isFloat := numericWidth(t)
+	return bits > 0 && !isFloat
+}
+
+// numericWidth returns the bit width of a numeric type and whether it is a
+// floating point type. int and float are 64 bits wide.
+func numericWidth(t Type) (bits int, isFloat bool) {
+	if prim, ok := t.(*PrimitiveType); ok {
+		switch prim.Kind {
+		case Int8Kind:
+			return 8, false
+		case Int16Kind:
+			return 16, false
+		case Int32Kind:
+			return 32, false
+		case IntKind, Int64Kind:
+			return 64, false
+		case Float32Kind:
+			return 32, true
+		case FloatKind, Float64Kind:
+			return 64, true
+		}
+	}
+	return 0, false
+}
+
 // IsStringType checks if a type is string
 func IsStringType(t Type) bool {
 	if prim, ok := t.(*PrimitiveType); ok {
@@ -0,0 +1,24 @@
+package vm
+
+import "io"
+
+// initConsole defines the global console object. console.log writes its
+// arguments like print does; console.error and console.warn write them to
+// the VM's Stderr.
+func (vm *VM) initConsole() {
+	obj := NewObject()
+	obj.Set("log", consoleMethod("log", func(vm *VM) io.Writer { return vm.Stdout }))
+	obj.Set("error", consoleMethod("error", func(vm *VM) io.Writer { return vm.Stderr }))
+	obj.Set("warn", consoleMethod("warn", func(vm *VM) io.Writer { return vm.Stderr }))
+
+	vm.SetGlobal("console", NewObjectValue(obj))
+}
+
+// consoleMethod returns a console function writing a line to output(vm)
+func consoleMethod(name string, output func(vm *VM) io.Writer) Value {
+	return NewNativeFunctionValue(NewNativeFunction("console."+name,
+		func(vm *VM, args []Value) (Value, error) {
+			writeLine(output(vm), args)
+			return NilValue, nil
+		}, 0, -1))
+}
@@ -0,0 +1,176 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// GoFunc is a Go function that scripts can call. Its arguments are
+// converted with ToGo and its result with ValueOf.
+type GoFunc func(args ...interface{}) (interface{}, error)
+
+// ValueOf converts a Go value to a script value. Supported types are nil,
+// bool, the integer and float types, string, []interface{} and
+// map[string]interface{}, nested to any depth, and Value itself. A uint or
+// uint64 above math.MaxInt64 is an error, as script integers are signed.
+// Map keys become object properties in sorted order.
+func ValueOf(v interface{}) (Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return NullValue, nil
+	case Value:
+		return v, nil
+	case bool:
+		return NewBoolValue(v), nil
+	case int:
+		return NewIntValue(int64(v)), nil
+	case int8:
+		return NewIntValue(int64(v)), nil
+	case int16:
+		return NewIntValue(int64(v)), nil
+	case int32:
+		return NewIntValue(int64(v)), nil
+	case int64:
+		return NewIntValue(v), nil
+	case uint8:
+		return NewIntValue(int64(v)), nil
+	case uint16:
+		return NewIntValue(int64(v)), nil
+	case uint32:
+		return NewIntValue(int64(v)), nil
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return NilValue, fmt.Errorf("cannot convert Go value %d of type %T: out of int range", v, v)
+		}
+		return NewIntValue(int64(v)), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return NilValue, fmt.Errorf("cannot convert Go value %d of type %T: out of int range", v, v)
+		}
+		return NewIntValue(int64(v)), nil
+	case float32:
+		return NewFloatValue(float64(v)), nil
+	case float64:
+		return NewFloatValue(v), nil
+	case string:
+		return NewStringValue(v), nil
+	case []interface{}:
+		arr := NewArray(len(v))
+		for i, elem := range v {
+			value, err := ValueOf(elem)
+			if err != nil {
+				return NilValue, fmt.Errorf("element %d: %w", i, err)
+			}
+			arr.Push(value)
+		}
+		return NewArrayValue(arr), nil
+	case map[string]interface{}:
+		// Sort the keys so that property order is deterministic
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		obj := NewObject()
+		for _, key := range keys {
+			value, err := ValueOf(v[key])
+			if err != nil {
+				return NilValue, fmt.Errorf("property %q: %w", key, err)
+			}
+			obj.Set(key, value)
+		}
+		return NewObjectValue(obj), nil
+	default:
+		return NilValue, fmt.Errorf("cannot convert Go value of type %T", v)
+	}
+}
+
+// ToGo converts the value to a Go value: nil, bool, int64, float64,
+// string, []interface{} or map[string]interface{}. Functions and other
+// values with no Go equivalent are an error.
+func (v Value) ToGo() (interface{}, error) {
+	switch v.Type {
+	case TypeNil, TypeVoid, TypeNull:
+		return nil, nil
+	case TypeBool:
+		return v.AsBool(), nil
+	case TypeInt:
+		return v.AsInt(), nil
+	case TypeFloat:
+		return v.AsFloat(), nil
+	case TypeString:
+		return v.AsString(), nil
+	case TypeArray:
+		arr := v.AsArray()
+		result := make([]interface{}, arr.Length())
+		for i := range result {
+			elem, _ := arr.Get(i)
+			value, err := elem.ToGo()
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			result[i] = value
+		}
+		return result, nil
+	case TypeObject:
+		obj := v.AsObject()
+		result := make(map[string]interface{}, len(obj.Properties))
+		for _, key := range obj.Keys() {
+			elem, _ := obj.Get(key)
+			value, err := elem.ToGo()
+			if err != nil {
+				return nil, fmt.Errorf("property %q: %w", key, err)
+			}
+			result[key] = value
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s value to Go", v.TypeName())
+	}
+}
+
+// SetGlobalFunc makes fn callable from scripts as the global name, with
+// any number of arguments
+func (vm *VM) SetGlobalFunc(name string, fn GoFunc) {
+	vm.RegisterNativeFunction(name, func(machine *VM, args []Value) (Value, error) {
+		goArgs := make([]interface{}, len(args))
+		for i, arg := range args {
+			value, err := arg.ToGo()
+			if err != nil {
+				return NilValue, NewRuntimeError("%s: argument %d: %v", name, i+1, err)
+			}
+			goArgs[i] = value
+		}
+
+		result, err := fn(goArgs...)
+		if err != nil {
+			return NilValue, NewRuntimeError("%s: %v", name, err)
+		}
+		value, err := ValueOf(result)
+		if err != nil {
+			return NilValue, NewRuntimeError("%s: result: %v", name, err)
+		}
+		return value, nil
+	}, 0, -1)
+}
+
+// SetGlobalInt sets the global name to an int
+func (vm *VM) SetGlobalInt(name string, i int64) {
+	vm.SetGlobal(name, NewIntValue(i))
+}
+
+// SetGlobalString sets the global name to a string
+func (vm *VM) SetGlobalString(name string, s string) {
+	vm.SetGlobal(name, NewStringValue(s))
+}
+
+// GetGlobalValue returns the value of the global name converted with ToGo
+func (vm *VM) GetGlobalValue(name string) (interface{}, error) {
+	value, ok := vm.GetGlobal(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined global: %s", name)
+	}
+	return value.ToGo()
+}
@@ -0,0 +1,122 @@
+package vm_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xingleixu/TG-Script/compiler"
+	"github.com/xingleixu/TG-Script/lexer"
+	"github.com/xingleixu/TG-Script/parser"
+	"github.com/xingleixu/TG-Script/vm"
+)
+
+func TestValueOfRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected interface{} // result of ToGo on the converted value
+	}{
+		{"nil", nil, nil},
+		{"bool", true, true},
+		{"int", int(-7), int64(-7)},
+		{"int8", int8(-8), int64(-8)},
+		{"int16", int16(-16), int64(-16)},
+		{"int32", int32(-32), int64(-32)},
+		{"int64", int64(math.MinInt64), int64(math.MinInt64)},
+		{"uint", uint(7), int64(7)},
+		{"uint8", uint8(255), int64(255)},
+		{"uint16", uint16(65535), int64(65535)},
+		{"uint32", uint32(math.MaxUint32), int64(math.MaxUint32)},
+		{"uint64", uint64(math.MaxInt64), int64(math.MaxInt64)},
+		{"float32", float32(1.5), 1.5},
+		{"float64", 2.25, 2.25},
+		{"string", "text", "text"},
+		{"slice", []interface{}{1, "a", []interface{}{true}}, []interface{}{int64(1), "a", []interface{}{true}}},
+		{"map", map[string]interface{}{"b": 2.5, "a": nil}, map[string]interface{}{"a": nil, "b": 2.5}},
+		{"value", vm.NewStringValue("v"), "v"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := vm.ValueOf(tt.input)
+			if err != nil {
+				t.Fatalf("ValueOf failed: %v", err)
+			}
+			got, err := value.ToGo()
+			if err != nil {
+				t.Fatalf("ToGo failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValueOfErrors(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{uint64(math.MaxInt64 + 1), "cannot convert Go value 9223372036854775808 of type uint64: out of int range"},
+		{uint(math.MaxUint64), "cannot convert Go value 18446744073709551615 of type uint: out of int range"},
+		{struct{}{}, "cannot convert Go value of type struct {}"},
+		{[]interface{}{1, complex(1, 2)}, "element 1: cannot convert Go value of type complex128"},
+		{map[string]interface{}{"f": func() {}}, "property \"f\": cannot convert Go value of type func()"},
+	}
+
+	for _, tt := range tests {
+		_, err := vm.ValueOf(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%#v: expected error %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestEmbeddingHostAPI(t *testing.T) {
+	input := `
+result = double(base) + 2
+label = prefix + describe(result)
+`
+	function, err := compiler.CompileFunction(parser.New(lexer.New(input)).ParseProgram())
+	if err != nil {
+		t.Fatalf("compilation failed: %v", err)
+	}
+
+	machine := vm.NewVM()
+	machine.SetGlobalInt("base", 40)
+	machine.SetGlobalString("prefix", "answer: ")
+	machine.SetGlobalFunc("double", func(args ...interface{}) (interface{}, error) {
+		return args[0].(int64) * 2, nil
+	})
+	machine.SetGlobalFunc("describe", func(args ...interface{}) (interface{}, error) {
+		return fmt.Sprintf("%d", args[0]), nil
+	})
+	if _, err := machine.Execute(vm.NewClosure(function), nil); err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	result, err := machine.GetGlobalValue("result")
+	if err != nil || result != int64(82) {
+		t.Errorf("expected result 82, got %#v (%v)", result, err)
+	}
+	label, err := machine.GetGlobalValue("label")
+	if err != nil || label != "answer: 82" {
+		t.Errorf("expected label %q, got %#v (%v)", "answer: 82", label, err)
+	}
+	if _, err := machine.GetGlobalValue("missing"); err == nil {
+		t.Errorf("expected an error reading an undefined global")
+	}
+
+	// Errors of the Go function surface as runtime errors
+	machine.SetGlobalFunc("double", func(args ...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("no doubling today")
+	})
+	_, err = machine.Execute(vm.NewClosure(function), nil)
+	if err == nil || !strings.Contains(err.Error(), "double: no doubling today") {
+		t.Errorf("expected the host error, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package vm
+
+// CoroutineStatus represents the execution state of a coroutine
+type CoroutineStatus int
+
+const (
+	CoroutineSuspended CoroutineStatus = iota // created or yielded, waiting to be resumed
+	CoroutineRunning                          // currently executing
+	CoroutineDead                             // returned or failed
+)
+
+// String returns the string representation of the status
+func (s CoroutineStatus) String() string {
+	switch s {
+	case CoroutineSuspended:
+		return "suspended"
+	case CoroutineRunning:
+		return "running"
+	case CoroutineDead:
+		return "dead"
+	default:
+		return "unknown"
+	}
+}
+
+// savedUpvalue is an open upvalue captured from a suspended frame, recorded
+// by its register offset so it can be reopened wherever the frame resumes
+type savedUpvalue struct {
+	upvalue *Upvalue
+	offset  int
+}
+
+// Coroutine is a function whose execution can be suspended with Yield and
+// continued with Resume. While suspended, its frame state (PC, registers and
+// open upvalues) is kept outside the VM's register file, so other code can
+// run in between. Generators and iterators are built on top of it.
+type Coroutine struct {
+	vm      *VM
+	closure *Closure
+	status  CoroutineStatus
+	depth   int // frame index of the coroutine's frame while running
+
+	// Saved frame state while suspended
+	started   bool
+	pc        int
+	registers []Value
+	upvalues  []savedUpvalue
+	handlers  []exceptionHandler // try blocks active in the frame
+	resumeReg int                // register receiving the resumed value (-1 for none)
+
+	transfer Value // value passed out by Yield
+}
+
+// NewCoroutine creates a suspended coroutine that runs closure on vm
+func NewCoroutine(vm *VM, closure *Closure) *Coroutine {
+	return &Coroutine{
+		vm:        vm,
+		closure:   closure,
+		status:    CoroutineSuspended,
+		resumeReg: -1,
+		transfer:  NilValue,
+	}
+}
+
+// Status returns the coroutine's execution state
+func (co *Coroutine) Status() CoroutineStatus {
+	return co.status
+}
+
+// Resume continues the coroutine until it yields or returns. The first
+// resume passes v as the function's first argument; later resumes deliver v
+// as the result of the yield that suspended it. Resume returns the yielded
+// value, or the return value once the coroutine finishes.
+func (co *Coroutine) Resume(v Value) (Value, error) {
+	switch co.status {
+	case CoroutineRunning:
+		return NilValue, NewRuntimeError("cannot resume running coroutine")
+	case CoroutineDead:
+		return NilValue, NewRuntimeError("cannot resume dead coroutine")
+	}
+
+	vm := co.vm
+	function := co.closure.Function
+
+	// The coroutine's frame goes above the caller's registers, like a call
+	baseReg := 0
+	if vm.CurrentFrame != nil {
+		baseReg = vm.CurrentFrame.BaseReg + vm.CurrentFrame.NumRegs
+	}
+	if err := vm.PushFrame(co.closure, baseReg, function.NumLocals, -1, 1); err != nil {
+		return NilValue, err
+	}
+	co.depth = vm.FrameIndex
+
+	if !co.started {
+		co.started = true
+		vm.bindArguments(function, []Value{v})
+	} else {
+		co.restore(baseReg)
+		if co.resumeReg >= 0 {
+			vm.SetRegister(co.resumeReg, v)
+		}
+	}
+
+	previous, wasRunning := vm.coroutine, vm.Running
+	vm.coroutine = co
+	vm.Running = true
+	co.status = CoroutineRunning
+	defer func() {
+		vm.coroutine = previous
+		vm.Running = wasRunning
+	}()
+
+	return co.run()
+}
+
+// Yield suspends the running coroutine, handing v to the pending Resume.
+// The suspension takes effect once the current instruction completes, so
+// it can be called from opcodes and from native functions the coroutine
+// calls directly.
+func (co *Coroutine) Yield(v Value) error {
+	if co.status != CoroutineRunning {
+		return NewRuntimeError("cannot yield from a %s coroutine", co.status)
+	}
+	if co.vm.FrameIndex != co.depth {
+		return NewRuntimeError("cannot yield across a function call")
+	}
+
+	co.transfer = v
+	co.resumeReg = -1
+	co.status = CoroutineSuspended
+	return nil
+}
+
+// run executes the coroutine's frame until it yields, returns or fails
+func (co *Coroutine) run() (Value, error) {
+	vm := co.vm
+	for {
+		frame := vm.CurrentFrame
+
+		// Returns from the coroutine's own frame end the coroutine instead
+		// of writing into the caller's registers
+		if vm.FrameIndex == co.depth {
+			instructions := frame.Closure.Function.Instructions
+			if frame.PC >= len(instructions) {
+				return co.finish(NilValue), nil
+			}
+			if inst := instructions[frame.PC]; inst.GetOpCode() == OpReturn {
+				var result Value = NilValue
+				if inst.GetB() > 0 {
+					result = vm.GetRegister(inst.GetA())
+				}
+				return co.finish(result), nil
+			}
+		}
+
+		if vm.MaxSteps > 0 {
+			if err := vm.countStep(); err != nil {
+				co.status = CoroutineDead
+				vm.unwindFrames(co.depth - 1)
+				return NilValue, err
+			}
+		}
+		if err := vm.executeInstruction(); err != nil {
+			if vm.handleError(err, co.depth-1) {
+				continue
+			}
+			co.status = CoroutineDead
+			vm.unwindFrames(co.depth - 1)
+			return NilValue, err
+		}
+		if !vm.Running {
+			return co.finish(NilValue), nil
+		}
+
+		if co.status == CoroutineSuspended {
+			co.suspend()
+			return co.transfer, nil
+		}
+	}
+}
+
+// finish pops the coroutine's frame and marks it dead
+func (co *Coroutine) finish(result Value) Value {
+	co.vm.unwindFrames(co.depth - 1)
+	co.status = CoroutineDead
+	co.registers = nil
+	co.upvalues = nil
+	return result
+}
+
+// suspend saves the coroutine's frame state and pops its frame
+func (co *Coroutine) suspend() {
+	vm := co.vm
+	frame := vm.CurrentFrame
+	start := frame.BaseReg
+	end := start + frame.NumRegs
+	if end > len(vm.Registers) {
+		end = len(vm.Registers)
+	}
+
+	co.pc = frame.PC
+	co.registers = append(co.registers[:0], vm.Registers[start:end]...)
+
+	// Close upvalues pointing into the frame; they are reopened on resume
+	co.upvalues = co.upvalues[:0]
+	open := vm.OpenUpvalues[:0]
+	for _, uv := range vm.OpenUpvalues {
+		offset := registerOffset(vm, uv.Location, start, end)
+		if offset < 0 {
+			open = append(open, uv)
+			continue
+		}
+		uv.Close()
+		co.upvalues = append(co.upvalues, savedUpvalue{upvalue: uv, offset: offset})
+	}
+	vm.OpenUpvalues = open
+
+	// Keep the frame's exception handlers; PopFrame discards them
+	co.handlers = co.handlers[:0]
+	for _, handler := range vm.handlers {
+		if handler.frameIndex == co.depth {
+			co.handlers = append(co.handlers, handler)
+		}
+	}
+
+	vm.PopFrame()
+}
+
+// restore copies the saved frame state into the registers starting at baseReg
+func (co *Coroutine) restore(baseReg int) {
+	vm := co.vm
+	vm.CurrentFrame.PC = co.pc
+	copy(vm.Registers[baseReg:], co.registers)
+
+	for _, handler := range co.handlers {
+		handler.frameIndex = vm.FrameIndex
+		vm.handlers = append(vm.handlers, handler)
+	}
+
+	// Closed upvalues may have been updated while suspended
+	for _, saved := range co.upvalues {
+		location := &vm.Registers[baseReg+saved.offset]
+		*location = saved.upvalue.Closed
+		saved.upvalue.Location = location
+		saved.upvalue.Closed = NilValue
+		saved.upvalue.IsClosed = false
+		vm.OpenUpvalues = append(vm.OpenUpvalues, saved.upvalue)
+	}
+}
+
+// registerOffset returns the offset of location within registers
+// [start, end), or -1 if it points elsewhere
+func registerOffset(vm *VM, location *Value, start, end int) int {
+	for i := start; i < end; i++ {
+		if location == &vm.Registers[i] {
+			return i - start
+		}
+	}
+	return -1
+}
@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Disassemble returns a readable listing of fn: its parameter and register
+// counts, constant pool, global slots and instructions, followed by the
+// listings of the functions in its constant pool. Jumps are annotated with
+// the index of the instruction they go to, constant operands with the
+// constant's value and global slots with the global's name.
+func Disassemble(fn *Function) string {
+	var sb strings.Builder
+	disassemble(&sb, fn, make(map[*Function]bool))
+	return sb.String()
+}
+
+// disassemble appends the listing of fn and its nested functions to sb,
+// listing each function once
+func disassemble(sb *strings.Builder, fn *Function, seen map[*Function]bool) {
+	seen[fn] = true
+
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	variadic := ""
+	if fn.IsVariadic {
+		variadic = ", variadic"
+	}
+	fmt.Fprintf(sb, "function %s (params %d, locals %d, upvalues %d%s)\n",
+		name, fn.NumParams, fn.NumLocals, fn.NumUpvalues, variadic)
+
+	fmt.Fprintf(sb, "constants (%d):\n", len(fn.Constants))
+	for i, constant := range fn.Constants {
+		fmt.Fprintf(sb, "  K%-4d %-16s %s\n", i, constant.TypeName(), constantString(constant))
+	}
+
+	if len(fn.Globals) > 0 {
+		fmt.Fprintf(sb, "globals (%d):\n", len(fn.Globals))
+		for i, name := range fn.Globals {
+			fmt.Fprintf(sb, "  G%-4d %s\n", i, name)
+		}
+	}
+
+	fmt.Fprintf(sb, "instructions (%d):\n", len(fn.Instructions))
+	for pc, inst := range fn.Instructions {
+		line := "    "
+		if pc < len(fn.LineNumbers) && fn.LineNumbers[pc] > 0 {
+			line = fmt.Sprintf("%4d", fn.LineNumbers[pc])
+		}
+		text := inst.String()
+		if note := instructionNote(fn, pc, inst); note != "" {
+			text = fmt.Sprintf("%-28s ; %s", text, note)
+		}
+		fmt.Fprintf(sb, "  %04d  %s  %s\n", pc, line, text)
+	}
+
+	for _, constant := range fn.Constants {
+		if constant.Type != TypeFunction {
+			continue
+		}
+		if nested := constant.AsFunction(); !seen[nested] {
+			sb.WriteString("\n")
+			disassemble(sb, nested, seen)
+		}
+	}
+}
+
+// instructionNote explains the operands of the instruction at pc: the
+// target of a jump, the value of a constant or the name of a global
+func instructionNote(fn *Function, pc int, inst Instruction) string {
+	switch inst.GetOpCode() {
+	case OpJmp, OpSetupTry, OpForPrep, OpForLoop:
+		return fmt.Sprintf("to %04d", pc+1+inst.GetSBx())
+	case OpLoadK:
+		if constant, ok := fn.GetConstant(inst.GetBx()); ok {
+			return fmt.Sprintf("K%d = %s", inst.GetBx(), constantString(constant))
+		}
+	case OpGetGlobal, OpSetGlobal:
+		if bx := inst.GetBx(); bx < len(fn.Globals) {
+			return fmt.Sprintf("G%d = %s", bx, fn.Globals[bx])
+		}
+	}
+	return ""
+}
+
+// constantString formats a constant, quoting strings so that their
+// boundaries are visible
+func constantString(v Value) string {
+	if v.Type == TypeString {
+		return strconv.Quote(v.AsString())
+	}
+	return v.ToString()
+}
@@ -1,17 +1,106 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
+// StackFrame is one active call in a stack trace
+type StackFrame struct {
+	Function string // function name ("" for anonymous functions)
+	Line     int    // source line being executed (0 if unknown)
+}
+
+func (f StackFrame) String() string {
+	name := f.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("at %s (line %d)", name, f.Line)
+	}
+	return "at " + name
+}
+
+// StackTrace lists the active calls when an error occurred, innermost first
+type StackTrace []StackFrame
+
+// traceEdgeLines is the number of lines kept at each end of a long trace
+const traceEdgeLines = 10
+
+// String returns the trace with one indented frame per line. A run of
+// identical frames, as left by recursion, is shown once with its count, and
+// only the first and last lines of a long trace are kept.
+func (t StackTrace) String() string {
+	var lines []string
+	var counts []int
+	for i := 0; i < len(t); {
+		n := 1
+		for i+n < len(t) && t[i+n] == t[i] {
+			n++
+		}
+		line := "  " + t[i].String()
+		if n > 1 {
+			line += fmt.Sprintf(" ×%d", n)
+		}
+		lines = append(lines, line)
+		counts = append(counts, n)
+		i += n
+	}
+
+	if len(lines) > 2*traceEdgeLines {
+		hidden := 0
+		for _, n := range counts[traceEdgeLines : len(lines)-traceEdgeLines] {
+			hidden += n
+		}
+		tail := lines[len(lines)-traceEdgeLines:]
+		lines = append(lines[:traceEdgeLines:traceEdgeLines], fmt.Sprintf("  ... %d more frames", hidden))
+		lines = append(lines, tail...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// line returns the source line of the innermost frame (0 if unknown)
+func (t StackTrace) line() int {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[0].Line
+}
+
+// StackTraceOf returns the stack trace recorded in err, or nil if it has none
+func StackTraceOf(err error) StackTrace {
+	var vmErr *VMError
+	if errors.As(err, &vmErr) && len(vmErr.Trace) > 0 {
+		return vmErr.Trace
+	}
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) && len(runtimeErr.Stack) > 0 {
+		return runtimeErr.Stack
+	}
+	var throwErr *ThrowError
+	if errors.As(err, &throwErr) && len(throwErr.Stack) > 0 {
+		return throwErr.Stack
+	}
+	return nil
+}
+
 // RuntimeError represents a runtime error in the virtual machine
 type RuntimeError struct {
 	Message string
-	PC      int // program counter where error occurred
-	Stack   []string // call stack trace
+	PC      int        // program counter where error occurred
+	Line    int        // source line where error occurred (0 if unknown)
+	Stack   StackTrace // call stack trace
 }
 
 func (e *RuntimeError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("Runtime Error at line %d: %s", e.Line, e.Message)
+	}
+	if e.PC >= 0 {
+		return fmt.Sprintf("Runtime Error at pc %d: %s", e.PC, e.Message)
+	}
 	return fmt.Sprintf("Runtime Error: %s", e.Message)
 }
 
@@ -20,7 +109,36 @@ func NewRuntimeError(format string, args ...interface{}) *RuntimeError {
 	return &RuntimeError{
 		Message: fmt.Sprintf(format, args...),
 		PC:      -1,
-		Stack:   make([]string, 0),
+	}
+}
+
+// ThrowError carries a value raised by a throw statement that was not caught
+type ThrowError struct {
+	Value Value      // the thrown value
+	Stack StackTrace // call stack trace
+}
+
+func (e *ThrowError) Error() string {
+	return fmt.Sprintf("Uncaught %s", e.Value.ToString())
+}
+
+// NewThrowError creates a new throw error
+func NewThrowError(value Value) *ThrowError {
+	return &ThrowError{Value: value}
+}
+
+// ErrorValue returns the value a catch clause receives for err: the thrown
+// value for throw statements, or the error message for runtime errors
+func ErrorValue(err error) Value {
+	switch e := err.(type) {
+	case *ThrowError:
+		return e.Value
+	case *RuntimeError:
+		return NewStringValue(e.Message)
+	case *VMError:
+		return NewStringValue(e.Message)
+	default:
+		return NewStringValue(err.Error())
 	}
 }
 
@@ -54,13 +172,19 @@ type VMError struct {
 	Type    string
 	Message string
 	Cause   error
+	Line    int        // source line where error occurred (0 if unknown)
+	Trace   StackTrace // call stack trace
 }
 
 func (e *VMError) Error() string {
+	where := ""
+	if e.Line > 0 {
+		where = fmt.Sprintf(" at line %d", e.Line)
+	}
 	if e.Cause != nil {
-		return fmt.Sprintf("VM Error [%s]: %s (caused by: %v)", e.Type, e.Message, e.Cause)
+		return fmt.Sprintf("VM Error [%s]%s: %s (caused by: %v)", e.Type, where, e.Message, e.Cause)
 	}
-	return fmt.Sprintf("VM Error [%s]: %s", e.Type, e.Message)
+	return fmt.Sprintf("VM Error [%s]%s: %s", e.Type, where, e.Message)
 }
 
 func (e *VMError) Unwrap() error {
@@ -97,6 +221,12 @@ func IsRuntimeError(err error) bool {
 	return ok
 }
 
+// IsThrowError checks if an error is an uncaught thrown value
+func IsThrowError(err error) bool {
+	_, ok := err.(*ThrowError)
+	return ok
+}
+
 // IsCompileError checks if an error is a compile error
 func IsCompileError(err error) bool {
 	_, ok := err.(*CompileError)
@@ -113,4 +243,5 @@ var (
 	ErrInvalidOperation  = "InvalidOperation"
 	ErrIndexOutOfBounds  = "IndexOutOfBounds"
 	ErrInvalidArguments  = "InvalidArguments"
+	ErrStepLimit         = "StepLimit"
 )
\ No newline at end of file
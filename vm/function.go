@@ -1,14 +1,18 @@
 package vm
 
+import "fmt"
+
 // Function represents a compiled function
 type Function struct {
 	Name         string        // function name
 	Instructions []Instruction // bytecode instructions
 	Constants    []Value       // constant pool
+	Globals      []string      // global names, indexed by the slot operand of GETGLOBAL and SETGLOBAL
 	NumParams    int           // number of parameters
+	NumOptional  int           // number of trailing parameters that may be omitted
 	NumLocals    int           // number of local variables
 	NumUpvalues  int           // number of upvalues
-	IsVariadic   bool          // whether function accepts variable arguments
+	IsVariadic   bool          // whether the last parameter collects surplus arguments into an array
 	SourceFile   string        // source file name
 	LineNumbers  []int         // line number for each instruction
 }
@@ -72,7 +76,9 @@ func (f *Function) GetLineNumber(index int) int {
 	return f.LineNumbers[index]
 }
 
-// NativeFunctionType represents the signature of a native function
+// NativeFunctionType represents the signature of a native function.
+// args may share memory with the caller's registers, so it is only valid
+// until the function returns; copy it to keep the arguments.
 type NativeFunctionType func(vm *VM, args []Value) (Value, error)
 
 // NativeFunction represents a native (Go) function
@@ -93,20 +99,33 @@ func NewNativeFunction(name string, fn NativeFunctionType, minArgs, maxArgs int)
 	}
 }
 
-// Call calls the native function
+// Call calls the native function after checking the argument count
 func (nf *NativeFunction) Call(vm *VM, args []Value) (Value, error) {
-	// Check argument count
-	if len(args) < nf.MinArgs {
-		return NilValue, NewRuntimeError("function '%s' expects at least %d arguments, got %d", 
-			nf.Name, nf.MinArgs, len(args))
+	if len(args) < nf.MinArgs || (nf.MaxArgs >= 0 && len(args) > nf.MaxArgs) {
+		return NilValue, NewVMErrorWithType(ErrInvalidArguments, nil, "function '%s' expects %s, got %d",
+			nf.Name, nf.arity(), len(args))
 	}
 	
-	if nf.MaxArgs >= 0 && len(args) > nf.MaxArgs {
-		return NilValue, NewRuntimeError("function '%s' expects at most %d arguments, got %d", 
-			nf.Name, nf.MaxArgs, len(args))
+	return nf.Function(vm, args)
+}
+
+// arity describes the accepted argument count, e.g. "1 to 3 arguments"
+func (nf *NativeFunction) arity() string {
+	count := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
 	}
 	
-	return nf.Function(vm, args)
+	switch {
+	case nf.MaxArgs < 0:
+		return "at least " + count(nf.MinArgs)
+	case nf.MinArgs == nf.MaxArgs:
+		return count(nf.MaxArgs)
+	default:
+		return fmt.Sprintf("%d to %s", nf.MinArgs, count(nf.MaxArgs))
+	}
 }
 
 // Upvalue represents an upvalue (captured variable)
@@ -30,44 +30,54 @@ const (
 	OpBitNot // R(A) := ~R(B)
 	OpShl    // R(A) := R(B) << R(C)
 	OpShr    // R(A) := R(B) >> R(C)
+	OpUShr   // R(A) := R(B) >>> R(C)
 
 	// Comparison operations
-	OpEq // if R(B) == R(C) then PC++
-	OpNe // if R(B) != R(C) then PC++
-	OpLt // if R(B) < R(C) then PC++
-	OpLe // if R(B) <= R(C) then PC++
-	OpGt // if R(B) > R(C) then PC++
-	OpGe // if R(B) >= R(C) then PC++
+	OpEq // R(A) := R(B) == R(C)
+	OpNe // R(A) := R(B) != R(C)
+	OpLt // R(A) := R(B) < R(C)
+	OpLe // R(A) := R(B) <= R(C)
+	OpGt // R(A) := R(B) > R(C)
+	OpGe // R(A) := R(B) >= R(C)
+
+	OpStrictEq // R(A) := R(B) === R(C)
 
 	// Logical operations
 	OpNot // R(A) := !R(B)
-	OpAnd // R(A) := R(B) && R(C)
-	OpOr  // R(A) := R(B) || R(C)
 
 	// Control flow
-	OpJmp     // PC += sBx
-	OpTest    // if not R(A) then PC++
-	OpTestSet // if R(B) then R(A) := R(B) else PC++
+	OpJmp         // PC += sBx
+	OpTest        // if bool(R(A)) != bool(C) then PC++
+	OpTestSet     // if R(B) then R(A) := R(B) else PC++
+	OpTestNullish // if R(A) is null or undefined (C == 0) or neither (C == 1) then PC++
 
 	// Function calls
-	OpCall     // R(A)..R(A+C-1) := R(A)(R(A+1)..R(A+B-1))
-	OpTailCall // return R(A)(R(A+1)..R(A+B-1))
+	OpCall     // R(A) := R(A)(R(A+1)..R(A+B)); C == 0 discards the result, C&CallSpread expands R(A+1)
+	OpTailCall // return R(A)(R(A+1)..R(A+B))
 	OpReturn   // return R(A)..R(A+B-1)
+	OpYield    // suspend the running coroutine yielding R(B); R(A) := resumed value
+
+	// Exception handling
+	OpSetupTry // push handler: on error, R(A) := thrown value; PC += sBx
+	OpPopTry   // pop the innermost handler
+	OpThrow    // throw R(A)
 
 	// Object operations
 	OpNewTable  // R(A) := {} (size = B*C)
 	OpGetTable  // R(A) := R(B)[R(C)]
 	OpSetTable  // R(A)[R(B)] := R(C)
-	OpGetGlobal // R(A) := G[K(Bx)]
-	OpSetGlobal // G[K(Bx)] := R(A)
+	OpGetGlobal // R(A) := G[Globals[Bx]]
+	OpSetGlobal // G[Globals[Bx]] := R(A)
 	OpGetUpval  // R(A) := UpValue[B]
 	OpSetUpval  // UpValue[B] := R(A)
+	OpKeys      // R(A) := array of the keys of R(B)
 
 	// Array operations
 	OpNewArray // R(A) := [] (size = Bx)
 	OpGetIndex // R(A) := R(B)[R(C)]
 	OpSetIndex // R(A)[R(B)] := R(C)
 	OpLen      // R(A) := len(R(B))
+	OpSpread   // append the elements of R(B) to array R(A), or copy its properties into object R(A)
 
 	// String operations
 	OpConcat // R(A) := R(B) .. R(C)
@@ -75,23 +85,28 @@ const (
 	// Type operations
 	OpTypeOf   // R(A) := typeof(R(B))
 	OpInstanceOf // R(A) := R(B) instanceof R(C)
+	OpConvert    // R(A) := convert R(B) to numeric kind C
 
 	// Loop operations
-	OpForPrep // R(A) -= R(A+2); PC += sBx
-	OpForLoop // R(A) += R(A+2); if R(A) <= R(A+1) then PC += sBx; R(A+3) = R(A)
+	OpForPrep  // R(A) -= R(A+2); PC += sBx
+	OpForLoop  // R(A) += R(A+2); if R(A) <= R(A+1) then PC += sBx; R(A+3) = R(A)
+	OpIterNext // if R(B) has an element at cursor R(C): R(A) := element; advance R(C); PC++
 
 	// Closure operations
 	OpClosure // R(A) := closure(KPROTO[Bx])
 	OpClose   // close all variables in the stack up to (>=) R(A)
 
 	// Special operations
-	OpNop   // no operation
-	OpHalt  // halt execution
-	OpDebug // debug breakpoint
+	OpNop  // no operation
+	OpHalt // halt execution
 
 	OpCodeMax
 )
 
+// CallSpread is set in the C operand of OpCall when R(A+1) holds an array
+// whose elements are passed as the arguments, with B == 1
+const CallSpread = 2
+
 // Instruction represents a 32-bit virtual machine instruction
 type Instruction uint32
 
@@ -121,6 +136,10 @@ const (
 	BxOffset = (1 << (BxBits - 1)) // 131072
 )
 
+// Every opcode must fit in OpCodeBits; this fails to compile otherwise.
+// 62 of the 64 opcodes are in use.
+const _ = uint(1<<OpCodeBits - OpCodeMax)
+
 // Instruction format types
 type InstructionFormat int
 
@@ -161,25 +180,32 @@ var OpCodeInfos = [OpCodeMax]OpCodeInfo{
 	OpBitNot: {"BITNOT", FormatABC, true, true, false},
 	OpShl:    {"SHL", FormatABC, true, true, true},
 	OpShr:    {"SHR", FormatABC, true, true, true},
+	OpUShr:   {"USHR", FormatABC, true, true, true},
+
+	OpEq: {"EQ", FormatABC, true, true, true},
+	OpNe: {"NE", FormatABC, true, true, true},
+	OpLt: {"LT", FormatABC, true, true, true},
+	OpLe: {"LE", FormatABC, true, true, true},
+	OpGt: {"GT", FormatABC, true, true, true},
+	OpGe: {"GE", FormatABC, true, true, true},
 
-	OpEq: {"EQ", FormatABC, false, true, true},
-	OpNe: {"NE", FormatABC, false, true, true},
-	OpLt: {"LT", FormatABC, false, true, true},
-	OpLe: {"LE", FormatABC, false, true, true},
-	OpGt: {"GT", FormatABC, false, true, true},
-	OpGe: {"GE", FormatABC, false, true, true},
+	OpStrictEq: {"STRICTEQ", FormatABC, true, true, true},
 
 	OpNot: {"NOT", FormatABC, true, true, false},
-	OpAnd: {"AND", FormatABC, true, true, true},
-	OpOr:  {"OR", FormatABC, true, true, true},
 
-	OpJmp:     {"JMP", FormatABx, false, false, false},
-	OpTest:    {"TEST", FormatABC, false, true, false},
-	OpTestSet: {"TESTSET", FormatABC, true, true, false},
+	OpJmp:         {"JMP", FormatABx, false, false, false},
+	OpTest:        {"TEST", FormatABC, false, false, true},
+	OpTestSet:     {"TESTSET", FormatABC, true, true, false},
+	OpTestNullish: {"TESTNULLISH", FormatABC, false, true, false},
 
 	OpCall:     {"CALL", FormatABC, true, true, true},
 	OpTailCall: {"TAILCALL", FormatABC, false, true, true},
 	OpReturn:   {"RETURN", FormatABC, false, true, false},
+	OpYield:    {"YIELD", FormatABC, true, true, false},
+
+	OpSetupTry: {"SETUPTRY", FormatABx, true, false, false},
+	OpPopTry:   {"POPTRY", FormatABC, false, false, false},
+	OpThrow:    {"THROW", FormatABC, false, true, false},
 
 	OpNewTable:  {"NEWTABLE", FormatABC, true, true, true},
 	OpGetTable:  {"GETTABLE", FormatABC, true, true, true},
@@ -188,26 +214,29 @@ var OpCodeInfos = [OpCodeMax]OpCodeInfo{
 	OpSetGlobal: {"SETGLOBAL", FormatABx, false, false, false},
 	OpGetUpval:  {"GETUPVAL", FormatABC, true, true, false},
 	OpSetUpval:  {"SETUPVAL", FormatABC, false, true, false},
+	OpKeys:      {"KEYS", FormatABC, true, true, false},
 
 	OpNewArray: {"NEWARRAY", FormatABx, true, false, false},
 	OpGetIndex: {"GETINDEX", FormatABC, true, true, true},
 	OpSetIndex: {"SETINDEX", FormatABC, false, true, true},
 	OpLen:      {"LEN", FormatABC, true, true, false},
+	OpSpread:   {"SPREAD", FormatABC, false, true, false},
 
 	OpConcat: {"CONCAT", FormatABC, true, true, true},
 
 	OpTypeOf:     {"TYPEOF", FormatABC, true, true, false},
 	OpInstanceOf: {"INSTANCEOF", FormatABC, true, true, true},
+	OpConvert:    {"CONVERT", FormatABC, true, true, false},
 
-	OpForPrep: {"FORPREP", FormatABx, false, false, false},
-	OpForLoop: {"FORLOOP", FormatABx, false, false, false},
+	OpForPrep:  {"FORPREP", FormatABx, false, false, false},
+	OpForLoop:  {"FORLOOP", FormatABx, false, false, false},
+	OpIterNext: {"ITERNEXT", FormatABC, true, true, true},
 
 	OpClosure: {"CLOSURE", FormatABx, true, false, false},
 	OpClose:   {"CLOSE", FormatABC, false, true, false},
 
-	OpNop:   {"NOP", FormatABC, false, false, false},
-	OpHalt:  {"HALT", FormatABC, false, false, false},
-	OpDebug: {"DEBUG", FormatABC, false, false, false},
+	OpNop:  {"NOP", FormatABC, false, false, false},
+	OpHalt: {"HALT", FormatABC, false, false, false},
 }
 
 // CreateABC creates an ABC format instruction
@@ -274,6 +303,39 @@ func (inst Instruction) String() string {
 	}
 
 	info := OpCodeInfos[op]
+	switch op {
+	case OpJmp:
+		return fmt.Sprintf("%-10s %+d", info.Name, inst.GetSBx())
+	case OpSetupTry, OpForPrep, OpForLoop:
+		return fmt.Sprintf("%-10s R%d, %+d", info.Name, inst.GetA(), inst.GetSBx())
+	case OpTest:
+		// R(A) is tested against the truthiness in C
+		return fmt.Sprintf("%-10s R%d, %d", info.Name, inst.GetA(), inst.GetC())
+	case OpTestNullish:
+		if inst.GetC() != 0 {
+			return fmt.Sprintf("%-10s R%d, %d", info.Name, inst.GetA(), inst.GetC())
+		}
+		return fmt.Sprintf("%-10s R%d", info.Name, inst.GetA())
+	case OpLoadBool:
+		// B is the value loaded and C skips the next instruction
+		return fmt.Sprintf("%-10s R%d, %d, %d", info.Name, inst.GetA(), inst.GetB(), inst.GetC())
+	case OpCall:
+		// B is the argument count and C holds the call flags
+		return fmt.Sprintf("%-10s R%d, %d, %d", info.Name, inst.GetA(), inst.GetB(), inst.GetC())
+	case OpReturn:
+		// B is the number of values returned
+		return fmt.Sprintf("%-10s R%d, %d", info.Name, inst.GetA(), inst.GetB())
+	case OpSetTable, OpSetIndex:
+		// R(A) is the table written to
+		return fmt.Sprintf("%-10s R%d, R%d, R%d", info.Name, inst.GetA(), inst.GetB(), inst.GetC())
+	case OpSpread:
+		return fmt.Sprintf("%-10s R%d, R%d", info.Name, inst.GetA(), inst.GetB())
+	case OpSetUpval:
+		return fmt.Sprintf("%-10s R%d, %d", info.Name, inst.GetA(), inst.GetB())
+	case OpThrow, OpClose:
+		return fmt.Sprintf("%-10s R%d", info.Name, inst.GetA())
+	}
+
 	switch info.Format {
 	case FormatABC:
 		a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
@@ -289,12 +351,9 @@ func (inst Instruction) String() string {
 			return info.Name
 		}
 	case FormatABx:
+		// A is read or written by every ABx instruction not handled above
 		a, bx := inst.GetA(), inst.GetBx()
-		if info.HasA {
-			return fmt.Sprintf("%-10s R%d, %d", info.Name, a, bx)
-		} else {
-			return fmt.Sprintf("%-10s %d", info.Name, bx)
-		}
+		return fmt.Sprintf("%-10s R%d, %d", info.Name, a, bx)
 	case FormatAx:
 		ax := inst.GetAx()
 		return fmt.Sprintf("%-10s %d", info.Name, ax)
@@ -306,9 +365,8 @@ func (inst Instruction) String() string {
 // IsJump returns true if the instruction is a jump instruction
 func (inst Instruction) IsJump() bool {
 	op := inst.GetOpCode()
-	return op == OpJmp || op == OpTest || op == OpTestSet ||
-		op == OpEq || op == OpNe || op == OpLt || op == OpLe ||
-		op == OpGt || op == OpGe || op == OpForPrep || op == OpForLoop
+	return op == OpJmp || op == OpTest || op == OpTestSet || op == OpTestNullish ||
+		op == OpForPrep || op == OpForLoop || op == OpIterNext
 }
 
 // IsCall returns true if the instruction is a call instruction
@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxJSONDepth bounds the nesting of stringified values, which also stops
+// cyclic arrays and objects
+const maxJSONDepth = 256
+
+// initJSON defines the global JSON object with parse and stringify
+func (vm *VM) initJSON() {
+	obj := NewObject()
+
+	obj.Set("stringify", NewNativeFunctionValue(NewNativeFunction("JSON.stringify",
+		func(vm *VM, args []Value) (Value, error) {
+			text, err := stringifyJSON("JSON.stringify", args[0])
+			if err != nil {
+				return NilValue, err
+			}
+			return NewStringValue(text), nil
+		}, 1, 1)))
+
+	obj.Set("parse", NewNativeFunctionValue(NewNativeFunction("JSON.parse",
+		func(vm *VM, args []Value) (Value, error) {
+			if !args[0].IsString() {
+				return NilValue, NewVMErrorWithType(ErrInvalidArguments, nil, "JSON.parse expects a string, got %s", args[0].TypeName())
+			}
+			return parseJSON(args[0].AsString())
+		}, 1, 1)))
+
+	vm.SetGlobal("JSON", NewObjectValue(obj))
+}
+
+// stringifyJSON converts a value to JSON for the native caller. nil,
+// undefined and null become null, as do NaN and infinite floats; functions
+// cannot be converted. Object keys keep their insertion order.
+func stringifyJSON(caller string, v Value) (string, error) {
+	var sb strings.Builder
+	if err := writeJSON(&sb, v, 0); err != nil {
+		return "", NewRuntimeError("%s: %s", caller, err.Error())
+	}
+	return sb.String(), nil
+}
+
+// writeJSON appends the JSON encoding of v to sb
+func writeJSON(sb *strings.Builder, v Value, depth int) error {
+	if depth > maxJSONDepth {
+		return errors.New("value is nested too deeply or cyclic")
+	}
+
+	switch v.Type {
+	case TypeNil, TypeVoid, TypeNull:
+		sb.WriteString("null")
+	case TypeBool, TypeInt:
+		sb.WriteString(v.ToString())
+	case TypeFloat:
+		f := v.AsFloat()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			sb.WriteString("null")
+		} else {
+			// Keep a fraction on whole floats so they parse back as floats
+			text := strconv.FormatFloat(f, 'g', -1, 64)
+			if !strings.ContainsAny(text, ".e") {
+				text += ".0"
+			}
+			sb.WriteString(text)
+		}
+	case TypeString:
+		writeJSONString(sb, v.AsString())
+	case TypeArray:
+		sb.WriteByte('[')
+		for i, elem := range v.AsArray().Elements {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			if err := writeJSON(sb, elem, depth+1); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(']')
+	case TypeObject:
+		obj := v.AsObject()
+		sb.WriteByte('{')
+		for i, key := range obj.keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeJSONString(sb, key)
+			sb.WriteByte(':')
+			if err := writeJSON(sb, obj.Properties[key], depth+1); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot convert %s to JSON", v.TypeName())
+	}
+	return nil
+}
+
+// writeJSONString appends s as a quoted JSON string
+func writeJSONString(sb *strings.Builder, s string) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	sb.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// parseJSON converts JSON text to a value. Numbers without a fraction or
+// exponent become ints; objects keep the order of their keys.
+func parseJSON(text string) (Value, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	v, err := readJSON(dec)
+	if err != nil {
+		return NilValue, NewRuntimeError("JSON.parse: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return NilValue, NewRuntimeError("JSON.parse: unexpected data after value")
+	}
+	return v, nil
+}
+
+// readJSON decodes the next JSON value from dec
+func readJSON(dec *json.Decoder) (Value, error) {
+	token, err := dec.Token()
+	if err == io.EOF {
+		return NilValue, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return NilValue, err
+	}
+
+	switch t := token.(type) {
+	case nil:
+		return NullValue, nil
+	case bool:
+		return NewBoolValue(t), nil
+	case string:
+		return NewStringValue(t), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(t), 10, 64); err == nil {
+			return NewIntValue(i), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return NilValue, err
+		}
+		return NewFloatValue(f), nil
+	case json.Delim:
+		if t == '[' {
+			arr := NewArray(0)
+			for dec.More() {
+				elem, err := readJSON(dec)
+				if err != nil {
+					return NilValue, err
+				}
+				arr.Push(elem)
+			}
+			if _, err := dec.Token(); err != nil {
+				return NilValue, err
+			}
+			return NewArrayValue(arr), nil
+		}
+
+		obj := NewObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return NilValue, err
+			}
+			value, err := readJSON(dec)
+			if err != nil {
+				return NilValue, err
+			}
+			obj.Set(key.(string), value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return NilValue, err
+		}
+		return NewObjectValue(obj), nil
+	}
+	return NilValue, nil
+}
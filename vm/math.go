@@ -0,0 +1,106 @@
+package vm
+
+import (
+	"math"
+	"math/rand"
+)
+
+// initMath defines the global Math object. abs, min, max, sqrt, pow and
+// random return floats; floor, ceil and round return ints.
+func (vm *VM) initMath() {
+	obj := NewObject()
+	obj.Set("PI", NewFloatValue(math.Pi))
+	obj.Set("E", NewFloatValue(math.E))
+
+	setMathFunction(obj, "abs", 1, 1, func(vm *VM, args []Value) (Value, error) {
+		x, err := mathArg("abs", args, 0)
+		if err != nil {
+			return NilValue, err
+		}
+		return NewFloatValue(math.Abs(x)), nil
+	})
+	setMathFunction(obj, "floor", 1, 1, func(vm *VM, args []Value) (Value, error) {
+		return mathRound("floor", args, math.Floor)
+	})
+	setMathFunction(obj, "ceil", 1, 1, func(vm *VM, args []Value) (Value, error) {
+		return mathRound("ceil", args, math.Ceil)
+	})
+	setMathFunction(obj, "round", 1, 1, func(vm *VM, args []Value) (Value, error) {
+		// Halves round up, as in JavaScript: Math.round(-2.5) is -2
+		return mathRound("round", args, func(x float64) float64 {
+			return math.Floor(x + 0.5)
+		})
+	})
+	setMathFunction(obj, "sqrt", 1, 1, func(vm *VM, args []Value) (Value, error) {
+		x, err := mathArg("sqrt", args, 0)
+		if err != nil {
+			return NilValue, err
+		}
+		return NewFloatValue(math.Sqrt(x)), nil
+	})
+	setMathFunction(obj, "pow", 2, 2, func(vm *VM, args []Value) (Value, error) {
+		x, err := mathArg("pow", args, 0)
+		if err != nil {
+			return NilValue, err
+		}
+		y, err := mathArg("pow", args, 1)
+		if err != nil {
+			return NilValue, err
+		}
+		return NewFloatValue(math.Pow(x, y)), nil
+	})
+	setMathFunction(obj, "min", 1, -1, func(vm *VM, args []Value) (Value, error) {
+		return mathReduce("min", args, math.Min)
+	})
+	setMathFunction(obj, "max", 1, -1, func(vm *VM, args []Value) (Value, error) {
+		return mathReduce("max", args, math.Max)
+	})
+	setMathFunction(obj, "random", 0, 0, func(vm *VM, args []Value) (Value, error) {
+		return NewFloatValue(rand.Float64()), nil
+	})
+
+	vm.SetGlobal("Math", NewObjectValue(obj))
+}
+
+// setMathFunction stores a native function as a property of the Math object
+func setMathFunction(obj *Object, name string, minArgs, maxArgs int, fn NativeFunctionType) {
+	obj.Set(name, NewNativeFunctionValue(NewNativeFunction("Math."+name, fn, minArgs, maxArgs)))
+}
+
+// mathArg returns argument i of a Math function as a float
+func mathArg(name string, args []Value, i int) (float64, error) {
+	if !args[i].IsNumber() {
+		return 0, NewVMErrorWithType(ErrInvalidArguments, nil, "Math.%s expects a number, got %s", name, args[i].TypeName())
+	}
+	x, _ := args[i].ToFloat()
+	return x, nil
+}
+
+// mathRound applies a rounding function and returns the result as an int
+func mathRound(name string, args []Value, round func(float64) float64) (Value, error) {
+	x, err := mathArg(name, args, 0)
+	if err != nil {
+		return NilValue, err
+	}
+	r := round(x)
+	if math.IsNaN(r) || r < math.MinInt64 || r >= math.MaxInt64 {
+		return NilValue, NewRuntimeError("Math.%s: %s is out of int range", name, args[0].ToString())
+	}
+	return NewIntValue(int64(r)), nil
+}
+
+// mathReduce folds the arguments of min or max into a single float
+func mathReduce(name string, args []Value, pick func(a, b float64) float64) (Value, error) {
+	result, err := mathArg(name, args, 0)
+	if err != nil {
+		return NilValue, err
+	}
+	for i := 1; i < len(args); i++ {
+		x, err := mathArg(name, args, i)
+		if err != nil {
+			return NilValue, err
+		}
+		result = pick(result, x)
+	}
+	return NewFloatValue(result), nil
+}
@@ -2,8 +2,12 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // ValueType represents the type of a value in the virtual machine
@@ -24,34 +28,37 @@ const (
 	TypeUpvalue
 )
 
-// Value represents a value in the virtual machine
+// Value represents a value in the virtual machine. Numbers and booleans are
+// held inline so that arithmetic doesn't allocate; strings and heap values
+// are referenced through ptr.
 type Value struct {
 	Type ValueType
-	Data interface{}
+	bits uint64         // int64, float64 bits, bool or string length
+	ptr  unsafe.Pointer // string data, *Array, *Object, *Function or *NativeFunction
 }
 
 // Common value constants
 var (
-	NilValue   = Value{Type: TypeNil, Data: nil}
-	VoidValue  = Value{Type: TypeVoid, Data: nil}
-	NullValue  = Value{Type: TypeNull, Data: nil}
-	TrueValue  = Value{Type: TypeBool, Data: true}
-	FalseValue = Value{Type: TypeBool, Data: false}
+	NilValue   = Value{Type: TypeNil}
+	VoidValue  = Value{Type: TypeVoid}
+	NullValue  = Value{Type: TypeNull}
+	TrueValue  = Value{Type: TypeBool, bits: 1}
+	FalseValue = Value{Type: TypeBool, bits: 0}
 )
 
 // NewIntValue creates a new integer value
 func NewIntValue(i int64) Value {
-	return Value{Type: TypeInt, Data: i}
+	return Value{Type: TypeInt, bits: uint64(i)}
 }
 
 // NewFloatValue creates a new float value
 func NewFloatValue(f float64) Value {
-	return Value{Type: TypeFloat, Data: f}
+	return Value{Type: TypeFloat, bits: math.Float64bits(f)}
 }
 
 // NewStringValue creates a new string value
 func NewStringValue(s string) Value {
-	return Value{Type: TypeString, Data: s}
+	return Value{Type: TypeString, bits: uint64(len(s)), ptr: unsafe.Pointer(unsafe.StringData(s))}
 }
 
 // NewBoolValue creates a new boolean value
@@ -64,22 +71,66 @@ func NewBoolValue(b bool) Value {
 
 // NewArrayValue creates a new array value
 func NewArrayValue(arr *Array) Value {
-	return Value{Type: TypeArray, Data: arr}
+	return Value{Type: TypeArray, ptr: unsafe.Pointer(arr)}
 }
 
 // NewObjectValue creates a new object value
 func NewObjectValue(obj *Object) Value {
-	return Value{Type: TypeObject, Data: obj}
+	return Value{Type: TypeObject, ptr: unsafe.Pointer(obj)}
 }
 
 // NewFunctionValue creates a new function value
 func NewFunctionValue(fn *Function) Value {
-	return Value{Type: TypeFunction, Data: fn}
+	return Value{Type: TypeFunction, ptr: unsafe.Pointer(fn)}
 }
 
 // NewNativeFunctionValue creates a new native function value
 func NewNativeFunctionValue(fn *NativeFunction) Value {
-	return Value{Type: TypeNativeFunction, Data: fn}
+	return Value{Type: TypeNativeFunction, ptr: unsafe.Pointer(fn)}
+}
+
+// The As methods return the Go value held by a value. Like a type
+// assertion they may only be used once the value's Type is known.
+
+// AsBool returns the boolean held by a TypeBool value
+func (v Value) AsBool() bool {
+	return v.bits != 0
+}
+
+// AsInt returns the integer held by a TypeInt value
+func (v Value) AsInt() int64 {
+	return int64(v.bits)
+}
+
+// AsFloat returns the float held by a TypeFloat value
+func (v Value) AsFloat() float64 {
+	return math.Float64frombits(v.bits)
+}
+
+// AsString returns the string held by a TypeString value
+func (v Value) AsString() string {
+	return unsafe.String((*byte)(v.ptr), int(v.bits))
+}
+
+// AsArray returns the array held by a TypeArray value
+func (v Value) AsArray() *Array {
+	return (*Array)(v.ptr)
+}
+
+// AsObject returns the object held by a TypeObject value
+func (v Value) AsObject() *Object {
+	return (*Object)(v.ptr)
+}
+
+// AsFunction returns the function held by a TypeFunction value
+func (v Value) AsFunction() *Function {
+	return (*Function)(v.ptr)
+}
+
+// AsNativeFunction returns the native function held by a
+// TypeNativeFunction value
+func (v Value) AsNativeFunction() *NativeFunction {
+	return (*NativeFunction)(v.ptr)
 }
 
 // IsNil returns true if the value is nil
@@ -97,6 +148,11 @@ func (v Value) IsNull() bool {
 	return v.Type == TypeNull
 }
 
+// IsNullish returns true if the value is null, undefined or void
+func (v Value) IsNullish() bool {
+	return v.Type == TypeNil || v.Type == TypeNull || v.Type == TypeVoid
+}
+
 // IsBool returns true if the value is a boolean
 func (v Value) IsBool() bool {
 	return v.Type == TypeBool
@@ -148,13 +204,13 @@ func (v Value) ToBool() bool {
 	case TypeNil, TypeVoid, TypeNull:
 		return false
 	case TypeBool:
-		return v.Data.(bool)
+		return v.AsBool()
 	case TypeInt:
-		return v.Data.(int64) != 0
+		return v.AsInt() != 0
 	case TypeFloat:
-		return v.Data.(float64) != 0.0
+		return v.AsFloat() != 0.0
 	case TypeString:
-		return v.Data.(string) != ""
+		return v.AsString() != ""
 	default:
 		return true // objects, arrays, functions are truthy
 	}
@@ -164,17 +220,17 @@ func (v Value) ToBool() bool {
 func (v Value) ToInt() (int64, bool) {
 	switch v.Type {
 	case TypeInt:
-		return v.Data.(int64), true
+		return v.AsInt(), true
 	case TypeFloat:
-		f := v.Data.(float64)
+		f := v.AsFloat()
 		return int64(f), f == float64(int64(f))
 	case TypeString:
-		if i, err := strconv.ParseInt(v.Data.(string), 10, 64); err == nil {
+		if i, err := strconv.ParseInt(v.AsString(), 10, 64); err == nil {
 			return i, true
 		}
 		return 0, false
 	case TypeBool:
-		if v.Data.(bool) {
+		if v.AsBool() {
 			return 1, true
 		}
 		return 0, true
@@ -187,16 +243,16 @@ func (v Value) ToInt() (int64, bool) {
 func (v Value) ToFloat() (float64, bool) {
 	switch v.Type {
 	case TypeFloat:
-		return v.Data.(float64), true
+		return v.AsFloat(), true
 	case TypeInt:
-		return float64(v.Data.(int64)), true
+		return float64(v.AsInt()), true
 	case TypeString:
-		if f, err := strconv.ParseFloat(v.Data.(string), 64); err == nil {
+		if f, err := strconv.ParseFloat(v.AsString(), 64); err == nil {
 			return f, true
 		}
 		return 0.0, false
 	case TypeBool:
-		if v.Data.(bool) {
+		if v.AsBool() {
 			return 1.0, true
 		}
 		return 0.0, true
@@ -215,18 +271,18 @@ func (v Value) ToString() string {
 	case TypeNull:
 		return "null"
 	case TypeBool:
-		if v.Data.(bool) {
+		if v.AsBool() {
 			return "true"
 		}
 		return "false"
 	case TypeInt:
-		return strconv.FormatInt(v.Data.(int64), 10)
+		return strconv.FormatInt(v.AsInt(), 10)
 	case TypeFloat:
-		return strconv.FormatFloat(v.Data.(float64), 'g', -1, 64)
+		return strconv.FormatFloat(v.AsFloat(), 'g', -1, 64)
 	case TypeString:
-		return v.Data.(string)
+		return v.AsString()
 	case TypeArray:
-		arr := v.Data.(*Array)
+		arr := v.AsArray()
 		var parts []string
 		for i := 0; i < arr.Length(); i++ {
 			if val, ok := arr.Get(i); ok {
@@ -237,17 +293,23 @@ func (v Value) ToString() string {
 		}
 		return "[" + strings.Join(parts, ", ") + "]"
 	case TypeObject:
-		obj := v.Data.(*Object)
+		// Keys are sorted so that printing an object is deterministic
+		obj := v.AsObject()
+		keys := make([]string, 0, len(obj.Properties))
+		for key := range obj.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
 		var parts []string
-		for key, val := range obj.Properties {
-			parts = append(parts, fmt.Sprintf("%s: %s", key, val.ToString()))
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s: %s", key, obj.Properties[key].ToString()))
 		}
 		return "{" + strings.Join(parts, ", ") + "}"
 	case TypeFunction:
-		fn := v.Data.(*Function)
+		fn := v.AsFunction()
 		return fmt.Sprintf("function<%s>", fn.Name)
 	case TypeNativeFunction:
-		fn := v.Data.(*NativeFunction)
+		fn := v.AsNativeFunction()
 		return fmt.Sprintf("native_function<%s>", fn.Name)
 	default:
 		return fmt.Sprintf("unknown_type<%d>", v.Type)
@@ -286,6 +348,25 @@ func (v Value) TypeName() string {
 	}
 }
 
+// TypeOf returns the result of the JavaScript typeof operator for the value.
+// As in JavaScript, null and arrays report "object".
+func (v Value) TypeOf() string {
+	switch v.Type {
+	case TypeBool:
+		return "boolean"
+	case TypeInt, TypeFloat:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeFunction, TypeNativeFunction:
+		return "function"
+	case TypeNull, TypeArray, TypeObject:
+		return "object"
+	default:
+		return "undefined"
+	}
+}
+
 // Equals checks if two values are equal
 func (v Value) Equals(other Value) bool {
 	if v.Type != other.Type {
@@ -296,29 +377,106 @@ func (v Value) Equals(other Value) bool {
 	case TypeNil, TypeVoid, TypeNull:
 		return true
 	case TypeBool:
-		return v.Data.(bool) == other.Data.(bool)
+		return v.AsBool() == other.AsBool()
 	case TypeInt:
-		return v.Data.(int64) == other.Data.(int64)
+		return v.AsInt() == other.AsInt()
 	case TypeFloat:
-		return v.Data.(float64) == other.Data.(float64)
+		return v.AsFloat() == other.AsFloat()
 	case TypeString:
-		return v.Data.(string) == other.Data.(string)
+		return v.AsString() == other.AsString()
 	case TypeArray:
-		return v.Data.(*Array) == other.Data.(*Array) // reference equality
+		return v.AsArray() == other.AsArray() // reference equality
 	case TypeObject:
-		return v.Data.(*Object) == other.Data.(*Object) // reference equality
+		return v.AsObject() == other.AsObject() // reference equality
 	case TypeFunction:
-		return v.Data.(*Function) == other.Data.(*Function) // reference equality
+		return v.AsFunction() == other.AsFunction() // reference equality
 	case TypeNativeFunction:
-		return v.Data.(*NativeFunction) == other.Data.(*NativeFunction) // reference equality
+		return v.AsNativeFunction() == other.AsNativeFunction() // reference equality
 	default:
 		return false
 	}
 }
 
+// DeepEquals compares arrays by their elements and objects by their own
+// properties, to at most maxDepth levels of nesting; other values are
+// compared with Equals. A pair of arrays or objects met again while it is
+// being compared is taken to be equal, so cyclic values compare without
+// looping.
+func (v Value) DeepEquals(other Value, maxDepth int) bool {
+	return deepEquals(v, other, maxDepth, make(map[[2]unsafe.Pointer]bool))
+}
+
+// deepEquals implements DeepEquals. visited holds the pairs of arrays and
+// objects being compared.
+func deepEquals(a, b Value, depth int, visited map[[2]unsafe.Pointer]bool) bool {
+	if a.Type != b.Type || (a.Type != TypeArray && a.Type != TypeObject) {
+		return a.Equals(b)
+	}
+	if a.ptr == b.ptr {
+		return true
+	}
+	if depth <= 0 {
+		return false
+	}
+	pair := [2]unsafe.Pointer{a.ptr, b.ptr}
+	if visited[pair] {
+		return true
+	}
+	visited[pair] = true
+	
+	if a.Type == TypeArray {
+		left, right := a.AsArray().Elements, b.AsArray().Elements
+		if len(left) != len(right) {
+			return false
+		}
+		for i := range left {
+			if !deepEquals(left[i], right[i], depth-1, visited) {
+				return false
+			}
+		}
+		return true
+	}
+	
+	left, right := a.AsObject().Properties, b.AsObject().Properties
+	if len(left) != len(right) {
+		return false
+	}
+	for key, value := range left {
+		otherValue, ok := right[key]
+		if !ok || !deepEquals(value, otherValue, depth-1, visited) {
+			return false
+		}
+	}
+	return true
+}
+
+// LooseEquals is the equality used by == and !=. Unlike Equals it compares
+// numbers by value across int and float, and treats nil, null and undefined
+// as equal to each other.
+func (v Value) LooseEquals(other Value) bool {
+	if v.IsNumber() && other.IsNumber() && v.Type != other.Type {
+		vf, _ := v.ToFloat()
+		of, _ := other.ToFloat()
+		return vf == of
+	}
+	if v.IsNullish() && other.IsNullish() {
+		return true
+	}
+	return v.Equals(other)
+}
+
 // Compare compares two values (-1: less, 0: equal, 1: greater)
 func (v Value) Compare(other Value) (int, bool) {
-	// Try numeric comparison first
+	// Integers compare exactly, other numbers as floats
+	if v.Type == TypeInt && other.Type == TypeInt {
+		vi, oi := v.AsInt(), other.AsInt()
+		if vi < oi {
+			return -1, true
+		} else if vi > oi {
+			return 1, true
+		}
+		return 0, true
+	}
 	if v.IsNumber() && other.IsNumber() {
 		vf, _ := v.ToFloat()
 		of, _ := other.ToFloat()
@@ -333,8 +491,8 @@ func (v Value) Compare(other Value) (int, bool) {
 
 	// String comparison
 	if v.IsString() && other.IsString() {
-		vs := v.Data.(string)
-		os := other.Data.(string)
+		vs := v.AsString()
+		os := other.AsString()
 		if vs < os {
 			return -1, true
 		} else if vs > os {
@@ -346,8 +504,8 @@ func (v Value) Compare(other Value) (int, bool) {
 
 	// Boolean comparison
 	if v.IsBool() && other.IsBool() {
-		vb := v.Data.(bool)
-		ob := other.Data.(bool)
+		vb := v.AsBool()
+		ob := other.AsBool()
 		if !vb && ob {
 			return -1, true
 		} else if vb && !ob {
@@ -385,21 +543,34 @@ func (a *Array) Get(index int) (Value, bool) {
 	return a.Elements[index], true
 }
 
-// Set sets the element at the given index
+// Set sets the element at the given index. Setting the element at the
+// length appends it; indexes beyond that are out of range.
 func (a *Array) Set(index int, value Value) bool {
-	if index < 0 {
+	if index < 0 || index > len(a.Elements) {
 		return false
 	}
 	
-	// Extend array if necessary
-	for index >= len(a.Elements) {
-		a.Elements = append(a.Elements, NilValue)
+	if index == len(a.Elements) {
+		a.Elements = append(a.Elements, value)
+		return true
 	}
-	
 	a.Elements[index] = value
 	return true
 }
 
+// SetPadded is like Set, but fills the elements between the length and an
+// index beyond it with undefined
+func (a *Array) SetPadded(index int, value Value) bool {
+	if index < 0 {
+		return false
+	}
+	
+	for index > len(a.Elements) {
+		a.Elements = append(a.Elements, NilValue)
+	}
+	return a.Set(index, value)
+}
+
 // Push appends a value to the end of the array
 func (a *Array) Push(value Value) {
 	a.Elements = append(a.Elements, value)
@@ -416,10 +587,54 @@ func (a *Array) Pop() (Value, bool) {
 	return last, true
 }
 
+// IterNext returns the element of an iterable at cursor and the cursor of the
+// following element. ok is false once the iterable is exhausted. Arrays are
+// iterated by index and strings by character.
+func IterNext(iterable Value, cursor int) (element Value, next int, ok bool, err error) {
+	switch iterable.Type {
+	case TypeArray:
+		element, ok = iterable.AsArray().Get(cursor)
+		return element, cursor + 1, ok, nil
+	case TypeString:
+		s := iterable.AsString()
+		if cursor >= len(s) {
+			return NilValue, cursor, false, nil
+		}
+		r, size := utf8.DecodeRuneInString(s[cursor:])
+		return NewStringValue(string(r)), cursor + size, true, nil
+	default:
+		return NilValue, cursor, false, fmt.Errorf("value of type %s is not iterable", iterable.TypeName())
+	}
+}
+
+// KeysOf returns the keys of a value as an array of strings: an object's own
+// property keys in insertion order, or an array's indices
+func KeysOf(v Value) (*Array, error) {
+	switch v.Type {
+	case TypeObject:
+		obj := v.AsObject()
+		keys := NewArray(len(obj.keys))
+		for _, key := range obj.keys {
+			keys.Push(NewStringValue(key))
+		}
+		return keys, nil
+	case TypeArray:
+		length := v.AsArray().Length()
+		keys := NewArray(length)
+		for i := 0; i < length; i++ {
+			keys.Push(NewStringValue(strconv.Itoa(i)))
+		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over the keys of %s", v.TypeName())
+	}
+}
+
 // Object represents an object value
 type Object struct {
 	Properties map[string]Value
 	Prototype  *Object
+	keys       []string // own property keys in insertion order
 }
 
 // NewObject creates a new object
@@ -446,6 +661,9 @@ func (o *Object) Get(key string) (Value, bool) {
 
 // Set sets the property value for the given key
 func (o *Object) Set(key string, value Value) {
+	if _, exists := o.Properties[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
 	o.Properties[key] = value
 }
 
@@ -467,16 +685,94 @@ func (o *Object) Has(key string) bool {
 func (o *Object) Delete(key string) bool {
 	if _, ok := o.Properties[key]; ok {
 		delete(o.Properties, key)
+		for i, k := range o.keys {
+			if k == key {
+				o.keys = append(o.keys[:i], o.keys[i+1:]...)
+				break
+			}
+		}
 		return true
 	}
 	return false
 }
 
-// Keys returns all property keys
+// Keys returns the own property keys in insertion order
 func (o *Object) Keys() []string {
-	keys := make([]string, 0, len(o.Properties))
-	for key := range o.Properties {
-		keys = append(keys, key)
-	}
+	keys := make([]string, len(o.keys))
+	copy(keys, o.keys)
 	return keys
-}
\ No newline at end of file
+}
+
+// NumericKind identifies a sized numeric type for OpConvert
+type NumericKind int
+
+const (
+	KindInt8 NumericKind = iota + 1
+	KindInt16
+	KindInt32
+	KindInt64
+	KindFloat32
+	KindFloat64
+)
+
+// String returns the type name of the numeric kind
+func (k NumericKind) String() string {
+	switch k {
+	case KindInt8:
+		return "int8"
+	case KindInt16:
+		return "int16"
+	case KindInt32:
+		return "int32"
+	case KindInt64:
+		return "int64"
+	case KindFloat32:
+		return "float32"
+	case KindFloat64:
+		return "float64"
+	default:
+		return "unknown"
+	}
+}
+
+// ConvertNumber converts a numeric value to the given kind. Integer kinds
+// truncate floats toward zero and wrap around on overflow, like Go's
+// integer conversions.
+func ConvertNumber(v Value, kind NumericKind) (Value, error) {
+	if !v.IsNumber() {
+		return NilValue, NewRuntimeError("cannot convert %s to %s", v.TypeName(), kind.String())
+	}
+	
+	switch kind {
+	case KindFloat32:
+		f, _ := v.ToFloat()
+		return NewFloatValue(float64(float32(f))), nil
+	case KindFloat64:
+		f, _ := v.ToFloat()
+		return NewFloatValue(f), nil
+	}
+	
+	var n int64
+	if v.IsFloat() {
+		f := v.AsFloat()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return NilValue, NewRuntimeError("cannot convert %s to %s", v.ToString(), kind.String())
+		}
+		n = int64(f)
+	} else {
+		n = v.AsInt()
+	}
+	
+	switch kind {
+	case KindInt8:
+		return NewIntValue(int64(int8(n))), nil
+	case KindInt16:
+		return NewIntValue(int64(int16(n))), nil
+	case KindInt32:
+		return NewIntValue(int64(int32(n))), nil
+	case KindInt64:
+		return NewIntValue(n), nil
+	default:
+		return NilValue, NewRuntimeError("unknown numeric kind: %d", kind)
+	}
+}
@@ -2,7 +2,11 @@ package vm
 
 import (
 	"fmt"
+	"io"
 	"math"
+	"os"
+	"strconv"
+	"unicode/utf8"
 )
 
 // VM configuration constants
@@ -11,9 +15,13 @@ const (
 	MaxFrames       = 1024 // maximum call stack depth
 	MaxGlobals      = 1024 // maximum number of global variables
 	MaxConstants    = 1024 // maximum number of constants per function
-	DefaultStackSize = 2048 // default stack size
+	DefaultStackSize = 2048 // initial size of the register stack
+	MaxStackSize    = MaxFrames * MaxRegisters // maximum number of registers across all frames
 )
 
+// maxDeepEqualDepth bounds the nesting of the values deepEqual compares
+const maxDeepEqualDepth = 256
+
 // CallFrame represents a function call frame
 type CallFrame struct {
 	Closure     *Closure // function closure
@@ -22,20 +30,47 @@ type CallFrame struct {
 	NumRegs     int      // number of registers used by this frame
 	ReturnAddr  int      // return address (register to store result)
 	NumResults  int      // number of expected return values
+	globals     *globalLinks // VM slots of the function's global slots (nil until used)
+}
+
+// global is a global variable slot
+type global struct {
+	name    string
+	value   Value
+	defined bool
+}
+
+// globalLinks maps a function's global slots to the VM's slots
+type globalLinks struct {
+	slots []int
+}
+
+// exceptionHandler is an active try block
+type exceptionHandler struct {
+	frameIndex int // frame that set up the handler
+	pc         int // start of the handler code
+	reg        int // register receiving the thrown value
 }
 
 // VM represents the virtual machine
 type VM struct {
-	// Register file - the main execution context
-	Registers [MaxRegisters]Value
+	// Register stack shared by all frames; each frame's registers start at
+	// its BaseReg. PushFrame grows it as calls nest.
+	Registers []Value
+	registerErr error // set when an instruction writes outside the stack
 	
 	// Call stack
-	Frames      [MaxFrames]CallFrame
+	Frames      []*CallFrame
 	FrameIndex  int
 	CurrentFrame *CallFrame
+	framePool   []*CallFrame // free list of frames available for reuse
 	
-	// Global variables
-	Globals map[string]Value
+	// Global variables by slot. globalSlots maps names to slots for host
+	// interop; globalLinks maps the global slots of the functions run so far
+	// to the VM's slots.
+	globals     []global
+	globalSlots map[string]int
+	globalLinks map[*Function]*globalLinks
 	
 	// Native functions
 	NativeFunctions map[string]*NativeFunction
@@ -43,6 +78,12 @@ type VM struct {
 	// Open upvalues (for closure capture)
 	OpenUpvalues []*Upvalue
 	
+	// Coroutine currently running, if any
+	coroutine *Coroutine
+	
+	// Active exception handlers, innermost last
+	handlers []exceptionHandler
+	
 	// Execution state
 	Running bool
 	Error   error
@@ -50,12 +91,29 @@ type VM struct {
 	// Debug information
 	DebugMode bool
 	Breakpoints map[int]bool
+	
+	// ArrayHoles lets writes beyond the end of an array pad it with
+	// undefined instead of failing
+	ArrayHoles bool
+	
+	// MaxSteps bounds the instructions a top-level Execute or Call may run,
+	// including those of nested calls; 0 means no limit. Exceeding it
+	// fails with a StepLimit error that scripts can't catch.
+	MaxSteps int64
+	steps    int64 // instructions run since the top-level Execute began
+	
+	// Output written by print and console.log, and by console.error and
+	// console.warn
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
 // NewVM creates a new virtual machine
 func NewVM() *VM {
 	vm := &VM{
-		Globals:         make(map[string]Value),
+		Registers:       make([]Value, DefaultStackSize),
+		globalSlots:     make(map[string]int),
+		globalLinks:     make(map[*Function]*globalLinks),
 		NativeFunctions: make(map[string]*NativeFunction),
 		OpenUpvalues:    make([]*Upvalue, 0),
 		FrameIndex:      -1,
@@ -63,6 +121,8 @@ func NewVM() *VM {
 		Error:           nil,
 		DebugMode:       false,
 		Breakpoints:     make(map[int]bool),
+		Stdout:          os.Stdout,
+		Stderr:          os.Stderr,
 	}
 	
 	// Initialize built-in functions
@@ -75,58 +135,171 @@ func NewVM() *VM {
 func (vm *VM) initBuiltins() {
 	// Print function
 	vm.RegisterNativeFunction("print", func(vm *VM, args []Value) (Value, error) {
-		for i, arg := range args {
-			if i > 0 {
-				fmt.Print(" ")
-			}
-			fmt.Print(arg.ToString())
-		}
-		fmt.Println()
+		writeLine(vm.Stdout, args)
 		return NilValue, nil
 	}, 0, -1)
 	
 	// Type function
 	vm.RegisterNativeFunction("type", func(vm *VM, args []Value) (Value, error) {
-		if len(args) != 1 {
-			return NilValue, NewRuntimeError("type() expects exactly 1 argument")
-		}
 		return NewStringValue(args[0].TypeName()), nil
 	}, 1, 1)
 	
 	// Length function
 	vm.RegisterNativeFunction("len", func(vm *VM, args []Value) (Value, error) {
-		if len(args) != 1 {
-			return NilValue, NewRuntimeError("len() expects exactly 1 argument")
-		}
-		
 		arg := args[0]
 		switch arg.Type {
 		case TypeString:
-			return NewIntValue(int64(len(arg.Data.(string)))), nil
+			return NewIntValue(int64(utf8.RuneCountInString(arg.AsString()))), nil
 		case TypeArray:
-			return NewIntValue(int64(arg.Data.(*Array).Length())), nil
+			return NewIntValue(int64(arg.AsArray().Length())), nil
 		case TypeObject:
-			return NewIntValue(int64(len(arg.Data.(*Object).Properties))), nil
+			return NewIntValue(int64(len(arg.AsObject().Properties))), nil
 		default:
 			return NilValue, NewRuntimeError("len() not supported for type %s", arg.TypeName())
 		}
 	}, 1, 1)
+	
+	// push appends values to an array and returns its new length
+	vm.RegisterNativeFunction("push", func(vm *VM, args []Value) (Value, error) {
+		if args[0].Type != TypeArray {
+			return NilValue, NewRuntimeError("push() not supported for type %s", args[0].TypeName())
+		}
+		arr := args[0].AsArray()
+		for _, value := range args[1:] {
+			arr.Push(value)
+		}
+		return NewIntValue(int64(arr.Length())), nil
+	}, 1, -1)
+	
+	// pop removes the last element of an array and returns it, or
+	// undefined if the array is empty
+	vm.RegisterNativeFunction("pop", func(vm *VM, args []Value) (Value, error) {
+		if args[0].Type != TypeArray {
+			return NilValue, NewRuntimeError("pop() not supported for type %s", args[0].TypeName())
+		}
+		last, _ := args[0].AsArray().Pop()
+		return last, nil
+	}, 1, 1)
+	
+	// deepEqual compares arrays and objects by their contents
+	vm.RegisterNativeFunction("deepEqual", func(vm *VM, args []Value) (Value, error) {
+		return NewBoolValue(args[0].DeepEquals(args[1], maxDeepEqualDepth)), nil
+	}, 2, 2)
+	
+	// json converts a value to JSON text, like JSON.stringify
+	vm.RegisterNativeFunction("json", func(vm *VM, args []Value) (Value, error) {
+		text, err := stringifyJSON("json", args[0])
+		if err != nil {
+			return NilValue, err
+		}
+		return NewStringValue(text), nil
+	}, 1, 1)
+	
+	// Assert function, for self-checking scripts
+	vm.RegisterNativeFunction("assert", func(vm *VM, args []Value) (Value, error) {
+		if args[0].ToBool() {
+			return NilValue, nil
+		}
+		if len(args) > 1 {
+			return NilValue, NewRuntimeError("assertion failed: %s", args[1].ToString())
+		}
+		return NilValue, NewRuntimeError("assertion failed")
+	}, 1, 2)
+	
+	// console, Math and JSON objects
+	vm.initConsole()
+	vm.initMath()
+	vm.initJSON()
+}
+
+// SetOutput redirects the output of print and console to stdout and stderr
+func (vm *VM) SetOutput(stdout, stderr io.Writer) {
+	vm.Stdout = stdout
+	vm.Stderr = stderr
+}
+
+// writeLine writes values to w separated by spaces
+func writeLine(w io.Writer, args []Value) {
+	for i, arg := range args {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, arg.ToString())
+	}
+	fmt.Fprintln(w)
 }
 
-// RegisterNativeFunction registers a native function
+// RegisterNativeFunction registers a native function and makes it the
+// value of the global name
 func (vm *VM) RegisterNativeFunction(name string, fn NativeFunctionType, minArgs, maxArgs int) {
-	vm.NativeFunctions[name] = NewNativeFunction(name, fn, minArgs, maxArgs)
+	native := NewNativeFunction(name, fn, minArgs, maxArgs)
+	vm.NativeFunctions[name] = native
+	vm.SetGlobal(name, NewNativeFunctionValue(native))
 }
 
 // GetGlobal gets a global variable
 func (vm *VM) GetGlobal(name string) (Value, bool) {
-	val, ok := vm.Globals[name]
-	return val, ok
+	slot, ok := vm.globalSlots[name]
+	if !ok || !vm.globals[slot].defined {
+		return NilValue, false
+	}
+	return vm.globals[slot].value, true
 }
 
 // SetGlobal sets a global variable
 func (vm *VM) SetGlobal(name string, value Value) {
-	vm.Globals[name] = value
+	slot := vm.globalSlot(name)
+	vm.globals[slot].value = value
+	vm.globals[slot].defined = true
+}
+
+// Globals returns the global variables by name, leaving out the registered
+// native functions
+func (vm *VM) Globals() map[string]Value {
+	globals := make(map[string]Value)
+	for _, g := range vm.globals {
+		if !g.defined {
+			continue
+		}
+		if g.value.Type == TypeNativeFunction && g.value.AsNativeFunction() == vm.NativeFunctions[g.name] {
+			continue
+		}
+		globals[g.name] = g.value
+	}
+	return globals
+}
+
+// globalSlot returns the slot of the global name, adding an undefined slot
+// if it has none
+func (vm *VM) globalSlot(name string) int {
+	if slot, ok := vm.globalSlots[name]; ok {
+		return slot
+	}
+	vm.globals = append(vm.globals, global{name: name})
+	vm.globalSlots[name] = len(vm.globals) - 1
+	return len(vm.globals) - 1
+}
+
+// frameGlobal returns the global of global slot index of the current
+// function. The function's slots are linked to the VM's on first use.
+func (vm *VM) frameGlobal(index int) (*global, error) {
+	frame := vm.CurrentFrame
+	if frame.globals == nil {
+		function := frame.Closure.Function
+		links, ok := vm.globalLinks[function]
+		if !ok {
+			links = &globalLinks{slots: make([]int, len(function.Globals))}
+			for i, name := range function.Globals {
+				links.slots[i] = vm.globalSlot(name)
+			}
+			vm.globalLinks[function] = links
+		}
+		frame.globals = links
+	}
+	if index >= len(frame.globals.slots) {
+		return nil, NewRuntimeError("invalid global slot %d", index)
+	}
+	return &vm.globals[frame.globals.slots[index]], nil
 }
 
 // GetRegister gets a register value
@@ -139,17 +312,19 @@ func (vm *VM) GetRegister(index int) Value {
 	actualIndex := index
 	if vm.CurrentFrame != nil {
 		actualIndex = vm.CurrentFrame.BaseReg + index
-		if actualIndex >= MaxRegisters {
-			return NilValue
-		}
+	}
+	if actualIndex >= len(vm.Registers) {
+		return NilValue
 	}
 	
 	return vm.Registers[actualIndex]
 }
 
-// SetRegister sets a register value
+// SetRegister sets a register value. A failed write is reported as the
+// error of the instruction being executed.
 func (vm *VM) SetRegister(index int, value Value) bool {
 	if index < 0 || index >= MaxRegisters {
+		vm.registerErr = NewVMErrorWithType(ErrInvalidOperation, nil, "register %d out of range", index)
 		return false
 	}
 	
@@ -157,30 +332,66 @@ func (vm *VM) SetRegister(index int, value Value) bool {
 	actualIndex := index
 	if vm.CurrentFrame != nil {
 		actualIndex = vm.CurrentFrame.BaseReg + index
-		if actualIndex >= MaxRegisters {
-			return false
-		}
+	}
+	if err := vm.growStack(actualIndex + 1); err != nil {
+		vm.registerErr = err
+		return false
 	}
 	
 	vm.Registers[actualIndex] = value
 	return true
 }
 
+// growStack makes the register stack hold at least size registers, moving
+// open upvalues that point into it
+func (vm *VM) growStack(size int) error {
+	if size <= len(vm.Registers) {
+		return nil
+	}
+	if size > MaxStackSize {
+		return NewVMErrorWithType(ErrStackOverflow, nil, "register stack overflow (max %d registers)", MaxStackSize)
+	}
+	
+	newSize := 2 * len(vm.Registers)
+	if newSize < size {
+		newSize = size
+	}
+	if newSize > MaxStackSize {
+		newSize = MaxStackSize
+	}
+	
+	registers := make([]Value, newSize)
+	copy(registers, vm.Registers)
+	for _, uv := range vm.OpenUpvalues {
+		if offset := registerOffset(vm, uv.Location, 0, len(vm.Registers)); offset >= 0 {
+			uv.Location = &registers[offset]
+		}
+	}
+	vm.Registers = registers
+	return nil
+}
+
 // PushFrame pushes a new call frame
 func (vm *VM) PushFrame(closure *Closure, baseReg, numRegs, returnAddr, numResults int) error {
 	if vm.FrameIndex >= MaxFrames-1 {
 		return NewVMErrorWithType(ErrStackOverflow, nil, "call stack overflow")
 	}
+	if err := vm.growStack(baseReg + numRegs); err != nil {
+		return err
+	}
 	
-	vm.FrameIndex++
-	frame := &vm.Frames[vm.FrameIndex]
-	frame.Closure = closure
-	frame.PC = 0
-	frame.BaseReg = baseReg
-	frame.NumRegs = numRegs
-	frame.ReturnAddr = returnAddr
-	frame.NumResults = numResults
+	frame := vm.allocFrame()
+	*frame = CallFrame{
+		Closure:    closure,
+		PC:         0,
+		BaseReg:    baseReg,
+		NumRegs:    numRegs,
+		ReturnAddr: returnAddr,
+		NumResults: numResults,
+	}
 	
+	vm.Frames = append(vm.Frames, frame)
+	vm.FrameIndex++
 	vm.CurrentFrame = frame
 	return nil
 }
@@ -191,9 +402,19 @@ func (vm *VM) PopFrame() error {
 		return NewVMErrorWithType(ErrStackUnderflow, nil, "call stack underflow")
 	}
 	
+	// Handlers set up by the frame are no longer reachable
+	for n := len(vm.handlers); n > 0 && vm.handlers[n-1].frameIndex >= vm.FrameIndex; n-- {
+		vm.handlers = vm.handlers[:n-1]
+	}
+	
+	frame := vm.Frames[vm.FrameIndex]
+	vm.Frames[vm.FrameIndex] = nil
+	vm.Frames = vm.Frames[:vm.FrameIndex]
+	vm.releaseFrame(frame)
+	
 	vm.FrameIndex--
 	if vm.FrameIndex >= 0 {
-		vm.CurrentFrame = &vm.Frames[vm.FrameIndex]
+		vm.CurrentFrame = vm.Frames[vm.FrameIndex]
 	} else {
 		vm.CurrentFrame = nil
 	}
@@ -201,32 +422,71 @@ func (vm *VM) PopFrame() error {
 	return nil
 }
 
+// unwindFrames pops frames until the call stack is back at the given depth
+func (vm *VM) unwindFrames(depth int) {
+	for vm.FrameIndex > depth {
+		vm.PopFrame()
+	}
+}
+
+// allocFrame takes a frame from the pool, allocating a new one if the pool is empty
+func (vm *VM) allocFrame() *CallFrame {
+	if n := len(vm.framePool); n > 0 {
+		frame := vm.framePool[n-1]
+		vm.framePool[n-1] = nil
+		vm.framePool = vm.framePool[:n-1]
+		return frame
+	}
+	return &CallFrame{}
+}
+
+// releaseFrame resets a frame and returns it to the pool
+func (vm *VM) releaseFrame(frame *CallFrame) {
+	*frame = CallFrame{} // drop references so pooled frames don't keep closures alive
+	vm.framePool = append(vm.framePool, frame)
+}
+
 // Execute executes a function
 func (vm *VM) Execute(closure *Closure, args []Value) (Value, error) {
-	// Set up initial frame
-	if err := vm.PushFrame(closure, 0, closure.Function.NumLocals, 0, 1); err != nil {
-		return NilValue, err
+	// Frames left above this depth are released when execution ends
+	baseDepth := vm.FrameIndex
+	defer vm.unwindFrames(baseDepth)
+	
+	// Forget the global links of functions from earlier runs; functions
+	// still referenced relink on their next call
+	if baseDepth == -1 {
+		clear(vm.globalLinks)
+		vm.steps = 0
 	}
 	
-	// Copy arguments to registers
-	for i, arg := range args {
-		if i < closure.Function.NumParams {
-			vm.SetRegister(i, arg)
-		}
+	// Set up initial frame, above the caller's registers when called from
+	// a native function
+	baseReg := 0
+	if vm.CurrentFrame != nil {
+		baseReg = vm.CurrentFrame.BaseReg + vm.CurrentFrame.NumRegs
 	}
-	
-	// Initialize remaining parameters to nil
-	for i := len(args); i < closure.Function.NumParams; i++ {
-		vm.SetRegister(i, NilValue)
+	if err := vm.PushFrame(closure, baseReg, closure.Function.NumLocals, 0, 1); err != nil {
+		return NilValue, err
 	}
 	
+	vm.bindArguments(closure.Function, args)
+	
 	vm.Running = true
 	vm.Error = nil
 	
 	// Main execution loop
 	for vm.Running && vm.Error == nil {
+		if vm.MaxSteps > 0 {
+			if err := vm.countStep(); err != nil {
+				vm.Error = vm.attachStackTrace(err)
+				break
+			}
+		}
 		if err := vm.executeInstruction(); err != nil {
-			vm.Error = err
+			if vm.handleError(err, baseDepth) {
+				continue
+			}
+			vm.Error = vm.attachStackTrace(err)
 			break
 		}
 	}
@@ -243,6 +503,104 @@ func (vm *VM) Execute(closure *Closure, args []Value) (Value, error) {
 	return NilValue, nil
 }
 
+// countStep counts an instruction against MaxSteps. Once the limit is
+// exceeded it returns an error, which bypasses exception handlers so that
+// a script can't keep running by catching it.
+func (vm *VM) countStep() error {
+	vm.steps++
+	if vm.steps > vm.MaxSteps {
+		return NewVMErrorWithType(ErrStepLimit, nil, "step limit exceeded: ran %d instructions", vm.MaxSteps)
+	}
+	return nil
+}
+
+// Call calls a function value with args and returns its result, so that
+// host programs and native functions can call back into scripts
+func (vm *VM) Call(callee Value, args []Value) (Value, error) {
+	if !callee.IsCallable() {
+		return NilValue, NewRuntimeError("attempt to call %s value", callee.TypeName())
+	}
+	if len(args) >= MaxRegisters {
+		return NilValue, NewRuntimeError("too many arguments: %d", len(args))
+	}
+	
+	// Load the callee and arguments into consecutive registers, call, and
+	// halt with the result in register 0 for Execute to return
+	trampoline := NewFunction("call")
+	trampoline.NumLocals = len(args) + 1
+	trampoline.Constants = append([]Value{callee}, args...)
+	for i := range trampoline.Constants {
+		trampoline.Instructions = append(trampoline.Instructions, CreateABx(OpLoadK, i, i))
+	}
+	trampoline.Instructions = append(trampoline.Instructions,
+		CreateABC(OpCall, 0, len(args), 1),
+		CreateABC(OpHalt, 0, 0, 0))
+	
+	wasRunning := vm.Running
+	defer func() { vm.Running = wasRunning }()
+	return vm.Execute(NewClosure(trampoline), []Value{})
+}
+
+// StackTrace returns the active calls from the current frame to the root,
+// one indented "at function (line N)" per line
+func (vm *VM) StackTrace() string {
+	return vm.stackTrace().String()
+}
+
+// stackTrace lists the active calls from the current frame to the root,
+// with the source line each one is executing
+func (vm *VM) stackTrace() StackTrace {
+	trace := make(StackTrace, 0, vm.FrameIndex+1)
+	for i := vm.FrameIndex; i >= 0; i-- {
+		frame := vm.Frames[i]
+		function := frame.Closure.Function
+		trace = append(trace, StackFrame{
+			Function: function.Name,
+			Line:     function.GetLineNumber(frame.PC - 1),
+		})
+	}
+	return trace
+}
+
+// attachStackTrace records the current call stack, and the line it failed
+// at, in err. A trace already recorded by a nested Execute is kept, as it
+// reaches deeper.
+func (vm *VM) attachStackTrace(err error) error {
+	switch e := err.(type) {
+	case *VMError:
+		if e.Trace == nil {
+			e.Trace = vm.stackTrace()
+			e.Line = e.Trace.line()
+		}
+	case *RuntimeError:
+		if e.Stack == nil {
+			e.Stack = vm.stackTrace()
+			e.Line = e.Stack.line()
+		}
+	case *ThrowError:
+		if e.Stack == nil {
+			e.Stack = vm.stackTrace()
+		}
+	}
+	return err
+}
+
+// handleError transfers control to the innermost exception handler set up
+// by a frame above depth. It returns false if there is no such handler.
+func (vm *VM) handleError(err error, depth int) bool {
+	n := len(vm.handlers)
+	if n == 0 || vm.handlers[n-1].frameIndex <= depth {
+		return false
+	}
+	
+	handler := vm.handlers[n-1]
+	vm.handlers = vm.handlers[:n-1]
+	vm.unwindFrames(handler.frameIndex)
+	vm.CurrentFrame.PC = handler.pc
+	vm.SetRegister(handler.reg, ErrorValue(err))
+	return true
+}
+
 // executeInstruction executes a single instruction
 func (vm *VM) executeInstruction() error {
 	if vm.CurrentFrame == nil {
@@ -269,7 +627,12 @@ func (vm *VM) executeInstruction() error {
 	}
 	
 	// Execute instruction
-	return vm.executeOpCode(inst)
+	err := vm.executeOpCode(inst)
+	if err == nil && vm.registerErr != nil {
+		err = vm.registerErr
+	}
+	vm.registerErr = nil
+	return err
 }
 
 // executeOpCode executes a specific opcode
@@ -297,8 +660,14 @@ func (vm *VM) executeOpCode(inst Instruction) error {
 		return vm.opDiv(inst)
 	case OpMod:
 		return vm.opMod(inst)
+	case OpPow:
+		return vm.opPow(inst)
 	case OpNeg:
 		return vm.opNeg(inst)
+	case OpBitAnd, OpBitOr, OpBitXor, OpShl, OpShr, OpUShr:
+		return vm.opBitwise(inst)
+	case OpBitNot:
+		return vm.opBitNot(inst)
 	case OpEq:
 		return vm.opEq(inst)
 	case OpNe:
@@ -311,16 +680,32 @@ func (vm *VM) executeOpCode(inst Instruction) error {
 		return vm.opGt(inst)
 	case OpGe:
 		return vm.opGe(inst)
+	case OpStrictEq:
+		return vm.opStrictEq(inst)
 	case OpNot:
 		return vm.opNot(inst)
-	case OpAnd:
-		return vm.opAnd(inst)
-	case OpOr:
-		return vm.opOr(inst)
+	case OpTypeOf:
+		return vm.opTypeOf(inst)
+	case OpConvert:
+		return vm.opConvert(inst)
+	case OpIterNext:
+		return vm.opIterNext(inst)
+	case OpKeys:
+		return vm.opKeys(inst)
+	case OpYield:
+		return vm.opYield(inst)
+	case OpSetupTry:
+		return vm.opSetupTry(inst)
+	case OpPopTry:
+		return vm.opPopTry(inst)
+	case OpThrow:
+		return vm.opThrow(inst)
 	case OpJmp:
 		return vm.opJmp(inst)
 	case OpTest:
 		return vm.opTest(inst)
+	case OpTestNullish:
+		return vm.opTestNullish(inst)
 	case OpCall:
 		return vm.opCall(inst)
 	case OpReturn:
@@ -333,6 +718,10 @@ func (vm *VM) executeOpCode(inst Instruction) error {
 		return vm.opGetTable(inst)
 	case OpSetTable:
 		return vm.opSetTable(inst)
+	case OpLen:
+		return vm.opLen(inst)
+	case OpSpread:
+		return vm.opSpread(inst)
 	case OpGetGlobal:
 		return vm.opGetGlobal(inst)
 	case OpSetGlobal:
@@ -392,8 +781,8 @@ func (vm *VM) opAdd(inst Instruction) error {
 	
 	if vb.IsNumber() && vc.IsNumber() {
 		if vb.IsInt() && vc.IsInt() {
-			ib, _ := vb.ToInt()
-			ic, _ := vc.ToInt()
+			ib := vb.AsInt()
+			ic := vc.AsInt()
 			vm.SetRegister(a, NewIntValue(ib+ic))
 		} else {
 			fb, _ := vb.ToFloat()
@@ -401,8 +790,8 @@ func (vm *VM) opAdd(inst Instruction) error {
 			vm.SetRegister(a, NewFloatValue(fb+fc))
 		}
 	} else if vb.IsString() && vc.IsString() {
-		sb := vb.Data.(string)
-		sc := vc.Data.(string)
+		sb := vb.AsString()
+		sc := vc.AsString()
 		vm.SetRegister(a, NewStringValue(sb+sc))
 	} else {
 		return NewRuntimeError("cannot add %s and %s", vb.TypeName(), vc.TypeName())
@@ -420,8 +809,8 @@ func (vm *VM) opSub(inst Instruction) error {
 	}
 	
 	if vb.IsInt() && vc.IsInt() {
-		ib, _ := vb.ToInt()
-		ic, _ := vc.ToInt()
+		ib := vb.AsInt()
+		ic := vc.AsInt()
 		vm.SetRegister(a, NewIntValue(ib-ic))
 	} else {
 		fb, _ := vb.ToFloat()
@@ -441,8 +830,8 @@ func (vm *VM) opMul(inst Instruction) error {
 	}
 	
 	if vb.IsInt() && vc.IsInt() {
-		ib, _ := vb.ToInt()
-		ic, _ := vc.ToInt()
+		ib := vb.AsInt()
+		ic := vc.AsInt()
 		vm.SetRegister(a, NewIntValue(ib*ic))
 	} else {
 		fb, _ := vb.ToFloat()
@@ -461,6 +850,18 @@ func (vm *VM) opDiv(inst Instruction) error {
 		return NewRuntimeError("cannot divide %s and %s", vb.TypeName(), vc.TypeName())
 	}
 	
+	// Integer division truncates toward zero, matching the type checker's
+	// int / int => int
+	if vb.IsInt() && vc.IsInt() {
+		ib := vb.AsInt()
+		ic := vc.AsInt()
+		if ic == 0 {
+			return NewVMErrorWithType(ErrDivisionByZero, nil, "division by zero")
+		}
+		vm.SetRegister(a, NewIntValue(ib/ic))
+		return nil
+	}
+	
 	fb, _ := vb.ToFloat()
 	fc, _ := vc.ToFloat()
 	
@@ -480,6 +881,17 @@ func (vm *VM) opMod(inst Instruction) error {
 		return NewRuntimeError("cannot mod %s and %s", vb.TypeName(), vc.TypeName())
 	}
 	
+	// Integer remainder takes the sign of the dividend, like float modulo
+	if vb.IsInt() && vc.IsInt() {
+		ib := vb.AsInt()
+		ic := vc.AsInt()
+		if ic == 0 {
+			return NewVMErrorWithType(ErrDivisionByZero, nil, "modulo by zero")
+		}
+		vm.SetRegister(a, NewIntValue(ib%ic))
+		return nil
+	}
+	
 	fb, _ := vb.ToFloat()
 	fc, _ := vc.ToFloat()
 	
@@ -491,6 +903,42 @@ func (vm *VM) opMod(inst Instruction) error {
 	return nil
 }
 
+// opPow raises R(B) to the power R(C). Two ints give an int computed by
+// repeated squaring, which wraps on overflow; any float operand uses math.Pow.
+func (vm *VM) opPow(inst Instruction) error {
+	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
+	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
+	
+	if !vb.IsNumber() || !vc.IsNumber() {
+		return NewRuntimeError("cannot exponentiate %s and %s", vb.TypeName(), vc.TypeName())
+	}
+	
+	if vb.IsInt() && vc.IsInt() {
+		base, _ := vb.ToInt()
+		exp, _ := vc.ToInt()
+		if exp < 0 {
+			return vm.runtimeErrorAtPC("negative integer exponent %d; use a float base for fractional results", exp)
+		}
+		result := int64(1)
+		for exp > 0 {
+			if exp&1 != 0 {
+				result *= base
+			}
+			base *= base
+			exp >>= 1
+		}
+		vm.SetRegister(a, NewIntValue(result))
+		return nil
+	}
+	
+	fb, _ := vb.ToFloat()
+	fc, _ := vc.ToFloat()
+	vm.SetRegister(a, NewFloatValue(math.Pow(fb, fc)))
+	return nil
+}
+
+// opNeg negates a number. Like the other integer operators it wraps, so
+// negating the minimum int gives the minimum int; -0.0 stays negative zero.
 func (vm *VM) opNeg(inst Instruction) error {
 	a, b := inst.GetA(), inst.GetB()
 	vb := vm.GetRegister(b)
@@ -500,7 +948,7 @@ func (vm *VM) opNeg(inst Instruction) error {
 	}
 	
 	if vb.IsInt() {
-		ib, _ := vb.ToInt()
+		ib := vb.AsInt()
 		vm.SetRegister(a, NewIntValue(-ib))
 	} else {
 		fb, _ := vb.ToFloat()
@@ -510,11 +958,81 @@ func (vm *VM) opNeg(inst Instruction) error {
 	return nil
 }
 
+// opBitwise executes the binary bitwise and shift operators on 64-bit
+// integers. Shifts by 64 or more give 0, or -1 for >> of a negative value;
+// >>> shifts all 64 bits as unsigned.
+func (vm *VM) opBitwise(inst Instruction) error {
+	op, a, b, c := inst.GetOpCode(), inst.GetA(), inst.GetB(), inst.GetC()
+	
+	ib, err := vm.bitwiseOperand(vm.GetRegister(b))
+	if err != nil {
+		return err
+	}
+	ic, err := vm.bitwiseOperand(vm.GetRegister(c))
+	if err != nil {
+		return err
+	}
+	
+	var result int64
+	switch op {
+	case OpBitAnd:
+		result = ib & ic
+	case OpBitOr:
+		result = ib | ic
+	case OpBitXor:
+		result = ib ^ ic
+	default:
+		if ic < 0 {
+			return vm.runtimeErrorAtPC("negative shift count %d", ic)
+		}
+		switch op {
+		case OpShl:
+			result = ib << uint64(ic)
+		case OpShr:
+			result = ib >> uint64(ic)
+		case OpUShr:
+			result = int64(uint64(ib) >> uint64(ic))
+		}
+	}
+	
+	vm.SetRegister(a, NewIntValue(result))
+	return nil
+}
+
+func (vm *VM) opBitNot(inst Instruction) error {
+	a, b := inst.GetA(), inst.GetB()
+	
+	ib, err := vm.bitwiseOperand(vm.GetRegister(b))
+	if err != nil {
+		return err
+	}
+	
+	vm.SetRegister(a, NewIntValue(^ib))
+	return nil
+}
+
+// bitwiseOperand returns the integer value of a bitwise operand. Floats are
+// accepted only when they hold a whole number.
+func (vm *VM) bitwiseOperand(v Value) (int64, error) {
+	switch v.Type {
+	case TypeInt:
+		return v.AsInt(), nil
+	case TypeFloat:
+		f := v.AsFloat()
+		if i, ok := v.ToInt(); ok && f >= math.MinInt64 && f < math.MaxInt64 {
+			return i, nil
+		}
+		return 0, vm.runtimeErrorAtPC("bitwise operands must be integers, got %s", v.ToString())
+	default:
+		return 0, vm.runtimeErrorAtPC("bitwise operands must be integers, got %s", v.TypeName())
+	}
+}
+
 func (vm *VM) opEq(inst Instruction) error {
 	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
 	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
 	
-	result := vb.Equals(vc)
+	result := vb.LooseEquals(vc)
 	vm.SetRegister(a, NewBoolValue(result))
 	
 	return nil
@@ -524,12 +1042,23 @@ func (vm *VM) opNe(inst Instruction) error {
 	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
 	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
 	
-	result := !vb.Equals(vc)
+	result := !vb.LooseEquals(vc)
 	vm.SetRegister(a, NewBoolValue(result))
 	
 	return nil
 }
 
+// opStrictEq compares without coercion: values must have the same runtime
+// type, so 1 === 1.0 is false
+func (vm *VM) opStrictEq(inst Instruction) error {
+	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
+	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
+	
+	vm.SetRegister(a, NewBoolValue(vb.Equals(vc)))
+	
+	return nil
+}
+
 func (vm *VM) opLt(inst Instruction) error {
 	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
 	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
@@ -589,6 +1118,114 @@ func (vm *VM) opNot(inst Instruction) error {
 	return nil
 }
 
+func (vm *VM) opTypeOf(inst Instruction) error {
+	a, b := inst.GetA(), inst.GetB()
+	vb := vm.GetRegister(b)
+	vm.SetRegister(a, NewStringValue(vb.TypeOf()))
+	return nil
+}
+
+func (vm *VM) opConvert(inst Instruction) error {
+	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
+	vb := vm.GetRegister(b)
+	
+	result, err := ConvertNumber(vb, NumericKind(c))
+	if err != nil {
+		return err
+	}
+	vm.SetRegister(a, result)
+	return nil
+}
+
+func (vm *VM) opIterNext(inst Instruction) error {
+	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
+	iterable := vm.GetRegister(b)
+	cursor := vm.GetRegister(c)
+	
+	position, _ := cursor.ToInt()
+	element, next, ok, err := IterNext(iterable, int(position))
+	if err != nil {
+		return vm.runtimeErrorAtPC("%s", err.Error())
+	}
+	if ok {
+		vm.SetRegister(a, element)
+		vm.SetRegister(c, NewIntValue(int64(next)))
+		vm.CurrentFrame.PC++ // skip the jump out of the loop
+	}
+	
+	return nil
+}
+
+func (vm *VM) opKeys(inst Instruction) error {
+	a, b := inst.GetA(), inst.GetB()
+	
+	keys, err := KeysOf(vm.GetRegister(b))
+	if err != nil {
+		return vm.runtimeErrorAtPC("%s", err.Error())
+	}
+	vm.SetRegister(a, NewArrayValue(keys))
+	return nil
+}
+
+func (vm *VM) opLen(inst Instruction) error {
+	a, b := inst.GetA(), inst.GetB()
+	vb := vm.GetRegister(b)
+	
+	switch vb.Type {
+	case TypeString:
+		vm.SetRegister(a, NewIntValue(int64(utf8.RuneCountInString(vb.AsString()))))
+	case TypeArray:
+		vm.SetRegister(a, NewIntValue(int64(vb.AsArray().Length())))
+	case TypeObject:
+		vm.SetRegister(a, NewIntValue(int64(len(vb.AsObject().Properties))))
+	default:
+		return vm.runtimeErrorAtPC("cannot get length of %s", vb.TypeName())
+	}
+	return nil
+}
+
+// opSpread expands R(B) into the array or object literal being built in R(A)
+func (vm *VM) opSpread(inst Instruction) error {
+	a, b := inst.GetA(), inst.GetB()
+	target := vm.GetRegister(a)
+	source := vm.GetRegister(b)
+	
+	switch target.Type {
+	case TypeArray:
+		arr := target.AsArray()
+		switch source.Type {
+		case TypeArray:
+			arr.Elements = append(arr.Elements, source.AsArray().Elements...)
+		case TypeString:
+			for _, ch := range source.AsString() {
+				arr.Push(NewStringValue(string(ch)))
+			}
+		default:
+			return vm.runtimeErrorAtPC("cannot spread %s into an array", source.TypeName())
+		}
+	case TypeObject:
+		obj := target.AsObject()
+		switch source.Type {
+		case TypeObject:
+			src := source.AsObject()
+			for _, key := range src.keys {
+				obj.Set(key, src.Properties[key])
+			}
+		case TypeArray:
+			for i, elem := range source.AsArray().Elements {
+				obj.Set(strconv.Itoa(i), elem)
+			}
+		case TypeNil, TypeNull, TypeVoid:
+			// Spreading nothing copies no properties
+		default:
+			return vm.runtimeErrorAtPC("cannot spread %s into an object", source.TypeName())
+		}
+	default:
+		return vm.runtimeErrorAtPC("cannot spread into %s", target.TypeName())
+	}
+	return nil
+}
+
 func (vm *VM) opJmp(inst Instruction) error {
 	bx := inst.GetBx()
 	vm.CurrentFrame.PC += bx - BxOffset
@@ -596,11 +1233,26 @@ func (vm *VM) opJmp(inst Instruction) error {
 }
 
 func (vm *VM) opTest(inst Instruction) error {
-	a := inst.GetA()
+	a, c := inst.GetA(), inst.GetC()
 	va := vm.GetRegister(a)
 	
-	if va.ToBool() {
-		vm.CurrentFrame.PC++ // skip next instruction if condition is truthy
+	// Skip the next instruction (usually a jump) unless the truthiness of
+	// R(A) matches C: with C == 0 the jump is taken when R(A) is falsy
+	if va.ToBool() != (c != 0) {
+		vm.CurrentFrame.PC++
+	}
+	
+	return nil
+}
+
+func (vm *VM) opTestNullish(inst Instruction) error {
+	a, c := inst.GetA(), inst.GetC()
+	va := vm.GetRegister(a)
+	
+	// Skip the next instruction (usually a jump) if R(A) is null or
+	// undefined; with C == 1 skip it if R(A) is neither
+	if va.IsNullish() != (c != 0) {
+		vm.CurrentFrame.PC++
 	}
 	
 	return nil
@@ -612,15 +1264,28 @@ func (vm *VM) opCall(inst Instruction) error {
 	// Get function to call
 	fn := vm.GetRegister(a)
 	
-	// Collect arguments
-	args := make([]Value, b)
-	for i := 0; i < b; i++ {
-		args[i] = vm.GetRegister(a + 1 + i)
+	// B is the exact argument count, held in R(A+1)..R(A+B)
+	var args []Value
+	if c&CallSpread != 0 {
+		// The arguments were collected into one array at the call site
+		c &^= CallSpread
+		list := vm.GetRegister(a + 1)
+		if list.Type != TypeArray {
+			return vm.runtimeErrorAtPC("spread arguments must be an array, got %s", list.TypeName())
+		}
+		args = append(args, list.AsArray().Elements...)
+	} else {
+		// Pass a view of the argument registers rather than a copy
+		start := vm.CurrentFrame.BaseReg + a + 1
+		if err := vm.growStack(start + b); err != nil {
+			return err
+		}
+		args = vm.Registers[start : start+b : start+b]
 	}
 	
 	// Call function
 	if fn.Type == TypeNativeFunction {
-		nativeFn := fn.Data.(*NativeFunction)
+		nativeFn := fn.AsNativeFunction()
 		result, err := nativeFn.Call(vm, args)
 		if err != nil {
 			return err
@@ -632,15 +1297,19 @@ func (vm *VM) opCall(inst Instruction) error {
 		}
 	} else if fn.Type == TypeFunction {
 		// User-defined function call
-		function := fn.Data.(*Function)
+		function := fn.AsFunction()
 		
 		// Create closure for the function
 		closure := NewClosure(function)
 		
-		// Check argument count
-		if len(args) < function.NumParams {
+		// Check argument count; optional and rest parameters may receive nothing
+		required := function.NumParams - function.NumOptional
+		if function.IsVariadic {
+			required--
+		}
+		if len(args) < required {
 			return NewRuntimeError("function '%s' expects %d arguments, got %d", 
-				function.Name, function.NumParams, len(args))
+				function.Name, required, len(args))
 		}
 		
 		// Push new call frame
@@ -660,17 +1329,7 @@ func (vm *VM) opCall(inst Instruction) error {
 			return err
 		}
 		
-		// Copy arguments to registers
-		for i, arg := range args {
-			if i < function.NumParams {
-				vm.SetRegister(i, arg)
-			}
-		}
-		
-		// Initialize remaining parameters to nil
-		for i := len(args); i < function.NumParams; i++ {
-			vm.SetRegister(i, NilValue)
-		}
+		vm.bindArguments(function, args)
 	} else {
 		return NewRuntimeError("attempt to call %s value", fn.TypeName())
 	}
@@ -678,6 +1337,29 @@ func (vm *VM) opCall(inst Instruction) error {
 	return nil
 }
 
+// bindArguments copies args into the parameter registers of the current
+// frame. Missing parameters are nil; a rest parameter receives an array of
+// the surplus arguments, and other surplus arguments are dropped.
+func (vm *VM) bindArguments(function *Function, args []Value) {
+	fixed := function.NumParams
+	if function.IsVariadic {
+		fixed--
+		rest := NewArray(max(len(args)-fixed, 0))
+		if len(args) > fixed {
+			rest.Elements = append(rest.Elements, args[fixed:]...)
+		}
+		vm.SetRegister(fixed, NewArrayValue(rest))
+	}
+	
+	for i := 0; i < fixed; i++ {
+		if i < len(args) {
+			vm.SetRegister(i, args[i])
+		} else {
+			vm.SetRegister(i, NilValue)
+		}
+	}
+}
+
 func (vm *VM) opReturn(inst Instruction) error {
 	a, b := inst.GetA(), inst.GetB()
 	
@@ -708,6 +1390,45 @@ func (vm *VM) opReturn(inst Instruction) error {
 	return nil
 }
 
+func (vm *VM) opYield(inst Instruction) error {
+	a, b := inst.GetA(), inst.GetB()
+	
+	co := vm.coroutine
+	if co == nil {
+		return vm.runtimeErrorAtPC("yield outside of a coroutine")
+	}
+	if err := co.Yield(vm.GetRegister(b)); err != nil {
+		return err
+	}
+	
+	// The value passed to the next Resume lands in R(A)
+	co.resumeReg = a
+	return nil
+}
+
+func (vm *VM) opSetupTry(inst Instruction) error {
+	a, bx := inst.GetA(), inst.GetBx()
+	vm.handlers = append(vm.handlers, exceptionHandler{
+		frameIndex: vm.FrameIndex,
+		pc:         vm.CurrentFrame.PC + bx - BxOffset,
+		reg:        a,
+	})
+	return nil
+}
+
+func (vm *VM) opPopTry(inst Instruction) error {
+	n := len(vm.handlers)
+	if n == 0 || vm.handlers[n-1].frameIndex != vm.FrameIndex {
+		return vm.runtimeErrorAtPC("no exception handler to pop")
+	}
+	vm.handlers = vm.handlers[:n-1]
+	return nil
+}
+
+func (vm *VM) opThrow(inst Instruction) error {
+	return NewThrowError(vm.GetRegister(inst.GetA()))
+}
+
 func (vm *VM) opNewTable(inst Instruction) error {
 	a := inst.GetA()
 	obj := NewObject()
@@ -727,24 +1448,46 @@ func (vm *VM) opGetTable(inst Instruction) error {
 	table := vm.GetRegister(b)
 	key := vm.GetRegister(c)
 	
-	if table.Type == TypeObject && key.Type == TypeString {
-		obj := table.Data.(*Object)
-		keyStr := key.Data.(string)
-		if val, ok := obj.Get(keyStr); ok {
+	switch table.Type {
+	case TypeObject:
+		keyStr, ok := objectKey(key)
+		if !ok {
+			return vm.tableKeyError(table, key)
+		}
+		if val, ok := table.AsObject().Get(keyStr); ok {
 			vm.SetRegister(a, val)
 		} else {
 			vm.SetRegister(a, NilValue)
 		}
-	} else if table.Type == TypeArray && key.Type == TypeInt {
-		arr := table.Data.(*Array)
-		index, _ := key.ToInt()
-		if val, ok := arr.Get(int(index)); ok {
+	case TypeArray:
+		index, ok := arrayIndex(key)
+		if !ok {
+			return vm.tableKeyError(table, key)
+		}
+		if index < 0 {
+			return vm.runtimeErrorAtPC("cannot read array element at negative index %d", index)
+		}
+		if val, ok := table.AsArray().Get(index); ok {
 			vm.SetRegister(a, val)
 		} else {
 			vm.SetRegister(a, NilValue)
 		}
-	} else {
-		return NewRuntimeError("invalid table access: %s[%s]", table.TypeName(), key.TypeName())
+	case TypeString:
+		// Strings are indexed by character; past the end reads nil, as for arrays
+		index, ok := arrayIndex(key)
+		if !ok {
+			return vm.tableKeyError(table, key)
+		}
+		if index < 0 {
+			return vm.runtimeErrorAtPC("cannot read string character at negative index %d", index)
+		}
+		if char, ok := stringIndex(table.AsString(), index); ok {
+			vm.SetRegister(a, NewStringValue(char))
+		} else {
+			vm.SetRegister(a, NilValue)
+		}
+	default:
+		return vm.runtimeErrorAtPC("cannot read property '%s' of %s", key.ToString(), table.TypeName())
 	}
 	
 	return nil
@@ -756,88 +1499,125 @@ func (vm *VM) opSetTable(inst Instruction) error {
 	key := vm.GetRegister(b)
 	value := vm.GetRegister(c)
 	
-	if table.Type == TypeObject && key.Type == TypeString {
-		obj := table.Data.(*Object)
-		keyStr := key.Data.(string)
-		obj.Set(keyStr, value)
-	} else if table.Type == TypeArray && key.Type == TypeInt {
-		arr := table.Data.(*Array)
-		index, _ := key.ToInt()
-		arr.Set(int(index), value)
-	} else {
-		return NewRuntimeError("invalid table assignment: %s[%s]", table.TypeName(), key.TypeName())
+	switch table.Type {
+	case TypeObject:
+		keyStr, ok := objectKey(key)
+		if !ok {
+			return vm.tableKeyError(table, key)
+		}
+		table.AsObject().Set(keyStr, value)
+	case TypeArray:
+		index, ok := arrayIndex(key)
+		if !ok {
+			return vm.tableKeyError(table, key)
+		}
+		if index < 0 {
+			return vm.runtimeErrorAtPC("cannot set array element at negative index %d", index)
+		}
+		arr := table.AsArray()
+		if vm.ArrayHoles {
+			arr.SetPadded(index, value)
+		} else if !arr.Set(index, value) {
+			return vm.runtimeErrorAtPC("cannot set array element at index %d beyond length %d", index, arr.Length())
+		}
+	default:
+		return vm.runtimeErrorAtPC("cannot set property '%s' of %s", key.ToString(), table.TypeName())
 	}
 	
 	return nil
 }
 
-func (vm *VM) opGetGlobal(inst Instruction) error {
-	a, bx := inst.GetA(), inst.GetBx()
-	
-	// Get constant (should be string)
-	constant, ok := vm.CurrentFrame.Closure.Function.GetConstant(bx)
-	if !ok || constant.Type != TypeString {
-		return NewRuntimeError("invalid global name constant")
+// objectKey converts a key to an object property name.
+// Numbers are converted to strings, as in JavaScript.
+func objectKey(key Value) (string, bool) {
+	switch key.Type {
+	case TypeString:
+		return key.AsString(), true
+	case TypeInt, TypeFloat:
+		return key.ToString(), true
+	default:
+		return "", false
 	}
-	
-	name := constant.Data.(string)
-	
-	// Check native functions first
-	if nativeFn, ok := vm.NativeFunctions[name]; ok {
-		vm.SetRegister(a, NewNativeFunctionValue(nativeFn))
-		return nil
+}
+
+// arrayIndex converts a key to an array index.
+// Integral floats and numeric strings are accepted, as in JavaScript.
+// Strings must be in canonical form, so "01" and "+1" are not indices.
+func arrayIndex(key Value) (int, bool) {
+	switch key.Type {
+	case TypeInt:
+		return int(key.AsInt()), true
+	case TypeFloat:
+		f := key.AsFloat()
+		if f == math.Trunc(f) {
+			return int(f), true
+		}
+	case TypeString:
+		s := key.AsString()
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+			return int(n), true
+		}
 	}
-	
-	// Check global variables
-	if val, ok := vm.GetGlobal(name); ok {
-		vm.SetRegister(a, val)
-	} else {
-		return NewVMErrorWithType(ErrUndefinedVariable, nil, "undefined variable: %s", name)
+	return 0, false
+}
+
+// stringIndex returns the character at index in s, counting in runes
+func stringIndex(s string, index int) (string, bool) {
+	for _, r := range s {
+		if index == 0 {
+			return string(r), true
+		}
+		index--
 	}
-	
-	return nil
+	return "", false
 }
 
-func (vm *VM) opSetGlobal(inst Instruction) error {
-	a, bx := inst.GetA(), inst.GetBx()
-	
-	// Get constant (should be string)
-	constant, ok := vm.CurrentFrame.Closure.Function.GetConstant(bx)
-	if !ok || constant.Type != TypeString {
-		return NewRuntimeError("invalid global name constant")
+// tableKeyError reports a key that cannot be used to index table
+func (vm *VM) tableKeyError(table, key Value) error {
+	if key.Type == TypeString {
+		return vm.runtimeErrorAtPC("invalid %s key of type %s: %q", table.TypeName(), key.TypeName(), key.ToString())
 	}
-	
-	name := constant.Data.(string)
-	value := vm.GetRegister(a)
-	
-	vm.SetGlobal(name, value)
-	return nil
+	return vm.runtimeErrorAtPC("invalid %s key of type %s", table.TypeName(), key.TypeName())
 }
 
-func (vm *VM) opAnd(inst Instruction) error {
-	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
-	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
+// runtimeErrorAtPC creates a runtime error positioned at the instruction being executed
+func (vm *VM) runtimeErrorAtPC(format string, args ...interface{}) *RuntimeError {
+	err := NewRuntimeError(format, args...)
+	if vm.CurrentFrame != nil {
+		err.PC = vm.CurrentFrame.PC - 1
+	}
+	return err
+}
+
+func (vm *VM) opGetGlobal(inst Instruction) error {
+	a, bx := inst.GetA(), inst.GetBx()
 	
-	// JavaScript-style logical AND: if left is falsy, return left; otherwise return right
-	if !vb.ToBool() {
-		vm.SetRegister(a, vb)
-	} else {
-		vm.SetRegister(a, vc)
+	g, err := vm.frameGlobal(bx)
+	if err != nil {
+		return err
+	}
+	if g.defined {
+		vm.SetRegister(a, g.value)
+		return nil
 	}
 	
-	return nil
+	// Natives added to NativeFunctions directly rather than registered
+	if nativeFn, ok := vm.NativeFunctions[g.name]; ok {
+		vm.SetRegister(a, NewNativeFunctionValue(nativeFn))
+		return nil
+	}
+	
+	return NewVMErrorWithType(ErrUndefinedVariable, nil, "undefined variable: %s", g.name)
 }
 
-func (vm *VM) opOr(inst Instruction) error {
-	a, b, c := inst.GetA(), inst.GetB(), inst.GetC()
-	vb, vc := vm.GetRegister(b), vm.GetRegister(c)
+func (vm *VM) opSetGlobal(inst Instruction) error {
+	a, bx := inst.GetA(), inst.GetBx()
 	
-	// JavaScript-style logical OR: if left is truthy, return left; otherwise return right
-	if vb.ToBool() {
-		vm.SetRegister(a, vb)
-	} else {
-		vm.SetRegister(a, vc)
+	g, err := vm.frameGlobal(bx)
+	if err != nil {
+		return err
 	}
-	
+	g.value = vm.GetRegister(a)
+	g.defined = true
 	return nil
 }
\ No newline at end of file
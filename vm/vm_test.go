@@ -0,0 +1,1178 @@
+package vm
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Helper function to run hand-assembled instructions in a fresh VM
+func runInstructions(constants []Value, instructions ...Instruction) (*VM, error) {
+	function := NewFunction("main")
+	function.Constants = constants
+	function.Instructions = instructions
+	function.NumLocals = 8
+
+	machine := NewVM()
+	_, err := machine.Execute(NewClosure(function), []Value{})
+	return machine, err
+}
+
+func TestFramePoolReusesFrames(t *testing.T) {
+	machine := NewVM()
+	closure := NewClosure(NewFunction("f"))
+
+	if err := machine.PushFrame(closure, 0, 4, 2, 1); err != nil {
+		t.Fatalf("PushFrame failed: %v", err)
+	}
+	first := machine.CurrentFrame
+	first.PC = 42
+
+	if err := machine.PopFrame(); err != nil {
+		t.Fatalf("PopFrame failed: %v", err)
+	}
+	if machine.CurrentFrame != nil || machine.FrameIndex != -1 || len(machine.Frames) != 0 {
+		t.Fatalf("call stack not empty after pop. index=%d, frames=%d", machine.FrameIndex, len(machine.Frames))
+	}
+
+	other := NewClosure(NewFunction("g"))
+	if err := machine.PushFrame(other, 8, 3, -1, 0); err != nil {
+		t.Fatalf("PushFrame failed: %v", err)
+	}
+	second := machine.CurrentFrame
+
+	if second != first {
+		t.Fatalf("expected popped frame to be reused")
+	}
+
+	expected := CallFrame{Closure: other, PC: 0, BaseReg: 8, NumRegs: 3, ReturnAddr: -1, NumResults: 0}
+	if *second != expected {
+		t.Fatalf("reused frame has stale state. expected=%+v, got=%+v", expected, *second)
+	}
+}
+
+func TestFramePoolClearsReleasedFrames(t *testing.T) {
+	machine := NewVM()
+	closure := NewClosure(NewFunction("f"))
+
+	for i := 0; i < 3; i++ {
+		if err := machine.PushFrame(closure, i*4, 4, 0, 1); err != nil {
+			t.Fatalf("PushFrame failed: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := machine.PopFrame(); err != nil {
+			t.Fatalf("PopFrame failed: %v", err)
+		}
+	}
+
+	if len(machine.framePool) != 3 {
+		t.Fatalf("expected 3 pooled frames, got %d", len(machine.framePool))
+	}
+	for i, frame := range machine.framePool {
+		if *frame != (CallFrame{}) {
+			t.Errorf("pooled frame %d was not reset: %+v", i, *frame)
+		}
+	}
+
+	if err := machine.PopFrame(); err == nil {
+		t.Fatalf("expected stack underflow error")
+	}
+}
+
+func TestFrameStackOverflow(t *testing.T) {
+	machine := NewVM()
+	closure := NewClosure(NewFunction("f"))
+
+	for i := 0; i < MaxFrames; i++ {
+		if err := machine.PushFrame(closure, 0, 0, -1, 0); err != nil {
+			t.Fatalf("PushFrame %d failed: %v", i, err)
+		}
+	}
+
+	if err := machine.PushFrame(closure, 0, 0, -1, 0); err == nil {
+		t.Fatalf("expected stack overflow error after %d frames", MaxFrames)
+	}
+}
+
+func TestRegisterStackGrows(t *testing.T) {
+	machine := NewVM()
+	closure := NewClosure(NewFunction("f"))
+
+	// An open upvalue follows its register when the stack is reallocated
+	machine.Registers[3] = NewIntValue(7)
+	captured := NewUpvalue(&machine.Registers[3])
+	machine.OpenUpvalues = append(machine.OpenUpvalues, captured)
+
+	base := 0
+	for i := 0; i < 100; i++ {
+		if err := machine.PushFrame(closure, base, MaxRegisters, -1, 0); err != nil {
+			t.Fatalf("PushFrame %d failed: %v", i, err)
+		}
+		base += MaxRegisters
+	}
+	if len(machine.Registers) < base {
+		t.Fatalf("register stack not grown. len=%d, want at least %d", len(machine.Registers), base)
+	}
+
+	captured.Set(NewIntValue(8))
+	if !machine.Registers[3].Equals(NewIntValue(8)) {
+		t.Errorf("upvalue not moved with the register stack. got %s", machine.Registers[3].ToString())
+	}
+}
+
+func TestRegisterStackOverflow(t *testing.T) {
+	machine := NewVM()
+	closure := NewClosure(NewFunction("f"))
+
+	err := machine.PushFrame(closure, MaxStackSize-4, 8, -1, 0)
+	if err == nil || !strings.Contains(err.Error(), "register stack overflow") {
+		t.Fatalf("expected register stack overflow, got %v", err)
+	}
+
+	// Writes past the end of the stack fail the instruction
+	function := NewFunction("f")
+	function.Instructions = []Instruction{CreateABx(OpLoadInt, 10, 1)}
+	if err := machine.PushFrame(NewClosure(function), MaxStackSize-4, 4, -1, 0); err != nil {
+		t.Fatalf("PushFrame failed: %v", err)
+	}
+	if err := machine.executeInstruction(); err == nil || !strings.Contains(err.Error(), "register stack overflow") {
+		t.Errorf("expected register stack overflow, got %v", err)
+	}
+}
+
+func TestCallFunctionValue(t *testing.T) {
+	// add(a, b) returns a + b
+	add := NewFunction("add")
+	add.NumParams = 2
+	add.NumLocals = 3
+	add.Instructions = []Instruction{
+		CreateABC(OpAdd, 2, 0, 1),
+		CreateABC(OpReturn, 2, 1, 0),
+	}
+
+	machine := NewVM()
+	result, err := machine.Call(NewFunctionValue(add), []Value{NewIntValue(2), NewIntValue(3)})
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if !result.Equals(NewIntValue(5)) {
+		t.Errorf("expected 5, got %s", result.ToString())
+	}
+	if machine.FrameIndex != -1 {
+		t.Errorf("call left %d frames on the call stack", machine.FrameIndex+1)
+	}
+
+	if _, err := machine.Call(NewIntValue(1), nil); err == nil {
+		t.Errorf("expected error calling an integer")
+	}
+}
+
+func TestTestPolarity(t *testing.T) {
+	tests := []struct {
+		value, c int
+		jumps    bool
+	}{
+		{0, 0, true},
+		{1, 0, false},
+		{0, 1, false},
+		{1, 1, true},
+	}
+
+	for _, tt := range tests {
+		// R0 = value; TEST R0, c; JMP +1; R1 = true
+		machine, err := runInstructions(nil,
+			CreateABC(OpLoadBool, 0, tt.value, 0),
+			CreateABC(OpTest, 0, 0, tt.c),
+			CreateABx(OpJmp, 0, 1+BxOffset),
+			CreateABC(OpLoadBool, 1, 1, 0),
+		)
+		if err != nil {
+			t.Fatalf("execution failed: %v", err)
+		}
+		if jumped := !machine.GetRegister(1).ToBool(); jumped != tt.jumps {
+			t.Errorf("TEST %d, %d: expected jump=%v, got %v", tt.value, tt.c, tt.jumps, jumped)
+		}
+	}
+}
+
+func TestObjectNumberKeyCoercion(t *testing.T) {
+	// obj[0] = "zero"; r4 = obj["0"]; r5 = obj[0]
+	constants := []Value{NewIntValue(0), NewStringValue("zero"), NewStringValue("0")}
+	machine, err := runInstructions(constants,
+		CreateABC(OpNewTable, 0, 0, 0),
+		CreateABx(OpLoadK, 1, 0),
+		CreateABx(OpLoadK, 2, 1),
+		CreateABC(OpSetTable, 0, 1, 2),
+		CreateABx(OpLoadK, 3, 2),
+		CreateABC(OpGetTable, 4, 0, 3),
+		CreateABC(OpGetTable, 5, 0, 1),
+	)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	for _, reg := range []int{4, 5} {
+		if got := machine.GetRegister(reg); !got.Equals(NewStringValue("zero")) {
+			t.Errorf("R(%d) wrong. expected=zero, got=%s", reg, got.ToString())
+		}
+	}
+}
+
+func TestArrayStringIndexCoercion(t *testing.T) {
+	// arr["0"] = "zero"; r4 = arr[0]; r5 = arr[0.0]
+	constants := []Value{NewStringValue("0"), NewStringValue("zero"), NewIntValue(0), NewFloatValue(0.0)}
+	machine, err := runInstructions(constants,
+		CreateABx(OpNewArray, 0, 0),
+		CreateABx(OpLoadK, 1, 0),
+		CreateABx(OpLoadK, 2, 1),
+		CreateABC(OpSetTable, 0, 1, 2),
+		CreateABx(OpLoadK, 3, 2),
+		CreateABC(OpGetTable, 4, 0, 3),
+		CreateABx(OpLoadK, 3, 3),
+		CreateABC(OpGetTable, 5, 0, 3),
+	)
+	if err != nil {
+		t.Fatalf("execution failed: %v", err)
+	}
+
+	for _, reg := range []int{4, 5} {
+		if got := machine.GetRegister(reg); !got.Equals(NewStringValue("zero")) {
+			t.Errorf("R(%d) wrong. expected=zero, got=%s", reg, got.ToString())
+		}
+	}
+}
+
+func TestInvalidTableKeyErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		constants   []Value
+		table       Instruction
+		expectedMsg string
+	}{
+		{"array with boolean key", []Value{NewBoolValue(true)}, CreateABx(OpNewArray, 0, 0), "invalid array key of type boolean"},
+		{"array with non-numeric string", []Value{NewStringValue("x")}, CreateABx(OpNewArray, 0, 0), `invalid array key of type string: "x"`},
+		{"array with leading zero", []Value{NewStringValue("01")}, CreateABx(OpNewArray, 0, 0), `invalid array key of type string: "01"`},
+		{"array with plus sign", []Value{NewStringValue("+1")}, CreateABx(OpNewArray, 0, 0), `invalid array key of type string: "+1"`},
+		{"array with negative zero", []Value{NewStringValue("-0")}, CreateABx(OpNewArray, 0, 0), `invalid array key of type string: "-0"`},
+		{"object with null key", []Value{NullValue}, CreateABC(OpNewTable, 0, 0, 0), "invalid object key of type null"},
+		{"property of integer", []Value{NewStringValue("x"), NewIntValue(3)}, CreateABx(OpLoadK, 0, 1), "cannot read property 'x' of integer"},
+	}
+
+	for _, tt := range tests {
+		_, err := runInstructions(tt.constants,
+			tt.table,
+			CreateABx(OpLoadK, 1, 0),
+			CreateABC(OpGetTable, 2, 0, 1),
+		)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+
+		runtimeErr, ok := err.(*RuntimeError)
+		if !ok {
+			t.Errorf("%s: expected *RuntimeError, got %T", tt.name, err)
+			continue
+		}
+		if !strings.Contains(runtimeErr.Message, tt.expectedMsg) {
+			t.Errorf("%s: wrong message. expected to contain %q, got %q", tt.name, tt.expectedMsg, runtimeErr.Message)
+		}
+		if runtimeErr.PC != 2 {
+			t.Errorf("%s: wrong pc. expected=2, got=%d", tt.name, runtimeErr.PC)
+		}
+	}
+}
+
+func TestDivision(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		expected    Value
+	}{
+		// Integer division truncates toward zero
+		{NewIntValue(10), NewIntValue(2), NewIntValue(5)},
+		{NewIntValue(7), NewIntValue(2), NewIntValue(3)},
+		{NewIntValue(-7), NewIntValue(2), NewIntValue(-3)},
+		{NewIntValue(7), NewIntValue(-2), NewIntValue(-3)},
+		// Any float operand gives float division
+		{NewFloatValue(7), NewIntValue(2), NewFloatValue(3.5)},
+		{NewIntValue(7), NewFloatValue(2), NewFloatValue(3.5)},
+	}
+
+	for _, tt := range tests {
+		machine, err := runInstructions([]Value{tt.left, tt.right},
+			CreateABx(OpLoadK, 1, 0),
+			CreateABx(OpLoadK, 2, 1),
+			CreateABC(OpDiv, 0, 1, 2),
+			CreateABC(OpHalt, 0, 0, 0),
+		)
+		if err != nil {
+			t.Errorf("%s / %s failed: %v", tt.left.ToString(), tt.right.ToString(), err)
+			continue
+		}
+		got := machine.Registers[0]
+		if !got.Equals(tt.expected) || got.Type != tt.expected.Type {
+			t.Errorf("%s / %s wrong. expected=%s (%s), got=%s (%s)", tt.left.ToString(), tt.right.ToString(),
+				tt.expected.ToString(), tt.expected.TypeName(), got.ToString(), got.TypeName())
+		}
+	}
+
+	for _, divisor := range []Value{NewIntValue(0), NewFloatValue(0)} {
+		_, err := runInstructions([]Value{NewIntValue(1), divisor},
+			CreateABx(OpLoadK, 1, 0),
+			CreateABx(OpLoadK, 2, 1),
+			CreateABC(OpDiv, 0, 1, 2),
+			CreateABC(OpHalt, 0, 0, 0),
+		)
+		if err == nil || !strings.Contains(err.Error(), "division by zero") {
+			t.Errorf("expected division by zero error for divisor %s, got %v", divisor.ToString(), err)
+		}
+	}
+}
+
+func TestNegation(t *testing.T) {
+	tests := []struct {
+		operand  Value
+		expected Value
+	}{
+		{NewIntValue(5), NewIntValue(-5)},
+		{NewFloatValue(2.5), NewFloatValue(-2.5)},
+		// Negating the minimum int wraps, like the other integer operators
+		{NewIntValue(math.MinInt64), NewIntValue(math.MinInt64)},
+		{NewIntValue(math.MaxInt64), NewIntValue(-math.MaxInt64)},
+	}
+
+	for _, tt := range tests {
+		machine, err := runInstructions([]Value{tt.operand},
+			CreateABx(OpLoadK, 1, 0),
+			CreateABC(OpNeg, 0, 1, 0),
+			CreateABC(OpHalt, 0, 0, 0),
+		)
+		if err != nil {
+			t.Errorf("-%s failed: %v", tt.operand.ToString(), err)
+			continue
+		}
+		got := machine.Registers[0]
+		if !got.Equals(tt.expected) || got.Type != tt.expected.Type {
+			t.Errorf("-%s wrong. expected=%s (%s), got=%s (%s)", tt.operand.ToString(),
+				tt.expected.ToString(), tt.expected.TypeName(), got.ToString(), got.TypeName())
+		}
+	}
+
+	// Negating zero gives negative zero
+	machine, err := runInstructions([]Value{NewFloatValue(0)},
+		CreateABx(OpLoadK, 1, 0),
+		CreateABC(OpNeg, 0, 1, 0),
+		CreateABC(OpHalt, 0, 0, 0),
+	)
+	if err != nil {
+		t.Fatalf("-0.0 failed: %v", err)
+	}
+	if f, _ := machine.Registers[0].ToFloat(); !math.Signbit(f) {
+		t.Errorf("-0.0 wrong. got=%v", f)
+	}
+
+	_, err = runInstructions([]Value{NewStringValue("s")},
+		CreateABx(OpLoadK, 1, 0),
+		CreateABC(OpNeg, 0, 1, 0),
+		CreateABC(OpHalt, 0, 0, 0),
+	)
+	if err == nil || !strings.Contains(err.Error(), "cannot negate string") {
+		t.Errorf("expected cannot negate string error, got %v", err)
+	}
+}
+
+func TestModulo(t *testing.T) {
+	tests := []struct {
+		left, right Value
+		expected    Value
+	}{
+		// Integer remainder keeps the sign of the dividend
+		{NewIntValue(7), NewIntValue(3), NewIntValue(1)},
+		{NewIntValue(-7), NewIntValue(3), NewIntValue(-1)},
+		{NewIntValue(7), NewIntValue(-3), NewIntValue(1)},
+		// Any float operand gives float modulo
+		{NewFloatValue(7.5), NewIntValue(2), NewFloatValue(1.5)},
+		{NewIntValue(7), NewFloatValue(2), NewFloatValue(1)},
+	}
+
+	for _, tt := range tests {
+		machine, err := runInstructions([]Value{tt.left, tt.right},
+			CreateABx(OpLoadK, 1, 0),
+			CreateABx(OpLoadK, 2, 1),
+			CreateABC(OpMod, 0, 1, 2),
+			CreateABC(OpHalt, 0, 0, 0),
+		)
+		if err != nil {
+			t.Errorf("%s %% %s failed: %v", tt.left.ToString(), tt.right.ToString(), err)
+			continue
+		}
+		got := machine.Registers[0]
+		if !got.Equals(tt.expected) || got.Type != tt.expected.Type {
+			t.Errorf("%s %% %s wrong. expected=%s (%s), got=%s (%s)", tt.left.ToString(), tt.right.ToString(),
+				tt.expected.ToString(), tt.expected.TypeName(), got.ToString(), got.TypeName())
+		}
+	}
+
+	for _, divisor := range []Value{NewIntValue(0), NewFloatValue(0)} {
+		_, err := runInstructions([]Value{NewIntValue(1), divisor},
+			CreateABx(OpLoadK, 1, 0),
+			CreateABx(OpLoadK, 2, 1),
+			CreateABC(OpMod, 0, 1, 2),
+			CreateABC(OpHalt, 0, 0, 0),
+		)
+		if err == nil || !strings.Contains(err.Error(), "modulo by zero") {
+			t.Errorf("expected modulo by zero error for divisor %s, got %v", divisor.ToString(), err)
+		}
+	}
+}
+
+func TestConvertNumber(t *testing.T) {
+	tests := []struct {
+		value    Value
+		kind     NumericKind
+		expected Value
+	}{
+		{NewIntValue(127), KindInt8, NewIntValue(127)},
+		{NewIntValue(128), KindInt8, NewIntValue(-128)},
+		{NewIntValue(70000), KindInt16, NewIntValue(4464)},
+		{NewIntValue(1 << 31), KindInt32, NewIntValue(-1 << 31)},
+		{NewFloatValue(-2.7), KindInt64, NewIntValue(-2)},
+		{NewIntValue(3), KindFloat64, NewFloatValue(3)},
+		{NewFloatValue(0.1), KindFloat32, NewFloatValue(float64(float32(0.1)))},
+	}
+
+	for _, tt := range tests {
+		got, err := ConvertNumber(tt.value, tt.kind)
+		if err != nil {
+			t.Errorf("ConvertNumber(%s, %s) failed: %v", tt.value.ToString(), tt.kind, err)
+			continue
+		}
+		if !got.Equals(tt.expected) || got.Type != tt.expected.Type {
+			t.Errorf("ConvertNumber(%s, %s) wrong. expected=%s, got=%s",
+				tt.value.ToString(), tt.kind, tt.expected.ToString(), got.ToString())
+		}
+	}
+
+	if _, err := ConvertNumber(NewStringValue("1"), KindInt8); err == nil {
+		t.Errorf("expected error converting a string")
+	}
+}
+
+func TestIterNext(t *testing.T) {
+	arr := NewArray(2)
+	arr.Push(NewIntValue(1))
+	arr.Push(NewIntValue(2))
+
+	tests := []struct {
+		name     string
+		iterable Value
+		expected []Value
+	}{
+		{"array", NewArrayValue(arr), []Value{NewIntValue(1), NewIntValue(2)}},
+		{"string", NewStringValue("aé"), []Value{NewStringValue("a"), NewStringValue("é")}},
+		{"empty string", NewStringValue(""), nil},
+	}
+
+	for _, tt := range tests {
+		var elements []Value
+		cursor := 0
+		for {
+			element, next, ok, err := IterNext(tt.iterable, cursor)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			if !ok {
+				break
+			}
+			elements = append(elements, element)
+			cursor = next
+		}
+
+		if len(elements) != len(tt.expected) {
+			t.Errorf("%s: expected %d elements, got %d", tt.name, len(tt.expected), len(elements))
+			continue
+		}
+		for i := range elements {
+			if !elements[i].Equals(tt.expected[i]) {
+				t.Errorf("%s: element %d wrong. expected=%s, got=%s",
+					tt.name, i, tt.expected[i].ToString(), elements[i].ToString())
+			}
+		}
+	}
+
+	if _, _, _, err := IterNext(NewIntValue(3), 0); err == nil {
+		t.Errorf("expected error iterating an integer")
+	}
+}
+
+func TestObjectKeysInsertionOrder(t *testing.T) {
+	obj := NewObject()
+	for _, key := range []string{"z", "a", "m"} {
+		obj.Set(key, NewIntValue(1))
+	}
+	obj.Set("a", NewIntValue(2)) // updating keeps the original position
+	obj.Delete("z")
+	obj.Set("z", NewIntValue(3)) // re-adding moves the key to the end
+
+	keys, err := KeysOf(NewObjectValue(obj))
+	if err != nil {
+		t.Fatalf("KeysOf failed: %v", err)
+	}
+
+	expected := []string{"a", "m", "z"}
+	if keys.Length() != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), keys.Length())
+	}
+	for i, key := range expected {
+		got, _ := keys.Get(i)
+		if !got.Equals(NewStringValue(key)) {
+			t.Errorf("key %d wrong. expected=%s, got=%s", i, key, got.ToString())
+		}
+	}
+
+	if _, err := KeysOf(NewIntValue(1)); err == nil {
+		t.Errorf("expected error for keys of an integer")
+	}
+}
+
+func TestCoroutineYieldAndResume(t *testing.T) {
+	// function(start) {
+	//     r3 = 10
+	//     r1 = yield start + r3
+	//     r2 = yield r1 + r3
+	//     return start + r1 + r2
+	// }
+	function := NewFunction("co")
+	function.NumParams = 1
+	function.NumLocals = 5
+	function.Constants = []Value{NewIntValue(10)}
+	function.Instructions = []Instruction{
+		CreateABx(OpLoadK, 3, 0),
+		CreateABC(OpAdd, 4, 0, 3),
+		CreateABC(OpYield, 1, 4, 0),
+		CreateABC(OpAdd, 4, 1, 3),
+		CreateABC(OpYield, 2, 4, 0),
+		CreateABC(OpAdd, 4, 0, 1),
+		CreateABC(OpAdd, 4, 4, 2),
+		CreateABC(OpReturn, 4, 1, 0),
+	}
+
+	machine := NewVM()
+	co := NewCoroutine(machine, NewClosure(function))
+
+	steps := []struct {
+		resume   Value
+		expected Value
+		status   CoroutineStatus
+	}{
+		{NewIntValue(1), NewIntValue(11), CoroutineSuspended},
+		{NewIntValue(5), NewIntValue(15), CoroutineSuspended},
+		{NewIntValue(100), NewIntValue(106), CoroutineDead},
+	}
+
+	for i, step := range steps {
+		// Clobber the register file to prove state is restored on resume
+		for r := range machine.Registers {
+			machine.Registers[r] = NewStringValue("garbage")
+		}
+
+		result, err := co.Resume(step.resume)
+		if err != nil {
+			t.Fatalf("resume %d failed: %v", i, err)
+		}
+		if !result.Equals(step.expected) {
+			t.Errorf("resume %d wrong. expected=%s, got=%s", i, step.expected.ToString(), result.ToString())
+		}
+		if co.Status() != step.status {
+			t.Errorf("resume %d: expected status %s, got %s", i, step.status, co.Status())
+		}
+		if machine.FrameIndex != -1 {
+			t.Errorf("resume %d left %d frames on the call stack", i, machine.FrameIndex+1)
+		}
+	}
+
+	if _, err := co.Resume(NilValue); err == nil {
+		t.Errorf("expected error resuming a dead coroutine")
+	}
+}
+
+func TestCoroutineRestoresUpvalues(t *testing.T) {
+	// R(0) is captured by an upvalue, updated while the coroutine is
+	// suspended and returned after it resumes
+	function := NewFunction("co")
+	function.NumLocals = 2
+	function.Instructions = []Instruction{
+		CreateABC(OpYield, 1, 0, 0),
+		CreateABC(OpReturn, 0, 1, 0),
+	}
+
+	machine := NewVM()
+	co := NewCoroutine(machine, NewClosure(function))
+
+	// The coroutine frame starts at register 0 when nothing else is running
+	machine.Registers[0] = NewIntValue(1)
+	captured := NewUpvalue(&machine.Registers[0])
+	machine.OpenUpvalues = append(machine.OpenUpvalues, captured)
+
+	if _, err := co.Resume(NilValue); err != nil {
+		t.Fatalf("first resume failed: %v", err)
+	}
+	if !captured.IsClosed {
+		t.Fatalf("expected upvalue to be closed while suspended")
+	}
+	captured.Set(NewIntValue(42))
+
+	result, err := co.Resume(NilValue)
+	if err != nil {
+		t.Fatalf("second resume failed: %v", err)
+	}
+	if !result.Equals(NewIntValue(42)) {
+		t.Errorf("expected updated upvalue value 42, got %s", result.ToString())
+	}
+}
+
+func TestYieldOutsideCoroutine(t *testing.T) {
+	_, err := runInstructions(nil, CreateABC(OpYield, 0, 1, 0))
+	if err == nil || !strings.Contains(err.Error(), "yield outside of a coroutine") {
+		t.Errorf("expected yield outside of a coroutine error, got %v", err)
+	}
+}
+
+func BenchmarkDeepCallChain(b *testing.B) {
+	machine := NewVM()
+	closure := NewClosure(NewFunction("f"))
+	const depth = 512
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for d := 0; d < depth; d++ {
+			machine.PushFrame(closure, 0, 0, -1, 0)
+		}
+		for d := 0; d < depth; d++ {
+			machine.PopFrame()
+		}
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	for _, i := range []int64{0, 1, -1, math.MaxInt64, math.MinInt64} {
+		if got := NewIntValue(i).AsInt(); got != i {
+			t.Errorf("int %d read back as %d", i, got)
+		}
+	}
+	for _, f := range []float64{0, 2.5, -1e300, math.Inf(-1), math.SmallestNonzeroFloat64} {
+		if got := NewFloatValue(f).AsFloat(); got != f {
+			t.Errorf("float %g read back as %g", f, got)
+		}
+	}
+	if nan := NewFloatValue(math.NaN()); !math.IsNaN(nan.AsFloat()) || nan.Equals(nan) {
+		t.Errorf("NaN should read back as NaN and not equal itself")
+	}
+	if negZero := NewFloatValue(math.Copysign(0, -1)); !math.Signbit(negZero.AsFloat()) || negZero.ToString() != "-0" {
+		t.Errorf("-0 lost its sign: %s", negZero.ToString())
+	}
+	for _, s := range []string{"", "a", "héllo, 世界"} {
+		v := NewStringValue(s)
+		if v.AsString() != s || v.ToString() != s {
+			t.Errorf("string %q read back as %q", s, v.AsString())
+		}
+	}
+	if !NewBoolValue(true).AsBool() || NewBoolValue(false).AsBool() {
+		t.Errorf("booleans read back wrong")
+	}
+
+	// Strings compare by content, heap values by reference
+	if !NewStringValue("ab").Equals(NewStringValue(strings.Clone("ab"))) {
+		t.Errorf("equal strings with separate storage should be equal")
+	}
+	arr := NewArray(0)
+	if !NewArrayValue(arr).Equals(NewArrayValue(arr)) || NewArrayValue(arr).Equals(NewArrayValue(NewArray(0))) {
+		t.Errorf("arrays should compare by reference")
+	}
+	if NewIntValue(1).Equals(NewFloatValue(1)) || NewIntValue(0).Equals(FalseValue) {
+		t.Errorf("values of different types should not be equal")
+	}
+
+	// Integers beyond float precision still compare exactly
+	if cmp, _ := NewIntValue(math.MaxInt64 - 1).Compare(NewIntValue(math.MaxInt64)); cmp != -1 {
+		t.Errorf("expected MaxInt64-1 < MaxInt64, got %d", cmp)
+	}
+}
+
+// BenchmarkIntegerLoop sums the integers below 10 million in a while loop:
+// sum = 0; i = 0; while (i < n) { sum = sum + i; i = i + 1 }
+func BenchmarkIntegerLoop(b *testing.B) {
+	const n = 10000000
+	function := NewFunction("main")
+	function.NumLocals = 5
+	function.Constants = []Value{NewIntValue(n)}
+	function.Instructions = []Instruction{
+		CreateABx(OpLoadInt, 0, BxOffset),
+		CreateABx(OpLoadInt, 1, BxOffset),
+		CreateABx(OpLoadK, 2, 0),
+		CreateABx(OpLoadInt, 3, 1+BxOffset),
+		CreateABC(OpLt, 4, 1, 2),
+		CreateABC(OpTest, 4, 0, 0),
+		CreateABx(OpJmp, 0, 3+BxOffset),
+		CreateABC(OpAdd, 0, 0, 1),
+		CreateABC(OpAdd, 1, 1, 3),
+		CreateABx(OpJmp, 0, -6+BxOffset),
+	}
+	expected := NewIntValue(n * (n - 1) / 2)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		machine := NewVM()
+		if _, err := machine.Execute(NewClosure(function), []Value{}); err != nil {
+			b.Fatalf("execution failed: %v", err)
+		}
+		if result := machine.GetRegister(0); !result.Equals(expected) {
+			b.Fatalf("wrong sum. expected=%s, got=%s", expected.ToString(), result.ToString())
+		}
+	}
+}
+
+func TestStackTraceOnError(t *testing.T) {
+	// fail divides by zero on line 7 when called from line 3 of main
+	fail := NewFunction("fail")
+	fail.NumLocals = 2
+	fail.Instructions = []Instruction{
+		CreateABx(OpLoadInt, 0, 1+BxOffset),
+		CreateABx(OpLoadInt, 1, BxOffset),
+		CreateABC(OpDiv, 0, 0, 1),
+	}
+	fail.LineNumbers = []int{6, 6, 7}
+
+	main := NewFunction("main")
+	main.NumLocals = 1
+	main.Constants = []Value{NewFunctionValue(fail)}
+	main.Instructions = []Instruction{
+		CreateABx(OpLoadK, 0, 0),
+		CreateABC(OpCall, 0, 0, 1),
+		CreateABC(OpHalt, 0, 0, 0),
+	}
+	main.LineNumbers = []int{3, 3, 4}
+
+	_, err := NewVM().Execute(NewClosure(main), []Value{})
+	vmErr, ok := err.(*VMError)
+	if !ok {
+		t.Fatalf("expected *VMError, got %T (%v)", err, err)
+	}
+
+	expected := StackTrace{{Function: "fail", Line: 7}, {Function: "main", Line: 3}}
+	if len(vmErr.Trace) != len(expected) {
+		t.Fatalf("wrong trace: %v", vmErr.Trace)
+	}
+	for i, frame := range expected {
+		if vmErr.Trace[i] != frame {
+			t.Errorf("frame %d: expected %v, got %v", i, frame, vmErr.Trace[i])
+		}
+	}
+	if got := vmErr.Trace.String(); got != "  at fail (line 7)\n  at main (line 3)" {
+		t.Errorf("wrong trace string: %q", got)
+	}
+}
+
+func TestLongStackTraceString(t *testing.T) {
+	// a and b call each other, so no two neighbouring frames are the same
+	var trace StackTrace
+	for i := 0; i < 30; i++ {
+		trace = append(trace, StackFrame{Function: "a", Line: 2}, StackFrame{Function: "b", Line: 5})
+	}
+	trace = append(trace, StackFrame{Function: "main", Line: 7})
+
+	lines := strings.Split(trace.String(), "\n")
+	if len(lines) != 21 {
+		t.Fatalf("expected 21 lines, got %d:\n%s", len(lines), trace)
+	}
+	if lines[0] != "  at a (line 2)" || lines[9] != "  at b (line 5)" {
+		t.Errorf("wrong first lines: %q", lines[:10])
+	}
+	if lines[10] != "  ... 41 more frames" {
+		t.Errorf("wrong elision line: %q", lines[10])
+	}
+	if lines[20] != "  at main (line 7)" {
+		t.Errorf("wrong last line: %q", lines[20])
+	}
+}
+
+func TestNativeFunctionArity(t *testing.T) {
+	machine := NewVM()
+	count := func(vm *VM, args []Value) (Value, error) {
+		return NewIntValue(int64(len(args))), nil
+	}
+	machine.RegisterNativeFunction("one", count, 1, 1)
+	machine.RegisterNativeFunction("range", count, 1, 3)
+	machine.RegisterNativeFunction("many", count, 2, -1)
+
+	tests := []struct {
+		name     string
+		args     int
+		expected string // error message, or "" if the call succeeds
+	}{
+		{"one", 1, ""},
+		{"one", 2, "function 'one' expects 1 argument, got 2"},
+		{"range", 0, "function 'range' expects 1 to 3 arguments, got 0"},
+		{"range", 3, ""},
+		{"range", 4, "function 'range' expects 1 to 3 arguments, got 4"},
+		{"many", 1, "function 'many' expects at least 2 arguments, got 1"},
+		{"many", 10, ""},
+	}
+
+	for _, tt := range tests {
+		args := make([]Value, tt.args)
+		for i := range args {
+			args[i] = NewIntValue(int64(i))
+		}
+		callee := NewNativeFunctionValue(machine.NativeFunctions[tt.name])
+		result, err := machine.Call(callee, args)
+
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("%s with %d args: unexpected error %v", tt.name, tt.args, err)
+			} else if !result.Equals(NewIntValue(int64(tt.args))) {
+				t.Errorf("%s with %d args: wrong result %s", tt.name, tt.args, result.ToString())
+			}
+			continue
+		}
+
+		vmErr, ok := err.(*VMError)
+		if !ok {
+			t.Errorf("%s with %d args: expected *VMError, got %T (%v)", tt.name, tt.args, err, err)
+			continue
+		}
+		if vmErr.Type != ErrInvalidArguments || vmErr.Message != tt.expected {
+			t.Errorf("%s with %d args: expected %s %q, got %s %q",
+				tt.name, tt.args, ErrInvalidArguments, tt.expected, vmErr.Type, vmErr.Message)
+		}
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	function := NewFunction("main")
+	function.NumLocals = 8
+	function.Globals = []string{"print", "console"}
+	function.Constants = []Value{
+		NewStringValue("hello"), NewIntValue(42),
+		NewStringValue("error"), NewStringValue("oops"),
+	}
+	function.Instructions = []Instruction{
+		// print("hello", 42)
+		CreateABx(OpGetGlobal, 0, 0),
+		CreateABx(OpLoadK, 1, 0),
+		CreateABx(OpLoadK, 2, 1),
+		CreateABC(OpCall, 0, 2, 0),
+		// console.error("oops")
+		CreateABx(OpGetGlobal, 0, 1),
+		CreateABx(OpLoadK, 1, 2),
+		CreateABC(OpGetTable, 0, 0, 1),
+		CreateABx(OpLoadK, 1, 3),
+		CreateABC(OpCall, 0, 1, 0),
+		CreateABC(OpHalt, 0, 0, 0),
+	}
+
+	var stdout, stderr bytes.Buffer
+	machine := NewVM()
+	machine.SetOutput(&stdout, &stderr)
+	if _, err := machine.Execute(NewClosure(function), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stdout.String(); got != "hello 42\n" {
+		t.Errorf("stdout = %q, want %q", got, "hello 42\n")
+	}
+	if got := stderr.String(); got != "oops\n" {
+		t.Errorf("stderr = %q, want %q", got, "oops\n")
+	}
+}
+
+func TestGlobalSlots(t *testing.T) {
+	function := NewFunction("main")
+	function.NumLocals = 4
+	function.Globals = []string{"answer", "legacy", "missing"}
+	function.Constants = []Value{NewIntValue(42)}
+	function.Instructions = []Instruction{
+		CreateABx(OpLoadK, 0, 0),
+		CreateABx(OpSetGlobal, 0, 0),
+		CreateABx(OpGetGlobal, 1, 1),
+		CreateABC(OpHalt, 0, 0, 0),
+	}
+
+	machine := NewVM()
+	// A native added to the map directly still resolves by name
+	machine.NativeFunctions["legacy"] = NewNativeFunction("legacy", func(vm *VM, args []Value) (Value, error) {
+		return NilValue, nil
+	}, 0, 0)
+	if _, err := machine.Execute(NewClosure(function), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, ok := machine.GetGlobal("answer"); !ok || !value.Equals(NewIntValue(42)) {
+		t.Errorf("answer = %s (%v), want 42", value.ToString(), ok)
+	}
+	if legacy := machine.GetRegister(1); legacy.Type != TypeNativeFunction {
+		t.Errorf("R(1) = %s, want the legacy native", legacy.ToString())
+	}
+	if _, ok := machine.GetGlobal("missing"); ok {
+		t.Errorf("missing is defined before it is set")
+	}
+
+	// Globals lists script globals but not the registered natives
+	globals := machine.Globals()
+	if _, ok := globals["answer"]; !ok {
+		t.Errorf("Globals() is missing answer")
+	}
+	if _, ok := globals["print"]; ok {
+		t.Errorf("Globals() lists the print native")
+	}
+
+	// The host sees the same slot the script writes
+	machine.SetGlobal("answer", NewIntValue(7))
+	function.Instructions = []Instruction{
+		CreateABx(OpGetGlobal, 0, 0),
+		CreateABx(OpGetGlobal, 1, 2),
+		CreateABC(OpHalt, 0, 0, 0),
+	}
+	_, err := machine.Execute(NewClosure(function), nil)
+	if result := machine.GetRegister(0); !result.Equals(NewIntValue(7)) {
+		t.Errorf("R(0) = %s, want 7", result.ToString())
+	}
+	vmErr, ok := err.(*VMError)
+	if !ok || vmErr.Type != ErrUndefinedVariable || vmErr.Message != "undefined variable: missing" {
+		t.Errorf("expected an undefined variable error for missing, got %v", err)
+	}
+}
+
+func TestCallPassesExactArguments(t *testing.T) {
+	var received []Value
+	record := NewNativeFunctionValue(NewNativeFunction("record", func(vm *VM, args []Value) (Value, error) {
+		received = append([]Value(nil), args...)
+		return NewIntValue(int64(len(args))), nil
+	}, 0, -1))
+
+	constants := []Value{record, NewIntValue(10), NewIntValue(20), NewStringValue("garbage")}
+	machine, err := runInstructions(constants,
+		CreateABx(OpLoadK, 3, 3),
+		CreateABx(OpLoadK, 0, 0),
+		CreateABx(OpLoadK, 1, 1),
+		CreateABx(OpLoadK, 2, 2),
+		CreateABC(OpCall, 0, 2, 1),
+		CreateABC(OpHalt, 0, 0, 0),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received) != 2 || !received[0].Equals(NewIntValue(10)) || !received[1].Equals(NewIntValue(20)) {
+		t.Fatalf("native received %v, want [10 20]", received)
+	}
+	if result := machine.GetRegister(0); !result.Equals(NewIntValue(2)) {
+		t.Errorf("R(0) = %s, want 2", result.ToString())
+	}
+
+	// With no arguments the native sees an empty list even if R(A+1) is set
+	machine, err = runInstructions(constants,
+		CreateABx(OpLoadK, 0, 0),
+		CreateABx(OpLoadK, 1, 3),
+		CreateABC(OpCall, 0, 0, 0),
+		CreateABC(OpHalt, 0, 0, 0),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("native received %v, want no arguments", received)
+	}
+	// C == 0 leaves R(A) untouched
+	if callee := machine.GetRegister(0); callee.Type != TypeNativeFunction {
+		t.Errorf("R(0) = %s, want the callee to survive a discarded call", callee.ToString())
+	}
+}
+
+func TestCallDiscardsUserFunctionResult(t *testing.T) {
+	callee := NewFunction("five")
+	callee.Constants = []Value{NewIntValue(5)}
+	callee.Instructions = []Instruction{
+		CreateABx(OpLoadK, 0, 0),
+		CreateABC(OpReturn, 0, 1, 0),
+	}
+	callee.NumLocals = 1
+
+	machine, err := runInstructions([]Value{NewFunctionValue(callee)},
+		CreateABx(OpLoadK, 0, 0),
+		CreateABC(OpCall, 0, 0, 0),
+		CreateABC(OpHalt, 0, 0, 0),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := machine.GetRegister(0); result.Type != TypeFunction {
+		t.Errorf("R(0) = %s, want the callee to survive a discarded call", result.ToString())
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	inner := NewObject()
+	inner.Set("name", NewStringValue("tg \"script\"\n<1>"))
+	inner.Set("tags", NewArrayValue(&Array{Elements: []Value{NewStringValue("a"), NullValue}}))
+	outer := NewObject()
+	outer.Set("id", NewIntValue(42))
+	outer.Set("ratio", NewFloatValue(0.5))
+	outer.Set("whole", NewFloatValue(2))
+	outer.Set("ok", TrueValue)
+	outer.Set("inner", NewObjectValue(inner))
+	outer.Set("matrix", NewArrayValue(&Array{Elements: []Value{
+		NewArrayValue(&Array{Elements: []Value{NewIntValue(1), NewIntValue(2)}}),
+		NewArrayValue(&Array{Elements: []Value{}}),
+	}}))
+
+	expected := `{"id":42,"ratio":0.5,"whole":2.0,"ok":true,` +
+		`"inner":{"name":"tg \"script\"\n<1>","tags":["a",null]},"matrix":[[1,2],[]]}`
+	text, err := stringifyJSON("JSON.stringify", NewObjectValue(outer))
+	if err != nil {
+		t.Fatalf("stringify failed: %v", err)
+	}
+	if text != expected {
+		t.Fatalf("wrong JSON.\nexpected: %s\ngot:      %s", expected, text)
+	}
+
+	parsed, err := parseJSON(text)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	again, err := stringifyJSON("JSON.stringify", parsed)
+	if err != nil {
+		t.Fatalf("stringify of parsed value failed: %v", err)
+	}
+	if again != expected {
+		t.Errorf("round trip changed the value.\nexpected: %s\ngot:      %s", expected, again)
+	}
+
+	obj := parsed.AsObject()
+	if id, _ := obj.Get("id"); id.Type != TypeInt {
+		t.Errorf("expected id to parse as int, got %s", id.TypeName())
+	}
+	if whole, _ := obj.Get("whole"); whole.Type != TypeFloat {
+		t.Errorf("expected whole to parse as float, got %s", whole.TypeName())
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	for _, text := range []string{"", "{", `{"a" 1}`, "[1,]", "[1] 2", "nope"} {
+		if _, err := parseJSON(text); err == nil {
+			t.Errorf("expected parse error for %q", text)
+		}
+	}
+
+	cyclic := NewArray(1)
+	cyclic.Push(NewArrayValue(cyclic))
+	if _, err := stringifyJSON("JSON.stringify", NewArrayValue(cyclic)); err == nil {
+		t.Errorf("expected error stringifying a cyclic array")
+	}
+	_, err := stringifyJSON("json", NewFunctionValue(NewFunction("f")))
+	if err == nil || !strings.Contains(err.Error(), "json: cannot convert function to JSON") {
+		t.Errorf("expected error stringifying a function, got %v", err)
+	}
+}
+
+func TestDeepEquals(t *testing.T) {
+	array := func(elements ...Value) Value { return NewArrayValue(&Array{Elements: elements}) }
+	object := func(pairs ...Value) Value {
+		obj := NewObject()
+		for i := 0; i < len(pairs); i += 2 {
+			obj.Set(pairs[i].AsString(), pairs[i+1])
+		}
+		return NewObjectValue(obj)
+	}
+	a, b := NewStringValue("a"), NewStringValue("b")
+
+	tests := []struct {
+		left, right Value
+		expected    bool
+	}{
+		{NewIntValue(1), NewIntValue(1), true},
+		{NewIntValue(1), NewFloatValue(1), false},
+		{array(NewIntValue(1), NewIntValue(2)), array(NewIntValue(1), NewIntValue(2)), true},
+		{array(NewIntValue(1), NewIntValue(2)), array(NewIntValue(2), NewIntValue(1)), false},
+		{array(NewIntValue(1)), array(NewIntValue(1), NewIntValue(2)), false},
+		// Objects compare by their properties, whatever the key order
+		{object(a, NewIntValue(1), b, array()), object(b, array(), a, NewIntValue(1)), true},
+		{object(a, NewIntValue(1)), object(a, NewIntValue(2)), false},
+		{object(a, NilValue), object(b, NilValue), false},
+		{array(object(a, array(NewStringValue("x")))), array(object(a, array(NewStringValue("x")))), true},
+		{array(), object(), false},
+	}
+	for _, tt := range tests {
+		if got := tt.left.DeepEquals(tt.right, 8); got != tt.expected {
+			t.Errorf("%s deep equals %s = %v, want %v", tt.left.ToString(), tt.right.ToString(), got, tt.expected)
+		}
+	}
+
+	// Cycles of the same shape are equal
+	first, second := NewArray(1), NewArray(1)
+	first.Push(NewArrayValue(first))
+	second.Push(NewArrayValue(second))
+	if !NewArrayValue(first).DeepEquals(NewArrayValue(second), 8) {
+		t.Errorf("expected cyclic arrays of the same shape to be equal")
+	}
+
+	// Nesting beyond maxDepth is not compared
+	nested := array(array(array(NewIntValue(1))))
+	if nested.DeepEquals(array(array(array(NewIntValue(1)))), 2) {
+		t.Errorf("expected values nested beyond maxDepth to be unequal")
+	}
+}
+
+func TestObjectToStringSortsKeys(t *testing.T) {
+	obj := NewObject()
+	obj.Set("b", NewIntValue(2))
+	obj.Set("a", NewArrayValue(&Array{Elements: []Value{NewIntValue(1)}}))
+	obj.Set("c", NewStringValue("x"))
+	for i := 0; i < 10; i++ {
+		if got := NewObjectValue(obj).ToString(); got != "{a: [1], b: 2, c: x}" {
+			t.Fatalf("ToString() = %s, want {a: [1], b: 2, c: x}", got)
+		}
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	// An infinite loop: JMP -1 jumps back to itself
+	loop := NewFunction("main")
+	loop.NumLocals = 1
+	loop.Instructions = []Instruction{CreateABx(OpJmp, 0, -1+BxOffset)}
+
+	machine := NewVM()
+	machine.MaxSteps = 1000
+	_, err := machine.Execute(NewClosure(loop), []Value{})
+	vmErr, ok := err.(*VMError)
+	if !ok || vmErr.Type != ErrStepLimit {
+		t.Fatalf("expected a %s error, got %v", ErrStepLimit, err)
+	}
+	if !strings.Contains(err.Error(), "step limit exceeded: ran 1000 instructions") {
+		t.Errorf("wrong message: %v", err)
+	}
+
+	// Each top-level Execute gets a fresh budget
+	count := NewFunction("main")
+	count.NumLocals = 2
+	count.Instructions = []Instruction{
+		CreateABx(OpLoadInt, 0, BxOffset),
+		CreateABx(OpLoadInt, 1, 1+BxOffset),
+		CreateABC(OpAdd, 0, 0, 1),
+		CreateABC(OpAdd, 0, 0, 1),
+		CreateABC(OpHalt, 0, 0, 0),
+	}
+	machine.MaxSteps = 5
+	for i := 0; i < 2; i++ {
+		result, err := machine.Execute(NewClosure(count), []Value{})
+		if err != nil {
+			t.Fatalf("run %d: unexpected error: %v", i+1, err)
+		}
+		if !result.Equals(NewIntValue(2)) {
+			t.Errorf("run %d: expected 2, got %s", i+1, result.ToString())
+		}
+	}
+	machine.MaxSteps = 4
+	if _, err := machine.Execute(NewClosure(count), []Value{}); err == nil {
+		t.Error("expected the step limit to stop a 5 instruction run")
+	}
+}